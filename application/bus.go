@@ -1,15 +1,20 @@
 package application
 
 import (
+	"context"
 	"errors"
 	"reflect"
+
+	"escama/domain/events"
+
+	"github.com/google/uuid"
 )
 
 type Command interface{}
 type Query interface{}
 
 type CommandHandler interface {
-	Handle(Command) error
+	Handle(ctx context.Context, cmd Command) error
 }
 
 type QueryHandler interface {
@@ -29,13 +34,25 @@ func (cb *CommandBus) Register(cmd Command, handler CommandHandler) {
 	cb.handlers[cmdType] = handler
 }
 
-func (cb *CommandBus) Dispatch(cmd Command) error {
+// Dispatch ejecuta el comando con su handler. Cada comando recibe un ID propio que queda
+// como causa de los eventos que genere; si el contexto no trae una correlación, el
+// comando inicia una nueva con ese mismo ID. El resto de la metadata (actor, fuente,
+// versión del cliente) la aporta quien invoca al bus.
+func (cb *CommandBus) Dispatch(ctx context.Context, cmd Command) error {
 	cmdType := reflect.TypeOf(cmd)
 	handler, ok := cb.handlers[cmdType]
 	if !ok {
 		return errors.New("no command handler registered for type: " + cmdType.String())
 	}
-	return handler.Handle(cmd)
+
+	commandID := uuid.New().String()
+	metadata := events.MetadataFromContext(ctx)
+	if metadata.CorrelationID == "" {
+		metadata.CorrelationID = commandID
+	}
+	metadata.CausationID = commandID
+
+	return handler.Handle(events.WithMetadata(ctx, metadata), cmd)
 }
 
 type QueryBus struct {
@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"escama/domain"
+	"escama/domain/money"
+)
+
+// ErrAccountRequired se devuelve al registrar un gasto o ingreso sin cuenta
+var ErrAccountRequired = errors.New("account is required")
+
+// ErrAccountNotFound se devuelve cuando la cuenta indicada no existe
+var ErrAccountNotFound = errors.New("account not found")
+
+// LoadAccountFunc carga una cuenta por ID; devuelve nil si no existe
+type LoadAccountFunc func(ctx context.Context, id string) (*domain.Account, error)
+
+// checkAccount verifica que el movimiento por amount se pueda registrar en la cuenta:
+// que exista, esté abierta y use la moneda del importe
+func checkAccount(ctx context.Context, load LoadAccountFunc, accountID string, amount money.Money) error {
+	if accountID == "" {
+		return ErrAccountRequired
+	}
+	if load == nil {
+		return fmt.Errorf("no account loader configured")
+	}
+
+	account, err := load(ctx, accountID)
+	if err != nil {
+		return fmt.Errorf("failed to load account: %w", err)
+	}
+	if account == nil {
+		return fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+	}
+	return account.Accepts(amount)
+}
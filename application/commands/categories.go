@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"escama/domain"
+)
+
+// ErrCategoryNotFound se devuelve cuando la categoría indicada no existe
+var ErrCategoryNotFound = errors.New("category not found")
+
+// LoadCategoryFunc carga una categoría por ID; devuelve nil si no existe
+type LoadCategoryFunc func(ctx context.Context, id string) (*domain.Category, error)
+
+// checkCategory verifica que se pueda registrar un movimiento del tipo indicado en la
+// categoría: que exista, no esté eliminada y sea de ese tipo o de ambos. Los movimientos
+// sin categoría no se verifican.
+func checkCategory(ctx context.Context, load LoadCategoryFunc, categoryID, movementType string) error {
+	if categoryID == "" {
+		return nil
+	}
+	if load == nil {
+		return fmt.Errorf("no category loader configured")
+	}
+
+	category, err := load(ctx, categoryID)
+	if err != nil {
+		return fmt.Errorf("failed to load category: %w", err)
+	}
+	if category == nil {
+		return fmt.Errorf("%w: %s", ErrCategoryNotFound, categoryID)
+	}
+	return category.Accepts(movementType)
+}
+
+// loadWithAncestors carga la categoría indicada junto con los IDs de sus ancestros, para
+// validar que la jerarquía no forme ciclos. Sin id devuelve nil.
+func loadWithAncestors(ctx context.Context, load LoadCategoryFunc, id string) (*domain.Category, []string, error) {
+	if id == "" {
+		return nil, nil, nil
+	}
+	if load == nil {
+		return nil, nil, fmt.Errorf("no category loader configured")
+	}
+
+	category, err := load(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load category %s: %w", id, err)
+	}
+	if category == nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrCategoryNotFound, id)
+	}
+
+	ancestors, err := categoryAncestors(ctx, load, category)
+	if err != nil {
+		return nil, nil, err
+	}
+	return category, ancestors, nil
+}
+
+// categoryAncestors devuelve los IDs de los ancestros de la categoría, del padre hacia
+// arriba. Un ancestro fusionado cuenta como la categoría que lo absorbió.
+func categoryAncestors(ctx context.Context, load LoadCategoryFunc, category *domain.Category) ([]string, error) {
+	var ancestors []string
+	seen := map[string]bool{category.ID: true}
+
+	for next := category.ParentID; next != ""; {
+		if seen[next] {
+			return nil, fmt.Errorf("%w: %s", domain.ErrCategoryCycle, next)
+		}
+		seen[next] = true
+
+		ancestor, err := load(ctx, next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load category %s: %w", next, err)
+		}
+		if ancestor == nil {
+			break
+		}
+		ancestors = append(ancestors, ancestor.ID)
+
+		if ancestor.MergedInto != "" {
+			next = ancestor.MergedInto
+		} else {
+			next = ancestor.ParentID
+		}
+	}
+	return ancestors, nil
+}
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"context"
+	"time"
+
+	"escama/domain"
+	"escama/domain/money"
+)
+
+type CreateAccountCommand struct {
+	ID             *string
+	Name           string
+	Kind           string // bank, cash o card
+	Currency       string
+	OpeningBalance *money.Money // opcional: saldo al comienzo de OpeningDate
+	OpeningDate    time.Time
+	IdempotencyKey string // opcional: los reintentos con la misma clave no crean otro agregado
+}
+
+type CreateAccountHandler struct {
+	Save      func(ctx context.Context, account *domain.Account) error
+	Processed ProcessedFunc
+}
+
+func (h *CreateAccountHandler) Handle(ctx context.Context, cmd CreateAccountCommand) error {
+	id := newAggregateID(cmd.ID, "Account", cmd.IdempotencyKey)
+	account, err := domain.NewAccount(id, cmd.Name, cmd.Kind, cmd.Currency)
+	if err != nil {
+		return err
+	}
+
+	// El saldo inicial se guarda en la misma escritura que la creación
+	if cmd.OpeningBalance != nil {
+		if err := account.SetOpeningBalance(*cmd.OpeningBalance, cmd.OpeningDate); err != nil {
+			return err
+		}
+	}
+
+	// Los eventos quedan en el outbox del Event Store; el relay se encarga de publicarlos
+	err = h.Save(withIdempotencyKey(ctx, cmd.IdempotencyKey), account)
+	return replayed(ctx, h.Processed, id, cmd.IdempotencyKey, err)
+}
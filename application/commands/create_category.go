@@ -4,35 +4,35 @@ import (
 	"context"
 
 	"escama/domain"
-	"escama/domain/events"
-
-	"github.com/google/uuid"
 )
 
 type CreateCategoryCommand struct {
-	ID   *string
-	Name string
+	ID             *string
+	Name           string
+	ParentID       string // opcional: categoría padre; vacío crea una de primer nivel
+	Kind           string // income, expense o both (por defecto)
+	IdempotencyKey string // opcional: los reintentos con la misma clave no crean otro agregado
 }
 
 type CreateCategoryHandler struct {
-	Save    func(ctx context.Context, category *domain.Category) error
-	Publish func(ctx context.Context, events []events.DomainEvent) error
+	Save         func(ctx context.Context, category *domain.Category) error
+	Processed    ProcessedFunc
+	LoadCategory LoadCategoryFunc // carga la categoría padre
 }
 
 func (h *CreateCategoryHandler) Handle(ctx context.Context, cmd CreateCategoryCommand) error {
-	if cmd.ID == nil {
-		id := uuid.New().String()
-		cmd.ID = &id
-	}
-	category := domain.NewCategory(*cmd.ID, cmd.Name)
-
-	if err := h.Save(ctx, category); err != nil {
+	id := newAggregateID(cmd.ID, "Category", cmd.IdempotencyKey)
+	parent, _, err := loadWithAncestors(ctx, h.LoadCategory, cmd.ParentID)
+	if err != nil {
 		return err
 	}
 
-	if err := h.Publish(ctx, category.UncommittedEvents()); err != nil {
+	category, err := domain.NewCategory(id, cmd.Name, cmd.Kind, parent)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	// Los eventos quedan en el outbox del Event Store; el relay se encarga de publicarlos
+	err = h.Save(withIdempotencyKey(ctx, cmd.IdempotencyKey), category)
+	return replayed(ctx, h.Processed, id, cmd.IdempotencyKey, err)
 }
@@ -5,39 +5,41 @@ import (
 	"time"
 
 	"escama/domain"
-	"escama/domain/events"
-
-	"github.com/google/uuid"
+	"escama/domain/money"
 )
 
 type CreateExpenseCommand struct {
-	ID          *string
-	Name        string
-	CategoryID  string
-	Amount      float64
-	Description *string
-	Date        time.Time
+	ID             *string
+	Name           string
+	AccountID      string
+	CategoryID     string
+	Amount         money.Money
+	Description    *string
+	Date           time.Time
+	IdempotencyKey string // opcional: los reintentos con la misma clave no crean otro agregado
 }
 
 type CreateExpenseHandler struct {
-	Save    func(ctx context.Context, expense *domain.Expense) error
-	Publish func(ctx context.Context, events []events.DomainEvent) error
+	Save      func(ctx context.Context, expense *domain.Expense) error
+	Processed ProcessedFunc
+	// LoadAccount carga la cuenta del movimiento para verificar que acepte el importe
+	LoadAccount LoadAccountFunc
+	// LoadCategory carga la categoría para verificar que admita gastos
+	LoadCategory LoadCategoryFunc
 }
 
 func (h *CreateExpenseHandler) Handle(ctx context.Context, cmd CreateExpenseCommand) error {
-	if cmd.ID == nil {
-		id := uuid.New().String()
-		cmd.ID = &id
-	}
-	expense := domain.NewExpense(*cmd.ID, cmd.CategoryID, cmd.Amount, cmd.Description, cmd.Date)
-
-	if err := h.Save(ctx, expense); err != nil {
+	if err := checkAccount(ctx, h.LoadAccount, cmd.AccountID, cmd.Amount); err != nil {
 		return err
 	}
-
-	if err := h.Publish(ctx, expense.UncommittedEvents()); err != nil {
+	if err := checkCategory(ctx, h.LoadCategory, cmd.CategoryID, "expense"); err != nil {
 		return err
 	}
 
-	return nil
+	id := newAggregateID(cmd.ID, "Expense", cmd.IdempotencyKey)
+	expense := domain.NewExpense(id, cmd.AccountID, cmd.CategoryID, cmd.Amount, cmd.Description, cmd.Date)
+
+	// Los eventos quedan en el outbox del Event Store; el relay se encarga de publicarlos
+	err := h.Save(withIdempotencyKey(ctx, cmd.IdempotencyKey), expense)
+	return replayed(ctx, h.Processed, id, cmd.IdempotencyKey, err)
 }
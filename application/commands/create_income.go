@@ -5,38 +5,40 @@ import (
 	"time"
 
 	"escama/domain"
-	"escama/domain/events"
-
-	"github.com/google/uuid"
+	"escama/domain/money"
 )
 
 type CreateIncomeCommand struct {
-	ID          *string
-	CategoryID  string
-	Amount      float64
-	Description *string
-	Date        time.Time
+	ID             *string
+	AccountID      string
+	CategoryID     string
+	Amount         money.Money
+	Description    *string
+	Date           time.Time
+	IdempotencyKey string // opcional: los reintentos con la misma clave no crean otro agregado
 }
 
 type CreateIncomeHandler struct {
-	Save    func(ctx context.Context, income *domain.Income) error
-	Publish func(ctx context.Context, events []events.DomainEvent) error
+	Save      func(ctx context.Context, income *domain.Income) error
+	Processed ProcessedFunc
+	// LoadAccount carga la cuenta del movimiento para verificar que acepte el importe
+	LoadAccount LoadAccountFunc
+	// LoadCategory carga la categoría para verificar que admita ingresos
+	LoadCategory LoadCategoryFunc
 }
 
 func (h *CreateIncomeHandler) Handle(ctx context.Context, cmd CreateIncomeCommand) error {
-	if cmd.ID == nil {
-		id := uuid.New().String()
-		cmd.ID = &id
-	}
-	income := domain.NewIncome(*cmd.ID, cmd.CategoryID, cmd.Amount, cmd.Description, cmd.Date)
-
-	if err := h.Save(ctx, income); err != nil {
+	if err := checkAccount(ctx, h.LoadAccount, cmd.AccountID, cmd.Amount); err != nil {
 		return err
 	}
-
-	if err := h.Publish(ctx, income.UncommittedEvents()); err != nil {
+	if err := checkCategory(ctx, h.LoadCategory, cmd.CategoryID, "income"); err != nil {
 		return err
 	}
 
-	return nil
+	id := newAggregateID(cmd.ID, "Income", cmd.IdempotencyKey)
+	income := domain.NewIncome(id, cmd.AccountID, cmd.CategoryID, cmd.Amount, cmd.Description, cmd.Date)
+
+	// Los eventos quedan en el outbox del Event Store; el relay se encarga de publicarlos
+	err := h.Save(withIdempotencyKey(ctx, cmd.IdempotencyKey), income)
+	return replayed(ctx, h.Processed, id, cmd.IdempotencyKey, err)
 }
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"context"
+	"time"
+
+	"escama/domain"
+	"escama/domain/money"
+)
+
+type CreateTransferCommand struct {
+	ID             *string
+	FromAccountID  string
+	ToAccountID    string
+	Amount         money.Money  // importe que sale de la cuenta origen
+	ReceivedAmount *money.Money // opcional: importe que entra en la cuenta destino si su moneda es otra
+	Description    *string
+	Date           time.Time
+	IdempotencyKey string // opcional: los reintentos con la misma clave no crean otro agregado
+}
+
+type CreateTransferHandler struct {
+	Save      func(ctx context.Context, transfer *domain.Transfer) error
+	Processed ProcessedFunc
+	// LoadAccount carga las cuentas de la transferencia para verificar que acepten los importes
+	LoadAccount LoadAccountFunc
+}
+
+func (h *CreateTransferHandler) Handle(ctx context.Context, cmd CreateTransferCommand) error {
+	receivedAmount := receivedOrSent(cmd.Amount, cmd.ReceivedAmount)
+	if err := checkTransferAccounts(ctx, h.LoadAccount, cmd.FromAccountID, cmd.ToAccountID, cmd.Amount, receivedAmount); err != nil {
+		return err
+	}
+
+	id := newAggregateID(cmd.ID, "Transfer", cmd.IdempotencyKey)
+	transfer, err := domain.NewTransfer(id, cmd.FromAccountID, cmd.ToAccountID, cmd.Amount, receivedAmount, cmd.Description, cmd.Date)
+	if err != nil {
+		return err
+	}
+
+	// Los eventos quedan en el outbox del Event Store; el relay se encarga de publicarlos
+	err = h.Save(withIdempotencyKey(ctx, cmd.IdempotencyKey), transfer)
+	return replayed(ctx, h.Processed, id, cmd.IdempotencyKey, err)
+}
+
+// receivedOrSent devuelve el importe recibido por la cuenta destino: el indicado o, si
+// no se indicó, el mismo que sale de la cuenta origen
+func receivedOrSent(amount money.Money, received *money.Money) money.Money {
+	if received == nil {
+		return amount
+	}
+	return *received
+}
+
+// checkTransferAccounts verifica que las dos cuentas acepten sus importes
+func checkTransferAccounts(ctx context.Context, load LoadAccountFunc, fromAccountID, toAccountID string, amount, receivedAmount money.Money) error {
+	if err := checkAccount(ctx, load, fromAccountID, amount); err != nil {
+		return err
+	}
+	return checkAccount(ctx, load, toAccountID, receivedAmount)
+}
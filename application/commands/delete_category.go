@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"escama/infrastructure/repositories"
+)
+
+type DeleteCategoryCommand struct {
+	ID             string
+	IdempotencyKey string // opcional: un reintento con la misma clave no vuelve a aplicarse
+}
+
+type DeleteCategoryHandler struct {
+	Repository *repositories.CategoryRepository
+}
+
+func (h *DeleteCategoryHandler) Handle(ctx context.Context, cmd DeleteCategoryCommand) error {
+	// Un reintento de un comando ya aplicado termina sin agregar eventos
+	if cmd.IdempotencyKey != "" {
+		done, err := h.Repository.Processed(ctx, cmd.ID, cmd.IdempotencyKey)
+		if err != nil {
+			return fmt.Errorf("failed to check idempotency key: %w", err)
+		}
+		if done {
+			return nil
+		}
+	}
+
+	category, err := h.Repository.GetByID(ctx, cmd.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load category: %w", err)
+	}
+
+	if category == nil {
+		return fmt.Errorf("%w: %s", ErrCategoryNotFound, cmd.ID)
+	}
+
+	// Eliminar la categoría; sus movimientos la conservan con su último nombre
+	if err := category.Delete(); err != nil {
+		return err
+	}
+
+	// Guardar cambios; los eventos quedan en el outbox y el relay los publica
+	err = h.Repository.Save(withIdempotencyKey(ctx, cmd.IdempotencyKey), category)
+	if err := replayed(ctx, h.Repository.Processed, cmd.ID, cmd.IdempotencyKey, err); err != nil {
+		return fmt.Errorf("failed to save category: %w", err)
+	}
+
+	return nil
+}
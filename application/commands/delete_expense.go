@@ -4,20 +4,30 @@ import (
 	"context"
 	"fmt"
 
-	"escama/domain/events"
 	"escama/infrastructure/repositories"
 )
 
 type DeleteExpenseCommand struct {
-	ID string
+	ID             string
+	IdempotencyKey string // opcional: un reintento con la misma clave no vuelve a aplicarse
 }
 
 type DeleteExpenseHandler struct {
 	Repository *repositories.ExpenseRepository
-	Publish    func(ctx context.Context, events []events.DomainEvent) error
 }
 
 func (h *DeleteExpenseHandler) Handle(ctx context.Context, cmd DeleteExpenseCommand) error {
+	// Un reintento de un comando ya aplicado termina sin agregar eventos
+	if cmd.IdempotencyKey != "" {
+		done, err := h.Repository.Processed(ctx, cmd.ID, cmd.IdempotencyKey)
+		if err != nil {
+			return fmt.Errorf("failed to check idempotency key: %w", err)
+		}
+		if done {
+			return nil
+		}
+	}
+
 	// Cargar el gasto existente
 	expense, err := h.Repository.GetByID(ctx, cmd.ID)
 	if err != nil {
@@ -31,15 +41,11 @@ func (h *DeleteExpenseHandler) Handle(ctx context.Context, cmd DeleteExpenseComm
 	// Eliminar el gasto
 	expense.Delete()
 
-	// Guardar cambios
-	if err := h.Repository.Save(ctx, expense); err != nil {
+	// Guardar cambios; los eventos quedan en el outbox y el relay los publica
+	err = h.Repository.Save(withIdempotencyKey(ctx, cmd.IdempotencyKey), expense)
+	if err := replayed(ctx, h.Repository.Processed, cmd.ID, cmd.IdempotencyKey, err); err != nil {
 		return fmt.Errorf("failed to save expense: %w", err)
 	}
 
-	// Publicar eventos
-	if err := h.Publish(ctx, expense.UncommittedEvents()); err != nil {
-		return fmt.Errorf("failed to publish events: %w", err)
-	}
-
 	return nil
 }
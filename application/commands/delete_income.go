@@ -4,20 +4,30 @@ import (
 	"context"
 	"fmt"
 
-	"escama/domain/events"
 	"escama/infrastructure/repositories"
 )
 
 type DeleteIncomeCommand struct {
-	ID string
+	ID             string
+	IdempotencyKey string // opcional: un reintento con la misma clave no vuelve a aplicarse
 }
 
 type DeleteIncomeHandler struct {
 	Repository *repositories.IncomeRepository
-	Publish    func(ctx context.Context, events []events.DomainEvent) error
 }
 
 func (h *DeleteIncomeHandler) Handle(ctx context.Context, cmd DeleteIncomeCommand) error {
+	// Un reintento de un comando ya aplicado termina sin agregar eventos
+	if cmd.IdempotencyKey != "" {
+		done, err := h.Repository.Processed(ctx, cmd.ID, cmd.IdempotencyKey)
+		if err != nil {
+			return fmt.Errorf("failed to check idempotency key: %w", err)
+		}
+		if done {
+			return nil
+		}
+	}
+
 	// Cargar el ingreso existente
 	income, err := h.Repository.GetByID(ctx, cmd.ID)
 	if err != nil {
@@ -31,15 +41,11 @@ func (h *DeleteIncomeHandler) Handle(ctx context.Context, cmd DeleteIncomeComman
 	// Eliminar el ingreso
 	income.Delete()
 
-	// Guardar cambios
-	if err := h.Repository.Save(ctx, income); err != nil {
+	// Guardar cambios; los eventos quedan en el outbox y el relay los publica
+	err = h.Repository.Save(withIdempotencyKey(ctx, cmd.IdempotencyKey), income)
+	if err := replayed(ctx, h.Repository.Processed, cmd.ID, cmd.IdempotencyKey, err); err != nil {
 		return fmt.Errorf("failed to save income: %w", err)
 	}
 
-	// Publicar eventos
-	if err := h.Publish(ctx, income.UncommittedEvents()); err != nil {
-		return fmt.Errorf("failed to publish events: %w", err)
-	}
-
 	return nil
 }
@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"escama/infrastructure/repositories"
+)
+
+type DeleteTransferCommand struct {
+	ID             string
+	IdempotencyKey string // opcional: un reintento con la misma clave no vuelve a aplicarse
+}
+
+type DeleteTransferHandler struct {
+	Repository *repositories.TransferRepository
+}
+
+func (h *DeleteTransferHandler) Handle(ctx context.Context, cmd DeleteTransferCommand) error {
+	// Un reintento de un comando ya aplicado termina sin agregar eventos
+	if cmd.IdempotencyKey != "" {
+		done, err := h.Repository.Processed(ctx, cmd.ID, cmd.IdempotencyKey)
+		if err != nil {
+			return fmt.Errorf("failed to check idempotency key: %w", err)
+		}
+		if done {
+			return nil
+		}
+	}
+
+	// Cargar la transferencia existente
+	transfer, err := h.Repository.GetByID(ctx, cmd.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load transfer: %w", err)
+	}
+
+	if transfer == nil {
+		return fmt.Errorf("transfer not found: %s", cmd.ID)
+	}
+
+	// Eliminar la transferencia
+	transfer.Delete()
+
+	// Guardar cambios; los eventos quedan en el outbox y el relay los publica
+	err = h.Repository.Save(withIdempotencyKey(ctx, cmd.IdempotencyKey), transfer)
+	if err := replayed(ctx, h.Repository.Processed, cmd.ID, cmd.IdempotencyKey, err); err != nil {
+		return fmt.Errorf("failed to save transfer: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"context"
+	"errors"
+
+	"escama/domain/events"
+	"escama/infrastructure/eventstore"
+
+	"github.com/google/uuid"
+)
+
+// ProcessedFunc indica si el stream del agregado ya tiene eventos de un comando con la
+// clave de idempotencia dada
+type ProcessedFunc func(ctx context.Context, aggregateID, key string) (bool, error)
+
+// IdempotentID devuelve el ID del agregado que crea un comando con clave de idempotencia.
+// Es el mismo en cada reintento, de modo que un reintento choca con el stream ya creado
+// en lugar de crear un agregado nuevo.
+func IdempotentID(aggregateType, key string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("escama:"+aggregateType+":"+key)).String()
+}
+
+// newAggregateID devuelve el ID del agregado que crea el comando: el indicado, el derivado
+// de la clave de idempotencia o uno nuevo
+func newAggregateID(id *string, aggregateType, key string) string {
+	switch {
+	case id != nil:
+		return *id
+	case key != "":
+		return IdempotentID(aggregateType, key)
+	default:
+		return uuid.New().String()
+	}
+}
+
+// withIdempotencyKey agrega la clave a la metadata con que se guardan los eventos del
+// comando: así queda registrado como procesado en la misma escritura
+func withIdempotencyKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	metadata := events.MetadataFromContext(ctx)
+	metadata.IdempotencyKey = key
+	return events.WithMetadata(ctx, metadata)
+}
+
+// replayed interpreta el error al guardar un comando con clave de idempotencia. Si el
+// stream cambió porque ya tiene los eventos de esa misma clave, el comando es un reintento
+// ya procesado y termina sin error, igual que la primera vez.
+func replayed(ctx context.Context, processed ProcessedFunc, aggregateID, key string, err error) error {
+	if key == "" || processed == nil || !errors.Is(err, eventstore.ErrConcurrencyConflict) {
+		return err
+	}
+
+	done, lookupErr := processed(ctx, aggregateID, key)
+	if lookupErr != nil || !done {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"escama/domain"
+	"escama/domain/money"
+	"escama/infrastructure/eventstore"
+	"escama/infrastructure/repositories"
+)
+
+func TestReplayedKeyDoesNotAppendEvents(t *testing.T) {
+	ctx := context.Background()
+	store := eventstore.NewInMemoryEventStore()
+	expenses := repositories.NewExpenseRepository(store)
+
+	create := &CreateExpenseHandler{
+		Save:      expenses.Save,
+		Processed: expenses.Processed,
+		LoadAccount: func(ctx context.Context, id string) (*domain.Account, error) {
+			return &domain.Account{ID: id, Name: "Banco", Currency: money.PYG}, nil
+		},
+	}
+	tag := &TagExpenseHandler{Repository: expenses}
+	untag := &UntagExpenseHandler{Repository: expenses}
+
+	// storedEvents devuelve la cantidad de eventos del store, de todos los streams
+	storedEvents := func() int {
+		all, err := store.GetAllEvents(ctx, eventstore.TimeFilter{})
+		if err != nil {
+			t.Fatalf("GetAllEvents: %v", err)
+		}
+		return len(all)
+	}
+
+	createCmd := CreateExpenseCommand{
+		AccountID:      "banco",
+		Amount:         money.New(50000, money.PYG),
+		Date:           time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+		IdempotencyKey: "crear-1",
+	}
+	for attempt := 1; attempt <= 2; attempt++ {
+		if err := create.Handle(ctx, createCmd); err != nil {
+			t.Fatalf("create attempt %d: %v", attempt, err)
+		}
+	}
+	if got := storedEvents(); got != 1 {
+		t.Fatalf("after replaying create: %d events, want 1", got)
+	}
+
+	id := IdempotentID("Expense", "crear-1")
+	tagCmd := TagExpenseCommand{ID: id, Tags: []string{"viaje"}, IdempotencyKey: "etiquetar-1"}
+	if err := tag.Handle(ctx, tagCmd); err != nil {
+		t.Fatalf("tag: %v", err)
+	}
+	if err := untag.Handle(ctx, UntagExpenseCommand{ID: id, Tags: []string{"viaje"}, IdempotencyKey: "quitar-1"}); err != nil {
+		t.Fatalf("untag: %v", err)
+	}
+
+	// El reintento del primer comando termina sin error y no vuelve a etiquetar el gasto
+	if err := tag.Handle(ctx, tagCmd); err != nil {
+		t.Fatalf("replayed tag: %v", err)
+	}
+	if got := storedEvents(); got != 3 {
+		t.Errorf("after replaying tag: %d events, want 3", got)
+	}
+
+	expense, err := expenses.GetByID(ctx, id)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if expense == nil || expense.Amount != createCmd.Amount || len(expense.Tags) != 0 {
+		t.Errorf("expense = %+v, want the created amount and no tags", expense)
+	}
+}
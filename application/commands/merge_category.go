@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"escama/infrastructure/repositories"
+)
+
+type MergeCategoryCommand struct {
+	ID             string
+	TargetID       string // categoría que absorbe los movimientos
+	IdempotencyKey string // opcional: un reintento con la misma clave no vuelve a aplicarse
+}
+
+type MergeCategoryHandler struct {
+	Repository *repositories.CategoryRepository
+}
+
+func (h *MergeCategoryHandler) Handle(ctx context.Context, cmd MergeCategoryCommand) error {
+	// Un reintento de un comando ya aplicado termina sin agregar eventos
+	if cmd.IdempotencyKey != "" {
+		done, err := h.Repository.Processed(ctx, cmd.ID, cmd.IdempotencyKey)
+		if err != nil {
+			return fmt.Errorf("failed to check idempotency key: %w", err)
+		}
+		if done {
+			return nil
+		}
+	}
+
+	category, err := h.Repository.GetByID(ctx, cmd.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load category: %w", err)
+	}
+
+	if category == nil {
+		return fmt.Errorf("%w: %s", ErrCategoryNotFound, cmd.ID)
+	}
+
+	target, targetAncestors, err := loadWithAncestors(ctx, h.Repository.GetByID, cmd.TargetID)
+	if err != nil {
+		return err
+	}
+
+	// Fusionar: las proyecciones pasan los movimientos y las subcategorías a target
+	if err := category.MergeInto(target, targetAncestors); err != nil {
+		return err
+	}
+
+	// Guardar cambios; los eventos quedan en el outbox y el relay los publica
+	err = h.Repository.Save(withIdempotencyKey(ctx, cmd.IdempotencyKey), category)
+	if err := replayed(ctx, h.Repository.Processed, cmd.ID, cmd.IdempotencyKey, err); err != nil {
+		return fmt.Errorf("failed to save category: %w", err)
+	}
+
+	return nil
+}
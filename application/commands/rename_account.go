@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"escama/infrastructure/repositories"
+)
+
+type RenameAccountCommand struct {
+	ID             string
+	Name           string
+	IdempotencyKey string // opcional: un reintento con la misma clave no vuelve a aplicarse
+}
+
+type RenameAccountHandler struct {
+	Repository *repositories.AccountRepository
+}
+
+func (h *RenameAccountHandler) Handle(ctx context.Context, cmd RenameAccountCommand) error {
+	// Un reintento de un comando ya aplicado termina sin agregar eventos
+	if cmd.IdempotencyKey != "" {
+		done, err := h.Repository.Processed(ctx, cmd.ID, cmd.IdempotencyKey)
+		if err != nil {
+			return fmt.Errorf("failed to check idempotency key: %w", err)
+		}
+		if done {
+			return nil
+		}
+	}
+
+	account, err := h.Repository.GetByID(ctx, cmd.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load account: %w", err)
+	}
+
+	if account == nil {
+		return fmt.Errorf("%w: %s", ErrAccountNotFound, cmd.ID)
+	}
+
+	if err := account.Rename(cmd.Name); err != nil {
+		return err
+	}
+
+	// Guardar cambios; los eventos quedan en el outbox y el relay los publica
+	err = h.Repository.Save(withIdempotencyKey(ctx, cmd.IdempotencyKey), account)
+	if err := replayed(ctx, h.Repository.Processed, cmd.ID, cmd.IdempotencyKey, err); err != nil {
+		return fmt.Errorf("failed to save account: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"escama/infrastructure/repositories"
+)
+
+type SetCategoryKindCommand struct {
+	ID             string
+	Kind           string // income, expense o both
+	IdempotencyKey string // opcional: un reintento con la misma clave no vuelve a aplicarse
+}
+
+type SetCategoryKindHandler struct {
+	Repository *repositories.CategoryRepository
+}
+
+func (h *SetCategoryKindHandler) Handle(ctx context.Context, cmd SetCategoryKindCommand) error {
+	// Un reintento de un comando ya aplicado termina sin agregar eventos
+	if cmd.IdempotencyKey != "" {
+		done, err := h.Repository.Processed(ctx, cmd.ID, cmd.IdempotencyKey)
+		if err != nil {
+			return fmt.Errorf("failed to check idempotency key: %w", err)
+		}
+		if done {
+			return nil
+		}
+	}
+
+	category, err := h.Repository.GetByID(ctx, cmd.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load category: %w", err)
+	}
+
+	if category == nil {
+		return fmt.Errorf("%w: %s", ErrCategoryNotFound, cmd.ID)
+	}
+
+	if err := category.SetKind(cmd.Kind); err != nil {
+		return err
+	}
+
+	// Guardar cambios; los eventos quedan en el outbox y el relay los publica
+	err = h.Repository.Save(withIdempotencyKey(ctx, cmd.IdempotencyKey), category)
+	if err := replayed(ctx, h.Repository.Processed, cmd.ID, cmd.IdempotencyKey, err); err != nil {
+		return fmt.Errorf("failed to save category: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"escama/infrastructure/repositories"
+)
+
+type SetCategoryParentCommand struct {
+	ID             string
+	ParentID       string // vacío: la categoría pasa a ser de primer nivel
+	IdempotencyKey string // opcional: un reintento con la misma clave no vuelve a aplicarse
+}
+
+type SetCategoryParentHandler struct {
+	Repository *repositories.CategoryRepository
+}
+
+func (h *SetCategoryParentHandler) Handle(ctx context.Context, cmd SetCategoryParentCommand) error {
+	// Un reintento de un comando ya aplicado termina sin agregar eventos
+	if cmd.IdempotencyKey != "" {
+		done, err := h.Repository.Processed(ctx, cmd.ID, cmd.IdempotencyKey)
+		if err != nil {
+			return fmt.Errorf("failed to check idempotency key: %w", err)
+		}
+		if done {
+			return nil
+		}
+	}
+
+	category, err := h.Repository.GetByID(ctx, cmd.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load category: %w", err)
+	}
+
+	if category == nil {
+		return fmt.Errorf("%w: %s", ErrCategoryNotFound, cmd.ID)
+	}
+
+	parent, parentAncestors, err := loadWithAncestors(ctx, h.Repository.GetByID, cmd.ParentID)
+	if err != nil {
+		return err
+	}
+
+	// Mover la categoría; SetParent rechaza los cambios que formarían un ciclo
+	if err := category.SetParent(parent, parentAncestors); err != nil {
+		return err
+	}
+
+	// Guardar cambios; los eventos quedan en el outbox y el relay los publica
+	err = h.Repository.Save(withIdempotencyKey(ctx, cmd.IdempotencyKey), category)
+	if err := replayed(ctx, h.Repository.Processed, cmd.ID, cmd.IdempotencyKey, err); err != nil {
+		return fmt.Errorf("failed to save category: %w", err)
+	}
+
+	return nil
+}
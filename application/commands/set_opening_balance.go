@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"escama/domain/money"
+	"escama/infrastructure/repositories"
+)
+
+type SetOpeningBalanceCommand struct {
+	AccountID      string
+	Amount         money.Money
+	Date           time.Time // los movimientos anteriores a este día ya están en el saldo
+	IdempotencyKey string    // opcional: un reintento con la misma clave no vuelve a aplicarse
+}
+
+type SetOpeningBalanceHandler struct {
+	Repository *repositories.AccountRepository
+}
+
+func (h *SetOpeningBalanceHandler) Handle(ctx context.Context, cmd SetOpeningBalanceCommand) error {
+	// Un reintento de un comando ya aplicado termina sin agregar eventos
+	if cmd.IdempotencyKey != "" {
+		done, err := h.Repository.Processed(ctx, cmd.AccountID, cmd.IdempotencyKey)
+		if err != nil {
+			return fmt.Errorf("failed to check idempotency key: %w", err)
+		}
+		if done {
+			return nil
+		}
+	}
+
+	account, err := h.Repository.GetByID(ctx, cmd.AccountID)
+	if err != nil {
+		return fmt.Errorf("failed to load account: %w", err)
+	}
+
+	if account == nil {
+		return fmt.Errorf("%w: %s", ErrAccountNotFound, cmd.AccountID)
+	}
+
+	if err := account.SetOpeningBalance(cmd.Amount, cmd.Date); err != nil {
+		return err
+	}
+
+	// Guardar cambios; los eventos quedan en el outbox y el relay los publica
+	err = h.Repository.Save(withIdempotencyKey(ctx, cmd.IdempotencyKey), account)
+	if err := replayed(ctx, h.Repository.Processed, cmd.AccountID, cmd.IdempotencyKey, err); err != nil {
+		return fmt.Errorf("failed to save account: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"escama/infrastructure/repositories"
+)
+
+type TagIncomeCommand struct {
+	ID             string
+	Tags           []string
+	IdempotencyKey string // opcional: un reintento con la misma clave no vuelve a aplicarse
+}
+
+type TagIncomeHandler struct {
+	Repository *repositories.IncomeRepository
+}
+
+func (h *TagIncomeHandler) Handle(ctx context.Context, cmd TagIncomeCommand) error {
+	// Un reintento de un comando ya aplicado termina sin agregar eventos
+	if cmd.IdempotencyKey != "" {
+		done, err := h.Repository.Processed(ctx, cmd.ID, cmd.IdempotencyKey)
+		if err != nil {
+			return fmt.Errorf("failed to check idempotency key: %w", err)
+		}
+		if done {
+			return nil
+		}
+	}
+
+	income, err := h.Repository.GetByID(ctx, cmd.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load income: %w", err)
+	}
+
+	if income == nil {
+		return fmt.Errorf("income not found: %s", cmd.ID)
+	}
+
+	// Las etiquetas que el ingreso ya tiene no generan eventos
+	for _, tag := range cmd.Tags {
+		if err := income.AddTag(tag); err != nil {
+			return err
+		}
+	}
+
+	// Guardar cambios; los eventos quedan en el outbox y el relay los publica
+	err = h.Repository.Save(withIdempotencyKey(ctx, cmd.IdempotencyKey), income)
+	if err := replayed(ctx, h.Repository.Processed, cmd.ID, cmd.IdempotencyKey, err); err != nil {
+		return fmt.Errorf("failed to save income: %w", err)
+	}
+
+	return nil
+}
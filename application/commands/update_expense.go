@@ -5,24 +5,40 @@ import (
 	"fmt"
 	"time"
 
-	"escama/domain/events"
+	"escama/domain/money"
 	"escama/infrastructure/repositories"
 )
 
 type UpdateExpenseCommand struct {
-	ID          string
-	CategoryID  string
-	Amount      float64
-	Description *string
-	Date        time.Time
+	ID             string
+	AccountID      string // vacío conserva la cuenta actual
+	CategoryID     string
+	Amount         money.Money
+	Description    *string
+	Date           time.Time
+	IdempotencyKey string // opcional: un reintento con la misma clave no vuelve a aplicarse
 }
 
 type UpdateExpenseHandler struct {
 	Repository *repositories.ExpenseRepository
-	Publish    func(ctx context.Context, events []events.DomainEvent) error
+	// LoadAccount carga la cuenta del movimiento para verificar que acepte el importe
+	LoadAccount LoadAccountFunc
+	// LoadCategory carga la nueva categoría, si cambia, para verificar que admita gastos
+	LoadCategory LoadCategoryFunc
 }
 
 func (h *UpdateExpenseHandler) Handle(ctx context.Context, cmd UpdateExpenseCommand) error {
+	// Un reintento de un comando ya aplicado termina sin agregar eventos
+	if cmd.IdempotencyKey != "" {
+		done, err := h.Repository.Processed(ctx, cmd.ID, cmd.IdempotencyKey)
+		if err != nil {
+			return fmt.Errorf("failed to check idempotency key: %w", err)
+		}
+		if done {
+			return nil
+		}
+	}
+
 	// Cargar el gasto existente
 	expense, err := h.Repository.GetByID(ctx, cmd.ID)
 	if err != nil {
@@ -34,16 +50,25 @@ func (h *UpdateExpenseHandler) Handle(ctx context.Context, cmd UpdateExpenseComm
 	}
 
 	// Actualizar el gasto
-	expense.Update(cmd.CategoryID, cmd.Amount, cmd.Description, cmd.Date)
-
-	// Guardar cambios
-	if err := h.Repository.Save(ctx, expense); err != nil {
-		return fmt.Errorf("failed to save expense: %w", err)
+	accountID := cmd.AccountID
+	if accountID == "" {
+		accountID = expense.AccountID
 	}
+	if err := checkAccount(ctx, h.LoadAccount, accountID, cmd.Amount); err != nil {
+		return err
+	}
+	// Los movimientos anteriores a los tipos de categoría pueden conservar la suya
+	if cmd.CategoryID != expense.CategoryID {
+		if err := checkCategory(ctx, h.LoadCategory, cmd.CategoryID, "expense"); err != nil {
+			return err
+		}
+	}
+	expense.Update(accountID, cmd.CategoryID, cmd.Amount, cmd.Description, cmd.Date)
 
-	// Publicar eventos
-	if err := h.Publish(ctx, expense.UncommittedEvents()); err != nil {
-		return fmt.Errorf("failed to publish events: %w", err)
+	// Guardar cambios; los eventos quedan en el outbox y el relay los publica
+	err = h.Repository.Save(withIdempotencyKey(ctx, cmd.IdempotencyKey), expense)
+	if err := replayed(ctx, h.Repository.Processed, cmd.ID, cmd.IdempotencyKey, err); err != nil {
+		return fmt.Errorf("failed to save expense: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"escama/domain/money"
+	"escama/infrastructure/repositories"
+)
+
+type UpdateTransferCommand struct {
+	ID             string
+	FromAccountID  string // vacío conserva la cuenta origen actual
+	ToAccountID    string // vacío conserva la cuenta destino actual
+	Amount         money.Money
+	ReceivedAmount *money.Money // opcional: importe recibido si la moneda de la cuenta destino es otra
+	Description    *string
+	Date           time.Time
+	IdempotencyKey string // opcional: un reintento con la misma clave no vuelve a aplicarse
+}
+
+type UpdateTransferHandler struct {
+	Repository *repositories.TransferRepository
+	// LoadAccount carga las cuentas de la transferencia para verificar que acepten los importes
+	LoadAccount LoadAccountFunc
+}
+
+func (h *UpdateTransferHandler) Handle(ctx context.Context, cmd UpdateTransferCommand) error {
+	// Un reintento de un comando ya aplicado termina sin agregar eventos
+	if cmd.IdempotencyKey != "" {
+		done, err := h.Repository.Processed(ctx, cmd.ID, cmd.IdempotencyKey)
+		if err != nil {
+			return fmt.Errorf("failed to check idempotency key: %w", err)
+		}
+		if done {
+			return nil
+		}
+	}
+
+	// Cargar la transferencia existente
+	transfer, err := h.Repository.GetByID(ctx, cmd.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load transfer: %w", err)
+	}
+
+	if transfer == nil {
+		return fmt.Errorf("transfer not found: %s", cmd.ID)
+	}
+
+	// Actualizar la transferencia
+	fromAccountID := cmd.FromAccountID
+	if fromAccountID == "" {
+		fromAccountID = transfer.FromAccountID
+	}
+	toAccountID := cmd.ToAccountID
+	if toAccountID == "" {
+		toAccountID = transfer.ToAccountID
+	}
+	receivedAmount := receivedOrSent(cmd.Amount, cmd.ReceivedAmount)
+	if err := checkTransferAccounts(ctx, h.LoadAccount, fromAccountID, toAccountID, cmd.Amount, receivedAmount); err != nil {
+		return err
+	}
+	if err := transfer.Update(fromAccountID, toAccountID, cmd.Amount, receivedAmount, cmd.Description, cmd.Date); err != nil {
+		return err
+	}
+
+	// Guardar cambios; los eventos quedan en el outbox y el relay los publica
+	err = h.Repository.Save(withIdempotencyKey(ctx, cmd.IdempotencyKey), transfer)
+	if err := replayed(ctx, h.Repository.Processed, cmd.ID, cmd.IdempotencyKey, err); err != nil {
+		return fmt.Errorf("failed to save transfer: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package queries
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"escama/domain/money"
+	"escama/infrastructure/projections"
+)
+
+// Account representa una cuenta con su saldo actual
+type Account struct {
+	ID             string      `json:"id"`
+	Name           string      `json:"name"`
+	Kind           string      `json:"kind"` // "bank", "cash" o "card"
+	Currency       string      `json:"currency"`
+	OpeningBalance money.Money `json:"opening_balance"`
+	OpeningDate    time.Time   `json:"opening_date"`
+	Balance        money.Money `json:"balance"`
+	Closed         bool        `json:"closed"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+// GetAccountsQuery consulta para obtener las cuentas
+type GetAccountsQuery struct {
+	IncludeClosed bool
+}
+
+// GetAccounts obtiene las cuentas con su saldo: el saldo inicial más los ingresos y las
+// transferencias recibidas, menos los gastos y las transferencias enviadas, registrados
+// en cada una desde la fecha del saldo inicial
+func (h *ProjectionQueryHandler) GetAccounts(ctx context.Context, query GetAccountsQuery) ([]Account, error) {
+	projectionAccounts, err := h.projectionStore.GetAccounts(ctx)
+	if err != nil {
+		return []Account{}, err
+	}
+
+	totals, err := h.projectionStore.GetAccountTotals(ctx, "")
+	if err != nil {
+		return []Account{}, err
+	}
+
+	accounts := make([]Account, 0, len(projectionAccounts))
+	for _, pa := range projectionAccounts {
+		if pa.Closed && !query.IncludeClosed {
+			continue
+		}
+		account, err := accountBalance(pa, totals)
+		if err != nil {
+			return []Account{}, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
+}
+
+// GetAccountByID obtiene una cuenta específica por ID con su saldo
+func (h *ProjectionQueryHandler) GetAccountByID(ctx context.Context, id string) (*Account, error) {
+	projectionAccount, err := h.projectionStore.GetAccountByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if projectionAccount == nil {
+		return nil, nil
+	}
+
+	totals, err := h.projectionStore.GetAccountTotals(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := accountBalance(*projectionAccount, totals)
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// accountBalance arma el DTO de la cuenta sumando al saldo inicial los totales que el
+// store calculó para ella
+func accountBalance(pa projections.AccountProjection, totals []projections.AccountTotal) (Account, error) {
+	balance := money.New(0, pa.Currency)
+	if !pa.OpeningBalance.IsZero() {
+		balance = pa.OpeningBalance
+	}
+
+	for _, total := range totals {
+		if total.AccountID != pa.ID {
+			continue
+		}
+
+		var err error
+		if balance, err = balance.Add(total.Total); err != nil {
+			return Account{}, fmt.Errorf("failed to add movements to account %s: %w", pa.ID, err)
+		}
+	}
+
+	return Account{
+		ID:             pa.ID,
+		Name:           pa.Name,
+		Kind:           pa.Kind,
+		Currency:       pa.Currency,
+		OpeningBalance: pa.OpeningBalance,
+		OpeningDate:    pa.OpeningDate,
+		Balance:        balance,
+		Closed:         pa.Closed,
+		CreatedAt:      pa.CreatedAt,
+	}, nil
+}
@@ -0,0 +1,222 @@
+package queries
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"escama/domain/events"
+	"escama/domain/money"
+	"escama/infrastructure/eventstore"
+	"escama/infrastructure/snapshots"
+)
+
+// BalanceSummaryID identifica el snapshot con el resumen de los movimientos archivados
+const BalanceSummaryID = "archive-balance-summary"
+
+// BalanceSummaryType tipo de agregado con que se guarda el resumen entre los snapshots
+const BalanceSummaryType = "BalanceSummary"
+
+// summaryDayLayout clave de cada día del resumen (fecha de los movimientos)
+const summaryDayLayout = events.BusinessDayLayout
+
+// summaryVersion versión del formato del resumen; los guardados con otra se ignoran
+const summaryVersion = 5
+
+// archive lo implementa el Event Store con archivo en frío
+type archive interface {
+	ArchivedThrough() (int64, error)
+}
+
+// DailyTotals ingresos y gastos archivados de los movimientos de una fecha en una moneda,
+// para poder filtrarlos por fecha y convertirlos con la cotización del día
+type DailyTotals struct {
+	Date       string                   `json:"date"` // fecha de los movimientos, YYYY-MM-DD
+	Currency   string                   `json:"currency"`
+	Income     money.Money              `json:"income"`
+	Expense    money.Money              `json:"expense"`
+	Categories map[string]CategoryTotal `json:"categories,omitempty"` // gastos por categoría
+}
+
+// CategoryTotal gasto acumulado de una categoría
+type CategoryTotal struct {
+	Total money.Money `json:"total"`
+	Count int         `json:"count"`
+}
+
+// balanceSummary resumen de los eventos archivados hasta una posición global: alcanza
+// para calcular balances y gastos por categoría sin leer los segmentos
+type balanceSummary struct {
+	Version       int                       `json:"version"`
+	Days          map[string][]*DailyTotals `json:"days"`                  // por fecha de los movimientos
+	CategoryNames map[string]string         `json:"category_names"`        // nombre de cada categoría, incluidas las eliminadas
+	MergedInto    map[string]string         `json:"merged_into,omitempty"` // categoría en que se fusionó cada una
+	Parents       map[string]string         `json:"parents,omitempty"`     // categoría padre de cada subcategoría
+}
+
+// categoryIndex devuelve el estado de las categorías al momento del resumen, para
+// seguir aplicándole los eventos posteriores
+func (s *balanceSummary) categoryIndex() *categoryIndex {
+	index := newCategoryIndex()
+	for id, name := range s.CategoryNames {
+		index.categories[id] = &Category{ID: id, Name: name, ParentID: s.Parents[id]}
+	}
+	for id, target := range s.MergedInto {
+		index.deleted[id] = true
+		index.mergedInto[id] = target
+	}
+	return index
+}
+
+// SetSummaries indica dónde guardar y leer el resumen de los eventos archivados
+// (ver SummarizeArchive). Sin resumen vigente, las consultas leen los segmentos.
+func (h *MovementsQueryHandler) SetSummaries(store snapshots.Store) {
+	h.summaries = store
+}
+
+// SummarizeArchive recorre los eventos archivados y guarda como snapshot el resumen diario
+// de sus movimientos. Se ejecuta después de cada archivado; devuelve la última posición
+// resumida (0 si no hay nada archivado).
+func (h *MovementsQueryHandler) SummarizeArchive(ctx context.Context) (int64, error) {
+	if h.summaries == nil {
+		return 0, fmt.Errorf("no snapshot store configured for summaries")
+	}
+	archived, ok := h.eventStore.(archive)
+	if !ok {
+		return 0, nil
+	}
+	through, err := archived.ArchivedThrough()
+	if err != nil || through == 0 {
+		return 0, err
+	}
+
+	summary := balanceSummary{
+		Version: summaryVersion,
+		Days:    make(map[string][]*DailyTotals),
+	}
+	var movementEvents []events.StoredEvent
+	categories := newCategoryIndex()
+	filter := eventstore.StreamFilter{EventTypes: append([]string{"ExpenseCreated", "IncomeCreated"}, categoryEventTypes...)}
+	err = h.eventStore.Stream(ctx, filter, func(storedEvent events.StoredEvent) error {
+		if storedEvent.GlobalPosition > through {
+			return eventstore.ErrStopStream
+		}
+		if storedEvent.EventType == "ExpenseCreated" || storedEvent.EventType == "IncomeCreated" {
+			movementEvents = append(movementEvents, storedEvent)
+			return nil
+		}
+
+		categories.apply(storedEvent)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read archived events: %w", err)
+	}
+	summary.CategoryNames = categories.names()
+	summary.MergedInto = categories.mergedInto
+	summary.Parents = categories.parentIDs()
+
+	for _, movement := range h.eventsToMovements(movementEvents) {
+		totals := summary.totals(movement)
+		if err := newReporting("", nil).addMovements(&totals.Income, &totals.Expense, []Movement{movement}); err != nil {
+			return 0, err
+		}
+		if movement.Type == "expense" {
+			category := totals.Categories[movement.CategoryID]
+			if category.Total, err = category.Total.Add(movement.Amount); err != nil {
+				return 0, fmt.Errorf("failed to add expense %s: %w", movement.ID, err)
+			}
+			category.Count++
+			totals.Categories[movement.CategoryID] = category
+		}
+	}
+
+	state, err := json.Marshal(summary)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode archive summary: %w", err)
+	}
+	err = h.summaries.Save(ctx, snapshots.Snapshot{
+		AggregateID:   BalanceSummaryID,
+		AggregateType: BalanceSummaryType,
+		Version:       int(through),
+		State:         state,
+		TakenAt:       time.Now().UTC(),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to save archive summary: %w", err)
+	}
+	return through, nil
+}
+
+// archiveSummary devuelve el resumen si corresponde a los eventos archivados actuales,
+// junto con la última posición que cubre. Un resumen ausente, viejo o ilegible se ignora.
+func (h *MovementsQueryHandler) archiveSummary(ctx context.Context) (*balanceSummary, int64) {
+	if h.summaries == nil {
+		return nil, 0
+	}
+	archived, ok := h.eventStore.(archive)
+	if !ok {
+		return nil, 0
+	}
+	through, err := archived.ArchivedThrough()
+	if err != nil || through == 0 {
+		return nil, 0
+	}
+
+	snapshot, err := h.summaries.Get(ctx, BalanceSummaryID)
+	if err != nil {
+		log.Printf("Ignoring archive summary: %v", err)
+		return nil, 0
+	}
+	if snapshot == nil || int64(snapshot.Version) != through {
+		return nil, 0
+	}
+
+	var summary balanceSummary
+	if err := json.Unmarshal(snapshot.State, &summary); err != nil {
+		log.Printf("Ignoring archive summary: %v", err)
+		return nil, 0
+	}
+	if summary.Version != summaryVersion {
+		return nil, 0
+	}
+	return &summary, through
+}
+
+// totals devuelve el grupo del resumen al que suma el movimiento: el de su fecha y su moneda
+func (s *balanceSummary) totals(movement Movement) *DailyTotals {
+	date := movement.Date.Format(summaryDayLayout)
+	for _, totals := range s.Days[date] {
+		if totals.Currency == movement.Amount.Currency {
+			return totals
+		}
+	}
+
+	totals := &DailyTotals{
+		Date:       date,
+		Currency:   movement.Amount.Currency,
+		Categories: make(map[string]CategoryTotal),
+	}
+	s.Days[date] = append(s.Days[date], totals)
+	return totals
+}
+
+// between devuelve los días del resumen comprendidos en el rango, con el mismo criterio
+// que los movimientos sin archivar; nil no limita
+func (s *balanceSummary) between(startDate, endDate *time.Time) []*DailyTotals {
+	var days []*DailyTotals
+	for day, dayTotals := range s.Days {
+		if inDateRange(day, startDate, endDate) {
+			days = append(days, dayTotals...)
+		}
+	}
+	return days
+}
+
+// date devuelve la fecha de los movimientos del grupo, con la que se convierten
+func (t *DailyTotals) date() time.Time {
+	date, _ := time.Parse(summaryDayLayout, t.Date)
+	return date
+}
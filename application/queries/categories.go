@@ -2,9 +2,11 @@ package queries
 
 import (
 	"context"
+	"log"
 	"sort"
 	"time"
 
+	"escama/domain"
 	"escama/domain/events"
 	"escama/infrastructure/eventstore"
 )
@@ -13,11 +15,42 @@ import (
 type Category struct {
 	ID        string    `json:"id"`
 	Name      string    `json:"name"`
+	ParentID  string    `json:"parent_id,omitempty"` // vacío en las de primer nivel
+	Kind      string    `json:"kind"`                // "income", "expense" o "both"
+	Archived  bool      `json:"archived"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
-// GetCategoriesQuery consulta para obtener todas las categorías
-type GetCategoriesQuery struct{}
+// GetCategoriesQuery consulta para obtener las categorías. Las eliminadas y las
+// fusionadas en otra nunca se incluyen. Kind, si se indica ("income" o "expense"),
+// deja solo las que se pueden usar con ese tipo de movimiento.
+type GetCategoriesQuery struct {
+	IncludeArchived bool
+	Kind            string
+}
+
+// matches indica si la categoría entra en el resultado de la consulta
+func (q GetCategoriesQuery) matches(category Category) bool {
+	if category.Archived && !q.IncludeArchived {
+		return false
+	}
+	return q.Kind == "" || category.Kind == domain.CategoryKindBoth || category.Kind == q.Kind
+}
+
+// categoryKind tipo de la categoría; las creadas antes de que existieran los tipos
+// no tienen uno y sirven para ambos
+func categoryKind(kind string) string {
+	if kind == "" {
+		return domain.CategoryKindBoth
+	}
+	return kind
+}
+
+// categoryEventTypes eventos con los que se reconstruye el estado de las categorías
+var categoryEventTypes = []string{
+	"CategoryCreated", "CategoryRenamed", "CategoryArchived", "CategoryUnarchived", "CategoryDeleted", "CategoryMerged",
+	"CategoryParentChanged", "CategoryKindChanged",
+}
 
 // CategoriesQueryHandler maneja consultas de categorías
 type CategoriesQueryHandler struct {
@@ -29,57 +62,203 @@ func NewCategoriesQueryHandler(eventStore eventstore.EventStore) *CategoriesQuer
 }
 
 func (h *CategoriesQueryHandler) GetCategories(ctx context.Context, query GetCategoriesQuery) ([]Category, error) {
-	storedEvents, err := h.eventStore.GetAllEvents(ctx, nil, nil)
+	index, err := h.index(ctx)
 	if err != nil {
 		return []Category{}, err
 	}
 
-	categories := h.eventsToCategories(storedEvents)
-	if categories == nil {
-		return []Category{}, nil
+	// Convertir mapa a slice y ordenar por nombre
+	categories := make([]Category, 0, len(index.categories))
+	for id, category := range index.categories {
+		if index.deleted[id] || !query.matches(*category) {
+			continue
+		}
+		result := *category
+		result.ParentID = index.parent(id) // Si el padre se fusionó, la que lo absorbió
+		categories = append(categories, result)
 	}
+
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Name < categories[j].Name
+	})
+
 	return categories, nil
 }
 
-// Helper para convertir eventos a categorías
-func (h *CategoriesQueryHandler) eventsToCategories(storedEvents []events.StoredEvent) []Category {
-	categoryMap := make(map[string]Category)
+// index reconstruye el estado de todas las categorías desde sus eventos
+func (h *CategoriesQueryHandler) index(ctx context.Context) (*categoryIndex, error) {
+	index := newCategoryIndex()
 
-	for _, storedEvent := range storedEvents {
-		if storedEvent.EventType == "CategoryCreated" {
-			categoryID := h.getStringFromPayload(storedEvent.Payload, "CategoryID", "category_id")
-			categoryName := h.getStringFromPayload(storedEvent.Payload, "Name", "name")
+	// Solo se leen los eventos de categorías, sin cargar el resto del historial
+	filter := eventstore.StreamFilter{EventTypes: categoryEventTypes}
+	err := h.eventStore.Stream(ctx, filter, func(storedEvent events.StoredEvent) error {
+		index.apply(storedEvent)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
 
-			if categoryID != "" && categoryName != "" {
-				categoryMap[categoryID] = Category{
-					ID:        categoryID,
-					Name:      categoryName,
-					CreatedAt: storedEvent.OccurredAt,
-				}
+// categoryIndex estado de las categorías armado desde sus eventos. Conserva las
+// eliminadas, porque sus movimientos siguen mostrando su último nombre, y la categoría
+// en que se fusionó cada una.
+type categoryIndex struct {
+	categories map[string]*Category
+	deleted    map[string]bool
+	mergedInto map[string]string
+}
+
+func newCategoryIndex() *categoryIndex {
+	return &categoryIndex{
+		categories: make(map[string]*Category),
+		deleted:    make(map[string]bool),
+		mergedInto: make(map[string]string),
+	}
+}
+
+// apply aplica un evento de categoría; los que no se pueden decodificar se omiten
+func (idx *categoryIndex) apply(storedEvent events.StoredEvent) {
+	domainEvent, err := storedEvent.Decode()
+	if err != nil {
+		log.Printf("Skipping event %s: %v", storedEvent.ID, err)
+		return
+	}
+
+	switch e := domainEvent.(type) {
+	case events.CategoryCreated:
+		if e.CategoryID != "" && e.Name != "" {
+			idx.categories[e.CategoryID] = &Category{
+				ID: e.CategoryID, Name: e.Name, ParentID: e.ParentID, Kind: categoryKind(e.Kind), CreatedAt: storedEvent.OccurredAt,
 			}
 		}
+	case events.CategoryKindChanged:
+		if category, exists := idx.categories[e.CategoryID]; exists {
+			category.Kind = categoryKind(e.Kind)
+		}
+	case events.CategoryParentChanged:
+		if category, exists := idx.categories[e.CategoryID]; exists {
+			category.ParentID = e.ParentID
+		}
+	case events.CategoryRenamed:
+		if category, exists := idx.categories[e.CategoryID]; exists {
+			category.Name = e.Name
+		}
+	case events.CategoryArchived:
+		if category, exists := idx.categories[e.CategoryID]; exists {
+			category.Archived = true
+		}
+	case events.CategoryUnarchived:
+		if category, exists := idx.categories[e.CategoryID]; exists {
+			category.Archived = false
+		}
+	case events.CategoryDeleted:
+		idx.deleted[e.CategoryID] = true
+	case events.CategoryMerged:
+		idx.deleted[e.CategoryID] = true
+		idx.mergedInto[e.CategoryID] = e.TargetID
 	}
+}
 
-	// Convertir mapa a slice y ordenar por nombre
-	categories := make([]Category, 0, len(categoryMap))
-	for _, category := range categoryMap {
-		categories = append(categories, category)
+// resolve devuelve la categoría que tiene hoy los movimientos de categoryID, siguiendo
+// las fusiones
+func (idx *categoryIndex) resolve(categoryID string) string {
+	for hops := 0; hops < len(idx.mergedInto); hops++ {
+		target, merged := idx.mergedInto[categoryID]
+		if !merged {
+			break
+		}
+		categoryID = target
 	}
+	return categoryID
+}
 
-	sort.Slice(categories, func(i, j int) bool {
-		return categories[i].Name < categories[j].Name
-	})
+// name devuelve el nombre actual de la categoría, incluso si fue eliminada
+func (idx *categoryIndex) name(categoryID string) (string, bool) {
+	category, exists := idx.categories[categoryID]
+	if !exists {
+		return "", false
+	}
+	return category.Name, true
+}
+
+// parent devuelve la categoría padre de categoryID; si el padre se fusionó, la que lo
+// absorbió
+func (idx *categoryIndex) parent(categoryID string) string {
+	category, exists := idx.categories[categoryID]
+	if !exists || category.ParentID == "" {
+		return ""
+	}
+	return idx.resolve(category.ParentID)
+}
 
-	return categories
+// path devuelve la rama de la categoría, desde la de primer nivel hasta ella misma
+func (idx *categoryIndex) path(categoryID string) []string {
+	path := []string{categoryID}
+	seen := map[string]bool{categoryID: true}
+	for parentID := idx.parent(categoryID); parentID != "" && !seen[parentID]; parentID = idx.parent(parentID) {
+		seen[parentID] = true
+		path = append([]string{parentID}, path...)
+	}
+	return path
 }
 
-func (h *CategoriesQueryHandler) getStringFromPayload(payload map[string]interface{}, keys ...string) string {
-	for _, key := range keys {
-		if val, ok := payload[key]; ok {
-			if strVal, ok := val.(string); ok {
-				return strVal
+// parents indica qué categorías tienen subcategorías vigentes
+func (idx *categoryIndex) parents() map[string]bool {
+	parents := make(map[string]bool)
+	for id := range idx.categories {
+		if parentID := idx.parent(id); parentID != "" && !idx.deleted[id] {
+			parents[parentID] = true
+		}
+	}
+	return parents
+}
+
+// expenseGroup devuelve la categoría en la que se suma un gasto de categoryID según la
+// consulta: ella misma, su ancestro del nivel pedido o, si se consulta una categoría
+// padre, la subcategoría de esa rama. false si el gasto queda fuera de la rama consultada.
+func (idx *categoryIndex) expenseGroup(categoryID string, query GetExpensesByCategoryQuery) (string, bool) {
+	if categoryID == "" {
+		return "", query.ParentID == ""
+	}
+
+	path := idx.path(categoryID)
+	if query.ParentID != "" {
+		for i, id := range path {
+			if id != query.ParentID {
+				continue
+			}
+			if i+1 < len(path) {
+				return path[i+1], true
 			}
+			return id, true // Gastos registrados en la categoría padre misma
+		}
+		return "", false
+	}
+
+	if query.Level > 0 && len(path) > query.Level {
+		return path[query.Level-1], true
+	}
+	return categoryID, true
+}
+
+// names nombre actual de cada categoría, incluidas las eliminadas
+func (idx *categoryIndex) names() map[string]string {
+	names := make(map[string]string, len(idx.categories))
+	for id, category := range idx.categories {
+		names[id] = category.Name
+	}
+	return names
+}
+
+// parentIDs categoría padre de cada categoría que tiene una
+func (idx *categoryIndex) parentIDs() map[string]string {
+	parentIDs := make(map[string]string)
+	for id, category := range idx.categories {
+		if category.ParentID != "" {
+			parentIDs[id] = category.ParentID
 		}
 	}
-	return ""
+	return parentIDs
 }
@@ -0,0 +1,74 @@
+package queries
+
+import (
+	"sort"
+	"time"
+
+	"escama/domain/money"
+)
+
+// categoryExpenses acumula los gastos por categoría agrupándolos según la consulta: por
+// categoría, por ancestro del nivel pedido o por subcategoría de la categoría consultada
+type categoryExpenses struct {
+	query      GetExpensesByCategoryQuery
+	categories *categoryIndex
+	report     reporting
+	totals     map[string]*CategoryExpense
+}
+
+func newCategoryExpenses(query GetExpensesByCategoryQuery, categories *categoryIndex, report reporting) *categoryExpenses {
+	return &categoryExpenses{
+		query:      query,
+		categories: categories,
+		report:     report,
+		totals:     make(map[string]*CategoryExpense),
+	}
+}
+
+// add suma count gastos por amount de la categoría categoryID. name es el nombre que se
+// muestra si la categoría no está en el índice (por ejemplo, una eliminada).
+func (e *categoryExpenses) add(categoryID, name string, amount money.Money, count int, on time.Time) error {
+	groupID, included := e.categories.expenseGroup(categoryID, e.query)
+	if !included {
+		return nil
+	}
+
+	if groupName, exists := e.categories.name(groupID); exists {
+		name = groupName
+	} else if groupID != categoryID || name == "" {
+		name = "Sin categoría"
+	}
+	if groupID == "" {
+		groupID, name = "Sin categoría", "Sin categoría"
+	}
+
+	existing, exists := e.totals[groupID]
+	if !exists {
+		existing = &CategoryExpense{CategoryID: groupID, CategoryName: name, ParentID: e.categories.parent(groupID)}
+		e.totals[groupID] = existing
+	}
+	total, err := e.report.add(existing.Total, amount, on)
+	if err != nil {
+		return err
+	}
+	existing.Total = total
+	existing.Count += count
+	return nil
+}
+
+// result devuelve los totales ordenados por total descendente
+func (e *categoryExpenses) result() []CategoryExpense {
+	parents := e.categories.parents()
+
+	result := make([]CategoryExpense, 0, len(e.totals))
+	for id, expense := range e.totals {
+		// Los gastos propios de la categoría consultada no se vuelven a desglosar
+		expense.HasChildren = parents[id] && id != e.query.ParentID
+		result = append(result, *expense)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Total.Amount > result[j].Total.Amount
+	})
+	return result
+}
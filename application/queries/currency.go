@@ -0,0 +1,85 @@
+package queries
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"escama/domain/money"
+)
+
+// Converter convierte importes a otra moneda con la cotización de una fecha (ver
+// rates.Store)
+type Converter interface {
+	Convert(amount money.Money, currency string, on time.Time) (money.Money, error)
+}
+
+// reporting suma importes en la moneda de un reporte, convirtiendo cada uno con la
+// cotización de la fecha de su movimiento. Sin moneda de reporte no convierte: los
+// importes de monedas distintas devuelven money.ErrCurrencyMismatch.
+type reporting struct {
+	currency  string
+	converter Converter
+}
+
+func newReporting(currency string, converter Converter) reporting {
+	return reporting{currency: strings.ToUpper(strings.TrimSpace(currency)), converter: converter}
+}
+
+// add suma amount, del día on, a total
+func (r reporting) add(total, amount money.Money, on time.Time) (money.Money, error) {
+	switch {
+	case r.currency == "" || amount.Currency == r.currency:
+	case amount.IsZero():
+		amount = money.New(0, r.currency)
+	case r.converter == nil:
+		return money.Money{}, fmt.Errorf("no exchange rates configured to convert %s to %s", amount.Currency, r.currency)
+	default:
+		converted, err := r.converter.Convert(amount, r.currency, on)
+		if err != nil {
+			return money.Money{}, err
+		}
+		amount = converted
+	}
+	return total.Add(amount)
+}
+
+// addMovements suma los ingresos y los gastos a sus totales
+func (r reporting) addMovements(totalIncome, totalExpense *money.Money, movements []Movement) error {
+	for _, movement := range movements {
+		var err error
+		switch movement.Type {
+		case "income":
+			*totalIncome, err = r.add(*totalIncome, movement.Amount, movement.Date)
+		case "expense":
+			*totalExpense, err = r.add(*totalExpense, movement.Amount, movement.Date)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to add %s %s: %w", movement.Type, movement.ID, err)
+		}
+	}
+	return nil
+}
+
+// balance arma el balance del período. Los totales quedan en la moneda del reporte o,
+// sin ella, en la de los movimientos (la moneda por defecto si no hubo ninguno).
+func (r reporting) balance(totalIncome, totalExpense money.Money, query GetBalanceQuery) (Balance, error) {
+	netBalance, err := totalIncome.Sub(totalExpense)
+	if err != nil {
+		return Balance{}, fmt.Errorf("failed to compute net balance: %w", err)
+	}
+
+	currency := r.currency
+	if currency == "" {
+		currency = netBalance.Currency
+	}
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+	return Balance{
+		TotalIncome:  money.New(totalIncome.Amount, currency),
+		TotalExpense: money.New(totalExpense.Amount, currency),
+		NetBalance:   money.New(netBalance.Amount, currency),
+		Period:       query.StartDate.Format("2006-01-02") + " - " + query.EndDate.Format("2006-01-02"),
+	}, nil
+}
@@ -2,23 +2,30 @@ package queries
 
 import (
 	"context"
+	"fmt"
+	"log"
 	"sort"
 	"time"
 
+	"escama/domain"
 	"escama/domain/events"
+	"escama/domain/money"
 	"escama/infrastructure/eventstore"
+	"escama/infrastructure/snapshots"
 )
 
 // Movement representa un movimiento en el flujo de caja
 type Movement struct {
-	ID           string    `json:"id"`
-	Type         string    `json:"type"` // "income" o "expense"
-	CategoryID   string    `json:"category_id"`
-	CategoryName string    `json:"category_name"`
-	Amount       float64   `json:"amount"`
-	Description  *string   `json:"description"`
-	Date         time.Time `json:"date"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID           string      `json:"id"`
+	Type         string      `json:"type"` // "income" o "expense"
+	AccountID    string      `json:"account_id,omitempty"`
+	CategoryID   string      `json:"category_id"`
+	CategoryName string      `json:"category_name"`
+	Amount       money.Money `json:"amount"`
+	Description  *string     `json:"description"`
+	Date         time.Time   `json:"date"`
+	Tags         []string    `json:"tags,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
 }
 
 // PaginatedMovements representa una respuesta paginada de movimientos
@@ -33,16 +40,17 @@ type PaginatedMovements struct {
 
 // Balance representa el balance de un período
 type Balance struct {
-	TotalIncome  float64 `json:"total_income"`
-	TotalExpense float64 `json:"total_expense"`
-	NetBalance   float64 `json:"net_balance"`
-	Period       string  `json:"period"`
+	TotalIncome  money.Money `json:"total_income"`
+	TotalExpense money.Money `json:"total_expense"`
+	NetBalance   money.Money `json:"net_balance"`
+	Period       string      `json:"period"`
 }
 
 // GetMovementsQuery consulta para obtener movimientos con filtros de fecha
 type GetMovementsQuery struct {
 	StartDate *time.Time
 	EndDate   *time.Time
+	Tag       string // solo los movimientos con esta etiqueta, ya normalizada; vacía no filtra
 	Limit     int
 	Offset    int
 }
@@ -51,26 +59,40 @@ type GetMovementsQuery struct {
 type GetBalanceQuery struct {
 	StartDate time.Time
 	EndDate   time.Time
+	Currency  string // moneda del reporte; vacía suma sin convertir
 }
 
 // CategoryExpense representa el gasto total por categoría
 type CategoryExpense struct {
-	CategoryID   string  `json:"category_id"`
-	CategoryName string  `json:"category_name"`
-	Total        float64 `json:"total"`
-	Count        int     `json:"count"`
+	CategoryID   string      `json:"category_id"`
+	CategoryName string      `json:"category_name"`
+	ParentID     string      `json:"parent_id,omitempty"`
+	HasChildren  bool        `json:"has_children"` // se puede desglosar consultando con ParentID
+	Total        money.Money `json:"total"`
+	Count        int         `json:"count"`
 }
 
-// GetExpensesByCategoryQuery consulta para obtener gastos agrupados por categoría
+// GetExpensesByCategoryQuery consulta para obtener gastos agrupados por categoría. Sin
+// Level ni ParentID cada categoría suma solo sus propios gastos.
 type GetExpensesByCategoryQuery struct {
 	StartDate *time.Time
 	EndDate   *time.Time
+	Currency  string // moneda del reporte; vacía suma sin convertir
+	Level     int    // suma las subcategorías en su ancestro de este nivel (1: primer nivel)
+	ParentID  string // solo la rama de esta categoría, sumada en cada subcategoría directa
 }
 
-// MovementsQueryHandler maneja consultas de movimientos
+// tagEventTypes eventos con los que se reconstruyen las etiquetas de los movimientos
+var tagEventTypes = []string{"ExpenseTagAdded", "ExpenseTagRemoved", "IncomeTagAdded", "IncomeTagRemoved"}
+
+// MovementsQueryHandler maneja consultas de movimientos. Con un resumen de los eventos
+// archivados (SetSummaries), los balances y los gastos por categoría solo leen los
+// eventos del store principal.
 type MovementsQueryHandler struct {
 	eventStore        eventstore.EventStore
 	categoriesHandler *CategoriesQueryHandler
+	summaries         snapshots.Store
+	converter         Converter
 }
 
 func NewMovementsQueryHandler(eventStore eventstore.EventStore) *MovementsQueryHandler {
@@ -80,39 +102,155 @@ func NewMovementsQueryHandler(eventStore eventstore.EventStore) *MovementsQueryH
 	}
 }
 
+// SetConverter indica con qué cotizaciones convertir los balances y los gastos por
+// categoría a la moneda pedida en la consulta
+func (h *MovementsQueryHandler) SetConverter(converter Converter) {
+	h.converter = converter
+}
+
 func (h *MovementsQueryHandler) GetMovements(ctx context.Context, query GetMovementsQuery) ([]Movement, error) {
-	storedEvents, err := h.eventStore.GetAllEvents(ctx, query.StartDate, query.EndDate)
+	movements, _, err := h.categorizedMovements(ctx, query)
+	return movements, err
+}
+
+// categorizedMovements devuelve los movimientos del rango junto con el estado de las
+// categorías con que se nombraron
+func (h *MovementsQueryHandler) categorizedMovements(ctx context.Context, query GetMovementsQuery) ([]Movement, *categoryIndex, error) {
+	// El rango es el de la fecha de los movimientos, igual que en el resumen del archivo
+	storedEvents, err := h.eventStore.GetAllEvents(ctx, eventstore.TimeFilter{
+		BusinessFrom: query.StartDate,
+		BusinessTo:   query.EndDate,
+	})
 	if err != nil {
-		return []Movement{}, err
+		return []Movement{}, nil, err
 	}
 
-	movements := h.eventsToMovements(storedEvents)
-	if movements == nil {
-		return []Movement{}, nil
+	// Obtener categorías para mapear nombres y fusiones
+	categories, err := h.categoriesHandler.index(ctx)
+	if err != nil {
+		return []Movement{}, nil, err
 	}
 
-	// Obtener categorías para mapear nombres
-	categories, err := h.categoriesHandler.GetCategories(ctx, GetCategoriesQuery{})
+	// Las etiquetas se agregan en cualquier momento: sus eventos se leen sin el rango
+	tags, err := h.movementTags(ctx)
 	if err != nil {
-		return []Movement{}, err
+		return []Movement{}, nil, err
+	}
+
+	movements := make([]Movement, 0, len(storedEvents))
+	for _, movement := range h.eventsToMovements(storedEvents) {
+		movement.Tags = tags[movement.ID]
+		if query.Tag == "" || domain.HasTag(movement.Tags, query.Tag) {
+			movements = append(movements, movement)
+		}
+	}
+
+	nameCategories(movements, categories)
+	return movements, categories, nil
+}
+
+// movementTags devuelve las etiquetas actuales de cada movimiento, ordenadas
+func (h *MovementsQueryHandler) movementTags(ctx context.Context) (map[string][]string, error) {
+	current := make(map[string]map[string]bool)
+	setTag := func(movementID, tag string, added bool) {
+		if current[movementID] == nil {
+			current[movementID] = make(map[string]bool)
+		}
+		if added {
+			current[movementID][tag] = true
+		} else {
+			delete(current[movementID], tag)
+		}
 	}
 
-	// Crear un mapa de ID a nombre de categoría
-	categoryNames := make(map[string]string)
-	for _, category := range categories {
-		categoryNames[category.ID] = category.Name
+	err := h.eventStore.Stream(ctx, eventstore.StreamFilter{EventTypes: tagEventTypes}, func(storedEvent events.StoredEvent) error {
+		domainEvent, err := storedEvent.Decode()
+		if err != nil {
+			log.Printf("Skipping event %s: %v", storedEvent.ID, err)
+			return nil
+		}
+
+		switch e := domainEvent.(type) {
+		case events.ExpenseTagAdded:
+			setTag(e.ExpenseID, e.Tag, true)
+		case events.ExpenseTagRemoved:
+			setTag(e.ExpenseID, e.Tag, false)
+		case events.IncomeTagAdded:
+			setTag(e.IncomeID, e.Tag, true)
+		case events.IncomeTagRemoved:
+			setTag(e.IncomeID, e.Tag, false)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tag events: %w", err)
 	}
 
-	// Agregar nombres de categorías a los movimientos
+	tags := make(map[string][]string, len(current))
+	for movementID, movementTags := range current {
+		for tag := range movementTags {
+			tags[movementID] = append(tags[movementID], tag)
+		}
+		sort.Strings(tags[movementID])
+	}
+	return tags, nil
+}
+
+// recentMovements devuelve los movimientos del rango posteriores a la posición global
+// indicada (los que no cubre el resumen del archivo) y el estado de todas las
+// categorías: el del resumen más los eventos de categorías posteriores
+func (h *MovementsQueryHandler) recentMovements(ctx context.Context, summary *balanceSummary, through int64, startDate, endDate *time.Time) ([]Movement, *categoryIndex, error) {
+	categories := summary.categoryIndex()
+
+	var movementEvents []events.StoredEvent
+	filter := eventstore.StreamFilter{
+		EventTypes:   append([]string{"ExpenseCreated", "IncomeCreated"}, categoryEventTypes...),
+		FromPosition: through,
+	}
+	err := h.eventStore.Stream(ctx, filter, func(storedEvent events.StoredEvent) error {
+		if storedEvent.EventType != "ExpenseCreated" && storedEvent.EventType != "IncomeCreated" {
+			categories.apply(storedEvent)
+			return nil
+		}
+
+		if day, ok := storedEvent.BusinessDay(); !ok || !inDateRange(day, startDate, endDate) {
+			return nil
+		}
+		movementEvents = append(movementEvents, storedEvent)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	movements := h.eventsToMovements(movementEvents)
+	nameCategories(movements, categories)
+	return movements, categories, nil
+}
+
+// inDateRange indica si el día (YYYY-MM-DD) cae en el rango comparando días completos,
+// cada límite en su propia zona horaria, como BusinessFrom y BusinessTo del Event Store
+func inDateRange(day string, startDate, endDate *time.Time) bool {
+	if startDate != nil && day < startDate.Format(events.BusinessDayLayout) {
+		return false
+	}
+	if endDate != nil && day > endDate.Format(events.BusinessDayLayout) {
+		return false
+	}
+	return true
+}
+
+// nameCategories pasa cada movimiento a la categoría en que se fusionó la suya, si
+// corresponde, y le agrega el nombre actual de su categoría
+func nameCategories(movements []Movement, categories *categoryIndex) {
 	for i := range movements {
-		if name, exists := categoryNames[movements[i].CategoryID]; exists {
+		movements[i].CategoryID = categories.resolve(movements[i].CategoryID)
+		if name, exists := categories.name(movements[i].CategoryID); exists {
 			movements[i].CategoryName = name
 		} else {
 			movements[i].CategoryName = "Sin categoría"
 		}
 	}
-
-	return movements, nil
 }
 
 func (h *MovementsQueryHandler) GetPaginatedMovements(ctx context.Context, query GetMovementsQuery) (PaginatedMovements, error) {
@@ -120,6 +258,7 @@ func (h *MovementsQueryHandler) GetPaginatedMovements(ctx context.Context, query
 	allMovements, err := h.GetMovements(ctx, GetMovementsQuery{
 		StartDate: query.StartDate,
 		EndDate:   query.EndDate,
+		Tag:       query.Tag,
 	})
 	if err != nil {
 		return PaginatedMovements{}, err
@@ -165,149 +304,79 @@ func (h *MovementsQueryHandler) GetPaginatedMovements(ctx context.Context, query
 }
 
 func (h *MovementsQueryHandler) GetBalance(ctx context.Context, query GetBalanceQuery) (Balance, error) {
-	movements, err := h.GetMovements(ctx, GetMovementsQuery{
-		StartDate: &query.StartDate,
-		EndDate:   &query.EndDate,
-	})
+	var movements []Movement
+	var totalIncome, totalExpense money.Money
+	var err error
+	report := newReporting(query.Currency, h.converter)
+
+	if summary, through := h.archiveSummary(ctx); summary != nil {
+		for _, totals := range summary.between(&query.StartDate, &query.EndDate) {
+			if totalIncome, err = report.add(totalIncome, totals.Income, totals.date()); err != nil {
+				return Balance{}, fmt.Errorf("failed to add archived income: %w", err)
+			}
+			if totalExpense, err = report.add(totalExpense, totals.Expense, totals.date()); err != nil {
+				return Balance{}, fmt.Errorf("failed to add archived expenses: %w", err)
+			}
+		}
+		movements, _, err = h.recentMovements(ctx, summary, through, &query.StartDate, &query.EndDate)
+	} else {
+		movements, err = h.GetMovements(ctx, GetMovementsQuery{
+			StartDate: &query.StartDate,
+			EndDate:   &query.EndDate,
+		})
+	}
 	if err != nil {
 		return Balance{}, err
 	}
 
-	var totalIncome, totalExpense float64
-	for _, movement := range movements {
-		if movement.Type == "income" {
-			totalIncome += movement.Amount
-		} else if movement.Type == "expense" {
-			totalExpense += movement.Amount
-		}
+	if err := report.addMovements(&totalIncome, &totalExpense, movements); err != nil {
+		return Balance{}, err
 	}
-
-	return Balance{
-		TotalIncome:  totalIncome,
-		TotalExpense: totalExpense,
-		NetBalance:   totalIncome - totalExpense,
-		Period:       query.StartDate.Format("2006-01-02") + " - " + query.EndDate.Format("2006-01-02"),
-	}, nil
+	return report.balance(totalIncome, totalExpense, query)
 }
 
 func (h *MovementsQueryHandler) GetExpensesByCategory(ctx context.Context, query GetExpensesByCategoryQuery) ([]CategoryExpense, error) {
-	movements, err := h.GetMovements(ctx, GetMovementsQuery{
-		StartDate: query.StartDate,
-		EndDate:   query.EndDate,
-	})
+	report := newReporting(query.Currency, h.converter)
+
+	var movements []Movement
+	var categories *categoryIndex
+	var err error
+	summary, through := h.archiveSummary(ctx)
+	if summary != nil {
+		movements, categories, err = h.recentMovements(ctx, summary, through, query.StartDate, query.EndDate)
+	} else {
+		movements, categories, err = h.categorizedMovements(ctx, GetMovementsQuery{
+			StartDate: query.StartDate,
+			EndDate:   query.EndDate,
+		})
+	}
 	if err != nil {
 		return []CategoryExpense{}, err
 	}
 
 	// Agrupar gastos por categoría
-	categoryTotals := make(map[string]*CategoryExpense)
-
-	for _, movement := range movements {
-		if movement.Type == "expense" {
-			categoryID := movement.CategoryID
-			categoryName := movement.CategoryName
-
-			if categoryID == "" {
-				categoryID = "Sin categoría"
-				categoryName = "Sin categoría"
-			}
-
-			if existing, exists := categoryTotals[categoryID]; exists {
-				existing.Total += movement.Amount
-				existing.Count++
-			} else {
-				categoryTotals[categoryID] = &CategoryExpense{
-					CategoryID:   categoryID,
-					CategoryName: categoryName,
-					Total:        movement.Amount,
-					Count:        1,
+	expenses := newCategoryExpenses(query, categories, report)
+	if summary != nil {
+		// Los gastos archivados entran ya agrupados por categoría; las fusionadas después
+		// del archivado se suman a la categoría que las absorbió
+		for _, totals := range summary.between(query.StartDate, query.EndDate) {
+			for categoryID, category := range totals.Categories {
+				if err := expenses.add(categories.resolve(categoryID), "", category.Total, category.Count, totals.date()); err != nil {
+					return []CategoryExpense{}, fmt.Errorf("failed to add archived expenses: %w", err)
 				}
 			}
 		}
 	}
 
-	// Convertir mapa a slice y ordenar por total descendente
-	result := make([]CategoryExpense, 0, len(categoryTotals))
-	for _, expense := range categoryTotals {
-		result = append(result, *expense)
-	}
-
-	sort.Slice(result, func(i, j int) bool {
-		return result[i].Total > result[j].Total
-	})
-
-	return result, nil
-}
-
-func (h *MovementsQueryHandler) getStringFromPayload(payload map[string]interface{}, keys ...string) string {
-	for _, key := range keys {
-		if val, ok := payload[key]; ok {
-			if strVal, ok := val.(string); ok {
-				return strVal
-			}
-		}
-	}
-	return ""
-}
-
-func (h *MovementsQueryHandler) getFloat64FromPayload(payload map[string]interface{}, keys ...string) float64 {
-	for _, key := range keys {
-		if val, ok := payload[key]; ok {
-			switch v := val.(type) {
-			case float64:
-				return v
-			case float32:
-				return float64(v)
-			case int:
-				return float64(v)
-			case int64:
-				return float64(v)
+	for _, movement := range movements {
+		if movement.Type == "expense" {
+			if err := expenses.add(movement.CategoryID, movement.CategoryName, movement.Amount, 1, movement.Date); err != nil {
+				return []CategoryExpense{}, fmt.Errorf("failed to add expense %s: %w", movement.ID, err)
 			}
 		}
 	}
-	return 0
-}
 
-func (h *MovementsQueryHandler) getStringPtrFromPayload(payload map[string]interface{}, keys ...string) *string {
-	for _, key := range keys {
-		if val, ok := payload[key]; ok {
-			if strVal, ok := val.(string); ok && strVal != "" {
-				return &strVal
-			}
-		}
-	}
-	return nil
-}
-
-func (h *MovementsQueryHandler) getTimeFromPayload(payload map[string]interface{}, keys ...string) time.Time {
-	for _, key := range keys {
-		if val, ok := payload[key]; ok {
-			// Intentar diferentes formatos de fecha
-			switch v := val.(type) {
-			case string:
-				// RFC3339 format
-				if parsedDate, err := time.Parse(time.RFC3339, v); err == nil {
-					return parsedDate
-				}
-				// Otros formatos comunes
-				formats := []string{
-					"2006-01-02T15:04:05Z07:00",
-					"2006-01-02T15:04:05.000Z",
-					"2006-01-02T15:04:05",
-					"2006-01-02 15:04:05",
-					"2006-01-02",
-				}
-				for _, format := range formats {
-					if parsedDate, err := time.Parse(format, v); err == nil {
-						return parsedDate
-					}
-				}
-			case time.Time:
-				return v
-			}
-		}
-	}
-	return time.Time{}
+	return expenses.result(), nil
 }
 
 // Helper para convertir eventos a movements
@@ -315,45 +384,47 @@ func (h *MovementsQueryHandler) eventsToMovements(storedEvents []events.StoredEv
 	movements := make([]Movement, 0, len(storedEvents))
 
 	for _, storedEvent := range storedEvents {
-		switch storedEvent.EventType {
-		case "ExpenseCreated":
-			movement := Movement{
-				Type:      "expense",
-				CreatedAt: storedEvent.OccurredAt,
-			}
-
-			// Probar tanto PascalCase como snake_case
-			movement.ID = h.getStringFromPayload(storedEvent.Payload, "ExpenseID", "expense_id")
-			movement.CategoryID = h.getStringFromPayload(storedEvent.Payload, "CategoryID", "category_id")
-			movement.Amount = h.getFloat64FromPayload(storedEvent.Payload, "Amount", "amount")
-			movement.Description = h.getStringPtrFromPayload(storedEvent.Payload, "Description", "description")
-			movement.Date = h.getTimeFromPayload(storedEvent.Payload, "Date", "date")
+		domainEvent, err := storedEvent.Decode()
+		if err != nil {
+			log.Printf("Skipping event %s: %v", storedEvent.ID, err)
+			continue
+		}
 
-			if movement.Date.IsZero() {
-				movement.Date = storedEvent.OccurredAt
+		var movement Movement
+		switch e := domainEvent.(type) {
+		case events.ExpenseCreated:
+			movement = Movement{
+				ID:          e.ExpenseID,
+				Type:        "expense",
+				AccountID:   e.AccountID,
+				CategoryID:  e.CategoryID,
+				Amount:      e.Amount,
+				Description: e.Description,
+				Date:        e.Date,
+				CreatedAt:   storedEvent.OccurredAt,
 			}
 
-			movements = append(movements, movement)
-
-		case "IncomeCreated":
-			movement := Movement{
-				Type:      "income",
-				CreatedAt: storedEvent.OccurredAt,
+		case events.IncomeCreated:
+			movement = Movement{
+				ID:          e.IncomeID,
+				Type:        "income",
+				AccountID:   e.AccountID,
+				CategoryID:  e.CategoryID,
+				Amount:      e.Amount,
+				Description: e.Description,
+				Date:        e.Date,
+				CreatedAt:   storedEvent.OccurredAt,
 			}
 
-			// Probar tanto PascalCase como snake_case
-			movement.ID = h.getStringFromPayload(storedEvent.Payload, "IncomeID", "income_id")
-			movement.CategoryID = h.getStringFromPayload(storedEvent.Payload, "CategoryID", "category_id")
-			movement.Amount = h.getFloat64FromPayload(storedEvent.Payload, "Amount", "amount")
-			movement.Description = h.getStringPtrFromPayload(storedEvent.Payload, "Description", "description")
-			movement.Date = h.getTimeFromPayload(storedEvent.Payload, "Date", "date")
-
-			if movement.Date.IsZero() {
-				movement.Date = storedEvent.OccurredAt
-			}
+		default:
+			continue
+		}
 
-			movements = append(movements, movement)
+		if movement.Date.IsZero() {
+			movement.Date = storedEvent.OccurredAt
 		}
+
+		movements = append(movements, movement)
 	}
 
 	// Ordenar por fecha descendente
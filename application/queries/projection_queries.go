@@ -2,25 +2,34 @@ package queries
 
 import (
 	"context"
-	"time"
+	"fmt"
 
+	"escama/domain/money"
 	"escama/infrastructure/projections"
 )
 
 // ProjectionQueryHandler maneja consultas usando la base de datos de proyecciones
 type ProjectionQueryHandler struct {
-	projectionStore *projections.ProjectionStore
+	projectionStore projections.ProjectionStore
+	converter       Converter
 }
 
-func NewProjectionQueryHandler(projectionStore *projections.ProjectionStore) *ProjectionQueryHandler {
+func NewProjectionQueryHandler(projectionStore projections.ProjectionStore) *ProjectionQueryHandler {
 	return &ProjectionQueryHandler{
 		projectionStore: projectionStore,
 	}
 }
 
-// GetMovements obtiene movimientos paginados desde las proyecciones
-func (h *ProjectionQueryHandler) GetMovements(ctx context.Context, startDate, endDate *time.Time, limit, offset int) ([]Movement, int, error) {
-	projectionMovements, total, err := h.projectionStore.GetMovements(ctx, startDate, endDate, limit, offset)
+// SetConverter indica con qué cotizaciones convertir los balances y los gastos por
+// categoría a la moneda pedida en la consulta
+func (h *ProjectionQueryHandler) SetConverter(converter Converter) {
+	h.converter = converter
+}
+
+// GetMovements obtiene movimientos paginados desde las proyecciones; sin Limit devuelve
+// todos los del rango
+func (h *ProjectionQueryHandler) GetMovements(ctx context.Context, query GetMovementsQuery) ([]Movement, int, error) {
+	projectionMovements, total, err := h.projectionStore.GetMovements(ctx, query.StartDate, query.EndDate, query.Tag, query.Limit, query.Offset)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -31,11 +40,13 @@ func (h *ProjectionQueryHandler) GetMovements(ctx context.Context, startDate, en
 		movements[i] = Movement{
 			ID:           pm.ID,
 			Type:         pm.Type,
+			AccountID:    pm.AccountID,
 			CategoryID:   pm.CategoryID,
 			CategoryName: pm.CategoryName,
 			Amount:       pm.Amount,
 			Description:  pm.Description,
 			Date:         pm.Date,
+			Tags:         pm.Tags,
 			CreatedAt:    pm.CreatedAt,
 		}
 	}
@@ -45,7 +56,7 @@ func (h *ProjectionQueryHandler) GetMovements(ctx context.Context, startDate, en
 
 // GetPaginatedMovements obtiene movimientos con metadatos de paginación
 func (h *ProjectionQueryHandler) GetPaginatedMovements(ctx context.Context, query GetMovementsQuery) (PaginatedMovements, error) {
-	movements, total, err := h.GetMovements(ctx, query.StartDate, query.EndDate, query.Limit, query.Offset)
+	movements, total, err := h.GetMovements(ctx, query)
 	if err != nil {
 		return PaginatedMovements{}, err
 	}
@@ -69,78 +80,59 @@ func (h *ProjectionQueryHandler) GetPaginatedMovements(ctx context.Context, quer
 
 // GetBalance calcula el balance desde las proyecciones
 func (h *ProjectionQueryHandler) GetBalance(ctx context.Context, query GetBalanceQuery) (Balance, error) {
-	movements, _, err := h.GetMovements(ctx, &query.StartDate, &query.EndDate, 0, 0) // Sin paginación para el balance
+	movements, _, err := h.GetMovements(ctx, GetMovementsQuery{StartDate: &query.StartDate, EndDate: &query.EndDate}) // Sin paginación para el balance
 	if err != nil {
 		return Balance{}, err
 	}
 
-	var totalIncome, totalExpense float64
-	for _, movement := range movements {
-		if movement.Type == "income" {
-			totalIncome += movement.Amount
-		} else if movement.Type == "expense" {
-			totalExpense += movement.Amount
-		}
+	var totalIncome, totalExpense money.Money
+	report := newReporting(query.Currency, h.converter)
+	if err := report.addMovements(&totalIncome, &totalExpense, movements); err != nil {
+		return Balance{}, err
 	}
-
-	return Balance{
-		TotalIncome:  totalIncome,
-		TotalExpense: totalExpense,
-		NetBalance:   totalIncome - totalExpense,
-		Period:       query.StartDate.Format("2006-01-02") + " - " + query.EndDate.Format("2006-01-02"),
-	}, nil
+	return report.balance(totalIncome, totalExpense, query)
 }
 
 // GetExpensesByCategory obtiene gastos agrupados por categoría desde las proyecciones
 func (h *ProjectionQueryHandler) GetExpensesByCategory(ctx context.Context, query GetExpensesByCategoryQuery) ([]CategoryExpense, error) {
-	movements, _, err := h.GetMovements(ctx, query.StartDate, query.EndDate, 0, 0) // Sin paginación
+	movements, _, err := h.GetMovements(ctx, GetMovementsQuery{StartDate: query.StartDate, EndDate: query.EndDate}) // Sin paginación
 	if err != nil {
 		return []CategoryExpense{}, err
 	}
 
-	// Agrupar gastos por categoría
-	categoryTotals := make(map[string]*CategoryExpense)
+	categories, err := h.categoryIndex(ctx)
+	if err != nil {
+		return []CategoryExpense{}, err
+	}
 
+	// Agrupar gastos por categoría, o por la rama que pida la consulta
+	expenses := newCategoryExpenses(query, categories, newReporting(query.Currency, h.converter))
 	for _, movement := range movements {
 		if movement.Type == "expense" {
-			categoryID := movement.CategoryID
-			categoryName := movement.CategoryName
-
-			if categoryID == "" {
-				categoryID = "Sin categoría"
-				categoryName = "Sin categoría"
-			}
-
-			if existing, exists := categoryTotals[categoryID]; exists {
-				existing.Total += movement.Amount
-				existing.Count++
-			} else {
-				categoryTotals[categoryID] = &CategoryExpense{
-					CategoryID:   categoryID,
-					CategoryName: categoryName,
-					Total:        movement.Amount,
-					Count:        1,
-				}
+			if err := expenses.add(movement.CategoryID, movement.CategoryName, movement.Amount, 1, movement.Date); err != nil {
+				return []CategoryExpense{}, fmt.Errorf("failed to add expense %s: %w", movement.ID, err)
 			}
 		}
 	}
 
-	// Convertir mapa a slice y ordenar por total descendente
-	result := make([]CategoryExpense, 0, len(categoryTotals))
-	for _, expense := range categoryTotals {
-		result = append(result, *expense)
+	return expenses.result(), nil
+}
+
+// categoryIndex arma el estado de las categorías desde su proyección. Las eliminadas ya
+// no figuran: sus movimientos se agrupan con el nombre que conservan.
+func (h *ProjectionQueryHandler) categoryIndex(ctx context.Context) (*categoryIndex, error) {
+	projectionCategories, err := h.projectionStore.GetCategories(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	// Ordenar por total descendente (bubble sort simple)
-	for i := 0; i < len(result); i++ {
-		for j := i + 1; j < len(result); j++ {
-			if result[i].Total < result[j].Total {
-				result[i], result[j] = result[j], result[i]
-			}
+	index := newCategoryIndex()
+	for _, pc := range projectionCategories {
+		index.categories[pc.ID] = &Category{
+			ID: pc.ID, Name: pc.Name, ParentID: pc.ParentID, Kind: categoryKind(pc.Kind), Archived: pc.Archived, CreatedAt: pc.CreatedAt,
 		}
 	}
-
-	return result, nil
+	return index, nil
 }
 
 // GetCategories obtiene todas las categorías desde las proyecciones
@@ -150,14 +142,20 @@ func (h *ProjectionQueryHandler) GetCategories(ctx context.Context, query GetCat
 		return []Category{}, err
 	}
 
-	// Convertir proyecciones a DTOs
-	categories := make([]Category, len(projectionCategories))
-	for i, pc := range projectionCategories {
-		categories[i] = Category{
+	// Convertir proyecciones a DTOs; las eliminadas ya no están en la proyección
+	categories := make([]Category, 0, len(projectionCategories))
+	for _, pc := range projectionCategories {
+		category := Category{
 			ID:        pc.ID,
 			Name:      pc.Name,
+			ParentID:  pc.ParentID,
+			Kind:      categoryKind(pc.Kind),
+			Archived:  pc.Archived,
 			CreatedAt: pc.CreatedAt,
 		}
+		if query.matches(category) {
+			categories = append(categories, category)
+		}
 	}
 
 	return categories, nil
@@ -177,11 +175,13 @@ func (h *ProjectionQueryHandler) GetMovementByID(ctx context.Context, id string)
 	return &Movement{
 		ID:           projectionMovement.ID,
 		Type:         projectionMovement.Type,
+		AccountID:    projectionMovement.AccountID,
 		CategoryID:   projectionMovement.CategoryID,
 		CategoryName: projectionMovement.CategoryName,
 		Amount:       projectionMovement.Amount,
 		Description:  projectionMovement.Description,
 		Date:         projectionMovement.Date,
+		Tags:         projectionMovement.Tags,
 		CreatedAt:    projectionMovement.CreatedAt,
 	}, nil
 }
@@ -200,6 +200,9 @@ func (h *ProjectionQueryHandler) GetCategoryByID(ctx context.Context, id string)
 	return &Category{
 		ID:        projectionCategory.ID,
 		Name:      projectionCategory.Name,
+		ParentID:  projectionCategory.ParentID,
+		Kind:      categoryKind(projectionCategory.Kind),
+		Archived:  projectionCategory.Archived,
 		CreatedAt: projectionCategory.CreatedAt,
 	}, nil
 }
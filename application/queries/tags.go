@@ -0,0 +1,82 @@
+package queries
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"escama/domain/money"
+)
+
+// TagTotal ingresos y gastos de los movimientos con una etiqueta. Un movimiento con
+// varias etiquetas suma en cada una, por lo que los totales no se pueden sumar entre sí.
+type TagTotal struct {
+	Tag          string      `json:"tag"`
+	TotalIncome  money.Money `json:"total_income"`
+	TotalExpense money.Money `json:"total_expense"`
+	NetBalance   money.Money `json:"net_balance"`
+	Count        int         `json:"count"`
+}
+
+// GetTagTotalsQuery consulta para obtener los totales por etiqueta
+type GetTagTotalsQuery struct {
+	StartDate *time.Time
+	EndDate   *time.Time
+	Currency  string // moneda del reporte; vacía suma sin convertir
+}
+
+// GetTagTotals obtiene los ingresos y gastos de cada etiqueta desde las proyecciones,
+// ordenados por etiqueta
+func (h *ProjectionQueryHandler) GetTagTotals(ctx context.Context, query GetTagTotalsQuery) ([]TagTotal, error) {
+	movements, _, err := h.GetMovements(ctx, GetMovementsQuery{StartDate: query.StartDate, EndDate: query.EndDate}) // Sin paginación
+	if err != nil {
+		return []TagTotal{}, err
+	}
+
+	report := newReporting(query.Currency, h.converter)
+	totals := make(map[string]*TagTotal)
+	for _, movement := range movements {
+		for _, tag := range movement.Tags {
+			total, exists := totals[tag]
+			if !exists {
+				total = &TagTotal{Tag: tag}
+				totals[tag] = total
+			}
+
+			switch movement.Type {
+			case "income":
+				total.TotalIncome, err = report.add(total.TotalIncome, movement.Amount, movement.Date)
+			case "expense":
+				total.TotalExpense, err = report.add(total.TotalExpense, movement.Amount, movement.Date)
+			}
+			if err != nil {
+				return []TagTotal{}, fmt.Errorf("failed to add %s %s to tag %s: %w", movement.Type, movement.ID, tag, err)
+			}
+			total.Count++
+		}
+	}
+
+	result := make([]TagTotal, 0, len(totals))
+	for _, total := range totals {
+		netBalance, err := total.TotalIncome.Sub(total.TotalExpense)
+		if err != nil {
+			return []TagTotal{}, fmt.Errorf("failed to compute net balance of tag %s: %w", total.Tag, err)
+		}
+
+		// Los totales quedan en la moneda del reporte o, sin ella, en la de los movimientos
+		currency := report.currency
+		if currency == "" {
+			currency = netBalance.Currency
+		}
+		total.TotalIncome = money.New(total.TotalIncome.Amount, currency)
+		total.TotalExpense = money.New(total.TotalExpense.Amount, currency)
+		total.NetBalance = money.New(netBalance.Amount, currency)
+		result = append(result, *total)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Tag < result[j].Tag
+	})
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package queries
+
+import (
+	"context"
+	"time"
+
+	"escama/domain/money"
+	"escama/infrastructure/projections"
+)
+
+// Transfer representa una transferencia entre dos cuentas
+type Transfer struct {
+	ID             string      `json:"id"`
+	FromAccountID  string      `json:"from_account_id"`
+	ToAccountID    string      `json:"to_account_id"`
+	Amount         money.Money `json:"amount"`          // sale de la cuenta origen
+	ReceivedAmount money.Money `json:"received_amount"` // entra en la cuenta destino
+	Description    *string     `json:"description"`
+	Date           time.Time   `json:"date"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+// GetTransfersQuery consulta para obtener transferencias con filtros de fecha
+type GetTransfersQuery struct {
+	StartDate *time.Time
+	EndDate   *time.Time
+}
+
+// GetTransfers obtiene las transferencias desde las proyecciones
+func (h *ProjectionQueryHandler) GetTransfers(ctx context.Context, query GetTransfersQuery) ([]Transfer, error) {
+	projectionTransfers, err := h.projectionStore.GetTransfers(ctx, query.StartDate, query.EndDate)
+	if err != nil {
+		return []Transfer{}, err
+	}
+
+	transfers := make([]Transfer, len(projectionTransfers))
+	for i, pt := range projectionTransfers {
+		transfers[i] = newTransfer(pt)
+	}
+
+	return transfers, nil
+}
+
+// GetTransferByID obtiene una transferencia específica por ID
+func (h *ProjectionQueryHandler) GetTransferByID(ctx context.Context, id string) (*Transfer, error) {
+	projectionTransfer, err := h.projectionStore.GetTransferByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if projectionTransfer == nil {
+		return nil, nil
+	}
+
+	transfer := newTransfer(*projectionTransfer)
+	return &transfer, nil
+}
+
+func newTransfer(pt projections.TransferProjection) Transfer {
+	return Transfer{
+		ID:             pt.ID,
+		FromAccountID:  pt.FromAccountID,
+		ToAccountID:    pt.ToAccountID,
+		Amount:         pt.Amount,
+		ReceivedAmount: pt.ReceivedAmount,
+		Description:    pt.Description,
+		Date:           pt.Date,
+		CreatedAt:      pt.CreatedAt,
+	}
+}
@@ -3,9 +3,11 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/user"
 	"strconv"
 	"strings"
 	"time"
@@ -13,27 +15,42 @@ import (
 	"escama/application"
 	"escama/application/commands"
 	"escama/application/queries"
+	"escama/domain"
+	"escama/domain/events"
+	"escama/domain/money"
+	"escama/infrastructure/backend"
+	"escama/infrastructure/backup"
+	"escama/infrastructure/encryption"
 	"escama/infrastructure/eventbus"
 	"escama/infrastructure/eventstore"
 	"escama/infrastructure/projections"
+	"escama/infrastructure/rates"
 	"escama/infrastructure/repositories"
+	"escama/infrastructure/subscriptions"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/cobra"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// version del CLI; se puede fijar al compilar con -ldflags "-X main.version=..."
+var version = "dev"
+
 var (
+	appBackend             *backend.Backend
 	eventStore             eventstore.EventStore
 	commandBus             *application.CommandBus
 	queryHandler           *queries.ProjectionQueryHandler
 	categoriesQueryHandler *queries.CategoriesQueryHandler
 	eventPublisher         *eventbus.InMemoryEventPublisher
-	projectionStore        *projections.ProjectionStore
+	outboxRelay            *eventbus.OutboxRelay
+	subscriptionManager    *subscriptions.Manager
+	projectionSubscriber   *eventbus.ProjectionSubscriber
+	projectionStore        projections.ProjectionStore
 	categoryRepo           *repositories.CategoryRepository
 	expenseRepo            *repositories.ExpenseRepository
 	incomeRepo             *repositories.IncomeRepository
+	accountRepo            *repositories.AccountRepository
+	transferRepo           *repositories.TransferRepository
 )
 
 func init() {
@@ -42,40 +59,38 @@ func init() {
 		log.Println("Warning: .env file not found, using system environment variables")
 	}
 
-	// Configurar infrastructure - usar MongoDB
-	mongoStore, err := eventstore.NewMongoEventStore()
-	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
-	}
-	eventStore = mongoStore
-
-	// Configurar cliente MongoDB para proyecciones
-	connectionString := os.Getenv("MONGODB_CONNECTION_STRING")
-	if connectionString == "" {
-		connectionString = "mongodb://localhost:27017/escama"
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	mongoClient, err := mongo.Connect(ctx, options.Client().ApplyURI(connectionString))
+	// Configurar infrastructure según ESCAMA_BACKEND (MongoDB por defecto)
+	var err error
+	appBackend, err = backend.Open(backend.ConfigFromEnv())
 	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB for projections: %v", err)
+		log.Fatalf("Failed to open backend: %v", err)
 	}
+	eventStore = appBackend.EventStore
 
 	// Configurar proyecciones
-	projectionStore = projections.NewProjectionStore(mongoClient, "escama_read")
-	projectionSubscriber := eventbus.NewProjectionSubscriber(projectionStore)
+	projectionStore = appBackend.Projections
+	projectionSubscriber = eventbus.NewProjectionSubscriber(projectionStore)
+
+	// Las proyecciones se ponen al día desde su checkpoint, incluyendo eventos escritos por
+	// otros procesos o perdidos en una ejecución interrumpida
+	subscriptionManager = subscriptions.NewManager(eventStore, appBackend.Checkpoints)
+	subscriptionManager.Register(projectionSubscriber)
 
 	eventPublisher = eventbus.NewInMemoryEventPublisher()
-	eventPublisher.SetProjectionSubscriber(projectionSubscriber)
+	eventPublisher.SetSubscriptions(subscriptionManager)
+	outboxRelay = eventbus.NewOutboxRelay(appBackend.Outbox, eventPublisher.Publish)
 
 	categoryRepo = repositories.NewCategoryRepository(eventStore)
 	expenseRepo = repositories.NewExpenseRepository(eventStore)
 	incomeRepo = repositories.NewIncomeRepository(eventStore)
+	accountRepo = repositories.NewAccountRepository(eventStore)
+	transferRepo = repositories.NewTransferRepository(eventStore)
+	expenseRepo.SetSnapshots(appBackend.Snapshots, appBackend.SnapshotInterval)
+	incomeRepo.SetSnapshots(appBackend.Snapshots, appBackend.SnapshotInterval)
 
 	// Usar proyecciones para queries (más rápido)
 	queryHandler = queries.NewProjectionQueryHandler(projectionStore)
+	queryHandler.SetConverter(appBackend.Rates)
 	categoriesQueryHandler = queries.NewCategoriesQueryHandler(eventStore) // Mantenemos este por ahora
 
 	// Configurar command bus
@@ -83,54 +98,517 @@ func init() {
 
 	// Registrar handlers
 	createCategoryHandler := &commands.CreateCategoryHandler{
-		Save:    categoryRepo.Save,
-		Publish: eventPublisher.Publish,
+		Save:         categoryRepo.Save,
+		Processed:    categoryRepo.Processed,
+		LoadCategory: categoryRepo.GetByID,
 	}
 	commandBus.Register(commands.CreateCategoryCommand{}, &categoryCommandAdapter{handler: createCategoryHandler})
 
+	renameCategoryHandler := &commands.RenameCategoryHandler{
+		Repository: categoryRepo,
+	}
+	commandBus.Register(commands.RenameCategoryCommand{}, &renameCategoryCommandAdapter{handler: renameCategoryHandler})
+
+	archiveCategoryHandler := &commands.ArchiveCategoryHandler{
+		Repository: categoryRepo,
+	}
+	commandBus.Register(commands.ArchiveCategoryCommand{}, &archiveCategoryCommandAdapter{handler: archiveCategoryHandler})
+
+	unarchiveCategoryHandler := &commands.UnarchiveCategoryHandler{
+		Repository: categoryRepo,
+	}
+	commandBus.Register(commands.UnarchiveCategoryCommand{}, &unarchiveCategoryCommandAdapter{handler: unarchiveCategoryHandler})
+
+	deleteCategoryHandler := &commands.DeleteCategoryHandler{
+		Repository: categoryRepo,
+	}
+	commandBus.Register(commands.DeleteCategoryCommand{}, &deleteCategoryCommandAdapter{handler: deleteCategoryHandler})
+
+	setCategoryKindHandler := &commands.SetCategoryKindHandler{
+		Repository: categoryRepo,
+	}
+	commandBus.Register(commands.SetCategoryKindCommand{}, &setCategoryKindCommandAdapter{handler: setCategoryKindHandler})
+
+	setCategoryParentHandler := &commands.SetCategoryParentHandler{
+		Repository: categoryRepo,
+	}
+	commandBus.Register(commands.SetCategoryParentCommand{}, &setCategoryParentCommandAdapter{handler: setCategoryParentHandler})
+
+	mergeCategoryHandler := &commands.MergeCategoryHandler{
+		Repository: categoryRepo,
+	}
+	commandBus.Register(commands.MergeCategoryCommand{}, &mergeCategoryCommandAdapter{handler: mergeCategoryHandler})
+
 	createExpenseHandler := &commands.CreateExpenseHandler{
-		Save:    expenseRepo.Save,
-		Publish: eventPublisher.Publish,
+		Save:         expenseRepo.Save,
+		Processed:    expenseRepo.Processed,
+		LoadAccount:  accountRepo.GetByID,
+		LoadCategory: categoryRepo.GetByID,
 	}
 	commandBus.Register(commands.CreateExpenseCommand{}, &expenseCommandAdapter{handler: createExpenseHandler})
 
 	createIncomeHandler := &commands.CreateIncomeHandler{
-		Save:    incomeRepo.Save,
-		Publish: eventPublisher.Publish,
+		Save:         incomeRepo.Save,
+		Processed:    incomeRepo.Processed,
+		LoadAccount:  accountRepo.GetByID,
+		LoadCategory: categoryRepo.GetByID,
 	}
 	commandBus.Register(commands.CreateIncomeCommand{}, &incomeCommandAdapter{handler: createIncomeHandler})
 
 	// Registrar handlers de actualización
 	updateExpenseHandler := &commands.UpdateExpenseHandler{
-		Repository: expenseRepo,
-		Publish:    eventPublisher.Publish,
+		Repository:   expenseRepo,
+		LoadAccount:  accountRepo.GetByID,
+		LoadCategory: categoryRepo.GetByID,
 	}
 	commandBus.Register(commands.UpdateExpenseCommand{}, &updateExpenseCommandAdapter{handler: updateExpenseHandler})
 
 	updateIncomeHandler := &commands.UpdateIncomeHandler{
-		Repository: incomeRepo,
-		Publish:    eventPublisher.Publish,
+		Repository:   incomeRepo,
+		LoadAccount:  accountRepo.GetByID,
+		LoadCategory: categoryRepo.GetByID,
 	}
 	commandBus.Register(commands.UpdateIncomeCommand{}, &updateIncomeCommandAdapter{handler: updateIncomeHandler})
 
 	// Registrar handlers de eliminación
 	deleteExpenseHandler := &commands.DeleteExpenseHandler{
 		Repository: expenseRepo,
-		Publish:    eventPublisher.Publish,
 	}
 	commandBus.Register(commands.DeleteExpenseCommand{}, &deleteExpenseCommandAdapter{handler: deleteExpenseHandler})
 
 	deleteIncomeHandler := &commands.DeleteIncomeHandler{
 		Repository: incomeRepo,
-		Publish:    eventPublisher.Publish,
 	}
 	commandBus.Register(commands.DeleteIncomeCommand{}, &deleteIncomeCommandAdapter{handler: deleteIncomeHandler})
+
+	// Registrar handlers de etiquetas
+	tagExpenseHandler := &commands.TagExpenseHandler{
+		Repository: expenseRepo,
+	}
+	commandBus.Register(commands.TagExpenseCommand{}, &tagExpenseCommandAdapter{handler: tagExpenseHandler})
+
+	untagExpenseHandler := &commands.UntagExpenseHandler{
+		Repository: expenseRepo,
+	}
+	commandBus.Register(commands.UntagExpenseCommand{}, &untagExpenseCommandAdapter{handler: untagExpenseHandler})
+
+	tagIncomeHandler := &commands.TagIncomeHandler{
+		Repository: incomeRepo,
+	}
+	commandBus.Register(commands.TagIncomeCommand{}, &tagIncomeCommandAdapter{handler: tagIncomeHandler})
+
+	untagIncomeHandler := &commands.UntagIncomeHandler{
+		Repository: incomeRepo,
+	}
+	commandBus.Register(commands.UntagIncomeCommand{}, &untagIncomeCommandAdapter{handler: untagIncomeHandler})
+
+	// Registrar handlers de cuentas
+	createAccountHandler := &commands.CreateAccountHandler{
+		Save:      accountRepo.Save,
+		Processed: accountRepo.Processed,
+	}
+	commandBus.Register(commands.CreateAccountCommand{}, &createAccountCommandAdapter{handler: createAccountHandler})
+
+	renameAccountHandler := &commands.RenameAccountHandler{
+		Repository: accountRepo,
+	}
+	commandBus.Register(commands.RenameAccountCommand{}, &renameAccountCommandAdapter{handler: renameAccountHandler})
+
+	setOpeningBalanceHandler := &commands.SetOpeningBalanceHandler{
+		Repository: accountRepo,
+	}
+	commandBus.Register(commands.SetOpeningBalanceCommand{}, &setOpeningBalanceCommandAdapter{handler: setOpeningBalanceHandler})
+
+	closeAccountHandler := &commands.CloseAccountHandler{
+		Repository: accountRepo,
+	}
+	commandBus.Register(commands.CloseAccountCommand{}, &closeAccountCommandAdapter{handler: closeAccountHandler})
+
+	// Registrar handlers de transferencias
+	createTransferHandler := &commands.CreateTransferHandler{
+		Save:        transferRepo.Save,
+		Processed:   transferRepo.Processed,
+		LoadAccount: accountRepo.GetByID,
+	}
+	commandBus.Register(commands.CreateTransferCommand{}, &createTransferCommandAdapter{handler: createTransferHandler})
+
+	updateTransferHandler := &commands.UpdateTransferHandler{
+		Repository:  transferRepo,
+		LoadAccount: accountRepo.GetByID,
+	}
+	commandBus.Register(commands.UpdateTransferCommand{}, &updateTransferCommandAdapter{handler: updateTransferHandler})
+
+	deleteTransferHandler := &commands.DeleteTransferHandler{
+		Repository: transferRepo,
+	}
+	commandBus.Register(commands.DeleteTransferCommand{}, &deleteTransferCommandAdapter{handler: deleteTransferHandler})
 }
 
 var rootCmd = &cobra.Command{
-	Use:   "escama",
-	Short: "Gestor de finanzas personales con Event Sourcing",
-	Long:  "Una aplicación CLI para gestionar ingresos y gastos usando Event Sourcing con MongoDB",
+	Use:     "escama",
+	Version: version,
+	Short:   "Gestor de finanzas personales con Event Sourcing",
+	Long:    "Una aplicación CLI para gestionar ingresos y gastos usando Event Sourcing con MongoDB, SQLite o un archivo local",
+	// Publicar los eventos pendientes del outbox, incluidos los que dejó una ejecución interrumpida
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		if _, err := outboxRelay.Relay(context.Background()); err != nil {
+			fmt.Printf("⚠️  Error publicando eventos: %v\n", err)
+		}
+	},
+}
+
+var subscriptionsCmd = &cobra.Command{
+	Use:   "subscriptions",
+	Short: "Ver y reiniciar las suscripciones al Event Store",
+}
+
+var subscriptionsStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Ver la posición de cada suscripción",
+	Run: func(cmd *cobra.Command, args []string) {
+		positions, err := subscriptionManager.Positions(context.Background())
+		if err != nil {
+			log.Fatalf("Error getting subscription positions: %v", err)
+		}
+
+		fmt.Println("\n📡 Suscripciones")
+		fmt.Println("════════════════════════════════════")
+		for name, position := range positions {
+			fmt.Printf("  • %s: posición %d\n", name, position)
+		}
+	},
+}
+
+var subscriptionsResetCmd = &cobra.Command{
+	Use:   "reset [nombre]",
+	Short: "Reprocesar todo el historial en una suscripción (por ejemplo: projections)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		if err := subscriptionManager.Reset(ctx, args[0]); err != nil {
+			log.Fatalf("Error resetting subscription: %v", err)
+		}
+		if err := subscriptionManager.CatchUp(ctx); err != nil {
+			log.Fatalf("Error catching up subscriptions: %v", err)
+		}
+
+		fmt.Printf("🔄 Suscripción '%s' reprocesada desde el inicio\n", args[0])
+	},
+}
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Consultar el Event Store",
+}
+
+var listEventsCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Listar eventos con su metadata (correlación, actor, fuente)",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		correlationID, _ := cmd.Flags().GetString("correlation")
+		actor, _ := cmd.Flags().GetString("actor")
+		source, _ := cmd.Flags().GetString("source")
+		eventType, _ := cmd.Flags().GetString("type")
+		from, _ := cmd.Flags().GetInt64("from")
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		filter := eventstore.StreamFilter{
+			CorrelationID: correlationID,
+			Actor:         actor,
+			Source:        source,
+			FromPosition:  from,
+		}
+		if eventType != "" {
+			filter.EventTypes = []string{eventType}
+		}
+
+		fmt.Println("\n🧾 Eventos")
+		fmt.Println("════════════════════════════════════════════════════════════")
+
+		listed := 0
+		err := eventStore.Stream(ctx, filter, func(storedEvent events.StoredEvent) error {
+			metadata := storedEvent.Metadata
+			fmt.Printf("#%d %s %s %s (v%d)\n", storedEvent.GlobalPosition,
+				storedEvent.OccurredAt.Local().Format("2006-01-02 15:04:05"),
+				storedEvent.EventType, storedEvent.AggregateID, storedEvent.Sequence)
+			if !metadata.IsZero() {
+				fmt.Printf("   👤 %s · %s %s · correlación %s · causa %s\n",
+					valueOrDash(metadata.Actor), valueOrDash(metadata.Source), metadata.ClientVersion,
+					valueOrDash(metadata.CorrelationID), valueOrDash(metadata.CausationID))
+				if metadata.IdempotencyKey != "" {
+					fmt.Printf("   🔑 clave de idempotencia %s\n", metadata.IdempotencyKey)
+				}
+			}
+
+			listed++
+			if limit > 0 && listed == limit {
+				return eventstore.ErrStopStream
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatalf("Error listing events: %v", err)
+		}
+
+		fmt.Printf("\n%d eventos\n", listed)
+	},
+}
+
+var verifyEventsCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verificar la cadena de hashes del Event Store e informar el primer eslabón roto",
+	Run: func(cmd *cobra.Command, args []string) {
+		report, err := eventstore.VerifyChain(context.Background(), eventStore)
+		if err != nil {
+			log.Fatalf("Error verifying events: %v", err)
+		}
+
+		if report.Unhashed > 0 {
+			fmt.Printf("ℹ️  %d eventos anteriores a la cadena de hashes (sin verificar)\n", report.Unhashed)
+		}
+
+		if report.Break != nil {
+			chainBreak := report.Break
+			fmt.Printf("❌ Eslabón roto en la posición #%d (evento %s, agregado %s): %s\n",
+				chainBreak.GlobalPosition, chainBreak.EventID, chainBreak.AggregateID, chainBreakReason(chainBreak.Kind))
+			fmt.Printf("   %d eventos verificados antes del corte\n", report.Checked)
+			os.Exit(1)
+		}
+
+		fmt.Printf("✅ Cadena íntegra: %d eventos verificados\n", report.Checked)
+	},
+}
+
+var exportEventsCmd = &cobra.Command{
+	Use:   "export [archivo]",
+	Short: "Exportar el Event Store (o un rango de fechas) a un archivo portable .jsonl.gz",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var opts backup.ExportOptions
+		fromStr, _ := cmd.Flags().GetString("from")
+		toStr, _ := cmd.Flags().GetString("to")
+		if fromStr != "" {
+			from, err := time.Parse("2006-01-02", fromStr)
+			if err != nil {
+				log.Fatalf("Fecha inválida. Use formato YYYY-MM-DD: %v", err)
+			}
+			opts.From = &from
+		}
+		if toStr != "" {
+			to, err := time.Parse("2006-01-02", toStr)
+			if err != nil {
+				log.Fatalf("Fecha inválida. Use formato YYYY-MM-DD: %v", err)
+			}
+			// Incluir el día completo
+			to = to.Add(24*time.Hour - time.Nanosecond)
+			opts.To = &to
+		}
+
+		file, err := os.Create(args[0])
+		if err != nil {
+			log.Fatalf("Error creating archive: %v", err)
+		}
+
+		exported, err := backup.Export(context.Background(), eventStore, file, opts)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(args[0])
+			log.Fatalf("Error exporting events: %v", err)
+		}
+
+		fmt.Printf("📦 %d eventos exportados a %s\n", exported, args[0])
+	},
+}
+
+var importEventsCmd = &cobra.Command{
+	Use:   "import [archivo]",
+	Short: "Importar un archivo exportado, omitiendo los eventos existentes, y reconstruir las proyecciones",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			log.Fatalf("Error opening archive: %v", err)
+		}
+		defer file.Close()
+
+		result, err := backup.Import(ctx, eventStore, file, appBackend.Cipher)
+		if result.Imported > 0 || result.Skipped > 0 {
+			fmt.Printf("📥 %d eventos importados, %d ya existían\n", result.Imported, result.Skipped)
+		}
+		if err != nil {
+			log.Fatalf("Error importing events: %v", err)
+		}
+
+		if err := rebuildProjections(ctx); err != nil {
+			log.Fatalf("Error rebuilding projections: %v", err)
+		}
+		fmt.Println("🔄 Proyecciones reconstruidas")
+	},
+}
+
+var archiveEventsCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Mover a segmentos comprimidos los eventos de los años cerrados (o anteriores a --before)",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		now := time.Now().UTC()
+		before := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		if beforeStr, _ := cmd.Flags().GetString("before"); beforeStr != "" {
+			parsed, err := time.Parse("2006-01-02", beforeStr)
+			if err != nil {
+				log.Fatalf("Fecha inválida. Use formato YYYY-MM-DD: %v", err)
+			}
+			before = parsed
+		}
+
+		// Los eventos archivados salen del outbox: se publican antes los pendientes
+		if _, err := outboxRelay.Relay(ctx); err != nil {
+			log.Fatalf("Error publishing pending events: %v", err)
+		}
+
+		segment, err := appBackend.Archive.ArchiveBefore(ctx, before)
+		if err != nil {
+			log.Fatalf("Error archiving events: %v", err)
+		}
+		if segment == nil {
+			fmt.Printf("ℹ️  No hay eventos anteriores al %s para archivar\n", before.Format("2006-01-02"))
+		} else {
+			fmt.Printf("🗄️  %d eventos archivados en %s (posiciones #%d a #%d, %s a %s)\n",
+				segment.Events, segment.File, segment.FromPosition, segment.ToPosition,
+				segment.FirstOccurredAt.Format("2006-01-02"), segment.LastOccurredAt.Format("2006-01-02"))
+		}
+
+		// El resumen permite calcular balances sin abrir los segmentos
+		movementsHandler := queries.NewMovementsQueryHandler(eventStore)
+		movementsHandler.SetSummaries(appBackend.Snapshots)
+		through, err := movementsHandler.SummarizeArchive(ctx)
+		if err != nil {
+			log.Fatalf("Error summarizing archive: %v", err)
+		}
+		if through > 0 {
+			fmt.Printf("📊 Resumen de balances actualizado hasta la posición #%d\n", through)
+		}
+	},
+}
+
+var projectionsCmd = &cobra.Command{
+	Use:   "projections",
+	Short: "Gestionar las proyecciones de lectura",
+}
+
+var rebuildProjectionsCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Borrar las proyecciones y regenerarlas desde el Event Store",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := rebuildProjections(context.Background()); err != nil {
+			log.Fatalf("Error rebuilding projections: %v", err)
+		}
+		fmt.Println("🔄 Proyecciones reconstruidas")
+	},
+}
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Gestionar las claves de cifrado del keyfile (ESCAMA_KEYFILE)",
+}
+
+var listKeysCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Listar las claves de cifrado por actor",
+	Run: func(cmd *cobra.Command, args []string) {
+		keyring := requireKeyring()
+
+		keys := keyring.Keys()
+		if len(keys) == 0 {
+			fmt.Println("No hay claves de cifrado")
+			return
+		}
+
+		// La última clave de cada actor es la activa
+		active := make(map[string]string)
+		for _, key := range keys {
+			active[key.Owner] = key.ID
+		}
+
+		fmt.Printf("%-18s %-20s %-17s %s\n", "ID", "Actor", "Creada", "Estado")
+		for _, key := range keys {
+			status := "anterior"
+			if active[key.Owner] == key.ID {
+				status = "activa"
+			}
+			fmt.Printf("%-18s %-20s %-17s %s\n",
+				key.ID,
+				key.Owner,
+				key.CreatedAt.Local().Format("2006-01-02 15:04"),
+				status)
+		}
+	},
+}
+
+var rotateKeyCmd = &cobra.Command{
+	Use:   "rotate [actor]",
+	Short: "Generar una nueva clave activa para el actor (por defecto el actual); las anteriores siguen descifrando",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		keyring := requireKeyring()
+
+		key, err := keyring.Rotate(keyOwner(args))
+		if err != nil {
+			log.Fatalf("Error rotating key: %v", err)
+		}
+
+		fmt.Printf("🔑 Nueva clave %s activa para %s\n", key.ID, key.Owner)
+	},
+}
+
+var deleteKeyCmd = &cobra.Command{
+	Use:   "delete [actor]",
+	Short: "Eliminar las claves del actor: sus montos y descripciones quedan ilegibles para siempre",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		keyring := requireKeyring()
+		owner := args[0]
+
+		fmt.Printf("⚠️  Los datos cifrados de %s no se podrán recuperar. ¿Eliminar sus claves? (y/N): ", owner)
+		reader := bufio.NewReader(os.Stdin)
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			log.Fatalf("Error al leer input: %v", err)
+		}
+
+		input = strings.TrimSpace(strings.ToLower(input))
+		if input != "y" && input != "yes" && input != "sí" && input != "si" {
+			fmt.Println("❌ Operación cancelada")
+			return
+		}
+
+		deleted, err := keyring.Delete(owner)
+		if err != nil {
+			log.Fatalf("Error deleting keys: %v", err)
+		}
+		if deleted == 0 {
+			fmt.Printf("ℹ️  %s no tiene claves\n", owner)
+			return
+		}
+		fmt.Printf("🗑️  %d claves de %s eliminadas\n", deleted, owner)
+
+		// Los snapshots y las proyecciones guardan copias de los datos ya descifrados:
+		// se regeneran para que tampoco conserven los del actor
+		if err := appBackend.Snapshots.DeleteAll(ctx, ""); err != nil {
+			log.Fatalf("Error deleting snapshots: %v", err)
+		}
+		if err := rebuildProjections(ctx); err != nil {
+			log.Fatalf("Error rebuilding projections: %v", err)
+		}
+		fmt.Println("🔄 Snapshots eliminados y proyecciones reconstruidas")
+	},
 }
 
 var categoryCmd = &cobra.Command{
@@ -138,22 +616,483 @@ var categoryCmd = &cobra.Command{
 	Short: "Gestión de categorías",
 }
 
-var createCategoryCmd = &cobra.Command{
-	Use:   "create [nombre]",
-	Short: "Crear una nueva categoría",
-	Args:  cobra.ExactArgs(1),
+var createCategoryCmd = &cobra.Command{
+	Use:   "create [nombre]",
+	Short: "Crear una nueva categoría",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		categoryName := args[0]
+
+		kind, _ := cmd.Flags().GetString("kind")
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		createCmd := commands.CreateCategoryCommand{
+			Name:           categoryName,
+			Kind:           kind,
+			IdempotencyKey: idempotencyKey,
+		}
+
+		var parent *queries.Category
+		if parentFlag, _ := cmd.Flags().GetString("parent"); parentFlag != "" {
+			var err error
+			if parent, err = findCategory(parentFlag, true); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			createCmd.ParentID = parent.ID
+		}
+
+		if err := commandBus.Dispatch(commandContext(), createCmd); err != nil {
+			if errors.Is(err, domain.ErrCategoryDeleted) {
+				log.Fatalf("❌ La categoría padre fue eliminada: %v", err)
+			}
+			if errors.Is(err, domain.ErrInvalidCategoryKind) {
+				log.Fatalf("❌ Tipo de categoría inválido (usa income, expense o both): %v", err)
+			}
+			log.Fatalf("Error creating category: %v", err)
+		}
+
+		if parent != nil {
+			fmt.Printf("✅ Categoría '%s' creada exitosamente dentro de '%s'\n", categoryName, parent.Name)
+			return
+		}
+		fmt.Printf("✅ Categoría '%s' creada exitosamente\n", categoryName)
+	},
+}
+
+var moveCategoryCmd = &cobra.Command{
+	Use:   "move [categoría] [padre]",
+	Short: "Mover una categoría dentro de otra; sin padre pasa a ser de primer nivel",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		category, err := findCategory(args[0], true)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		moveCmd := commands.SetCategoryParentCommand{
+			ID:             category.ID,
+			IdempotencyKey: idempotencyKey,
+		}
+
+		var parent *queries.Category
+		if len(args) == 2 {
+			if parent, err = findCategory(args[1], true); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			moveCmd.ParentID = parent.ID
+		}
+
+		if err := commandBus.Dispatch(commandContext(), moveCmd); err != nil {
+			if errors.Is(err, domain.ErrCategoryCycle) {
+				log.Fatalf("❌ '%s' no puede quedar dentro de '%s': la jerarquía formaría un ciclo", category.Name, parent.Name)
+			}
+			log.Fatalf("Error moving category: %v", err)
+		}
+
+		if parent != nil {
+			fmt.Printf("📂 Categoría '%s' movida dentro de '%s'\n", category.Name, parent.Name)
+			return
+		}
+		fmt.Printf("📂 Categoría '%s' movida al primer nivel\n", category.Name)
+	},
+}
+
+var kindCategoryCmd = &cobra.Command{
+	Use:   "kind [categoría] [tipo]",
+	Short: "Cambiar con qué movimientos se usa una categoría: income, expense o both",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		category, err := findCategory(args[0], true)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		kindCmd := commands.SetCategoryKindCommand{
+			ID:             category.ID,
+			Kind:           args[1],
+			IdempotencyKey: idempotencyKey,
+		}
+
+		if err := commandBus.Dispatch(commandContext(), kindCmd); err != nil {
+			if errors.Is(err, domain.ErrInvalidCategoryKind) {
+				log.Fatalf("❌ Tipo de categoría inválido (usa income, expense o both): %v", err)
+			}
+			log.Fatalf("Error changing category kind: %v", err)
+		}
+
+		fmt.Printf("✅ Categoría '%s' ahora es para %s\n", category.Name, categoryKindLabel(args[1]))
+	},
+}
+
+var renameCategoryCmd = &cobra.Command{
+	Use:   "rename [categoría] [nombre]",
+	Short: "Renombrar una categoría; sus movimientos muestran el nuevo nombre",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		category, err := findCategory(args[0], true)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		renameCmd := commands.RenameCategoryCommand{
+			ID:             category.ID,
+			Name:           args[1],
+			IdempotencyKey: idempotencyKey,
+		}
+
+		if err := commandBus.Dispatch(commandContext(), renameCmd); err != nil {
+			log.Fatalf("Error renaming category: %v", err)
+		}
+
+		fmt.Printf("✅ Categoría '%s' renombrada a '%s'\n", category.Name, args[1])
+	},
+}
+
+var archiveCategoryCmd = &cobra.Command{
+	Use:   "archive [categoría]",
+	Short: "Archivar una categoría; deja de ofrecerse para nuevos movimientos",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		category, err := findCategory(args[0], true)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		archiveCmd := commands.ArchiveCategoryCommand{
+			ID:             category.ID,
+			IdempotencyKey: idempotencyKey,
+		}
+
+		if err := commandBus.Dispatch(commandContext(), archiveCmd); err != nil {
+			if errors.Is(err, domain.ErrCategoryArchived) {
+				log.Fatalf("❌ La categoría '%s' ya está archivada", category.Name)
+			}
+			log.Fatalf("Error archiving category: %v", err)
+		}
+
+		fmt.Printf("📦 Categoría '%s' archivada\n", category.Name)
+	},
+}
+
+var unarchiveCategoryCmd = &cobra.Command{
+	Use:   "unarchive [categoría]",
+	Short: "Volver a ofrecer una categoría archivada",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		category, err := findCategory(args[0], true)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		unarchiveCmd := commands.UnarchiveCategoryCommand{
+			ID:             category.ID,
+			IdempotencyKey: idempotencyKey,
+		}
+
+		if err := commandBus.Dispatch(commandContext(), unarchiveCmd); err != nil {
+			if errors.Is(err, domain.ErrCategoryNotArchived) {
+				log.Fatalf("❌ La categoría '%s' no está archivada", category.Name)
+			}
+			log.Fatalf("Error unarchiving category: %v", err)
+		}
+
+		fmt.Printf("✅ Categoría '%s' desarchivada\n", category.Name)
+	},
+}
+
+var deleteCategoryCmd = &cobra.Command{
+	Use:   "delete [categoría]",
+	Short: "Eliminar una categoría; sus movimientos conservan el nombre",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		category, err := findCategory(args[0], true)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		// Confirmar eliminación
+		fmt.Printf("⚠️  ¿Estás seguro de que deseas eliminar la categoría '%s'? (y/N): ", category.Name)
+		reader := bufio.NewReader(os.Stdin)
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			log.Fatalf("Error al leer input: %v", err)
+		}
+
+		input = strings.TrimSpace(strings.ToLower(input))
+		if input != "y" && input != "yes" && input != "sí" && input != "si" {
+			fmt.Println("❌ Operación cancelada")
+			return
+		}
+
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		deleteCmd := commands.DeleteCategoryCommand{
+			ID:             category.ID,
+			IdempotencyKey: idempotencyKey,
+		}
+
+		if err := commandBus.Dispatch(commandContext(), deleteCmd); err != nil {
+			log.Fatalf("Error deleting category: %v", err)
+		}
+
+		fmt.Printf("🗑️  Categoría '%s' eliminada\n", category.Name)
+	},
+}
+
+var mergeCategoryCmd = &cobra.Command{
+	Use:   "merge [origen] [destino]",
+	Short: "Fusionar una categoría en otra; sus movimientos pasan a la de destino",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		source, err := findCategory(args[0], true)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		target, err := findCategory(args[1], true)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		mergeCmd := commands.MergeCategoryCommand{
+			ID:             source.ID,
+			TargetID:       target.ID,
+			IdempotencyKey: idempotencyKey,
+		}
+
+		if err := commandBus.Dispatch(commandContext(), mergeCmd); err != nil {
+			if errors.Is(err, domain.ErrCategoryMergeSelf) {
+				log.Fatalf("❌ No se puede fusionar una categoría consigo misma")
+			}
+			if errors.Is(err, domain.ErrCategoryCycle) {
+				log.Fatalf("❌ No se puede fusionar '%s' en una de sus subcategorías", source.Name)
+			}
+			log.Fatalf("Error merging category: %v", err)
+		}
+
+		fmt.Printf("🔀 Categoría '%s' fusionada en '%s'\n", source.Name, target.Name)
+	},
+}
+
+var listCategoriesCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Ver las categorías",
+	Run: func(cmd *cobra.Command, args []string) {
+		includeArchived, _ := cmd.Flags().GetBool("all")
+		kind, _ := cmd.Flags().GetString("kind")
+		if kind != "" && kind != domain.CategoryKindIncome && kind != domain.CategoryKindExpense {
+			log.Fatalf("❌ Tipo inválido '%s' (usa income o expense)", kind)
+		}
+		query := queries.GetCategoriesQuery{IncludeArchived: includeArchived, Kind: kind}
+		categories, err := categoriesQueryHandler.GetCategories(context.Background(), query)
+		if err != nil {
+			log.Fatalf("Error getting categories: %v", err)
+		}
+
+		if len(categories) == 0 {
+			fmt.Println("📋 No hay categorías registradas")
+			return
+		}
+
+		fmt.Printf("\n📋 Categorías (%d)\n", len(categories))
+		fmt.Printf("════════════════════════════════════════════════════════════\n")
+
+		// Mostrar el árbol: cada subcategoría debajo de su padre. Las que tienen un padre
+		// que no se lista (por ejemplo, archivado) se muestran en el primer nivel.
+		listed := make(map[string]bool, len(categories))
+		for _, category := range categories {
+			listed[category.ID] = true
+		}
+		children := make(map[string][]queries.Category)
+		for _, category := range categories {
+			parentID := category.ParentID
+			if !listed[parentID] {
+				parentID = ""
+			}
+			children[parentID] = append(children[parentID], category)
+		}
+
+		var printTree func(parentID string, depth int)
+		printTree = func(parentID string, depth int) {
+			for _, category := range children[parentID] {
+				status := ""
+				if category.Kind != domain.CategoryKindBoth {
+					status = " [" + categoryKindLabel(category.Kind) + "]"
+				}
+				if category.Archived {
+					status += " (archivada)"
+				}
+				fmt.Printf("%s🏷️  %s%s\n", strings.Repeat("   ", depth), category.Name, status)
+				printTree(category.ID, depth+1)
+			}
+		}
+		printTree("", 0)
+	},
+}
+
+var accountCmd = &cobra.Command{
+	Use:   "account",
+	Short: "Gestión de cuentas (bancos, efectivo y tarjetas)",
+}
+
+var createAccountCmd = &cobra.Command{
+	Use:   "create [nombre]",
+	Short: "Crear una nueva cuenta",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		accountName := args[0]
+
+		kind, _ := cmd.Flags().GetString("kind")
+		currency, _ := cmd.Flags().GetString("currency")
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		createCmd := commands.CreateAccountCommand{
+			Name:           accountName,
+			Kind:           kind,
+			Currency:       currency,
+			IdempotencyKey: idempotencyKey,
+		}
+
+		if openingStr, _ := cmd.Flags().GetString("opening-balance"); openingStr != "" {
+			openingBalance, err := money.Parse(openingStr, currency)
+			if err != nil {
+				log.Fatalf("Saldo inicial inválido: %v", err)
+			}
+			createCmd.OpeningBalance = &openingBalance
+			createCmd.OpeningDate = openingDate(cmd)
+		}
+
+		if err := commandBus.Dispatch(commandContext(), createCmd); err != nil {
+			if errors.Is(err, domain.ErrInvalidAccountKind) {
+				log.Fatalf("❌ Tipo de cuenta inválido, usa bank, cash o card: %v", err)
+			}
+			log.Fatalf("Error creating account: %v", err)
+		}
+
+		fmt.Printf("✅ Cuenta '%s' creada exitosamente\n", accountName)
+	},
+}
+
+var renameAccountCmd = &cobra.Command{
+	Use:   "rename [cuenta] [nombre]",
+	Short: "Renombrar una cuenta",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		account, err := findAccount(args[0])
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		renameCmd := commands.RenameAccountCommand{
+			ID:             account.ID,
+			Name:           args[1],
+			IdempotencyKey: idempotencyKey,
+		}
+
+		if err := commandBus.Dispatch(commandContext(), renameCmd); err != nil {
+			if errors.Is(err, domain.ErrAccountClosed) {
+				log.Fatalf("❌ La cuenta '%s' está cerrada: %v", account.Name, err)
+			}
+			log.Fatalf("Error renaming account: %v", err)
+		}
+
+		fmt.Printf("✅ Cuenta '%s' renombrada a '%s'\n", account.Name, args[1])
+	},
+}
+
+var openingBalanceCmd = &cobra.Command{
+	Use:   "opening-balance [cuenta] [monto]",
+	Short: "Fijar el saldo inicial de una cuenta",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		account, err := findAccount(args[0])
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		amount, err := money.Parse(args[1], account.Currency)
+		if err != nil {
+			log.Fatalf("Monto inválido: %v", err)
+		}
+
+		date := openingDate(cmd)
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		setCmd := commands.SetOpeningBalanceCommand{
+			AccountID:      account.ID,
+			Amount:         amount,
+			Date:           date,
+			IdempotencyKey: idempotencyKey,
+		}
+
+		if err := commandBus.Dispatch(commandContext(), setCmd); err != nil {
+			if errors.Is(err, domain.ErrAccountClosed) {
+				log.Fatalf("❌ La cuenta '%s' está cerrada: %v", account.Name, err)
+			}
+			log.Fatalf("Error setting opening balance: %v", err)
+		}
+
+		fmt.Printf("🏦 Saldo inicial de '%s': %s al %s\n", account.Name, amount, date.Format("2006-01-02"))
+	},
+}
+
+var closeAccountCmd = &cobra.Command{
+	Use:   "close [cuenta]",
+	Short: "Cerrar una cuenta; ya no acepta nuevos movimientos",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		account, err := findAccount(args[0])
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		closeCmd := commands.CloseAccountCommand{
+			ID:             account.ID,
+			IdempotencyKey: idempotencyKey,
+		}
+
+		if err := commandBus.Dispatch(commandContext(), closeCmd); err != nil {
+			if errors.Is(err, domain.ErrAccountClosed) {
+				log.Fatalf("❌ La cuenta '%s' ya está cerrada", account.Name)
+			}
+			log.Fatalf("Error closing account: %v", err)
+		}
+
+		fmt.Printf("🔒 Cuenta '%s' cerrada\n", account.Name)
+	},
+}
+
+var listAccountsCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Ver las cuentas con su saldo",
 	Run: func(cmd *cobra.Command, args []string) {
-		categoryName := args[0]
+		ctx := context.Background()
+		catchUpProjections(ctx)
 
-		createCmd := commands.CreateCategoryCommand{
-			Name: categoryName,
+		includeClosed, _ := cmd.Flags().GetBool("all")
+		accounts, err := queryHandler.GetAccounts(ctx, queries.GetAccountsQuery{IncludeClosed: includeClosed})
+		if err != nil {
+			log.Fatalf("Error getting accounts: %v", err)
 		}
 
-		if err := commandBus.Dispatch(createCmd); err != nil {
-			log.Fatalf("Error creating category: %v", err)
+		if len(accounts) == 0 {
+			fmt.Println("🏦 No hay cuentas registradas")
+			return
 		}
 
-		fmt.Printf("✅ Categoría '%s' creada exitosamente\n", categoryName)
+		fmt.Printf("\n🏦 Cuentas (%d)\n", len(accounts))
+		fmt.Printf("════════════════════════════════════════════════════════════\n")
+
+		for _, account := range accounts {
+			status := ""
+			if account.Closed {
+				status = " (cerrada)"
+			}
+			fmt.Printf("%s %s [%s] - %s%s\n", accountIcon(account.Kind), account.Name, account.Kind, account.Balance, status)
+		}
 	},
 }
 
@@ -163,13 +1102,24 @@ var expenseCmd = &cobra.Command{
 }
 
 var createExpenseCmd = &cobra.Command{
-	Use:   "create [monto] [descripcion] [--category nombre-categoria]",
+	Use:   "create [monto] [descripcion] [--category nombre-categoria] [--account nombre-cuenta]",
 	Short: "Registrar un nuevo gasto",
 	Args:  cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		amountStr := args[0]
 
-		amount, err := strconv.ParseFloat(amountStr, 64)
+		// Obtener cuenta desde flag o selector interactivo
+		account, err := accountFromFlag(cmd, "account")
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if account == nil {
+			if account, err = selectAccount(); err != nil {
+				log.Fatalf("Error al seleccionar cuenta: %v", err)
+			}
+		}
+
+		amount, err := money.Parse(amountStr, amountCurrency(cmd, account))
 		if err != nil {
 			log.Fatalf("Monto inválido: %v", err)
 		}
@@ -192,7 +1142,7 @@ var createExpenseCmd = &cobra.Command{
 				log.Fatalf("Error: %v", err)
 			}
 		} else {
-			selectedCategory, err := selectCategory()
+			selectedCategory, err := selectCategory(domain.CategoryKindExpense)
 			if err != nil {
 				log.Fatalf("Error al seleccionar categoría: %v", err)
 			}
@@ -213,19 +1163,25 @@ var createExpenseCmd = &cobra.Command{
 			movementDate = time.Now()
 		}
 
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
 		createCmd := commands.CreateExpenseCommand{
-			CategoryID:  categoryID,
-			Amount:      amount,
-			Description: description,
-			Date:        movementDate,
+			AccountID:      account.ID,
+			CategoryID:     categoryID,
+			Amount:         amount,
+			Description:    description,
+			Date:           movementDate,
+			IdempotencyKey: idempotencyKey,
 		}
 
-		if err := commandBus.Dispatch(createCmd); err != nil {
+		if err := commandBus.Dispatch(commandContext(), createCmd); err != nil {
+			if errors.Is(err, domain.ErrCategoryKindMismatch) {
+				log.Fatalf("❌ La categoría no admite gastos: %v", err)
+			}
 			log.Fatalf("Error creating expense: %v", err)
 		}
 
 		dateDisplay := movementDate.Format("2006-01-02")
-		fmt.Printf("💸 Gasto de ₲%.0f registrado exitosamente para el %s\n", amount, dateDisplay)
+		fmt.Printf("💸 Gasto de %s registrado exitosamente para el %s\n", amount, dateDisplay)
 	},
 }
 
@@ -235,13 +1191,24 @@ var incomeCmd = &cobra.Command{
 }
 
 var createIncomeCmd = &cobra.Command{
-	Use:   "create [monto] [descripcion] [--category nombre-categoria]",
+	Use:   "create [monto] [descripcion] [--category nombre-categoria] [--account nombre-cuenta]",
 	Short: "Registrar un nuevo ingreso",
 	Args:  cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		amountStr := args[0]
 
-		amount, err := strconv.ParseFloat(amountStr, 64)
+		// Obtener cuenta desde flag o selector interactivo
+		account, err := accountFromFlag(cmd, "account")
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		if account == nil {
+			if account, err = selectAccount(); err != nil {
+				log.Fatalf("Error al seleccionar cuenta: %v", err)
+			}
+		}
+
+		amount, err := money.Parse(amountStr, amountCurrency(cmd, account))
 		if err != nil {
 			log.Fatalf("Monto inválido: %v", err)
 		}
@@ -264,7 +1231,7 @@ var createIncomeCmd = &cobra.Command{
 				log.Fatalf("Error: %v", err)
 			}
 		} else {
-			selectedCategory, err := selectCategory()
+			selectedCategory, err := selectCategory(domain.CategoryKindIncome)
 			if err != nil {
 				log.Fatalf("Error al seleccionar categoría: %v", err)
 			}
@@ -285,25 +1252,31 @@ var createIncomeCmd = &cobra.Command{
 			movementDate = time.Now()
 		}
 
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
 		createCmd := commands.CreateIncomeCommand{
-			CategoryID:  categoryID,
-			Amount:      amount,
-			Description: description,
-			Date:        movementDate,
+			AccountID:      account.ID,
+			CategoryID:     categoryID,
+			Amount:         amount,
+			Description:    description,
+			Date:           movementDate,
+			IdempotencyKey: idempotencyKey,
 		}
 
-		if err := commandBus.Dispatch(createCmd); err != nil {
+		if err := commandBus.Dispatch(commandContext(), createCmd); err != nil {
+			if errors.Is(err, domain.ErrCategoryKindMismatch) {
+				log.Fatalf("❌ La categoría no admite ingresos: %v", err)
+			}
 			log.Fatalf("Error creating income: %v", err)
 		}
 
 		dateDisplay := movementDate.Format("2006-01-02")
-		fmt.Printf("💰 Ingreso de ₲%.0f registrado exitosamente para el %s\n", amount, dateDisplay)
+		fmt.Printf("💰 Ingreso de %s registrado exitosamente para el %s\n", amount, dateDisplay)
 	},
 }
 
 // Comando para actualizar gastos
 var updateExpenseCmd = &cobra.Command{
-	Use:   "update [id] [monto] [descripcion] [--category nombre-categoria]",
+	Use:   "update [id] [monto] [descripcion] [--category nombre-categoria] [--account nombre-cuenta]",
 	Short: "Actualizar un gasto existente",
 	Args:  cobra.MinimumNArgs(3),
 	Run: func(cmd *cobra.Command, args []string) {
@@ -311,7 +1284,19 @@ var updateExpenseCmd = &cobra.Command{
 		amountStr := args[1]
 		description := args[2]
 
-		amount, err := strconv.ParseFloat(amountStr, 64)
+		// Sin --account el movimiento queda en su cuenta actual
+		account, err := accountFromFlag(cmd, "account")
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		accountID := ""
+		if account != nil {
+			accountID = account.ID
+		} else if account, err = movementAccount(expenseID); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		amount, err := money.Parse(amountStr, amountCurrency(cmd, account))
 		if err != nil {
 			log.Fatalf("Monto inválido: %v", err)
 		}
@@ -328,7 +1313,7 @@ var updateExpenseCmd = &cobra.Command{
 				log.Fatalf("Error: %v", err)
 			}
 		} else {
-			selectedCategory, err := selectCategory()
+			selectedCategory, err := selectCategory(domain.CategoryKindExpense)
 			if err != nil {
 				log.Fatalf("Error al seleccionar categoría: %v", err)
 			}
@@ -349,26 +1334,38 @@ var updateExpenseCmd = &cobra.Command{
 			movementDate = time.Now()
 		}
 
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
 		updateCmd := commands.UpdateExpenseCommand{
-			ID:          expenseID,
-			CategoryID:  categoryID,
-			Amount:      amount,
-			Description: &description,
-			Date:        movementDate,
+			ID:             expenseID,
+			AccountID:      accountID,
+			CategoryID:     categoryID,
+			Amount:         amount,
+			Description:    &description,
+			Date:           movementDate,
+			IdempotencyKey: idempotencyKey,
 		}
 
-		if err := commandBus.Dispatch(updateCmd); err != nil {
+		if err := commandBus.Dispatch(commandContext(), updateCmd); err != nil {
+			if errors.Is(err, eventstore.ErrConcurrencyConflict) {
+				log.Fatalf("❌ El gasto fue modificado desde otra sesión, vuelve a intentarlo: %v", err)
+			}
+			if errors.Is(err, commands.ErrAccountRequired) {
+				log.Fatalf("❌ El gasto no tiene cuenta, indica una con --account: %v", err)
+			}
+			if errors.Is(err, domain.ErrCategoryKindMismatch) {
+				log.Fatalf("❌ La categoría no admite gastos: %v", err)
+			}
 			log.Fatalf("Error updating expense: %v", err)
 		}
 
 		dateDisplay := movementDate.Format("2006-01-02")
-		fmt.Printf("💸 Gasto actualizado: ₲%.0f para el %s\n", amount, dateDisplay)
+		fmt.Printf("💸 Gasto actualizado: %s para el %s\n", amount, dateDisplay)
 	},
 }
 
 // Comando para actualizar ingresos
 var updateIncomeCmd = &cobra.Command{
-	Use:   "update [id] [monto] [descripcion] [--category nombre-categoria]",
+	Use:   "update [id] [monto] [descripcion] [--category nombre-categoria] [--account nombre-cuenta]",
 	Short: "Actualizar un ingreso existente",
 	Args:  cobra.MinimumNArgs(3),
 	Run: func(cmd *cobra.Command, args []string) {
@@ -376,7 +1373,19 @@ var updateIncomeCmd = &cobra.Command{
 		amountStr := args[1]
 		description := args[2]
 
-		amount, err := strconv.ParseFloat(amountStr, 64)
+		// Sin --account el movimiento queda en su cuenta actual
+		account, err := accountFromFlag(cmd, "account")
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		accountID := ""
+		if account != nil {
+			accountID = account.ID
+		} else if account, err = movementAccount(incomeID); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+
+		amount, err := money.Parse(amountStr, amountCurrency(cmd, account))
 		if err != nil {
 			log.Fatalf("Monto inválido: %v", err)
 		}
@@ -393,7 +1402,7 @@ var updateIncomeCmd = &cobra.Command{
 				log.Fatalf("Error: %v", err)
 			}
 		} else {
-			selectedCategory, err := selectCategory()
+			selectedCategory, err := selectCategory(domain.CategoryKindIncome)
 			if err != nil {
 				log.Fatalf("Error al seleccionar categoría: %v", err)
 			}
@@ -414,20 +1423,32 @@ var updateIncomeCmd = &cobra.Command{
 			movementDate = time.Now()
 		}
 
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
 		updateCmd := commands.UpdateIncomeCommand{
-			ID:          incomeID,
-			CategoryID:  categoryID,
-			Amount:      amount,
-			Description: &description,
-			Date:        movementDate,
+			ID:             incomeID,
+			AccountID:      accountID,
+			CategoryID:     categoryID,
+			Amount:         amount,
+			Description:    &description,
+			Date:           movementDate,
+			IdempotencyKey: idempotencyKey,
 		}
 
-		if err := commandBus.Dispatch(updateCmd); err != nil {
+		if err := commandBus.Dispatch(commandContext(), updateCmd); err != nil {
+			if errors.Is(err, eventstore.ErrConcurrencyConflict) {
+				log.Fatalf("❌ El ingreso fue modificado desde otra sesión, vuelve a intentarlo: %v", err)
+			}
+			if errors.Is(err, commands.ErrAccountRequired) {
+				log.Fatalf("❌ El ingreso no tiene cuenta, indica una con --account: %v", err)
+			}
+			if errors.Is(err, domain.ErrCategoryKindMismatch) {
+				log.Fatalf("❌ La categoría no admite ingresos: %v", err)
+			}
 			log.Fatalf("Error updating income: %v", err)
 		}
 
 		dateDisplay := movementDate.Format("2006-01-02")
-		fmt.Printf("💰 Ingreso actualizado: ₲%.0f para el %s\n", amount, dateDisplay)
+		fmt.Printf("💰 Ingreso actualizado: %s para el %s\n", amount, dateDisplay)
 	},
 }
 
@@ -453,11 +1474,16 @@ var deleteExpenseCmd = &cobra.Command{
 			return
 		}
 
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
 		deleteCmd := commands.DeleteExpenseCommand{
-			ID: expenseID,
+			ID:             expenseID,
+			IdempotencyKey: idempotencyKey,
 		}
 
-		if err := commandBus.Dispatch(deleteCmd); err != nil {
+		if err := commandBus.Dispatch(commandContext(), deleteCmd); err != nil {
+			if errors.Is(err, eventstore.ErrConcurrencyConflict) {
+				log.Fatalf("❌ El gasto fue modificado desde otra sesión, vuelve a intentarlo: %v", err)
+			}
 			log.Fatalf("Error deleting expense: %v", err)
 		}
 
@@ -487,11 +1513,16 @@ var deleteIncomeCmd = &cobra.Command{
 			return
 		}
 
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
 		deleteCmd := commands.DeleteIncomeCommand{
-			ID: incomeID,
+			ID:             incomeID,
+			IdempotencyKey: idempotencyKey,
 		}
 
-		if err := commandBus.Dispatch(deleteCmd); err != nil {
+		if err := commandBus.Dispatch(commandContext(), deleteCmd); err != nil {
+			if errors.Is(err, eventstore.ErrConcurrencyConflict) {
+				log.Fatalf("❌ El ingreso fue modificado desde otra sesión, vuelve a intentarlo: %v", err)
+			}
 			log.Fatalf("Error deleting income: %v", err)
 		}
 
@@ -499,34 +1530,323 @@ var deleteIncomeCmd = &cobra.Command{
 	},
 }
 
+// Comandos para etiquetar gastos
+var tagExpenseCmd = &cobra.Command{
+	Use:   "tag [id] [etiquetas...]",
+	Short: "Agregar etiquetas a un gasto (por ejemplo: vacaciones-2026 auto)",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		tagCmd := commands.TagExpenseCommand{
+			ID:             args[0],
+			Tags:           args[1:],
+			IdempotencyKey: idempotencyKey,
+		}
+
+		if err := commandBus.Dispatch(commandContext(), tagCmd); err != nil {
+			if errors.Is(err, domain.ErrInvalidTag) {
+				log.Fatalf("❌ Etiqueta inválida, no puede estar vacía ni tener espacios o comas: %v", err)
+			}
+			if errors.Is(err, eventstore.ErrConcurrencyConflict) {
+				log.Fatalf("❌ El gasto fue modificado desde otra sesión, vuelve a intentarlo: %v", err)
+			}
+			log.Fatalf("Error tagging expense: %v", err)
+		}
+
+		fmt.Printf("🏷️  Gasto %s etiquetado: %s\n", args[0], strings.Join(args[1:], ", "))
+	},
+}
+
+var untagExpenseCmd = &cobra.Command{
+	Use:   "untag [id] [etiquetas...]",
+	Short: "Quitar etiquetas de un gasto",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		untagCmd := commands.UntagExpenseCommand{
+			ID:             args[0],
+			Tags:           args[1:],
+			IdempotencyKey: idempotencyKey,
+		}
+
+		if err := commandBus.Dispatch(commandContext(), untagCmd); err != nil {
+			if errors.Is(err, domain.ErrTagNotFound) {
+				log.Fatalf("❌ El gasto no tiene esa etiqueta: %v", err)
+			}
+			if errors.Is(err, domain.ErrInvalidTag) {
+				log.Fatalf("❌ Etiqueta inválida, no puede estar vacía ni tener espacios o comas: %v", err)
+			}
+			if errors.Is(err, eventstore.ErrConcurrencyConflict) {
+				log.Fatalf("❌ El gasto fue modificado desde otra sesión, vuelve a intentarlo: %v", err)
+			}
+			log.Fatalf("Error untagging expense: %v", err)
+		}
+
+		fmt.Printf("🏷️  Etiquetas quitadas del gasto %s: %s\n", args[0], strings.Join(args[1:], ", "))
+	},
+}
+
+// Comandos para etiquetar ingresos
+var tagIncomeCmd = &cobra.Command{
+	Use:   "tag [id] [etiquetas...]",
+	Short: "Agregar etiquetas a un ingreso (por ejemplo: vacaciones-2026 auto)",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		tagCmd := commands.TagIncomeCommand{
+			ID:             args[0],
+			Tags:           args[1:],
+			IdempotencyKey: idempotencyKey,
+		}
+
+		if err := commandBus.Dispatch(commandContext(), tagCmd); err != nil {
+			if errors.Is(err, domain.ErrInvalidTag) {
+				log.Fatalf("❌ Etiqueta inválida, no puede estar vacía ni tener espacios o comas: %v", err)
+			}
+			if errors.Is(err, eventstore.ErrConcurrencyConflict) {
+				log.Fatalf("❌ El ingreso fue modificado desde otra sesión, vuelve a intentarlo: %v", err)
+			}
+			log.Fatalf("Error tagging income: %v", err)
+		}
+
+		fmt.Printf("🏷️  Ingreso %s etiquetado: %s\n", args[0], strings.Join(args[1:], ", "))
+	},
+}
+
+var untagIncomeCmd = &cobra.Command{
+	Use:   "untag [id] [etiquetas...]",
+	Short: "Quitar etiquetas de un ingreso",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		untagCmd := commands.UntagIncomeCommand{
+			ID:             args[0],
+			Tags:           args[1:],
+			IdempotencyKey: idempotencyKey,
+		}
+
+		if err := commandBus.Dispatch(commandContext(), untagCmd); err != nil {
+			if errors.Is(err, domain.ErrTagNotFound) {
+				log.Fatalf("❌ El ingreso no tiene esa etiqueta: %v", err)
+			}
+			if errors.Is(err, domain.ErrInvalidTag) {
+				log.Fatalf("❌ Etiqueta inválida, no puede estar vacía ni tener espacios o comas: %v", err)
+			}
+			if errors.Is(err, eventstore.ErrConcurrencyConflict) {
+				log.Fatalf("❌ El ingreso fue modificado desde otra sesión, vuelve a intentarlo: %v", err)
+			}
+			log.Fatalf("Error untagging income: %v", err)
+		}
+
+		fmt.Printf("🏷️  Etiquetas quitadas del ingreso %s: %s\n", args[0], strings.Join(args[1:], ", "))
+	},
+}
+
+var transferCmd = &cobra.Command{
+	Use:   "transfer",
+	Short: "Transferencias entre cuentas (no cuentan como ingreso ni gasto)",
+}
+
+var createTransferCmd = &cobra.Command{
+	Use:   "create [monto] [descripcion] --from cuenta --to cuenta",
+	Short: "Registrar una transferencia entre dos cuentas",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		from, to := transferAccounts(cmd, nil)
+		amount, receivedAmount := transferAmounts(cmd, args[0], from, to)
+
+		var description *string
+		if len(args) > 1 {
+			desc := args[1]
+			description = &desc
+		}
+
+		transferDate := movementDateFlag(cmd)
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		createCmd := commands.CreateTransferCommand{
+			FromAccountID:  from.ID,
+			ToAccountID:    to.ID,
+			Amount:         amount,
+			ReceivedAmount: receivedAmount,
+			Description:    description,
+			Date:           transferDate,
+			IdempotencyKey: idempotencyKey,
+		}
+
+		if err := commandBus.Dispatch(commandContext(), createCmd); err != nil {
+			transferFailed("creating", err)
+		}
+
+		fmt.Printf("🔁 Transferencia de %s de '%s' a '%s' registrada para el %s\n", amount, from.Name, to.Name, transferDate.Format("2006-01-02"))
+	},
+}
+
+var updateTransferCmd = &cobra.Command{
+	Use:   "update [id] [monto] [descripcion] [--from cuenta] [--to cuenta]",
+	Short: "Actualizar una transferencia existente",
+	Args:  cobra.MinimumNArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		transferID := args[0]
+		description := args[2]
+
+		ctx := context.Background()
+		catchUpProjections(ctx)
+		current, err := queryHandler.GetTransferByID(ctx, transferID)
+		if err != nil {
+			log.Fatalf("Error getting transfer: %v", err)
+		}
+		if current == nil {
+			log.Fatalf("❌ Transferencia %s no encontrada", transferID)
+		}
+
+		// Sin --from/--to la transferencia conserva sus cuentas
+		from, to := transferAccounts(cmd, current)
+		amount, receivedAmount := transferAmounts(cmd, args[1], from, to)
+
+		transferDate := movementDateFlag(cmd)
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		updateCmd := commands.UpdateTransferCommand{
+			ID:             transferID,
+			FromAccountID:  from.ID,
+			ToAccountID:    to.ID,
+			Amount:         amount,
+			ReceivedAmount: receivedAmount,
+			Description:    &description,
+			Date:           transferDate,
+			IdempotencyKey: idempotencyKey,
+		}
+
+		if err := commandBus.Dispatch(commandContext(), updateCmd); err != nil {
+			transferFailed("updating", err)
+		}
+
+		fmt.Printf("🔁 Transferencia actualizada: %s de '%s' a '%s' para el %s\n", amount, from.Name, to.Name, transferDate.Format("2006-01-02"))
+	},
+}
+
+var deleteTransferCmd = &cobra.Command{
+	Use:   "delete [id]",
+	Short: "Eliminar una transferencia existente",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		transferID := args[0]
+
+		// Confirmar eliminación
+		fmt.Printf("⚠️  ¿Estás seguro de que deseas eliminar la transferencia %s? (y/N): ", transferID)
+		reader := bufio.NewReader(os.Stdin)
+		input, err := reader.ReadString('\n')
+		if err != nil {
+			log.Fatalf("Error al leer input: %v", err)
+		}
+
+		input = strings.TrimSpace(strings.ToLower(input))
+		if input != "y" && input != "yes" && input != "sí" && input != "si" {
+			fmt.Println("❌ Operación cancelada")
+			return
+		}
+
+		idempotencyKey, _ := cmd.Flags().GetString("idempotency-key")
+		deleteCmd := commands.DeleteTransferCommand{
+			ID:             transferID,
+			IdempotencyKey: idempotencyKey,
+		}
+
+		if err := commandBus.Dispatch(commandContext(), deleteCmd); err != nil {
+			transferFailed("deleting", err)
+		}
+
+		fmt.Printf("🔁 Transferencia %s eliminada exitosamente\n", transferID)
+	},
+}
+
+var listTransfersCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Ver las transferencias registradas",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		catchUpProjections(ctx)
+
+		transfers, err := queryHandler.GetTransfers(ctx, queries.GetTransfersQuery{})
+		if err != nil {
+			log.Fatalf("Error getting transfers: %v", err)
+		}
+
+		if len(transfers) == 0 {
+			fmt.Println("🔁 No hay transferencias registradas")
+			return
+		}
+
+		accounts, err := queryHandler.GetAccounts(ctx, queries.GetAccountsQuery{IncludeClosed: true})
+		if err != nil {
+			log.Fatalf("Error getting accounts: %v", err)
+		}
+		accountNames := make(map[string]string, len(accounts))
+		for _, account := range accounts {
+			accountNames[account.ID] = account.Name
+		}
+
+		fmt.Printf("\n🔁 Transferencias (%d)\n", len(transfers))
+		fmt.Printf("════════════════════════════════════════════════════════════\n")
+
+		for _, transfer := range transfers {
+			desc := "Sin descripción"
+			if transfer.Description != nil {
+				desc = *transfer.Description
+			}
+
+			amount := transfer.Amount.String()
+			if transfer.ReceivedAmount != transfer.Amount {
+				amount += " → " + transfer.ReceivedAmount.String()
+			}
+
+			fmt.Printf("%s - %s → %s - %s - %s - %s\n",
+				transfer.Date.Format("2006-01-02"),
+				valueOrDash(accountNames[transfer.FromAccountID]),
+				valueOrDash(accountNames[transfer.ToAccountID]),
+				amount,
+				desc,
+				transfer.ID)
+		}
+	},
+}
+
 var balanceCmd = &cobra.Command{
 	Use:   "balance",
 	Short: "Ver balance actual",
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := context.Background()
+		catchUpProjections(ctx)
 
 		// Balance del mes actual
 		now := time.Now()
 		startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
 		endOfMonth := startOfMonth.AddDate(0, 1, -1)
 
+		currency, _ := cmd.Flags().GetString("currency")
 		balance, err := queryHandler.GetBalance(ctx, queries.GetBalanceQuery{
 			StartDate: startOfMonth,
 			EndDate:   endOfMonth,
+			Currency:  currency,
 		})
+		if errors.Is(err, money.ErrCurrencyMismatch) {
+			log.Fatalf("❌ Hay movimientos en varias monedas, usa --currency para convertirlos a una: %v", err)
+		}
+		if errors.Is(err, rates.ErrRateNotFound) {
+			log.Fatalf("❌ Falta una cotización para convertir el balance (ver ESCAMA_RATES_DIR): %v", err)
+		}
 		if err != nil {
 			log.Fatalf("Error getting balance: %v", err)
 		}
 
 		fmt.Printf("\n📊 Balance del mes (%s)\n", balance.Period)
 		fmt.Printf("════════════════════════════════════\n")
-		fmt.Printf("💰 Total Ingresos:  ₲%.0f\n", balance.TotalIncome)
-		fmt.Printf("💸 Total Gastos:    ₲%.0f\n", balance.TotalExpense)
-		fmt.Printf("📈 Balance Neto:    ₲%.0f\n", balance.NetBalance)
+		fmt.Printf("💰 Total Ingresos:  %s\n", balance.TotalIncome)
+		fmt.Printf("💸 Total Gastos:    %s\n", balance.TotalExpense)
+		fmt.Printf("📈 Balance Neto:    %s\n", balance.NetBalance)
 
-		if balance.NetBalance > 0 {
+		if balance.NetBalance.Amount > 0 {
 			fmt.Printf("✅ ¡Felicitaciones! Tienes un balance positivo\n")
-		} else if balance.NetBalance < 0 {
+		} else if balance.NetBalance.Amount < 0 {
 			fmt.Printf("⚠️  Cuidado, tienes un balance negativo\n")
 		} else {
 			fmt.Printf("⚖️  Balance equilibrado\n")
@@ -539,72 +1859,328 @@ var movementsCmd = &cobra.Command{
 	Short: "Ver movimientos recientes",
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := context.Background()
+		catchUpProjections(ctx)
+
+		query := queries.GetMovementsQuery{}
+		if tag, _ := cmd.Flags().GetString("tag"); tag != "" {
+			normalized, err := domain.NormalizeTag(tag)
+			if err != nil {
+				log.Fatalf("❌ Etiqueta inválida, no puede estar vacía ni tener espacios o comas: %v", err)
+			}
+			query.Tag = normalized
+		}
 
-		paginatedResult, err := queryHandler.GetPaginatedMovements(ctx, queries.GetMovementsQuery{})
+		paginatedResult, err := queryHandler.GetPaginatedMovements(ctx, query)
 		if err != nil {
 			log.Fatalf("Error getting movements: %v", err)
 		}
 
-		movements := paginatedResult.Movements
+		movements := paginatedResult.Movements
+
+		if len(movements) == 0 {
+			fmt.Println("📝 No hay movimientos registrados")
+			return
+		}
+
+		fmt.Printf("\n📋 Movimientos recientes (%d)\n", len(movements))
+		fmt.Printf("════════════════════════════════════════════════════════════\n")
+
+		for _, movement := range movements {
+			typeIcon := "💸"
+			if movement.Type == "income" {
+				typeIcon = "💰"
+			}
+
+			desc := "Sin descripción"
+			if movement.Description != nil {
+				desc = *movement.Description
+			}
+
+			tags := ""
+			if len(movement.Tags) > 0 {
+				tags = " #" + strings.Join(movement.Tags, " #")
+			}
+
+			fmt.Printf("%s %s - %s - %s - %s%s\n",
+				typeIcon,
+				movement.Date.Format("2006-01-02"),
+				movement.Amount,
+				desc,
+				movement.CategoryID,
+				tags)
+		}
+	},
+}
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Reportes de ingresos y gastos",
+}
+
+var tagsReportCmd = &cobra.Command{
+	Use:   "tags",
+	Short: "Ver los ingresos y gastos de cada etiqueta",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		catchUpProjections(ctx)
+
+		currency, _ := cmd.Flags().GetString("currency")
+		query := queries.GetTagTotalsQuery{Currency: currency}
+		if from, _ := cmd.Flags().GetString("from"); from != "" {
+			startDate, err := time.Parse("2006-01-02", from)
+			if err != nil {
+				log.Fatalf("Fecha --from inválida. Use formato YYYY-MM-DD: %v", err)
+			}
+			query.StartDate = &startDate
+		}
+		if to, _ := cmd.Flags().GetString("to"); to != "" {
+			endDate, err := time.Parse("2006-01-02", to)
+			if err != nil {
+				log.Fatalf("Fecha --to inválida. Use formato YYYY-MM-DD: %v", err)
+			}
+			endOfDay := endDate.Add(24*time.Hour - time.Nanosecond) // Incluir el día completo
+			query.EndDate = &endOfDay
+		}
+
+		tagTotals, err := queryHandler.GetTagTotals(ctx, query)
+		if errors.Is(err, money.ErrCurrencyMismatch) {
+			log.Fatalf("❌ Hay movimientos en varias monedas, usa --currency para convertirlos a una: %v", err)
+		}
+		if errors.Is(err, rates.ErrRateNotFound) {
+			log.Fatalf("❌ Falta una cotización para convertir los totales (ver ESCAMA_RATES_DIR): %v", err)
+		}
+		if err != nil {
+			log.Fatalf("Error getting tag totals: %v", err)
+		}
+
+		if len(tagTotals) == 0 {
+			fmt.Println("🏷️  No hay movimientos etiquetados")
+			return
+		}
+
+		fmt.Printf("\n🏷️  Totales por etiqueta (%d)\n", len(tagTotals))
+		fmt.Printf("════════════════════════════════════════════════════════════\n")
+		fmt.Printf("%-20s %16s %16s %16s %6s\n", "Etiqueta", "Ingresos", "Gastos", "Neto", "Mov.")
+		for _, total := range tagTotals {
+			fmt.Printf("%-20s %16s %16s %16s %6d\n",
+				total.Tag,
+				total.TotalIncome,
+				total.TotalExpense,
+				total.NetBalance,
+				total.Count)
+		}
+		fmt.Println("\nℹ️  Un movimiento con varias etiquetas suma en cada una")
+	},
+}
+
+var snapshotsCmd = &cobra.Command{
+	Use:   "snapshots",
+	Short: "Gestionar snapshots de gastos e ingresos",
+}
+
+var rebuildSnapshotsCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Reconstruir los snapshots desde el Event Store",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		aggregateTypes, err := snapshotAggregateTypes(cmd)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+
+		// Cada agregado tiene un único evento de creación: alcanza con recorrer esos
+		rebuilt := 0
+		filter := eventstore.StreamFilter{
+			AggregateTypes: aggregateTypes,
+			EventTypes:     []string{"ExpenseCreated", "IncomeCreated"},
+		}
+		err = eventStore.Stream(ctx, filter, func(storedEvent events.StoredEvent) error {
+			// Borrar el snapshot anterior para forzar la reproducción completa del stream
+			if err := appBackend.Snapshots.Delete(ctx, storedEvent.AggregateID); err != nil {
+				return fmt.Errorf("failed to delete snapshot %s: %w", storedEvent.AggregateID, err)
+			}
+
+			switch storedEvent.AggregateType {
+			case "Expense":
+				expense, err := expenseRepo.GetByID(ctx, storedEvent.AggregateID)
+				if err == nil && expense != nil {
+					err = expenseRepo.TakeSnapshot(ctx, expense)
+				}
+				if err != nil {
+					return fmt.Errorf("failed to rebuild snapshot %s: %w", storedEvent.AggregateID, err)
+				}
+			case "Income":
+				income, err := incomeRepo.GetByID(ctx, storedEvent.AggregateID)
+				if err == nil && income != nil {
+					err = incomeRepo.TakeSnapshot(ctx, income)
+				}
+				if err != nil {
+					return fmt.Errorf("failed to rebuild snapshot %s: %w", storedEvent.AggregateID, err)
+				}
+			}
+			rebuilt++
+			return nil
+		})
+		if err != nil {
+			log.Fatalf("Error rebuilding snapshots: %v", err)
+		}
+
+		fmt.Printf("📸 %d snapshots reconstruidos\n", rebuilt)
+	},
+}
+
+var invalidateSnapshotsCmd = &cobra.Command{
+	Use:   "invalidate",
+	Short: "Eliminar snapshots; los agregados se reconstruirán desde sus eventos",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		aggregateTypes, err := snapshotAggregateTypes(cmd)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+
+		for _, aggregateType := range aggregateTypes {
+			if err := appBackend.Snapshots.DeleteAll(ctx, aggregateType); err != nil {
+				log.Fatalf("Error deleting snapshots: %v", err)
+			}
+		}
+
+		fmt.Println("🗑️  Snapshots eliminados")
+	},
+}
 
-		if len(movements) == 0 {
-			fmt.Println("📝 No hay movimientos registrados")
-			return
+// snapshotAggregateTypes traduce el flag --type a los tipos de agregado con snapshots
+func snapshotAggregateTypes(cmd *cobra.Command) ([]string, error) {
+	aggregateType, _ := cmd.Flags().GetString("type")
+	switch strings.ToLower(aggregateType) {
+	case "":
+		return []string{"Expense", "Income"}, nil
+	case "expense":
+		return []string{"Expense"}, nil
+	case "income":
+		return []string{"Income"}, nil
+	default:
+		return nil, fmt.Errorf("tipo inválido '%s'. Usa expense o income", aggregateType)
+	}
+}
+
+// commandContext devuelve el contexto con el que el CLI despacha comandos: los eventos
+// quedan registrados con el actor (ESCAMA_ACTOR o el usuario del sistema), la fuente
+// escama-cli y la versión del cliente
+func commandContext() context.Context {
+	actor := os.Getenv("ESCAMA_ACTOR")
+	if actor == "" {
+		if current, err := user.Current(); err == nil {
+			actor = current.Username
 		}
+	}
 
-		fmt.Printf("\n📋 Movimientos recientes (%d)\n", len(movements))
-		fmt.Printf("════════════════════════════════════════════════════════════\n")
+	return events.WithMetadata(context.Background(), events.Metadata{
+		Actor:         actor,
+		Source:        "escama-cli",
+		ClientVersion: version,
+	})
+}
 
-		for _, movement := range movements {
-			typeIcon := "💸"
-			if movement.Type == "income" {
-				typeIcon = "💰"
-			}
+// requireKeyring devuelve el keyring del backend o termina si el cifrado no está activo
+func requireKeyring() *encryption.Keyring {
+	if appBackend.Keyring == nil {
+		log.Fatalf("❌ El cifrado no está activo. Configura ESCAMA_KEYFILE con la ruta del keyfile")
+	}
+	return appBackend.Keyring
+}
 
-			desc := "Sin descripción"
-			if movement.Description != nil {
-				desc = *movement.Description
-			}
+// keyOwner devuelve el actor indicado o, si no hay, el actor con el que el CLI registra
+// los eventos
+func keyOwner(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return events.MetadataFromContext(commandContext()).Actor
+}
 
-			fmt.Printf("%s %s - ₲%.0f - %s - %s\n",
-				typeIcon,
-				movement.Date.Format("2006-01-02"),
-				movement.Amount,
-				desc,
-				movement.CategoryID)
-		}
-	},
+// chainBreakReason describe en castellano el tipo de eslabón roto
+func chainBreakReason(kind string) string {
+	switch kind {
+	case eventstore.BreakMissingHash:
+		return "el evento no tiene hash"
+	case eventstore.BreakHashMismatch:
+		return "el contenido no coincide con su hash (evento modificado)"
+	case eventstore.BreakGlobalLink:
+		return "no enlaza con el evento anterior del store (evento eliminado o insertado)"
+	case eventstore.BreakStreamLink:
+		return "no enlaza con el evento anterior de su agregado"
+	default:
+		return kind
+	}
+}
+
+// valueOrDash muestra "-" en lugar de un valor vacío
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// rebuildProjections borra las proyecciones y las regenera desde el inicio del Event Store
+func rebuildProjections(ctx context.Context) error {
+	if err := projectionStore.Reset(ctx); err != nil {
+		return err
+	}
+	if err := subscriptionManager.Reset(ctx, projectionSubscriber.Name()); err != nil {
+		return err
+	}
+	return subscriptionManager.CatchUp(ctx)
 }
 
-// findCategoryByName busca una categoría por su nombre y devuelve su ID
+// catchUpProjections aplica a las proyecciones los eventos que aún no procesaron antes
+// de consultarlas; si falla se muestran los datos disponibles
+func catchUpProjections(ctx context.Context) {
+	if err := subscriptionManager.CatchUp(ctx); err != nil {
+		fmt.Printf("⚠️  Las proyecciones pueden estar desactualizadas: %v\n", err)
+	}
+}
+
+// findCategoryByName busca una categoría no archivada por su nombre y devuelve su ID
 func findCategoryByName(categoryName string) (string, error) {
+	category, err := findCategory(categoryName, false)
+	if err != nil {
+		return "", err
+	}
+	return category.ID, nil
+}
+
+// findCategory busca una categoría por nombre o ID; las archivadas solo si includeArchived
+func findCategory(nameOrID string, includeArchived bool) (*queries.Category, error) {
 	ctx := context.Background()
-	categories, err := categoriesQueryHandler.GetCategories(ctx, queries.GetCategoriesQuery{})
+	categories, err := categoriesQueryHandler.GetCategories(ctx, queries.GetCategoriesQuery{IncludeArchived: includeArchived})
 	if err != nil {
-		return "", fmt.Errorf("error al obtener categorías: %w", err)
+		return nil, fmt.Errorf("error al obtener categorías: %w", err)
 	}
 
-	// Buscar por coincidencia exacta (sin importar mayúsculas/minúsculas)
-	for _, category := range categories {
-		if strings.EqualFold(category.Name, categoryName) {
-			return category.ID, nil
+	// Buscar por ID o por coincidencia exacta del nombre (sin importar mayúsculas/minúsculas)
+	for i, category := range categories {
+		if category.ID == nameOrID || strings.EqualFold(category.Name, nameOrID) {
+			return &categories[i], nil
 		}
 	}
 
 	// Si no hay coincidencia exacta, mostrar categorías disponibles
-	fmt.Printf("❌ Categoría '%s' no encontrada.\n", categoryName)
+	fmt.Printf("❌ Categoría '%s' no encontrada.\n", nameOrID)
 	fmt.Println("\n📋 Categorías disponibles:")
 	for _, category := range categories {
 		fmt.Printf("  • %s\n", category.Name)
 	}
 
-	return "", fmt.Errorf("categoría '%s' no encontrada", categoryName)
+	return nil, fmt.Errorf("categoría '%s' no encontrada", nameOrID)
 }
 
-// selectCategory muestra un selector interactivo de categorías existentes
-func selectCategory() (string, error) {
+// selectCategory muestra un selector interactivo de las categorías no archivadas que
+// admiten el tipo de movimiento indicado
+func selectCategory(kind string) (string, error) {
 	ctx := context.Background()
-	categories, err := categoriesQueryHandler.GetCategories(ctx, queries.GetCategoriesQuery{})
+	categories, err := categoriesQueryHandler.GetCategories(ctx, queries.GetCategoriesQuery{Kind: kind})
 	if err != nil {
 		return "", fmt.Errorf("error al obtener categorías: %w", err)
 	}
@@ -638,41 +2214,383 @@ func selectCategory() (string, error) {
 	return selectedCategory.ID, nil
 }
 
+// categoryKindLabel describe el tipo de categoría para mostrarlo
+func categoryKindLabel(kind string) string {
+	switch kind {
+	case domain.CategoryKindIncome:
+		return "ingresos"
+	case domain.CategoryKindExpense:
+		return "gastos"
+	default:
+		return "ingresos y gastos"
+	}
+}
+
+// findAccount busca una cuenta por nombre o ID
+func findAccount(nameOrID string) (*queries.Account, error) {
+	ctx := context.Background()
+	catchUpProjections(ctx)
+
+	accounts, err := queryHandler.GetAccounts(ctx, queries.GetAccountsQuery{IncludeClosed: true})
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener cuentas: %w", err)
+	}
+
+	// Buscar por ID o por nombre (sin importar mayúsculas/minúsculas)
+	for i, account := range accounts {
+		if account.ID == nameOrID || strings.EqualFold(account.Name, nameOrID) {
+			return &accounts[i], nil
+		}
+	}
+
+	fmt.Printf("❌ Cuenta '%s' no encontrada.\n", nameOrID)
+	fmt.Println("\n🏦 Cuentas disponibles:")
+	for _, account := range accounts {
+		fmt.Printf("  • %s\n", account.Name)
+	}
+
+	return nil, fmt.Errorf("cuenta '%s' no encontrada", nameOrID)
+}
+
+// selectAccount muestra un selector interactivo de las cuentas abiertas
+func selectAccount() (*queries.Account, error) {
+	ctx := context.Background()
+	catchUpProjections(ctx)
+
+	accounts, err := queryHandler.GetAccounts(ctx, queries.GetAccountsQuery{})
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener cuentas: %w", err)
+	}
+
+	if len(accounts) == 0 {
+		fmt.Println("❌ No hay cuentas abiertas.")
+		fmt.Println("💡 Crea una cuenta primero con: escama-cli account create [nombre]")
+		return nil, fmt.Errorf("no hay cuentas disponibles")
+	}
+
+	fmt.Println("\n🏦 Cuentas disponibles:")
+	for i, account := range accounts {
+		fmt.Printf("  %d. %s (%s)\n", i+1, account.Name, account.Currency)
+	}
+
+	fmt.Print("\n🎯 Selecciona una cuenta (número): ")
+	reader := bufio.NewReader(os.Stdin)
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("error al leer input: %w", err)
+	}
+
+	input = strings.TrimSpace(input)
+	selection, err := strconv.Atoi(input)
+	if err != nil || selection < 1 || selection > len(accounts) {
+		return nil, fmt.Errorf("selección inválida. Debe ser un número entre 1 y %d", len(accounts))
+	}
+
+	selectedAccount := accounts[selection-1]
+	fmt.Printf("✅ Cuenta seleccionada: %s\n", selectedAccount.Name)
+	return &selectedAccount, nil
+}
+
+// accountFromFlag devuelve la cuenta indicada en el flag, o nil si no se indicó
+func accountFromFlag(cmd *cobra.Command, flag string) (*queries.Account, error) {
+	accountFlag, _ := cmd.Flags().GetString(flag)
+	if accountFlag == "" {
+		return nil, nil
+	}
+	return findAccount(accountFlag)
+}
+
+// movementAccount devuelve la cuenta actual de un movimiento, o nil si no tiene
+func movementAccount(movementID string) (*queries.Account, error) {
+	ctx := context.Background()
+	catchUpProjections(ctx)
+
+	movement, err := queryHandler.GetMovementByID(ctx, movementID)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener el movimiento: %w", err)
+	}
+	if movement == nil || movement.AccountID == "" {
+		return nil, nil
+	}
+	return queryHandler.GetAccountByID(ctx, movement.AccountID)
+}
+
+// amountCurrency devuelve la moneda del monto: la de --currency si se indicó, si no la
+// de la cuenta del movimiento
+func amountCurrency(cmd *cobra.Command, account *queries.Account) string {
+	currency, _ := cmd.Flags().GetString("currency")
+	if account != nil && !cmd.Flags().Changed("currency") {
+		return account.Currency
+	}
+	return currency
+}
+
+// openingDate devuelve la fecha de --date o la actual
+func openingDate(cmd *cobra.Command) time.Time {
+	dateStr, _ := cmd.Flags().GetString("date")
+	if dateStr == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		log.Fatalf("Fecha inválida. Use formato YYYY-MM-DD: %v", err)
+	}
+	return date
+}
+
+// transferAccounts devuelve las cuentas de --from y --to; sin flag usa las de current,
+// la transferencia que se actualiza
+func transferAccounts(cmd *cobra.Command, current *queries.Transfer) (*queries.Account, *queries.Account) {
+	from, err := accountFromFlag(cmd, "from")
+	if err == nil && from == nil && current != nil {
+		from, err = queryHandler.GetAccountByID(context.Background(), current.FromAccountID)
+	}
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	to, err := accountFromFlag(cmd, "to")
+	if err == nil && to == nil && current != nil {
+		to, err = queryHandler.GetAccountByID(context.Background(), current.ToAccountID)
+	}
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	if from == nil || to == nil {
+		log.Fatalf("❌ Indica la cuenta origen con --from y la destino con --to")
+	}
+	return from, to
+}
+
+// transferAmounts interpreta el monto en la moneda de la cuenta origen y --received en
+// la de la cuenta destino
+func transferAmounts(cmd *cobra.Command, amountStr string, from, to *queries.Account) (money.Money, *money.Money) {
+	amount, err := money.Parse(amountStr, from.Currency)
+	if err != nil {
+		log.Fatalf("Monto inválido: %v", err)
+	}
+
+	receivedStr, _ := cmd.Flags().GetString("received")
+	if receivedStr == "" {
+		return amount, nil
+	}
+	receivedAmount, err := money.Parse(receivedStr, to.Currency)
+	if err != nil {
+		log.Fatalf("Monto recibido inválido: %v", err)
+	}
+	return amount, &receivedAmount
+}
+
+// movementDateFlag devuelve la fecha de --date o el momento actual
+func movementDateFlag(cmd *cobra.Command) time.Time {
+	dateStr, _ := cmd.Flags().GetString("date")
+	if dateStr == "" {
+		return time.Now()
+	}
+
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		log.Fatalf("Fecha inválida. Use formato YYYY-MM-DD: %v", err)
+	}
+	return date
+}
+
+// transferFailed termina el comando explicando por qué no se pudo guardar la transferencia
+func transferFailed(action string, err error) {
+	switch {
+	case errors.Is(err, domain.ErrSameAccount):
+		log.Fatalf("❌ La cuenta origen y la destino deben ser distintas")
+	case errors.Is(err, money.ErrCurrencyMismatch):
+		log.Fatalf("❌ Las cuentas usan monedas distintas, indica el monto acreditado con --received: %v", err)
+	case errors.Is(err, domain.ErrAccountClosed):
+		log.Fatalf("❌ No se puede transferir desde o hacia una cuenta cerrada: %v", err)
+	case errors.Is(err, eventstore.ErrConcurrencyConflict):
+		log.Fatalf("❌ La transferencia fue modificada desde otra sesión, vuelve a intentarlo: %v", err)
+	}
+	log.Fatalf("Error %s transfer: %v", action, err)
+}
+
+func accountIcon(kind string) string {
+	switch kind {
+	case domain.AccountKindCash:
+		return "💵"
+	case domain.AccountKindCard:
+		return "💳"
+	default:
+		return "🏦"
+	}
+}
+
 // Adapters (similar a main.go)
 type categoryCommandAdapter struct {
 	handler *commands.CreateCategoryHandler
 }
 
-func (a *categoryCommandAdapter) Handle(cmd application.Command) error {
+func (a *categoryCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
 	categoryCmd, ok := cmd.(commands.CreateCategoryCommand)
 	if !ok {
 		return fmt.Errorf("invalid command type for category handler")
 	}
-	return a.handler.Handle(context.Background(), categoryCmd)
+	return a.handler.Handle(ctx, categoryCmd)
+}
+
+type renameCategoryCommandAdapter struct {
+	handler *commands.RenameCategoryHandler
+}
+
+func (a *renameCategoryCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
+	renameCmd, ok := cmd.(commands.RenameCategoryCommand)
+	if !ok {
+		return fmt.Errorf("invalid command type for rename category handler")
+	}
+	return a.handler.Handle(ctx, renameCmd)
+}
+
+type archiveCategoryCommandAdapter struct {
+	handler *commands.ArchiveCategoryHandler
+}
+
+func (a *archiveCategoryCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
+	archiveCmd, ok := cmd.(commands.ArchiveCategoryCommand)
+	if !ok {
+		return fmt.Errorf("invalid command type for archive category handler")
+	}
+	return a.handler.Handle(ctx, archiveCmd)
+}
+
+type unarchiveCategoryCommandAdapter struct {
+	handler *commands.UnarchiveCategoryHandler
+}
+
+func (a *unarchiveCategoryCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
+	unarchiveCmd, ok := cmd.(commands.UnarchiveCategoryCommand)
+	if !ok {
+		return fmt.Errorf("invalid command type for unarchive category handler")
+	}
+	return a.handler.Handle(ctx, unarchiveCmd)
+}
+
+type deleteCategoryCommandAdapter struct {
+	handler *commands.DeleteCategoryHandler
+}
+
+func (a *deleteCategoryCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
+	deleteCmd, ok := cmd.(commands.DeleteCategoryCommand)
+	if !ok {
+		return fmt.Errorf("invalid command type for delete category handler")
+	}
+	return a.handler.Handle(ctx, deleteCmd)
+}
+
+type tagExpenseCommandAdapter struct {
+	handler *commands.TagExpenseHandler
+}
+
+func (a *tagExpenseCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
+	tagCmd, ok := cmd.(commands.TagExpenseCommand)
+	if !ok {
+		return fmt.Errorf("invalid command type for tag expense handler")
+	}
+	return a.handler.Handle(ctx, tagCmd)
+}
+
+type untagExpenseCommandAdapter struct {
+	handler *commands.UntagExpenseHandler
+}
+
+func (a *untagExpenseCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
+	untagCmd, ok := cmd.(commands.UntagExpenseCommand)
+	if !ok {
+		return fmt.Errorf("invalid command type for untag expense handler")
+	}
+	return a.handler.Handle(ctx, untagCmd)
+}
+
+type tagIncomeCommandAdapter struct {
+	handler *commands.TagIncomeHandler
+}
+
+func (a *tagIncomeCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
+	tagCmd, ok := cmd.(commands.TagIncomeCommand)
+	if !ok {
+		return fmt.Errorf("invalid command type for tag income handler")
+	}
+	return a.handler.Handle(ctx, tagCmd)
+}
+
+type untagIncomeCommandAdapter struct {
+	handler *commands.UntagIncomeHandler
+}
+
+func (a *untagIncomeCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
+	untagCmd, ok := cmd.(commands.UntagIncomeCommand)
+	if !ok {
+		return fmt.Errorf("invalid command type for untag income handler")
+	}
+	return a.handler.Handle(ctx, untagCmd)
+}
+
+type setCategoryKindCommandAdapter struct {
+	handler *commands.SetCategoryKindHandler
+}
+
+func (a *setCategoryKindCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
+	kindCmd, ok := cmd.(commands.SetCategoryKindCommand)
+	if !ok {
+		return fmt.Errorf("invalid command type for set category kind handler")
+	}
+	return a.handler.Handle(ctx, kindCmd)
+}
+
+type setCategoryParentCommandAdapter struct {
+	handler *commands.SetCategoryParentHandler
+}
+
+func (a *setCategoryParentCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
+	moveCmd, ok := cmd.(commands.SetCategoryParentCommand)
+	if !ok {
+		return fmt.Errorf("invalid command type for set category parent handler")
+	}
+	return a.handler.Handle(ctx, moveCmd)
+}
+
+type mergeCategoryCommandAdapter struct {
+	handler *commands.MergeCategoryHandler
+}
+
+func (a *mergeCategoryCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
+	mergeCmd, ok := cmd.(commands.MergeCategoryCommand)
+	if !ok {
+		return fmt.Errorf("invalid command type for merge category handler")
+	}
+	return a.handler.Handle(ctx, mergeCmd)
 }
 
 type expenseCommandAdapter struct {
 	handler *commands.CreateExpenseHandler
 }
 
-func (a *expenseCommandAdapter) Handle(cmd application.Command) error {
+func (a *expenseCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
 	expenseCmd, ok := cmd.(commands.CreateExpenseCommand)
 	if !ok {
 		return fmt.Errorf("invalid command type for expense handler")
 	}
-	return a.handler.Handle(context.Background(), expenseCmd)
+	return a.handler.Handle(ctx, expenseCmd)
 }
 
 type incomeCommandAdapter struct {
 	handler *commands.CreateIncomeHandler
 }
 
-func (a *incomeCommandAdapter) Handle(cmd application.Command) error {
+func (a *incomeCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
 	incomeCmd, ok := cmd.(commands.CreateIncomeCommand)
 	if !ok {
 		return fmt.Errorf("invalid command type for income handler")
 	}
-	return a.handler.Handle(context.Background(), incomeCmd)
+	return a.handler.Handle(ctx, incomeCmd)
 }
 
 // Adaptadores para comandos de actualización
@@ -680,24 +2598,24 @@ type updateExpenseCommandAdapter struct {
 	handler *commands.UpdateExpenseHandler
 }
 
-func (a *updateExpenseCommandAdapter) Handle(cmd application.Command) error {
+func (a *updateExpenseCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
 	updateCmd, ok := cmd.(commands.UpdateExpenseCommand)
 	if !ok {
 		return fmt.Errorf("invalid command type for update expense handler")
 	}
-	return a.handler.Handle(context.Background(), updateCmd)
+	return a.handler.Handle(ctx, updateCmd)
 }
 
 type updateIncomeCommandAdapter struct {
 	handler *commands.UpdateIncomeHandler
 }
 
-func (a *updateIncomeCommandAdapter) Handle(cmd application.Command) error {
+func (a *updateIncomeCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
 	updateCmd, ok := cmd.(commands.UpdateIncomeCommand)
 	if !ok {
 		return fmt.Errorf("invalid command type for update income handler")
 	}
-	return a.handler.Handle(context.Background(), updateCmd)
+	return a.handler.Handle(ctx, updateCmd)
 }
 
 // Adaptadores para comandos de eliminación
@@ -705,24 +2623,110 @@ type deleteExpenseCommandAdapter struct {
 	handler *commands.DeleteExpenseHandler
 }
 
-func (a *deleteExpenseCommandAdapter) Handle(cmd application.Command) error {
+func (a *deleteExpenseCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
 	deleteCmd, ok := cmd.(commands.DeleteExpenseCommand)
 	if !ok {
 		return fmt.Errorf("invalid command type for delete expense handler")
 	}
-	return a.handler.Handle(context.Background(), deleteCmd)
+	return a.handler.Handle(ctx, deleteCmd)
 }
 
 type deleteIncomeCommandAdapter struct {
 	handler *commands.DeleteIncomeHandler
 }
 
-func (a *deleteIncomeCommandAdapter) Handle(cmd application.Command) error {
+func (a *deleteIncomeCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
 	deleteCmd, ok := cmd.(commands.DeleteIncomeCommand)
 	if !ok {
 		return fmt.Errorf("invalid command type for delete income handler")
 	}
-	return a.handler.Handle(context.Background(), deleteCmd)
+	return a.handler.Handle(ctx, deleteCmd)
+}
+
+// Adaptadores para comandos de cuentas
+type createAccountCommandAdapter struct {
+	handler *commands.CreateAccountHandler
+}
+
+func (a *createAccountCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
+	createCmd, ok := cmd.(commands.CreateAccountCommand)
+	if !ok {
+		return fmt.Errorf("invalid command type for create account handler")
+	}
+	return a.handler.Handle(ctx, createCmd)
+}
+
+type renameAccountCommandAdapter struct {
+	handler *commands.RenameAccountHandler
+}
+
+func (a *renameAccountCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
+	renameCmd, ok := cmd.(commands.RenameAccountCommand)
+	if !ok {
+		return fmt.Errorf("invalid command type for rename account handler")
+	}
+	return a.handler.Handle(ctx, renameCmd)
+}
+
+type setOpeningBalanceCommandAdapter struct {
+	handler *commands.SetOpeningBalanceHandler
+}
+
+func (a *setOpeningBalanceCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
+	setCmd, ok := cmd.(commands.SetOpeningBalanceCommand)
+	if !ok {
+		return fmt.Errorf("invalid command type for set opening balance handler")
+	}
+	return a.handler.Handle(ctx, setCmd)
+}
+
+type closeAccountCommandAdapter struct {
+	handler *commands.CloseAccountHandler
+}
+
+func (a *closeAccountCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
+	closeCmd, ok := cmd.(commands.CloseAccountCommand)
+	if !ok {
+		return fmt.Errorf("invalid command type for close account handler")
+	}
+	return a.handler.Handle(ctx, closeCmd)
+}
+
+// Adaptadores para comandos de transferencias
+type createTransferCommandAdapter struct {
+	handler *commands.CreateTransferHandler
+}
+
+func (a *createTransferCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
+	createCmd, ok := cmd.(commands.CreateTransferCommand)
+	if !ok {
+		return fmt.Errorf("invalid command type for create transfer handler")
+	}
+	return a.handler.Handle(ctx, createCmd)
+}
+
+type updateTransferCommandAdapter struct {
+	handler *commands.UpdateTransferHandler
+}
+
+func (a *updateTransferCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
+	updateCmd, ok := cmd.(commands.UpdateTransferCommand)
+	if !ok {
+		return fmt.Errorf("invalid command type for update transfer handler")
+	}
+	return a.handler.Handle(ctx, updateCmd)
+}
+
+type deleteTransferCommandAdapter struct {
+	handler *commands.DeleteTransferHandler
+}
+
+func (a *deleteTransferCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
+	deleteCmd, ok := cmd.(commands.DeleteTransferCommand)
+	if !ok {
+		return fmt.Errorf("invalid command type for delete transfer handler")
+	}
+	return a.handler.Handle(ctx, deleteCmd)
 }
 
 func main() {
@@ -738,28 +2742,123 @@ func main() {
 	updateExpenseCmd.Flags().StringP("category", "c", "", "Nombre de la categoría para el gasto (si no se especifica, se pedirá interactivamente)")
 	updateIncomeCmd.Flags().StringP("category", "c", "", "Nombre de la categoría para el ingreso (si no se especifica, se pedirá interactivamente)")
 
+	// Moneda de los importes
+	for _, c := range []*cobra.Command{createExpenseCmd, createIncomeCmd, updateExpenseCmd, updateIncomeCmd} {
+		c.Flags().String("currency", money.DefaultCurrency, "Código ISO de la moneda del monto (por ejemplo: PYG, USD, EUR)")
+	}
+	// Cuenta de los movimientos
+	for _, c := range []*cobra.Command{createExpenseCmd, createIncomeCmd} {
+		c.Flags().StringP("account", "a", "", "Nombre de la cuenta del movimiento (si no se especifica, se pedirá interactivamente)")
+	}
+	for _, c := range []*cobra.Command{updateExpenseCmd, updateIncomeCmd} {
+		c.Flags().StringP("account", "a", "", "Nombre de la cuenta del movimiento (si no se especifica, se mantiene la actual)")
+	}
+
+	createAccountCmd.Flags().String("kind", domain.AccountKindBank, "Tipo de cuenta: bank, cash o card")
+	createAccountCmd.Flags().String("currency", money.DefaultCurrency, "Código ISO de la moneda de la cuenta (por ejemplo: PYG, USD, EUR)")
+	createAccountCmd.Flags().String("opening-balance", "", "Saldo inicial de la cuenta")
+	createAccountCmd.Flags().StringP("date", "t", "", "Fecha del saldo inicial (formato: YYYY-MM-DD). Si no se especifica, usa la fecha actual")
+	openingBalanceCmd.Flags().StringP("date", "t", "", "Fecha del saldo inicial (formato: YYYY-MM-DD). Si no se especifica, usa la fecha actual")
+	listAccountsCmd.Flags().Bool("all", false, "Incluir las cuentas cerradas")
+	createCategoryCmd.Flags().String("parent", "", "Nombre de la categoría padre (si no se especifica, se crea en el primer nivel)")
+	createCategoryCmd.Flags().String("kind", domain.CategoryKindBoth, "Tipo de movimientos de la categoría: income, expense o both")
+	listCategoriesCmd.Flags().Bool("all", false, "Incluir las categorías archivadas")
+	listCategoriesCmd.Flags().String("kind", "", "Mostrar solo las categorías que admiten este tipo de movimiento: income o expense")
+
+	for _, c := range []*cobra.Command{createTransferCmd, updateTransferCmd} {
+		c.Flags().String("from", "", "Nombre de la cuenta de la que sale el dinero")
+		c.Flags().String("to", "", "Nombre de la cuenta a la que entra el dinero")
+		c.Flags().String("received", "", "Monto acreditado en la cuenta destino, si su moneda es distinta")
+		c.Flags().StringP("date", "t", "", "Fecha de la transferencia (formato: YYYY-MM-DD). Si no se especifica, usa la fecha actual")
+	}
+
+	balanceCmd.Flags().String("currency", "", "Convertir los totales a esta moneda con la cotización de la fecha de cada movimiento")
+	movementsCmd.Flags().String("tag", "", "Mostrar solo los movimientos con esta etiqueta")
+	tagsReportCmd.Flags().String("from", "", "Incluir los movimientos desde esta fecha (formato: YYYY-MM-DD)")
+	tagsReportCmd.Flags().String("to", "", "Incluir los movimientos hasta esta fecha inclusive (formato: YYYY-MM-DD)")
+	tagsReportCmd.Flags().String("currency", "", "Convertir los totales a esta moneda con la cotización de la fecha de cada movimiento")
+
+	// Clave de idempotencia: reintentar un comando con la misma clave no lo aplica dos veces
+	for _, c := range []*cobra.Command{createCategoryCmd, renameCategoryCmd, archiveCategoryCmd, unarchiveCategoryCmd, deleteCategoryCmd, mergeCategoryCmd, moveCategoryCmd, kindCategoryCmd, createAccountCmd, renameAccountCmd, openingBalanceCmd, closeAccountCmd, createTransferCmd, updateTransferCmd, deleteTransferCmd, createExpenseCmd, createIncomeCmd, updateExpenseCmd, updateIncomeCmd, deleteExpenseCmd, deleteIncomeCmd, tagExpenseCmd, untagExpenseCmd, tagIncomeCmd, untagIncomeCmd} {
+		c.Flags().String("idempotency-key", "", "Clave única del comando; si ya fue procesado, el reintento no se aplica de nuevo")
+	}
+
+	rebuildSnapshotsCmd.Flags().String("type", "", "Tipo de agregado: expense o income (por defecto ambos)")
+	invalidateSnapshotsCmd.Flags().String("type", "", "Tipo de agregado: expense o income (por defecto ambos)")
+
+	listEventsCmd.Flags().String("correlation", "", "Mostrar solo los eventos con este ID de correlación")
+	listEventsCmd.Flags().String("actor", "", "Mostrar solo los eventos de este actor")
+	listEventsCmd.Flags().String("source", "", "Mostrar solo los eventos de esta fuente (por ejemplo: escama-cli)")
+	listEventsCmd.Flags().String("type", "", "Mostrar solo este tipo de evento (por ejemplo: ExpenseCreated)")
+	listEventsCmd.Flags().Int64("from", 0, "Mostrar los eventos posteriores a esta posición global")
+	listEventsCmd.Flags().Int("limit", 50, "Cantidad máxima de eventos a mostrar (0 para todos)")
+	exportEventsCmd.Flags().String("from", "", "Exportar eventos desde esta fecha (formato: YYYY-MM-DD)")
+	exportEventsCmd.Flags().String("to", "", "Exportar eventos hasta esta fecha inclusive (formato: YYYY-MM-DD)")
+	archiveEventsCmd.Flags().String("before", "", "Archivar los eventos anteriores a esta fecha (formato: YYYY-MM-DD, por defecto el 1 de enero del año actual)")
+
 	// Agregar subcomandos
 	categoryCmd.AddCommand(createCategoryCmd)
+	categoryCmd.AddCommand(renameCategoryCmd)
+	categoryCmd.AddCommand(archiveCategoryCmd)
+	categoryCmd.AddCommand(unarchiveCategoryCmd)
+	categoryCmd.AddCommand(deleteCategoryCmd)
+	categoryCmd.AddCommand(mergeCategoryCmd)
+	categoryCmd.AddCommand(moveCategoryCmd)
+	categoryCmd.AddCommand(kindCategoryCmd)
+	categoryCmd.AddCommand(listCategoriesCmd)
+	accountCmd.AddCommand(createAccountCmd)
+	accountCmd.AddCommand(renameAccountCmd)
+	accountCmd.AddCommand(openingBalanceCmd)
+	accountCmd.AddCommand(closeAccountCmd)
+	accountCmd.AddCommand(listAccountsCmd)
+	transferCmd.AddCommand(createTransferCmd)
+	transferCmd.AddCommand(updateTransferCmd)
+	transferCmd.AddCommand(deleteTransferCmd)
+	transferCmd.AddCommand(listTransfersCmd)
 	expenseCmd.AddCommand(createExpenseCmd)
 	expenseCmd.AddCommand(updateExpenseCmd)
 	expenseCmd.AddCommand(deleteExpenseCmd)
+	expenseCmd.AddCommand(tagExpenseCmd)
+	expenseCmd.AddCommand(untagExpenseCmd)
 	incomeCmd.AddCommand(createIncomeCmd)
 	incomeCmd.AddCommand(updateIncomeCmd)
 	incomeCmd.AddCommand(deleteIncomeCmd)
+	incomeCmd.AddCommand(tagIncomeCmd)
+	incomeCmd.AddCommand(untagIncomeCmd)
+	reportCmd.AddCommand(tagsReportCmd)
+	snapshotsCmd.AddCommand(rebuildSnapshotsCmd)
+	snapshotsCmd.AddCommand(invalidateSnapshotsCmd)
+	subscriptionsCmd.AddCommand(subscriptionsStatusCmd)
+	subscriptionsCmd.AddCommand(subscriptionsResetCmd)
+	eventsCmd.AddCommand(listEventsCmd)
+	eventsCmd.AddCommand(verifyEventsCmd)
+	eventsCmd.AddCommand(exportEventsCmd)
+	eventsCmd.AddCommand(importEventsCmd)
+	eventsCmd.AddCommand(archiveEventsCmd)
+	projectionsCmd.AddCommand(rebuildProjectionsCmd)
+	keysCmd.AddCommand(listKeysCmd)
+	keysCmd.AddCommand(rotateKeyCmd)
+	keysCmd.AddCommand(deleteKeyCmd)
 
 	rootCmd.AddCommand(categoryCmd)
+	rootCmd.AddCommand(accountCmd)
+	rootCmd.AddCommand(transferCmd)
 	rootCmd.AddCommand(expenseCmd)
 	rootCmd.AddCommand(incomeCmd)
 	rootCmd.AddCommand(balanceCmd)
 	rootCmd.AddCommand(movementsCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(snapshotsCmd)
+	rootCmd.AddCommand(subscriptionsCmd)
+	rootCmd.AddCommand(eventsCmd)
+	rootCmd.AddCommand(projectionsCmd)
+	rootCmd.AddCommand(keysCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 
-	// Cerrar conexión MongoDB al terminar
-	if mongoStore, ok := eventStore.(*eventstore.MongoEventStore); ok {
-		mongoStore.Close()
-	}
+	// Cerrar conexiones al terminar
+	appBackend.Close()
 }
@@ -10,14 +10,19 @@ import (
 	"time"
 
 	"escama/application/queries"
-	"escama/infrastructure/eventstore"
+	"escama/domain"
+	"escama/infrastructure/backend"
+	"escama/infrastructure/eventbus"
+	"escama/infrastructure/subscriptions"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 )
 
 type Server struct {
-	queryHandler *queries.MovementsQueryHandler
+	queryHandler      *queries.MovementsQueryHandler
+	projectionHandler *queries.ProjectionQueryHandler
+	reportingCurrency string // moneda de los totales cuando la consulta no indica otra
 }
 
 func main() {
@@ -26,17 +31,31 @@ func main() {
 		log.Println("Warning: .env file not found, using system environment variables")
 	}
 
-	// Configurar infrastructure - usar MongoDB
-	mongoStore, err := eventstore.NewMongoEventStore()
+	// Configurar infrastructure según ESCAMA_BACKEND (MongoDB por defecto)
+	config := backend.ConfigFromEnv()
+	store, err := backend.Open(config)
 	if err != nil {
-		log.Fatalf("Failed to connect to MongoDB: %v", err)
+		log.Fatalf("Failed to open backend: %v", err)
 	}
-	defer mongoStore.Close()
+	defer store.Close()
 
-	queryHandler := queries.NewMovementsQueryHandler(mongoStore)
+	queryHandler := queries.NewMovementsQueryHandler(store.EventStore)
+	queryHandler.SetSummaries(store.Snapshots)
+	queryHandler.SetConverter(store.Rates)
+
+	// Mantener las proyecciones al día con los eventos que escriben otros procesos (CLI)
+	subscriptionManager := subscriptions.NewManager(store.EventStore, store.Checkpoints)
+	subscriptionManager.Register(eventbus.NewProjectionSubscriber(store.Projections))
+	go subscriptionManager.Run(context.Background(), 5*time.Second)
+
+	// Las cuentas, con sus saldos, y las transferencias se leen de las proyecciones
+	projectionHandler := queries.NewProjectionQueryHandler(store.Projections)
+	projectionHandler.SetConverter(store.Rates)
 
 	server := &Server{
-		queryHandler: queryHandler,
+		queryHandler:      queryHandler,
+		projectionHandler: projectionHandler,
+		reportingCurrency: config.ReportingCurrency,
 	}
 
 	// Configurar rutas
@@ -47,6 +66,12 @@ func main() {
 	api.HandleFunc("/movements", server.getMovements).Methods("GET")
 	api.HandleFunc("/balance", server.getBalance).Methods("GET")
 	api.HandleFunc("/expenses-by-category", server.getExpensesByCategory).Methods("GET")
+	api.HandleFunc("/categories", server.getCategories).Methods("GET")
+	api.HandleFunc("/tags", server.getTagTotals).Methods("GET")
+	api.HandleFunc("/accounts", server.getAccounts).Methods("GET")
+	api.HandleFunc("/accounts/{id}", server.getAccount).Methods("GET")
+	api.HandleFunc("/transfers", server.getTransfers).Methods("GET")
+	api.HandleFunc("/transfers/{id}", server.getTransfer).Methods("GET")
 
 	// Servir archivos estáticos (HTML, CSS, JS)
 	r.PathPrefix("/").Handler(http.FileServer(http.Dir("./web/"))).Methods("GET")
@@ -64,8 +89,16 @@ func main() {
 func (s *Server) getMovements(w http.ResponseWriter, r *http.Request) {
 	ctx := context.Background()
 
-	// Parsear parámetros de fecha opcionales
+	// Parsear parámetros de fecha opcionales; tag deja solo los movimientos con esa etiqueta
 	query := queries.GetMovementsQuery{}
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		normalized, err := domain.NormalizeTag(tag)
+		if err != nil {
+			http.Error(w, "Invalid tag (no spaces or commas)", http.StatusBadRequest)
+			return
+		}
+		query.Tag = normalized
+	}
 
 	if startDateStr := r.URL.Query().Get("start_date"); startDateStr != "" {
 		if startDate, err := time.Parse("2006-01-02", startDateStr); err == nil {
@@ -143,6 +176,7 @@ func (s *Server) getBalance(w http.ResponseWriter, r *http.Request) {
 		balance, err := s.queryHandler.GetBalance(ctx, queries.GetBalanceQuery{
 			StartDate: startDate,
 			EndDate:   endDate,
+			Currency:  s.currency(r),
 		})
 		if err != nil {
 			http.Error(w, fmt.Sprintf("Error getting balance: %v", err), http.StatusInternalServerError)
@@ -172,6 +206,7 @@ func (s *Server) getBalance(w http.ResponseWriter, r *http.Request) {
 	balance, err := s.queryHandler.GetBalance(ctx, queries.GetBalanceQuery{
 		StartDate: startDate,
 		EndDate:   endOfDay,
+		Currency:  s.currency(r),
 	})
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Error getting balance: %v", err), http.StatusInternalServerError)
@@ -185,8 +220,21 @@ func (s *Server) getBalance(w http.ResponseWriter, r *http.Request) {
 func (s *Server) getExpensesByCategory(w http.ResponseWriter, r *http.Request) {
 	ctx := context.Background()
 
-	// Parsear parámetros de fecha opcionales
-	query := queries.GetExpensesByCategoryQuery{}
+	// Parsear parámetros de fecha opcionales; currency convierte los totales a esa moneda.
+	// level suma las subcategorías en su ancestro de ese nivel y parent_id desglosa una
+	// categoría en sus subcategorías directas.
+	query := queries.GetExpensesByCategoryQuery{
+		Currency: s.currency(r),
+		ParentID: r.URL.Query().Get("parent_id"),
+	}
+	if levelStr := r.URL.Query().Get("level"); levelStr != "" {
+		level, err := strconv.Atoi(levelStr)
+		if err != nil || level < 0 {
+			http.Error(w, "Invalid level (use a non-negative integer)", http.StatusBadRequest)
+			return
+		}
+		query.Level = level
+	}
 
 	if startDateStr := r.URL.Query().Get("start_date"); startDateStr != "" {
 		if startDate, err := time.Parse("2006-01-02", startDateStr); err == nil {
@@ -212,6 +260,152 @@ func (s *Server) getExpensesByCategory(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(expensesByCategory)
 }
 
+func (s *Server) getCategories(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+
+	// kind=income o kind=expense deja solo las categorías que admiten ese tipo de
+	// movimiento; include_archived=true incluye las archivadas
+	query := queries.GetCategoriesQuery{Kind: r.URL.Query().Get("kind")}
+	if query.Kind != "" && query.Kind != domain.CategoryKindIncome && query.Kind != domain.CategoryKindExpense {
+		http.Error(w, "Invalid kind (use income or expense)", http.StatusBadRequest)
+		return
+	}
+	if includeArchived, err := strconv.ParseBool(r.URL.Query().Get("include_archived")); err == nil {
+		query.IncludeArchived = includeArchived
+	}
+
+	categories, err := s.projectionHandler.GetCategories(ctx, query)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting categories: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(categories)
+}
+
+func (s *Server) getTagTotals(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+
+	// Parsear parámetros de fecha opcionales; currency convierte los totales a esa moneda
+	query := queries.GetTagTotalsQuery{Currency: s.currency(r)}
+
+	if startDateStr := r.URL.Query().Get("start_date"); startDateStr != "" {
+		if startDate, err := time.Parse("2006-01-02", startDateStr); err == nil {
+			query.StartDate = &startDate
+		}
+	}
+
+	if endDateStr := r.URL.Query().Get("end_date"); endDateStr != "" {
+		if endDate, err := time.Parse("2006-01-02", endDateStr); err == nil {
+			// Ajustar end_date al final del día
+			endOfDay := endDate.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+			query.EndDate = &endOfDay
+		}
+	}
+
+	tagTotals, err := s.projectionHandler.GetTagTotals(ctx, query)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting tag totals: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tagTotals)
+}
+
+func (s *Server) getAccounts(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+
+	// include_closed=true incluye las cuentas cerradas
+	query := queries.GetAccountsQuery{}
+	if includeClosed, err := strconv.ParseBool(r.URL.Query().Get("include_closed")); err == nil {
+		query.IncludeClosed = includeClosed
+	}
+
+	accounts, err := s.projectionHandler.GetAccounts(ctx, query)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting accounts: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(accounts)
+}
+
+func (s *Server) getAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+
+	account, err := s.projectionHandler.GetAccountByID(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting account: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if account == nil {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+}
+
+func (s *Server) getTransfers(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+
+	// Parsear parámetros de fecha opcionales
+	query := queries.GetTransfersQuery{}
+
+	if startDateStr := r.URL.Query().Get("start_date"); startDateStr != "" {
+		if startDate, err := time.Parse("2006-01-02", startDateStr); err == nil {
+			query.StartDate = &startDate
+		}
+	}
+
+	if endDateStr := r.URL.Query().Get("end_date"); endDateStr != "" {
+		if endDate, err := time.Parse("2006-01-02", endDateStr); err == nil {
+			// Ajustar end_date al final del día
+			endOfDay := endDate.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+			query.EndDate = &endOfDay
+		}
+	}
+
+	transfers, err := s.projectionHandler.GetTransfers(ctx, query)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting transfers: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfers)
+}
+
+func (s *Server) getTransfer(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+
+	transfer, err := s.projectionHandler.GetTransferByID(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error getting transfer: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if transfer == nil {
+		http.Error(w, "Transfer not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transfer)
+}
+
+// currency devuelve la moneda del parámetro currency o, sin él, la moneda de reporte
+// configurada: sin convertir, los totales con movimientos de varias monedas fallan
+func (s *Server) currency(r *http.Request) string {
+	if currency := r.URL.Query().Get("currency"); currency != "" {
+		return currency
+	}
+	return s.reportingCurrency
+}
+
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"escama/application/queries"
+	"escama/domain/events"
+	"escama/domain/money"
+	"escama/infrastructure/eventstore"
+	"escama/infrastructure/projections"
+	"escama/infrastructure/rates"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// mixedCurrencyServer arma un servidor con un ingreso en guaraníes y gastos en dólares y
+// guaraníes, todos con la etiqueta "viaje", y la cotización USD/PYG de julio de 2025
+func mixedCurrencyServer(t *testing.T) *Server {
+	ctx := context.Background()
+	day := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			t.Fatalf("invalid date %q: %v", value, err)
+		}
+		return parsed
+	}
+
+	store := eventstore.NewInMemoryEventStore()
+	streams := []struct {
+		id, aggregateType string
+		events            []events.DomainEvent
+	}{
+		{"sueldo", "Income", []events.DomainEvent{
+			events.IncomeCreated{IncomeID: "sueldo", CategoryID: "trabajo", Amount: money.New(1000000, money.PYG), Date: day("2025-07-02")},
+			events.NewIncomeTagAdded("sueldo", "viaje"),
+		}},
+		{"hotel", "Expense", []events.DomainEvent{
+			events.ExpenseCreated{ExpenseID: "hotel", CategoryID: "viajes", Amount: money.New(2550, "USD"), Date: day("2025-07-03")},
+			events.NewExpenseTagAdded("hotel", "viaje"),
+		}},
+		{"taxi", "Expense", []events.DomainEvent{
+			events.ExpenseCreated{ExpenseID: "taxi", CategoryID: "viajes", Amount: money.New(50000, money.PYG), Date: day("2025-07-04")},
+			events.NewExpenseTagAdded("taxi", "viaje"),
+		}},
+	}
+	for _, stream := range streams {
+		if err := store.Store(ctx, stream.id, stream.aggregateType, 0, stream.events); err != nil {
+			t.Fatalf("failed to store %s: %v", stream.id, err)
+		}
+	}
+
+	dir := t.TempDir()
+	csv := "date,from,to,rate\n2025-07-01,USD,PYG,7985.50\n"
+	if err := os.WriteFile(filepath.Join(dir, "rates.csv"), []byte(csv), 0o644); err != nil {
+		t.Fatalf("failed to write rates: %v", err)
+	}
+	rateStore, err := rates.Load(dir)
+	if err != nil {
+		t.Fatalf("failed to load rates: %v", err)
+	}
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "escama.db"))
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	projectionStore, err := projections.NewSQLiteProjectionStore(db)
+	if err != nil {
+		t.Fatalf("failed to open projections: %v", err)
+	}
+	storedEvents, err := store.GetAllEvents(ctx, eventstore.TimeFilter{})
+	if err != nil {
+		t.Fatalf("failed to read events: %v", err)
+	}
+	for _, storedEvent := range storedEvents {
+		if err := projectionStore.ProcessEvent(ctx, storedEvent); err != nil {
+			t.Fatalf("failed to project %s: %v", storedEvent.EventType, err)
+		}
+	}
+
+	queryHandler := queries.NewMovementsQueryHandler(store)
+	queryHandler.SetConverter(rateStore)
+	projectionHandler := queries.NewProjectionQueryHandler(projectionStore)
+	projectionHandler.SetConverter(rateStore)
+	return &Server{queryHandler: queryHandler, projectionHandler: projectionHandler, reportingCurrency: money.PYG}
+}
+
+// get llama al handler y decodifica su respuesta JSON en result
+func get(t *testing.T, handler http.HandlerFunc, target string, result any) {
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d: %s", target, recorder.Code, recorder.Body.String())
+	}
+	if err := json.NewDecoder(recorder.Body).Decode(result); err != nil {
+		t.Fatalf("GET %s: failed to decode response: %v", target, err)
+	}
+}
+
+func TestTotalsWithoutCurrencyUseReportingCurrency(t *testing.T) {
+	server := mixedCurrencyServer(t)
+	const period = "start_date=2025-07-01&end_date=2025-07-31"
+
+	// US$25.50 a 7985.50 son ₲203630 (redondeado), más el taxi de ₲50000
+	wantExpense := money.New(253630, money.PYG)
+
+	var balance queries.Balance
+	get(t, server.getBalance, "/api/balance?"+period, &balance)
+	if balance.TotalIncome != money.New(1000000, money.PYG) || balance.TotalExpense != wantExpense ||
+		balance.NetBalance != money.New(746370, money.PYG) {
+		t.Errorf("balance = %+v, want income ₲1000000, expense %v, net ₲746370", balance, wantExpense)
+	}
+
+	var expenses []queries.CategoryExpense
+	get(t, server.getExpensesByCategory, "/api/expenses-by-category?"+period, &expenses)
+	if len(expenses) != 1 || expenses[0].Total != wantExpense || expenses[0].Count != 2 {
+		t.Errorf("expenses by category = %+v, want one category with %v in 2 expenses", expenses, wantExpense)
+	}
+
+	var tagTotals []queries.TagTotal
+	get(t, server.getTagTotals, "/api/tags?"+period, &tagTotals)
+	if len(tagTotals) != 1 || tagTotals[0].TotalExpense != wantExpense || tagTotals[0].Count != 3 {
+		t.Errorf("tag totals = %+v, want viaje with %v in expenses and 3 movements", tagTotals, wantExpense)
+	}
+
+	// La moneda pedida tiene prioridad sobre la de reporte
+	get(t, server.getBalance, "/api/balance?currency=USD&"+period, &balance)
+	if balance.TotalExpense.Currency != "USD" {
+		t.Errorf("balance with currency=USD = %+v, want totals in USD", balance)
+	}
+}
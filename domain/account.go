@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"escama/domain/events"
+	"escama/domain/money"
+)
+
+// Tipos de cuenta: dónde vive el dinero de los movimientos
+const (
+	AccountKindBank = "bank" // cuenta bancaria
+	AccountKindCash = "cash" // efectivo o billetera
+	AccountKindCard = "card" // tarjeta de crédito o débito
+)
+
+// ErrInvalidAccountKind se devuelve al crear una cuenta de un tipo desconocido
+var ErrInvalidAccountKind = errors.New("invalid account kind")
+
+// ErrAccountClosed se devuelve al modificar una cuenta cerrada o registrar movimientos en ella
+var ErrAccountClosed = errors.New("account is closed")
+
+// Account cuenta bancaria, billetera de efectivo o tarjeta. Todos sus importes, incluido
+// el saldo inicial, están en Currency.
+type Account struct {
+	ID             string
+	Name           string
+	Kind           string
+	Currency       string
+	OpeningBalance money.Money
+	OpeningDate    time.Time
+	Closed         bool
+	Version        int // cantidad de eventos persistidos en el stream
+
+	uncommitted []events.DomainEvent
+}
+
+func NewAccount(id, name, kind, currency string) (*Account, error) {
+	switch kind {
+	case AccountKindBank, AccountKindCash, AccountKindCard:
+	default:
+		return nil, fmt.Errorf("%w: %q (expected %s, %s or %s)", ErrInvalidAccountKind, kind, AccountKindBank, AccountKindCash, AccountKindCard)
+	}
+
+	currency = money.New(0, currency).Currency
+	a := &Account{
+		ID:       id,
+		Name:     name,
+		Kind:     kind,
+		Currency: currency,
+	}
+
+	event := events.AccountCreated{
+		AccountID: id,
+		Name:      name,
+		Kind:      kind,
+		Currency:  currency,
+		Occurred:  time.Now().UTC(),
+	}
+	a.uncommitted = append(a.uncommitted, event)
+
+	return a, nil
+}
+
+func (a *Account) UncommittedEvents() []events.DomainEvent {
+	return a.uncommitted
+}
+
+func (a *Account) ClearUncommittedEvents() {
+	a.uncommitted = nil
+}
+
+func (a *Account) Rename(name string) error {
+	if a.Closed {
+		return fmt.Errorf("%w: %s", ErrAccountClosed, a.ID)
+	}
+	a.Name = name
+
+	event := events.AccountRenamed{AccountID: a.ID, Name: name, Occurred: time.Now().UTC()}
+	a.uncommitted = append(a.uncommitted, event)
+	return nil
+}
+
+// SetOpeningBalance fija el saldo de la cuenta al comienzo del día date
+func (a *Account) SetOpeningBalance(amount money.Money, date time.Time) error {
+	if err := a.Accepts(amount); err != nil {
+		return err
+	}
+	a.OpeningBalance = amount
+	a.OpeningDate = date
+
+	event := events.AccountOpeningBalanceSet{AccountID: a.ID, Amount: amount, Date: date, Occurred: time.Now().UTC()}
+	a.uncommitted = append(a.uncommitted, event)
+	return nil
+}
+
+func (a *Account) Close() error {
+	if a.Closed {
+		return fmt.Errorf("%w: %s", ErrAccountClosed, a.ID)
+	}
+	a.Closed = true
+
+	event := events.AccountClosed{AccountID: a.ID, Occurred: time.Now().UTC()}
+	a.uncommitted = append(a.uncommitted, event)
+	return nil
+}
+
+// Accepts indica si se puede registrar un movimiento por amount en la cuenta: debe estar
+// abierta y el importe en su moneda
+func (a *Account) Accepts(amount money.Money) error {
+	if a.Closed {
+		return fmt.Errorf("%w: %s", ErrAccountClosed, a.ID)
+	}
+	if amount.Currency != a.Currency {
+		return fmt.Errorf("%w: account %s is in %s, not %s", money.ErrCurrencyMismatch, a.Name, a.Currency, amount.Currency)
+	}
+	return nil
+}
@@ -1,32 +1,88 @@
 package domain
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"escama/domain/events"
 )
 
+// Tipos de categoría: con qué movimientos se puede usar
+const (
+	CategoryKindIncome  = "income"  // solo ingresos
+	CategoryKindExpense = "expense" // solo gastos
+	CategoryKindBoth    = "both"    // ingresos y gastos
+)
+
+// ErrInvalidCategoryKind se devuelve al usar un tipo de categoría desconocido
+var ErrInvalidCategoryKind = errors.New("invalid category kind")
+
+// ErrCategoryKindMismatch se devuelve al registrar un movimiento en una categoría de otro tipo
+var ErrCategoryKindMismatch = errors.New("category kind mismatch")
+
+// ErrCategoryDeleted se devuelve al modificar una categoría eliminada o fusionada en otra
+var ErrCategoryDeleted = errors.New("category is deleted")
+
+// ErrCategoryArchived se devuelve al archivar una categoría que ya está archivada
+var ErrCategoryArchived = errors.New("category is already archived")
+
+// ErrCategoryNotArchived se devuelve al desarchivar una categoría que no está archivada
+var ErrCategoryNotArchived = errors.New("category is not archived")
+
+// ErrCategoryMergeSelf se devuelve al fusionar una categoría consigo misma
+var ErrCategoryMergeSelf = errors.New("cannot merge a category into itself")
+
+// ErrCategoryCycle se devuelve cuando una categoría quedaría como ancestro de sí misma
+var ErrCategoryCycle = errors.New("category hierarchy cycle")
+
+// Category agrupa los movimientos. Una categoría archivada conserva sus movimientos pero
+// deja de ofrecerse al registrar nuevos; una eliminada o fusionada no admite más cambios.
+// Las categorías se anidan: ParentID es la categoría padre, vacía en las de primer nivel.
+// Kind indica si se usa para ingresos, gastos o ambos.
 type Category struct {
-	ID   string
-	Name string
+	ID         string
+	Name       string
+	ParentID   string
+	Kind       string
+	Archived   bool
+	Deleted    bool
+	MergedInto string // categoría que absorbió sus movimientos, si se fusionó
+	Version    int    // cantidad de eventos persistidos en el stream
 
 	uncommitted []events.DomainEvent
 }
 
-func NewCategory(id, name string) *Category {
+// NewCategory crea una categoría del tipo kind (vacío equivale a both) dentro de parent,
+// o de primer nivel si parent es nil
+func NewCategory(id, name, kind string, parent *Category) (*Category, error) {
+	kind, err := categoryKind(kind)
+	if err != nil {
+		return nil, err
+	}
+
 	c := &Category{
 		ID:   id,
 		Name: name,
+		Kind: kind,
+	}
+	if parent != nil {
+		if parent.Deleted {
+			return nil, fmt.Errorf("%w: %s", ErrCategoryDeleted, parent.ID)
+		}
+		c.ParentID = parent.ID
 	}
 
 	event := events.CategoryCreated{
 		CategoryID: id,
 		Name:       name,
+		ParentID:   c.ParentID,
+		Kind:       kind,
 		Occurred:   time.Now().UTC(),
 	}
 	c.uncommitted = append(c.uncommitted, event)
 
-	return c
+	return c, nil
 }
 
 func (c *Category) UncommittedEvents() []events.DomainEvent {
@@ -36,3 +92,165 @@ func (c *Category) UncommittedEvents() []events.DomainEvent {
 func (c *Category) ClearUncommittedEvents() {
 	c.uncommitted = nil
 }
+
+func (c *Category) Rename(name string) error {
+	if c.Deleted {
+		return fmt.Errorf("%w: %s", ErrCategoryDeleted, c.ID)
+	}
+	c.Name = name
+
+	event := events.CategoryRenamed{CategoryID: c.ID, Name: name, Occurred: time.Now().UTC()}
+	c.uncommitted = append(c.uncommitted, event)
+	return nil
+}
+
+// SetParent mueve la categoría dentro de parent, o al primer nivel si parent es nil.
+// parentAncestors son los IDs de los ancestros de parent: si la categoría está entre
+// ellos, o es el mismo parent, el cambio formaría un ciclo.
+func (c *Category) SetParent(parent *Category, parentAncestors []string) error {
+	if c.Deleted {
+		return fmt.Errorf("%w: %s", ErrCategoryDeleted, c.ID)
+	}
+
+	parentID := ""
+	if parent != nil {
+		if parent.Deleted {
+			return fmt.Errorf("%w: %s", ErrCategoryDeleted, parent.ID)
+		}
+		if parent.ID == c.ID {
+			return fmt.Errorf("%w: %s cannot be its own parent", ErrCategoryCycle, c.ID)
+		}
+		for _, ancestorID := range parentAncestors {
+			if ancestorID == c.ID {
+				return fmt.Errorf("%w: %s is an ancestor of %s", ErrCategoryCycle, c.ID, parent.ID)
+			}
+		}
+		parentID = parent.ID
+	}
+	if parentID == c.ParentID {
+		return nil
+	}
+	c.ParentID = parentID
+
+	event := events.CategoryParentChanged{CategoryID: c.ID, ParentID: parentID, Occurred: time.Now().UTC()}
+	c.uncommitted = append(c.uncommitted, event)
+	return nil
+}
+
+// SetKind cambia con qué movimientos se puede usar la categoría. Los ya registrados la
+// conservan aunque sean de otro tipo.
+func (c *Category) SetKind(kind string) error {
+	if c.Deleted {
+		return fmt.Errorf("%w: %s", ErrCategoryDeleted, c.ID)
+	}
+	kind, err := categoryKind(kind)
+	if err != nil {
+		return err
+	}
+	if kind == c.kind() {
+		return nil
+	}
+	c.Kind = kind
+
+	event := events.CategoryKindChanged{CategoryID: c.ID, Kind: kind, Occurred: time.Now().UTC()}
+	c.uncommitted = append(c.uncommitted, event)
+	return nil
+}
+
+// Accepts indica si se puede registrar un movimiento del tipo indicado ("income" o
+// "expense") en la categoría: no debe estar eliminada y debe ser de ese tipo o de ambos
+func (c *Category) Accepts(movementType string) error {
+	if c.Deleted {
+		return fmt.Errorf("%w: %s", ErrCategoryDeleted, c.ID)
+	}
+	if kind := c.kind(); kind != CategoryKindBoth && kind != movementType {
+		return fmt.Errorf("%w: category %s is for %s, not %s", ErrCategoryKindMismatch, c.Name, kind, movementType)
+	}
+	return nil
+}
+
+// kind devuelve el tipo de la categoría; las creadas antes de los tipos sirven para ambos
+func (c *Category) kind() string {
+	if c.Kind == "" {
+		return CategoryKindBoth
+	}
+	return c.Kind
+}
+
+// categoryKind valida el tipo de categoría; vacío equivale a both
+func categoryKind(kind string) (string, error) {
+	switch kind {
+	case "":
+		return CategoryKindBoth, nil
+	case CategoryKindIncome, CategoryKindExpense, CategoryKindBoth:
+		return kind, nil
+	default:
+		return "", fmt.Errorf("%w: %q (expected %s, %s or %s)", ErrInvalidCategoryKind, kind, CategoryKindIncome, CategoryKindExpense, CategoryKindBoth)
+	}
+}
+
+func (c *Category) Archive() error {
+	if c.Deleted {
+		return fmt.Errorf("%w: %s", ErrCategoryDeleted, c.ID)
+	}
+	if c.Archived {
+		return fmt.Errorf("%w: %s", ErrCategoryArchived, c.ID)
+	}
+	c.Archived = true
+
+	event := events.CategoryArchived{CategoryID: c.ID, Occurred: time.Now().UTC()}
+	c.uncommitted = append(c.uncommitted, event)
+	return nil
+}
+
+func (c *Category) Unarchive() error {
+	if c.Deleted {
+		return fmt.Errorf("%w: %s", ErrCategoryDeleted, c.ID)
+	}
+	if !c.Archived {
+		return fmt.Errorf("%w: %s", ErrCategoryNotArchived, c.ID)
+	}
+	c.Archived = false
+
+	event := events.CategoryUnarchived{CategoryID: c.ID, Occurred: time.Now().UTC()}
+	c.uncommitted = append(c.uncommitted, event)
+	return nil
+}
+
+// Delete elimina la categoría. Sus movimientos la conservan, con su último nombre.
+func (c *Category) Delete() error {
+	if c.Deleted {
+		return fmt.Errorf("%w: %s", ErrCategoryDeleted, c.ID)
+	}
+	c.Deleted = true
+
+	event := events.CategoryDeleted{CategoryID: c.ID, Occurred: time.Now().UTC()}
+	c.uncommitted = append(c.uncommitted, event)
+	return nil
+}
+
+// MergeInto fusiona la categoría en target: sus movimientos y sus subcategorías pasan a
+// target y ella queda eliminada. targetAncestors son los IDs de los ancestros de target:
+// una categoría no se puede fusionar en una de sus descendientes.
+func (c *Category) MergeInto(target *Category, targetAncestors []string) error {
+	if c.ID == target.ID {
+		return fmt.Errorf("%w: %s", ErrCategoryMergeSelf, c.ID)
+	}
+	if c.Deleted {
+		return fmt.Errorf("%w: %s", ErrCategoryDeleted, c.ID)
+	}
+	if target.Deleted {
+		return fmt.Errorf("%w: %s", ErrCategoryDeleted, target.ID)
+	}
+	for _, ancestorID := range targetAncestors {
+		if ancestorID == c.ID {
+			return fmt.Errorf("%w: %s is an ancestor of %s", ErrCategoryCycle, c.ID, target.ID)
+		}
+	}
+	c.Deleted = true
+	c.MergedInto = target.ID
+
+	event := events.CategoryMerged{CategoryID: c.ID, TargetID: target.ID, Occurred: time.Now().UTC()}
+	c.uncommitted = append(c.uncommitted, event)
+	return nil
+}
@@ -0,0 +1,16 @@
+package events
+
+import "time"
+
+type AccountClosed struct {
+	AccountID string    `json:"account_id"`
+	Occurred  time.Time `json:"occurred"`
+}
+
+func (e AccountClosed) EventType() string {
+	return "AccountClosed"
+}
+
+func (e AccountClosed) OccurredAt() time.Time {
+	return e.Occurred
+}
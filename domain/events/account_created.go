@@ -0,0 +1,19 @@
+package events
+
+import "time"
+
+type AccountCreated struct {
+	AccountID string    `json:"account_id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`     // bank, cash o card
+	Currency  string    `json:"currency"` // moneda de los movimientos de la cuenta
+	Occurred  time.Time `json:"occurred"`
+}
+
+func (e AccountCreated) EventType() string {
+	return "AccountCreated"
+}
+
+func (e AccountCreated) OccurredAt() time.Time {
+	return e.Occurred
+}
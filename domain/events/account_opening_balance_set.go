@@ -0,0 +1,24 @@
+package events
+
+import (
+	"time"
+
+	"escama/domain/money"
+)
+
+// AccountOpeningBalanceSet fija el saldo de la cuenta al comienzo del día Date; los
+// movimientos anteriores a esa fecha ya están incluidos en él
+type AccountOpeningBalanceSet struct {
+	AccountID string      `json:"account_id"`
+	Amount    money.Money `json:"amount"`
+	Date      time.Time   `json:"date"`
+	Occurred  time.Time   `json:"occurred"`
+}
+
+func (e AccountOpeningBalanceSet) EventType() string {
+	return "AccountOpeningBalanceSet"
+}
+
+func (e AccountOpeningBalanceSet) OccurredAt() time.Time {
+	return e.Occurred
+}
@@ -0,0 +1,17 @@
+package events
+
+import "time"
+
+type AccountRenamed struct {
+	AccountID string    `json:"account_id"`
+	Name      string    `json:"name"`
+	Occurred  time.Time `json:"occurred"`
+}
+
+func (e AccountRenamed) EventType() string {
+	return "AccountRenamed"
+}
+
+func (e AccountRenamed) OccurredAt() time.Time {
+	return e.Occurred
+}
@@ -9,11 +9,25 @@ type DomainEvent interface {
 	OccurredAt() time.Time
 }
 
+// StoredEvent es un evento tal como queda persistido en el Event Store.
+// Sequence es la versión del agregado luego de aplicar el evento (1, 2, 3...) y
+// GlobalPosition su posición monotónica dentro de todo el store. SchemaVersion indica la
+// forma del payload; al cargar, Upcast lo lleva a la versión actual. Metadata registra
+// el origen del evento (correlación, causa, actor, fuente y versión del cliente).
+// Hash cubre el contenido almacenado del evento y los hashes del evento anterior de su
+// stream (PrevStreamHash) y del store (PrevGlobalHash), formando una cadena verificable.
 type StoredEvent struct {
-	ID            string                 `bson:"_id"`
-	AggregateID   string                 `bson:"aggregate_id"`
-	AggregateType string                 `bson:"aggregate_type"`
-	EventType     string                 `bson:"event_type"`
-	Payload       map[string]interface{} `bson:"payload"`
-	OccurredAt    time.Time              `bson:"occurred_at"`
+	ID             string                 `bson:"_id" json:"id"`
+	AggregateID    string                 `bson:"aggregate_id" json:"aggregate_id"`
+	AggregateType  string                 `bson:"aggregate_type" json:"aggregate_type"`
+	Sequence       int                    `bson:"sequence" json:"sequence"`
+	GlobalPosition int64                  `bson:"global_position" json:"global_position"`
+	EventType      string                 `bson:"event_type" json:"event_type"`
+	SchemaVersion  int                    `bson:"schema_version,omitempty" json:"schema_version,omitempty"`
+	Payload        map[string]interface{} `bson:"payload" json:"payload"`
+	OccurredAt     time.Time              `bson:"occurred_at" json:"occurred_at"`
+	Metadata       Metadata               `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	Hash           string                 `bson:"hash,omitempty" json:"hash,omitempty"`
+	PrevStreamHash string                 `bson:"prev_stream_hash,omitempty" json:"prev_stream_hash,omitempty"`
+	PrevGlobalHash string                 `bson:"prev_global_hash,omitempty" json:"prev_global_hash,omitempty"`
 }
@@ -0,0 +1,16 @@
+package events
+
+import "time"
+
+type CategoryArchived struct {
+	CategoryID string    `json:"category_id"`
+	Occurred   time.Time `json:"occurred"`
+}
+
+func (e CategoryArchived) EventType() string {
+	return "CategoryArchived"
+}
+
+func (e CategoryArchived) OccurredAt() time.Time {
+	return e.Occurred
+}
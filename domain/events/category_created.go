@@ -5,6 +5,8 @@ import "time"
 type CategoryCreated struct {
 	CategoryID string    `json:"category_id"`
 	Name       string    `json:"name"`
+	ParentID   string    `json:"parent_id,omitempty"` // categoría padre; vacío en las de primer nivel
+	Kind       string    `json:"kind,omitempty"`      // income, expense o both; vacío equivale a both
 	Occurred   time.Time `json:"occurred"`
 }
 
@@ -0,0 +1,16 @@
+package events
+
+import "time"
+
+type CategoryDeleted struct {
+	CategoryID string    `json:"category_id"`
+	Occurred   time.Time `json:"occurred"`
+}
+
+func (e CategoryDeleted) EventType() string {
+	return "CategoryDeleted"
+}
+
+func (e CategoryDeleted) OccurredAt() time.Time {
+	return e.Occurred
+}
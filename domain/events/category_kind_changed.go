@@ -0,0 +1,17 @@
+package events
+
+import "time"
+
+type CategoryKindChanged struct {
+	CategoryID string    `json:"category_id"`
+	Kind       string    `json:"kind"`
+	Occurred   time.Time `json:"occurred"`
+}
+
+func (e CategoryKindChanged) EventType() string {
+	return "CategoryKindChanged"
+}
+
+func (e CategoryKindChanged) OccurredAt() time.Time {
+	return e.Occurred
+}
@@ -0,0 +1,17 @@
+package events
+
+import "time"
+
+type CategoryMerged struct {
+	CategoryID string    `json:"category_id"`
+	TargetID   string    `json:"target_id"`
+	Occurred   time.Time `json:"occurred"`
+}
+
+func (e CategoryMerged) EventType() string {
+	return "CategoryMerged"
+}
+
+func (e CategoryMerged) OccurredAt() time.Time {
+	return e.Occurred
+}
@@ -0,0 +1,17 @@
+package events
+
+import "time"
+
+type CategoryParentChanged struct {
+	CategoryID string    `json:"category_id"`
+	ParentID   string    `json:"parent_id"` // vacío: la categoría pasa a ser de primer nivel
+	Occurred   time.Time `json:"occurred"`
+}
+
+func (e CategoryParentChanged) EventType() string {
+	return "CategoryParentChanged"
+}
+
+func (e CategoryParentChanged) OccurredAt() time.Time {
+	return e.Occurred
+}
@@ -0,0 +1,17 @@
+package events
+
+import "time"
+
+type CategoryRenamed struct {
+	CategoryID string    `json:"category_id"`
+	Name       string    `json:"name"`
+	Occurred   time.Time `json:"occurred"`
+}
+
+func (e CategoryRenamed) EventType() string {
+	return "CategoryRenamed"
+}
+
+func (e CategoryRenamed) OccurredAt() time.Time {
+	return e.Occurred
+}
@@ -0,0 +1,16 @@
+package events
+
+import "time"
+
+type CategoryUnarchived struct {
+	CategoryID string    `json:"category_id"`
+	Occurred   time.Time `json:"occurred"`
+}
+
+func (e CategoryUnarchived) EventType() string {
+	return "CategoryUnarchived"
+}
+
+func (e CategoryUnarchived) OccurredAt() time.Time {
+	return e.Occurred
+}
@@ -1,14 +1,19 @@
 package events
 
-import "time"
+import (
+	"time"
+
+	"escama/domain/money"
+)
 
 type ExpenseCreated struct {
-	ExpenseID   string    `json:"expense_id"`
-	CategoryID  string    `json:"category_id"`
-	Amount      float64   `json:"amount"`
-	Description *string   `json:"description,omitempty"`
-	Date        time.Time `json:"date"`
-	Occurred    time.Time `json:"occurred"`
+	ExpenseID   string      `json:"expense_id"`
+	AccountID   string      `json:"account_id,omitempty"`
+	CategoryID  string      `json:"category_id"`
+	Amount      money.Money `json:"amount"`
+	Description *string     `json:"description,omitempty"`
+	Date        time.Time   `json:"date"`
+	Occurred    time.Time   `json:"occurred"`
 }
 
 func (e ExpenseCreated) EventType() string {
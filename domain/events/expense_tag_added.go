@@ -0,0 +1,25 @@
+package events
+
+import "time"
+
+type ExpenseTagAdded struct {
+	ExpenseID string    `json:"expense_id"`
+	Tag       string    `json:"tag"`
+	Occurred  time.Time `json:"occurred"`
+}
+
+func (e ExpenseTagAdded) EventType() string {
+	return "ExpenseTagAdded"
+}
+
+func (e ExpenseTagAdded) OccurredAt() time.Time {
+	return e.Occurred
+}
+
+func NewExpenseTagAdded(expenseID, tag string) ExpenseTagAdded {
+	return ExpenseTagAdded{
+		ExpenseID: expenseID,
+		Tag:       tag,
+		Occurred:  time.Now(),
+	}
+}
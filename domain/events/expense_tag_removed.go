@@ -0,0 +1,25 @@
+package events
+
+import "time"
+
+type ExpenseTagRemoved struct {
+	ExpenseID string    `json:"expense_id"`
+	Tag       string    `json:"tag"`
+	Occurred  time.Time `json:"occurred"`
+}
+
+func (e ExpenseTagRemoved) EventType() string {
+	return "ExpenseTagRemoved"
+}
+
+func (e ExpenseTagRemoved) OccurredAt() time.Time {
+	return e.Occurred
+}
+
+func NewExpenseTagRemoved(expenseID, tag string) ExpenseTagRemoved {
+	return ExpenseTagRemoved{
+		ExpenseID: expenseID,
+		Tag:       tag,
+		Occurred:  time.Now(),
+	}
+}
@@ -1,14 +1,19 @@
 package events
 
-import "time"
+import (
+	"time"
+
+	"escama/domain/money"
+)
 
 type ExpenseUpdated struct {
-	ExpenseID   string    `json:"expense_id"`
-	CategoryID  string    `json:"category_id"`
-	Amount      float64   `json:"amount"`
-	Description *string   `json:"description,omitempty"`
-	Date        time.Time `json:"date"`
-	Occurred    time.Time `json:"occurred"`
+	ExpenseID   string      `json:"expense_id"`
+	AccountID   string      `json:"account_id,omitempty"`
+	CategoryID  string      `json:"category_id"`
+	Amount      money.Money `json:"amount"`
+	Description *string     `json:"description,omitempty"`
+	Date        time.Time   `json:"date"`
+	Occurred    time.Time   `json:"occurred"`
 }
 
 func (e ExpenseUpdated) EventType() string {
@@ -19,9 +24,10 @@ func (e ExpenseUpdated) OccurredAt() time.Time {
 	return e.Occurred
 }
 
-func NewExpenseUpdated(expenseID, categoryID string, amount float64, description *string, date time.Time) ExpenseUpdated {
+func NewExpenseUpdated(expenseID, accountID, categoryID string, amount money.Money, description *string, date time.Time) ExpenseUpdated {
 	return ExpenseUpdated{
 		ExpenseID:   expenseID,
+		AccountID:   accountID,
 		CategoryID:  categoryID,
 		Amount:      amount,
 		Description: description,
@@ -1,14 +1,19 @@
 package events
 
-import "time"
+import (
+	"time"
+
+	"escama/domain/money"
+)
 
 type IncomeCreated struct {
-	IncomeID    string
-	CategoryID  string
-	Amount      float64
-	Description *string
-	Date        time.Time
-	Occurred    time.Time
+	IncomeID    string      `json:"income_id"`
+	AccountID   string      `json:"account_id,omitempty"`
+	CategoryID  string      `json:"category_id"`
+	Amount      money.Money `json:"amount"`
+	Description *string     `json:"description,omitempty"`
+	Date        time.Time   `json:"date"`
+	Occurred    time.Time   `json:"occurred"`
 }
 
 func (e IncomeCreated) EventType() string {
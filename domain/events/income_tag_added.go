@@ -0,0 +1,25 @@
+package events
+
+import "time"
+
+type IncomeTagAdded struct {
+	IncomeID string    `json:"income_id"`
+	Tag      string    `json:"tag"`
+	Occurred time.Time `json:"occurred"`
+}
+
+func (e IncomeTagAdded) EventType() string {
+	return "IncomeTagAdded"
+}
+
+func (e IncomeTagAdded) OccurredAt() time.Time {
+	return e.Occurred
+}
+
+func NewIncomeTagAdded(incomeID, tag string) IncomeTagAdded {
+	return IncomeTagAdded{
+		IncomeID: incomeID,
+		Tag:      tag,
+		Occurred: time.Now(),
+	}
+}
@@ -0,0 +1,25 @@
+package events
+
+import "time"
+
+type IncomeTagRemoved struct {
+	IncomeID string    `json:"income_id"`
+	Tag      string    `json:"tag"`
+	Occurred time.Time `json:"occurred"`
+}
+
+func (e IncomeTagRemoved) EventType() string {
+	return "IncomeTagRemoved"
+}
+
+func (e IncomeTagRemoved) OccurredAt() time.Time {
+	return e.Occurred
+}
+
+func NewIncomeTagRemoved(incomeID, tag string) IncomeTagRemoved {
+	return IncomeTagRemoved{
+		IncomeID: incomeID,
+		Tag:      tag,
+		Occurred: time.Now(),
+	}
+}
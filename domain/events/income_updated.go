@@ -1,14 +1,19 @@
 package events
 
-import "time"
+import (
+	"time"
+
+	"escama/domain/money"
+)
 
 type IncomeUpdated struct {
-	IncomeID    string    `json:"income_id"`
-	CategoryID  string    `json:"category_id"`
-	Amount      float64   `json:"amount"`
-	Description *string   `json:"description,omitempty"`
-	Date        time.Time `json:"date"`
-	Occurred    time.Time `json:"occurred"`
+	IncomeID    string      `json:"income_id"`
+	AccountID   string      `json:"account_id,omitempty"`
+	CategoryID  string      `json:"category_id"`
+	Amount      money.Money `json:"amount"`
+	Description *string     `json:"description,omitempty"`
+	Date        time.Time   `json:"date"`
+	Occurred    time.Time   `json:"occurred"`
 }
 
 func (e IncomeUpdated) EventType() string {
@@ -19,9 +24,10 @@ func (e IncomeUpdated) OccurredAt() time.Time {
 	return e.Occurred
 }
 
-func NewIncomeUpdated(incomeID, categoryID string, amount float64, description *string, date time.Time) IncomeUpdated {
+func NewIncomeUpdated(incomeID, accountID, categoryID string, amount money.Money, description *string, date time.Time) IncomeUpdated {
 	return IncomeUpdated{
 		IncomeID:    incomeID,
+		AccountID:   accountID,
 		CategoryID:  categoryID,
 		Amount:      amount,
 		Description: description,
@@ -0,0 +1,37 @@
+package events
+
+import "context"
+
+// Metadata describe quién y qué produjo un evento. Viaja en el context.Context desde el
+// command bus hasta el Event Store, que la guarda junto a cada evento.
+//
+// CorrelationID agrupa todos los eventos originados por una misma acción del usuario;
+// CausationID identifica el mensaje (comando o evento) que causó directamente el evento.
+// IdempotencyKey es la clave opcional que el cliente envió con el comando: registra qué
+// comandos ya se procesaron para que sus reintentos no agreguen eventos.
+type Metadata struct {
+	CorrelationID  string `bson:"correlation_id,omitempty" json:"correlation_id,omitempty"`
+	CausationID    string `bson:"causation_id,omitempty" json:"causation_id,omitempty"`
+	Actor          string `bson:"actor,omitempty" json:"actor,omitempty"`
+	Source         string `bson:"source,omitempty" json:"source,omitempty"`
+	ClientVersion  string `bson:"client_version,omitempty" json:"client_version,omitempty"`
+	IdempotencyKey string `bson:"idempotency_key,omitempty" json:"idempotency_key,omitempty"`
+}
+
+// IsZero permite omitir la metadata vacía al persistir (eventos anteriores a la metadata)
+func (m Metadata) IsZero() bool {
+	return m == Metadata{}
+}
+
+type metadataKey struct{}
+
+// WithMetadata devuelve un contexto que lleva la metadata indicada
+func WithMetadata(ctx context.Context, metadata Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, metadata)
+}
+
+// MetadataFromContext devuelve la metadata del contexto, o una vacía si no tiene
+func MetadataFromContext(ctx context.Context) Metadata {
+	metadata, _ := ctx.Value(metadataKey{}).(Metadata)
+	return metadata
+}
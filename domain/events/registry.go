@@ -0,0 +1,73 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrUnknownEventType se devuelve al decodificar un tipo de evento que no está registrado
+var ErrUnknownEventType = errors.New("unknown event type")
+
+// registry relaciona el nombre de cada tipo de evento con su tipo Go concreto
+var registry = map[string]reflect.Type{}
+
+func init() {
+	Register(CategoryCreated{})
+	Register(ExpenseCreated{})
+	Register(ExpenseUpdated{})
+	Register(ExpenseDeleted{})
+	Register(IncomeCreated{})
+	Register(IncomeUpdated{})
+	Register(IncomeDeleted{})
+	Register(AccountCreated{})
+	Register(AccountRenamed{})
+	Register(AccountClosed{})
+	Register(AccountOpeningBalanceSet{})
+	Register(TransferCreated{})
+	Register(TransferUpdated{})
+	Register(TransferDeleted{})
+	Register(CategoryRenamed{})
+	Register(CategoryArchived{})
+	Register(CategoryUnarchived{})
+	Register(CategoryDeleted{})
+	Register(CategoryMerged{})
+	Register(CategoryParentChanged{})
+	Register(CategoryKindChanged{})
+	Register(ExpenseTagAdded{})
+	Register(ExpenseTagRemoved{})
+	Register(IncomeTagAdded{})
+	Register(IncomeTagRemoved{})
+}
+
+// Register asocia el EventType() del evento con su tipo Go, para poder decodificarlo
+func Register(event DomainEvent) {
+	registry[event.EventType()] = reflect.TypeOf(event)
+}
+
+// Decode convierte el evento almacenado en su DomainEvent concreto (por valor, por
+// ejemplo ExpenseCreated), llevando antes el payload a la versión de esquema actual
+func (e StoredEvent) Decode() (DomainEvent, error) {
+	eventType, ok := registry[e.EventType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEventType, e.EventType)
+	}
+
+	upcasted, err := Upcast(e)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(upcasted.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload of event %s: %w", e.ID, err)
+	}
+
+	target := reflect.New(eventType)
+	if err := json.Unmarshal(data, target.Interface()); err != nil {
+		return nil, fmt.Errorf("failed to decode %s event %s: %w", e.EventType, e.ID, err)
+	}
+
+	return target.Elem().Interface().(DomainEvent), nil
+}
@@ -0,0 +1,280 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"escama/domain/money"
+)
+
+// Versiones de esquema de los payloads.
+//
+//	1: payloads históricos, con claves en PascalCase o snake_case según el evento y
+//	   fechas/números en el tipo que dejó cada store (time.Time, fecha BSON, int...)
+//	2: claves snake_case (las etiquetas json de cada evento), fechas como texto
+//	   RFC3339 y montos como float64
+//	3: montos como money.Money ({"amount": unidades menores, "currency": código ISO});
+//	   solo los eventos con importe
+const (
+	SchemaV1 = 1
+	SchemaV2 = 2
+	SchemaV3 = 3
+)
+
+// schemaVersions versión actual del payload de cada tipo de evento
+var schemaVersions = map[string]int{
+	"CategoryCreated": SchemaV2,
+	"ExpenseCreated":  SchemaV3,
+	"ExpenseUpdated":  SchemaV3,
+	"ExpenseDeleted":  SchemaV2,
+	"IncomeCreated":   SchemaV3,
+	"IncomeUpdated":   SchemaV3,
+	"IncomeDeleted":   SchemaV2,
+
+	"AccountCreated":           SchemaV2,
+	"AccountRenamed":           SchemaV2,
+	"AccountClosed":            SchemaV2,
+	"AccountOpeningBalanceSet": SchemaV3,
+
+	"TransferCreated": SchemaV3,
+	"TransferUpdated": SchemaV3,
+	"TransferDeleted": SchemaV2,
+
+	"CategoryRenamed":       SchemaV2,
+	"CategoryArchived":      SchemaV2,
+	"CategoryUnarchived":    SchemaV2,
+	"CategoryDeleted":       SchemaV2,
+	"CategoryMerged":        SchemaV2,
+	"CategoryParentChanged": SchemaV2,
+	"CategoryKindChanged":   SchemaV2,
+
+	"ExpenseTagAdded":   SchemaV2,
+	"ExpenseTagRemoved": SchemaV2,
+	"IncomeTagAdded":    SchemaV2,
+	"IncomeTagRemoved":  SchemaV2,
+}
+
+// Upcaster transforma un payload de una versión de esquema a la siguiente
+type Upcaster func(payload map[string]interface{}) (map[string]interface{}, error)
+
+// upcasters cadena de transformaciones por tipo de evento, indexada por versión de origen
+var upcasters = map[string]map[int]Upcaster{}
+
+func init() {
+	for eventType, version := range schemaVersions {
+		RegisterUpcaster(eventType, SchemaV1, upcastV1ToV2)
+		if version >= SchemaV3 {
+			RegisterUpcaster(eventType, SchemaV2, upcastV2ToV3)
+		}
+	}
+}
+
+// SchemaVersion devuelve la versión de esquema con la que se escriben los eventos del tipo dado
+func SchemaVersion(eventType string) int {
+	if version, ok := schemaVersions[eventType]; ok {
+		return version
+	}
+	return SchemaV1
+}
+
+// RegisterUpcaster agrega a la cadena la transformación de fromVersion a fromVersion+1
+func RegisterUpcaster(eventType string, fromVersion int, upcaster Upcaster) {
+	if upcasters[eventType] == nil {
+		upcasters[eventType] = make(map[int]Upcaster)
+	}
+	upcasters[eventType][fromVersion] = upcaster
+}
+
+// EncodePayload serializa un evento de dominio al payload canónico de su versión actual
+func EncodePayload(event DomainEvent) (map[string]interface{}, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// Upcast lleva el payload de un evento almacenado a la versión actual de su tipo.
+// Los eventos sin versión (anteriores al versionado) se consideran versión 1.
+func Upcast(storedEvent StoredEvent) (StoredEvent, error) {
+	version := storedEvent.SchemaVersion
+	if version == 0 {
+		version = SchemaV1
+	}
+
+	target := SchemaVersion(storedEvent.EventType)
+	payload := storedEvent.Payload
+	for version < target {
+		upcaster, ok := upcasters[storedEvent.EventType][version]
+		if !ok {
+			return storedEvent, fmt.Errorf("no upcaster for %s from schema version %d", storedEvent.EventType, version)
+		}
+
+		var err error
+		payload, err = upcaster(payload)
+		if err != nil {
+			return storedEvent, fmt.Errorf("failed to upcast %s %s to schema version %d: %w", storedEvent.EventType, storedEvent.ID, version+1, err)
+		}
+		version++
+	}
+
+	storedEvent.Payload = payload
+	storedEvent.SchemaVersion = version
+	return storedEvent, nil
+}
+
+// UpcastAll aplica Upcast a una lista de eventos almacenados
+func UpcastAll(storedEvents []StoredEvent) ([]StoredEvent, error) {
+	for i, storedEvent := range storedEvents {
+		upcasted, err := Upcast(storedEvent)
+		if err != nil {
+			return nil, err
+		}
+		storedEvents[i] = upcasted
+	}
+	return storedEvents, nil
+}
+
+// BusinessDayLayout formato del día de negocio que devuelve BusinessDay
+const BusinessDayLayout = "2006-01-02"
+
+// BusinessDay devuelve el día del movimiento que registra el evento, tal como figura en
+// la fecha de su payload (en la zona horaria con que la escribió el cliente), a
+// diferencia de OccurredAt, que es el momento en que se registró. Los eventos sin fecha,
+// como CategoryCreated o las bajas, devuelven false.
+func (e StoredEvent) BusinessDay() (string, bool) {
+	if e.SchemaVersion < SchemaVersion(e.EventType) {
+		upcasted, err := Upcast(e)
+		if err != nil {
+			return "", false
+		}
+		e = upcasted
+	}
+
+	date, ok := e.Payload["date"].(string)
+	if !ok {
+		return "", false
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, date)
+	if err != nil || parsed.IsZero() {
+		return "", false
+	}
+	return parsed.Format(BusinessDayLayout), true
+}
+
+// upcastV1ToV2 pasa las claves a snake_case y normaliza fechas y números
+func upcastV1ToV2(payload map[string]interface{}) (map[string]interface{}, error) {
+	upcasted := make(map[string]interface{}, len(payload))
+
+	for key, value := range payload {
+		canonicalKey := snakeCase(key)
+
+		// Si el payload trae ambas variantes, la snake_case es la que escribió el código actual
+		if _, exists := upcasted[canonicalKey]; exists && canonicalKey != key {
+			continue
+		}
+
+		normalized, err := normalizeValue(canonicalKey, value)
+		if err != nil {
+			return nil, err
+		}
+		upcasted[canonicalKey] = normalized
+	}
+
+	return upcasted, nil
+}
+
+// upcastV2ToV3 convierte el monto float64, que siempre estuvo en guaraníes, a money.Money.
+// Los montos nulos (datos cifrados con una clave eliminada) no cambian.
+func upcastV2ToV3(payload map[string]interface{}) (map[string]interface{}, error) {
+	upcasted := make(map[string]interface{}, len(payload))
+	for key, value := range payload {
+		upcasted[key] = value
+	}
+
+	amount, ok := payload["amount"].(float64)
+	if !ok {
+		return upcasted, nil
+	}
+	converted := money.FromMajor(amount, money.PYG)
+	upcasted["amount"] = map[string]interface{}{
+		"amount":   float64(converted.Amount),
+		"currency": converted.Currency,
+	}
+	return upcasted, nil
+}
+
+// normalizeValue convierte fechas a texto RFC3339 y números a float64
+func normalizeValue(key string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano), nil
+	case interface{ Time() time.Time }:
+		// Fechas nativas de un driver (por ejemplo primitive.DateTime de MongoDB)
+		return v.Time().UTC().Format(time.RFC3339Nano), nil
+	case int:
+		return float64(v), nil
+	case int32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case float32:
+		return float64(v), nil
+	case string:
+		if key == "date" || key == "occurred" {
+			return normalizeDate(v)
+		}
+	}
+	return value, nil
+}
+
+// normalizeDate reescribe en RFC3339 las fechas guardadas en otros formatos de texto
+func normalizeDate(value string) (string, error) {
+	if value == "" {
+		return value, nil
+	}
+
+	formats := []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+	}
+	for _, format := range formats {
+		if parsed, err := time.Parse(format, value); err == nil {
+			return parsed.Format(time.RFC3339Nano), nil
+		}
+	}
+
+	return "", fmt.Errorf("unrecognized date %q", value)
+}
+
+// snakeCase convierte ExpenseID en expense_id; las claves ya en snake_case no cambian
+func snakeCase(key string) string {
+	runes := []rune(key)
+	var b strings.Builder
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			previousLower := i > 0 && !unicode.IsUpper(runes[i-1]) && runes[i-1] != '_'
+			acronymEnd := i > 0 && unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if previousLower || acronymEnd {
+				b.WriteRune('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
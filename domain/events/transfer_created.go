@@ -0,0 +1,28 @@
+package events
+
+import (
+	"time"
+
+	"escama/domain/money"
+)
+
+// TransferCreated registra dinero que sale de una cuenta y entra en otra. ReceivedAmount
+// es lo acreditado en la cuenta destino; difiere de Amount si las monedas son distintas.
+type TransferCreated struct {
+	TransferID     string      `json:"transfer_id"`
+	FromAccountID  string      `json:"from_account_id"`
+	ToAccountID    string      `json:"to_account_id"`
+	Amount         money.Money `json:"amount"`
+	ReceivedAmount money.Money `json:"received_amount"`
+	Description    *string     `json:"description,omitempty"`
+	Date           time.Time   `json:"date"`
+	Occurred       time.Time   `json:"occurred"`
+}
+
+func (e TransferCreated) EventType() string {
+	return "TransferCreated"
+}
+
+func (e TransferCreated) OccurredAt() time.Time {
+	return e.Occurred
+}
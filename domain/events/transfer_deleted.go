@@ -0,0 +1,16 @@
+package events
+
+import "time"
+
+type TransferDeleted struct {
+	TransferID string    `json:"transfer_id"`
+	Occurred   time.Time `json:"occurred"`
+}
+
+func (e TransferDeleted) EventType() string {
+	return "TransferDeleted"
+}
+
+func (e TransferDeleted) OccurredAt() time.Time {
+	return e.Occurred
+}
@@ -0,0 +1,26 @@
+package events
+
+import (
+	"time"
+
+	"escama/domain/money"
+)
+
+type TransferUpdated struct {
+	TransferID     string      `json:"transfer_id"`
+	FromAccountID  string      `json:"from_account_id"`
+	ToAccountID    string      `json:"to_account_id"`
+	Amount         money.Money `json:"amount"`
+	ReceivedAmount money.Money `json:"received_amount"`
+	Description    *string     `json:"description,omitempty"`
+	Date           time.Time   `json:"date"`
+	Occurred       time.Time   `json:"occurred"`
+}
+
+func (e TransferUpdated) EventType() string {
+	return "TransferUpdated"
+}
+
+func (e TransferUpdated) OccurredAt() time.Time {
+	return e.Occurred
+}
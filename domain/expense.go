@@ -1,24 +1,30 @@
 package domain
 
 import (
+	"fmt"
 	"time"
 
 	"escama/domain/events"
+	"escama/domain/money"
 )
 
 type Expense struct {
 	ID          string
+	AccountID   string
 	CategoryID  string
-	Amount      float64
+	Amount      money.Money
 	Description *string
 	Date        time.Time
+	Tags        []string // etiquetas normalizadas (ver NormalizeTag)
+	Version     int      // cantidad de eventos persistidos en el stream
 
 	uncommitted []events.DomainEvent
 }
 
-func NewExpense(id, categoryID string, amount float64, description *string, date time.Time) *Expense {
+func NewExpense(id, accountID, categoryID string, amount money.Money, description *string, date time.Time) *Expense {
 	exp := &Expense{
 		ID:          id,
+		AccountID:   accountID,
 		CategoryID:  categoryID,
 		Amount:      amount,
 		Description: description,
@@ -27,6 +33,7 @@ func NewExpense(id, categoryID string, amount float64, description *string, date
 
 	event := events.ExpenseCreated{
 		ExpenseID:   id,
+		AccountID:   accountID,
 		CategoryID:  categoryID,
 		Amount:      amount,
 		Description: description,
@@ -46,13 +53,14 @@ func (e *Expense) ClearUncommittedEvents() {
 	e.uncommitted = nil
 }
 
-func (e *Expense) Update(categoryID string, amount float64, description *string, date time.Time) {
+func (e *Expense) Update(accountID, categoryID string, amount money.Money, description *string, date time.Time) {
+	e.AccountID = accountID
 	e.CategoryID = categoryID
 	e.Amount = amount
 	e.Description = description
 	e.Date = date
 
-	event := events.NewExpenseUpdated(e.ID, categoryID, amount, description, date)
+	event := events.NewExpenseUpdated(e.ID, accountID, categoryID, amount, description, date)
 	e.uncommitted = append(e.uncommitted, event)
 }
 
@@ -60,3 +68,35 @@ func (e *Expense) Delete() {
 	event := events.NewExpenseDeleted(e.ID)
 	e.uncommitted = append(e.uncommitted, event)
 }
+
+// AddTag agrega una etiqueta al gasto; si ya la tiene no genera un evento
+func (e *Expense) AddTag(tag string) error {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	if HasTag(e.Tags, tag) {
+		return nil
+	}
+
+	e.Tags = append(e.Tags, tag)
+	event := events.NewExpenseTagAdded(e.ID, tag)
+	e.uncommitted = append(e.uncommitted, event)
+	return nil
+}
+
+// RemoveTag quita una etiqueta del gasto
+func (e *Expense) RemoveTag(tag string) error {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	if !HasTag(e.Tags, tag) {
+		return fmt.Errorf("%w: %s", ErrTagNotFound, tag)
+	}
+
+	e.Tags = WithoutTag(e.Tags, tag)
+	event := events.NewExpenseTagRemoved(e.ID, tag)
+	e.uncommitted = append(e.uncommitted, event)
+	return nil
+}
@@ -1,24 +1,30 @@
 package domain
 
 import (
+	"fmt"
 	"time"
 
 	"escama/domain/events"
+	"escama/domain/money"
 )
 
 type Income struct {
 	ID          string
+	AccountID   string
 	CategoryID  string
-	Amount      float64
+	Amount      money.Money
 	Description *string
 	Date        time.Time
+	Tags        []string // etiquetas normalizadas (ver NormalizeTag)
+	Version     int      // cantidad de eventos persistidos en el stream
 
 	uncommitted []events.DomainEvent
 }
 
-func NewIncome(id, categoryID string, amount float64, description *string, date time.Time) *Income {
+func NewIncome(id, accountID, categoryID string, amount money.Money, description *string, date time.Time) *Income {
 	inc := &Income{
 		ID:          id,
+		AccountID:   accountID,
 		CategoryID:  categoryID,
 		Amount:      amount,
 		Description: description,
@@ -27,6 +33,7 @@ func NewIncome(id, categoryID string, amount float64, description *string, date
 
 	event := events.IncomeCreated{
 		IncomeID:    id,
+		AccountID:   accountID,
 		CategoryID:  categoryID,
 		Amount:      amount,
 		Description: description,
@@ -46,13 +53,14 @@ func (i *Income) ClearUncommittedEvents() {
 	i.uncommitted = nil
 }
 
-func (i *Income) Update(categoryID string, amount float64, description *string, date time.Time) {
+func (i *Income) Update(accountID, categoryID string, amount money.Money, description *string, date time.Time) {
+	i.AccountID = accountID
 	i.CategoryID = categoryID
 	i.Amount = amount
 	i.Description = description
 	i.Date = date
 
-	event := events.NewIncomeUpdated(i.ID, categoryID, amount, description, date)
+	event := events.NewIncomeUpdated(i.ID, accountID, categoryID, amount, description, date)
 	i.uncommitted = append(i.uncommitted, event)
 }
 
@@ -60,3 +68,35 @@ func (i *Income) Delete() {
 	event := events.NewIncomeDeleted(i.ID)
 	i.uncommitted = append(i.uncommitted, event)
 }
+
+// AddTag agrega una etiqueta al ingreso; si ya la tiene no genera un evento
+func (i *Income) AddTag(tag string) error {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	if HasTag(i.Tags, tag) {
+		return nil
+	}
+
+	i.Tags = append(i.Tags, tag)
+	event := events.NewIncomeTagAdded(i.ID, tag)
+	i.uncommitted = append(i.uncommitted, event)
+	return nil
+}
+
+// RemoveTag quita una etiqueta del ingreso
+func (i *Income) RemoveTag(tag string) error {
+	tag, err := NormalizeTag(tag)
+	if err != nil {
+		return err
+	}
+	if !HasTag(i.Tags, tag) {
+		return fmt.Errorf("%w: %s", ErrTagNotFound, tag)
+	}
+
+	i.Tags = WithoutTag(i.Tags, tag)
+	event := events.NewIncomeTagRemoved(i.ID, tag)
+	i.uncommitted = append(i.uncommitted, event)
+	return nil
+}
@@ -0,0 +1,188 @@
+// Package money representa importes con un entero en la unidad menor de su moneda
+// (centavos de dólar, guaraníes enteros...) y su código ISO 4217, para que sumar
+// movimientos no acumule errores de redondeo.
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// PYG guaraní, la moneda de los importes guardados antes de que existiera Money
+const PYG = "PYG"
+
+// DefaultCurrency moneda de los importes que no indican otra
+const DefaultCurrency = PYG
+
+// ErrCurrencyMismatch se devuelve al operar con importes de monedas distintas
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// ErrInvalidAmount se devuelve al leer un importe mal escrito o con más decimales de los
+// que admite su moneda
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// minorDigits decimales de las monedas que no usan dos (ISO 4217)
+var minorDigits = map[string]int{
+	"PYG": 0,
+	"CLP": 0,
+	"JPY": 0,
+	"KRW": 0,
+	"UYI": 0,
+	"BHD": 3,
+	"KWD": 3,
+	"OMR": 3,
+	"TND": 3,
+}
+
+// symbols prefijo con que se muestran las monedas más usadas; el resto usa su código
+var symbols = map[string]string{
+	"PYG": "₲",
+	"USD": "US$",
+	"EUR": "€",
+	"BRL": "R$",
+	"ARS": "AR$",
+}
+
+// Money importe en la unidad menor de Currency
+type Money struct {
+	Amount   int64  `bson:"amount" json:"amount"`
+	Currency string `bson:"currency" json:"currency"`
+}
+
+// New crea un importe a partir de su valor en la unidad menor de la moneda
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: normalizeCurrency(currency)}
+}
+
+// FromMajor convierte un importe en unidades de la moneda (por ejemplo, 25.5 dólares),
+// redondeado a la unidad menor. Solo debe usarse con importes guardados como float64.
+func FromMajor(value float64, currency string) Money {
+	currency = normalizeCurrency(currency)
+	scale := math.Pow10(MinorDigits(currency))
+	return Money{Amount: int64(math.Round(value * scale)), Currency: currency}
+}
+
+// Parse lee un importe escrito en unidades de la moneda ("120000", "25.50") sin pasar
+// por float64. Acepta punto o coma decimal.
+func Parse(text, currency string) (Money, error) {
+	currency = normalizeCurrency(currency)
+	digits := MinorDigits(currency)
+
+	value := strings.TrimSpace(text)
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(value, "-")
+
+	whole, fraction, _ := strings.Cut(strings.Replace(value, ",", ".", 1), ".")
+	if whole == "" && fraction == "" {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, text)
+	}
+	if len(fraction) > digits {
+		return Money{}, fmt.Errorf("%w: %q has more than %d decimals for %s", ErrInvalidAmount, text, digits, currency)
+	}
+	fraction += strings.Repeat("0", digits-len(fraction))
+
+	amount, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil || strings.ContainsAny(whole+fraction, "+-") {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, text)
+	}
+	if negative {
+		amount = -amount
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// MinorDigits devuelve la cantidad de decimales de la moneda
+func MinorDigits(currency string) int {
+	if digits, ok := minorDigits[normalizeCurrency(currency)]; ok {
+		return digits
+	}
+	return 2
+}
+
+// IsZero indica si el importe es cero, cualquiera sea su moneda
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Add suma dos importes de la misma moneda. Un cero sin moneda (el valor inicial de un
+// acumulador) toma la moneda del otro importe.
+func (m Money) Add(other Money) (Money, error) {
+	switch {
+	case m.Currency == "" && m.Amount == 0:
+		return other, nil
+	case other.Currency == "" && other.Amount == 0:
+		return m, nil
+	case m.Currency != other.Currency:
+		return Money{}, fmt.Errorf("%w: cannot add %s to %s", ErrCurrencyMismatch, other.Currency, m.Currency)
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Sub resta dos importes de la misma moneda
+func (m Money) Sub(other Money) (Money, error) {
+	return m.Add(other.Neg())
+}
+
+// Neg devuelve el importe con el signo opuesto
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Major devuelve el importe en unidades de la moneda. Es solo para mostrarlo o
+// exportarlo: los cálculos se hacen sobre Amount.
+func (m Money) Major() float64 {
+	return float64(m.Amount) / math.Pow10(MinorDigits(m.Currency))
+}
+
+// String muestra el importe con el símbolo de su moneda, por ejemplo ₲120000 o US$25.50
+func (m Money) String() string {
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	prefix, ok := symbols[currency]
+	if !ok {
+		prefix = currency + " "
+	}
+
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+
+	digits := MinorDigits(currency)
+	text := strconv.FormatInt(amount, 10)
+	if digits > 0 {
+		text = fmt.Sprintf("%0*d", digits+1, amount)
+		text = text[:len(text)-digits] + "." + text[len(text)-digits:]
+	}
+	return prefix + sign + text
+}
+
+// UnmarshalJSON acepta, además del objeto {"amount", "currency"}, un número suelto: así
+// se guardaban los importes (en guaraníes) antes de Money, por ejemplo en snapshots y
+// proyecciones cifradas
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var legacy float64
+	if err := json.Unmarshal(data, &legacy); err == nil {
+		*m = FromMajor(legacy, PYG)
+		return nil
+	}
+
+	type plain Money
+	var value plain
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*m = Money(value)
+	return nil
+}
+
+func normalizeCurrency(currency string) string {
+	return strings.ToUpper(strings.TrimSpace(currency))
+}
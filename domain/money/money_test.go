@@ -0,0 +1,75 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name     string
+		text     string
+		currency string
+		want     Money
+		wantErr  bool
+	}{
+		{"guaraníes enteros", "120000", "PYG", New(120000, PYG), false},
+		{"dólares con centavos", "25.50", "USD", New(2550, "USD"), false},
+		{"coma decimal y moneda en minúsculas", "25,5", " usd ", New(2550, "USD"), false},
+		{"sin parte entera", ".75", "EUR", New(75, "EUR"), false},
+		{"tres decimales", "1.234", "KWD", New(1234, "KWD"), false},
+		{"negativo", "-10.05", "USD", New(-1005, "USD"), false},
+		{"espacios alrededor", "  -300 ", "PYG", New(-300, PYG), false},
+		{"separador de miles en guaraníes", "120.000", "PYG", Money{}, true},
+		{"más decimales que la moneda", "1.234", "USD", Money{}, true},
+		{"vacío", "", "USD", Money{}, true},
+		{"solo signo", "-", "USD", Money{}, true},
+		{"doble signo", "--5", "USD", Money{}, true},
+		{"signo positivo", "+5", "USD", Money{}, true},
+		{"signo después del punto", "5.-1", "USD", Money{}, true},
+		{"texto", "abc", "USD", Money{}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Parse(tc.text, tc.currency)
+			if tc.wantErr {
+				if !errors.Is(err, ErrInvalidAmount) {
+					t.Fatalf("Parse(%q, %q) error = %v, want ErrInvalidAmount", tc.text, tc.currency, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q, %q): %v", tc.text, tc.currency, err)
+			}
+			if got != tc.want {
+				t.Errorf("Parse(%q, %q) = %+v, want %+v", tc.text, tc.currency, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFromMajor(t *testing.T) {
+	cases := []struct {
+		name     string
+		value    float64
+		currency string
+		want     Money
+	}{
+		{"guaraníes enteros", 120000, "PYG", New(120000, PYG)},
+		{"dólares con centavos", 25.5, "USD", New(2550, "USD")},
+		{"redondea en vez de truncar", 19.99, "USD", New(1999, "USD")},
+		{"tres decimales", -0.125, "KWD", New(-125, "KWD")},
+		{"medio se aleja del cero", 2.5, "PYG", New(3, PYG)},
+		{"medio negativo se aleja del cero", -2.5, "PYG", New(-3, PYG)},
+		{"moneda en minúsculas", 1.1, "brl", New(110, "BRL")},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := FromMajor(tc.value, tc.currency); got != tc.want {
+				t.Errorf("FromMajor(%v, %q) = %+v, want %+v", tc.value, tc.currency, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidTag se devuelve al usar una etiqueta vacía o con espacios o comas
+var ErrInvalidTag = errors.New("invalid tag")
+
+// ErrTagNotFound se devuelve al quitar una etiqueta que el movimiento no tiene
+var ErrTagNotFound = errors.New("tag not found")
+
+// NormalizeTag devuelve la etiqueta en minúsculas y sin espacios alrededor, de modo que
+// "Vacaciones-2026" y "vacaciones-2026" sean la misma
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return "", fmt.Errorf("%w: empty tag", ErrInvalidTag)
+	}
+	if strings.IndexFunc(tag, func(r rune) bool { return unicode.IsSpace(r) || r == ',' }) >= 0 {
+		return "", fmt.Errorf("%w: %q (tags cannot contain spaces or commas)", ErrInvalidTag, tag)
+	}
+	return tag, nil
+}
+
+// HasTag indica si la lista incluye la etiqueta
+func HasTag(tags []string, tag string) bool {
+	for _, existing := range tags {
+		if existing == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// WithoutTag devuelve la lista sin la etiqueta
+func WithoutTag(tags []string, tag string) []string {
+	result := make([]string, 0, len(tags))
+	for _, existing := range tags {
+		if existing != tag {
+			result = append(result, existing)
+		}
+	}
+	return result
+}
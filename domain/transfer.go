@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"escama/domain/events"
+	"escama/domain/money"
+)
+
+// ErrSameAccount se devuelve al transferir de una cuenta a sí misma
+var ErrSameAccount = errors.New("transfer source and destination must be different accounts")
+
+// Transfer dinero que pasa de una cuenta a otra. No es un ingreso ni un gasto: solo
+// mueve los saldos de las dos cuentas. Amount sale de FromAccountID en su moneda y
+// ReceivedAmount entra en ToAccountID en la suya.
+type Transfer struct {
+	ID             string
+	FromAccountID  string
+	ToAccountID    string
+	Amount         money.Money
+	ReceivedAmount money.Money
+	Description    *string
+	Date           time.Time
+	Version        int // cantidad de eventos persistidos en el stream
+
+	uncommitted []events.DomainEvent
+}
+
+func NewTransfer(id, fromAccountID, toAccountID string, amount, receivedAmount money.Money, description *string, date time.Time) (*Transfer, error) {
+	if fromAccountID == toAccountID {
+		return nil, fmt.Errorf("%w: %s", ErrSameAccount, fromAccountID)
+	}
+
+	t := &Transfer{
+		ID:             id,
+		FromAccountID:  fromAccountID,
+		ToAccountID:    toAccountID,
+		Amount:         amount,
+		ReceivedAmount: receivedAmount,
+		Description:    description,
+		Date:           date,
+	}
+
+	event := events.TransferCreated{
+		TransferID:     id,
+		FromAccountID:  fromAccountID,
+		ToAccountID:    toAccountID,
+		Amount:         amount,
+		ReceivedAmount: receivedAmount,
+		Description:    description,
+		Date:           date,
+		Occurred:       time.Now().UTC(),
+	}
+	t.uncommitted = append(t.uncommitted, event)
+
+	return t, nil
+}
+
+func (t *Transfer) UncommittedEvents() []events.DomainEvent {
+	return t.uncommitted
+}
+
+func (t *Transfer) ClearUncommittedEvents() {
+	t.uncommitted = nil
+}
+
+func (t *Transfer) Update(fromAccountID, toAccountID string, amount, receivedAmount money.Money, description *string, date time.Time) error {
+	if fromAccountID == toAccountID {
+		return fmt.Errorf("%w: %s", ErrSameAccount, fromAccountID)
+	}
+
+	t.FromAccountID = fromAccountID
+	t.ToAccountID = toAccountID
+	t.Amount = amount
+	t.ReceivedAmount = receivedAmount
+	t.Description = description
+	t.Date = date
+
+	event := events.TransferUpdated{
+		TransferID:     t.ID,
+		FromAccountID:  fromAccountID,
+		ToAccountID:    toAccountID,
+		Amount:         amount,
+		ReceivedAmount: receivedAmount,
+		Description:    description,
+		Date:           date,
+		Occurred:       time.Now().UTC(),
+	}
+	t.uncommitted = append(t.uncommitted, event)
+	return nil
+}
+
+func (t *Transfer) Delete() {
+	event := events.TransferDeleted{TransferID: t.ID, Occurred: time.Now().UTC()}
+	t.uncommitted = append(t.uncommitted, event)
+}
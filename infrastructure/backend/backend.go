@@ -0,0 +1,249 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"escama/infrastructure/encryption"
+	"escama/infrastructure/eventstore"
+	"escama/infrastructure/projections"
+	"escama/infrastructure/rates"
+	"escama/infrastructure/snapshots"
+	"escama/infrastructure/subscriptions"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Backend agrupa el Event Store (escritura), los snapshots, el modelo de lectura y los
+// checkpoints de sus suscripciones. Con un keyfile configurado, Keyring y Cipher cifran
+// los datos sensibles de los eventos, las proyecciones y los snapshots.
+//
+// EventStore lee también los eventos archivados en frío (Archive); Outbox trabaja solo
+// con el store principal, donde se escriben los eventos nuevos. Rates tiene las
+// cotizaciones para convertir los reportes a otra moneda.
+type Backend struct {
+	EventStore       eventstore.EventStore
+	Archive          *eventstore.ArchivedEventStore
+	Outbox           eventstore.Outbox
+	Projections      projections.ProjectionStore
+	Checkpoints      subscriptions.CheckpointStore
+	Snapshots        snapshots.Store
+	SnapshotInterval int
+	Keyring          *encryption.Keyring
+	Cipher           eventstore.PayloadCipher
+	Rates            *rates.Store
+
+	closers []func() error
+}
+
+// Open conecta el backend indicado en la configuración
+func Open(cfg Config) (*Backend, error) {
+	var b *Backend
+	var err error
+
+	switch cfg.Backend {
+	case Mongo:
+		b, err = openMongo()
+	case SQLite:
+		b, err = openSQLite(cfg)
+	case File:
+		b, err = openFile(cfg)
+	default:
+		return nil, fmt.Errorf("unknown backend %q (expected %s, %s or %s)", cfg.Backend, Mongo, SQLite, File)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Todos los Event Stores guardan su outbox en la misma escritura que los eventos
+	outbox, ok := b.EventStore.(eventstore.Outbox)
+	if !ok {
+		b.Close()
+		return nil, fmt.Errorf("event store for backend %q does not support an outbox", cfg.Backend)
+	}
+	b.Outbox = outbox
+	b.SnapshotInterval = cfg.SnapshotInterval
+
+	if cfg.KeyFile != "" {
+		if err := b.enableEncryption(cfg); err != nil {
+			b.Close()
+			return nil, err
+		}
+	}
+
+	b.Archive, err = eventstore.NewArchivedEventStore(b.EventStore, cfg.ArchiveDir)
+	if err != nil {
+		b.Close()
+		return nil, err
+	}
+	b.Archive.SetCipher(b.Cipher)
+	b.EventStore = b.Archive
+
+	b.Rates, err = rates.Load(cfg.RatesDir)
+	if err != nil {
+		b.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// enableEncryption cifra con las claves del keyfile los payloads del Event Store, los
+// movimientos proyectados y los snapshots
+func (b *Backend) enableEncryption(cfg Config) error {
+	keyring, err := encryption.OpenKeyring(cfg.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	encryptable, ok := b.EventStore.(eventstore.Encryptable)
+	if !ok {
+		return fmt.Errorf("event store for backend %q does not support encryption", cfg.Backend)
+	}
+	sealable, ok := b.Projections.(projections.Sealable)
+	if !ok {
+		return fmt.Errorf("projection store for backend %q does not support encryption", cfg.Backend)
+	}
+
+	b.Keyring = keyring
+	b.Cipher = encryption.NewFieldCipher(keyring)
+	encryptable.SetCipher(b.Cipher)
+	sealable.SetSealer(keyring)
+	b.Snapshots = snapshots.NewSealedStore(b.Snapshots, keyring)
+	return nil
+}
+
+// Close libera las conexiones abiertas por el backend
+func (b *Backend) Close() error {
+	var firstErr error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		if err := b.closers[i](); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func openMongo() (*Backend, error) {
+	mongoStore, err := eventstore.NewMongoEventStore()
+	if err != nil {
+		return nil, err
+	}
+
+	projectionStore := projections.NewMongoProjectionStore(mongoStore.Client(), "escama_read")
+	if err := projectionStore.MigrateAmounts(context.Background()); err != nil {
+		mongoStore.Close()
+		return nil, err
+	}
+	if err := projectionStore.EnsureIndexes(context.Background()); err != nil {
+		mongoStore.Close()
+		return nil, err
+	}
+
+	return &Backend{
+		EventStore:  mongoStore,
+		Projections: projectionStore,
+		Checkpoints: subscriptions.NewMongoCheckpointStore(mongoStore.Client().Database("escama_read")),
+		Snapshots:   snapshots.NewMongoStore(mongoStore.Client().Database("escama")),
+		closers:     []func() error{mongoStore.Close},
+	}, nil
+}
+
+func openSQLite(cfg Config) (*Backend, error) {
+	db, err := openSQLiteDB(cfg.SQLitePath)
+	if err != nil {
+		return nil, err
+	}
+
+	eventStore, err := eventstore.NewSQLiteEventStore(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	projectionStore, err := projections.NewSQLiteProjectionStore(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	checkpointStore, err := subscriptions.NewSQLiteCheckpointStore(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	snapshotStore, err := snapshots.NewSQLiteStore(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Backend{
+		EventStore:  eventStore,
+		Projections: projectionStore,
+		Checkpoints: checkpointStore,
+		Snapshots:   snapshotStore,
+		closers:     []func() error{db.Close},
+	}, nil
+}
+
+func openFile(cfg Config) (*Backend, error) {
+	fileStore, err := eventstore.NewFileEventStore(cfg.EventsFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := openSQLiteDB(cfg.SQLitePath)
+	if err != nil {
+		fileStore.Close()
+		return nil, err
+	}
+
+	projectionStore, err := projections.NewSQLiteProjectionStore(db)
+	if err != nil {
+		db.Close()
+		fileStore.Close()
+		return nil, err
+	}
+
+	checkpointStore, err := subscriptions.NewSQLiteCheckpointStore(db)
+	if err != nil {
+		db.Close()
+		fileStore.Close()
+		return nil, err
+	}
+
+	// Los snapshots son descartables: se guardan junto a las proyecciones
+	snapshotStore, err := snapshots.NewSQLiteStore(db)
+	if err != nil {
+		db.Close()
+		fileStore.Close()
+		return nil, err
+	}
+
+	return &Backend{
+		EventStore:  fileStore,
+		Projections: projectionStore,
+		Checkpoints: checkpointStore,
+		Snapshots:   snapshotStore,
+		closers:     []func() error{fileStore.Close, db.Close},
+	}, nil
+}
+
+func openSQLiteDB(path string) (*sql.DB, error) {
+	// WAL permite leer mientras otro proceso escribe; las transacciones toman el lock
+	// de escritura al comenzar para que la verificación de versión sea atómica
+	dsn := fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate", path)
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open SQLite database %s: %w", path, err)
+	}
+
+	return db, nil
+}
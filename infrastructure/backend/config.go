@@ -0,0 +1,66 @@
+package backend
+
+import (
+	"os"
+	"strconv"
+
+	"escama/domain/money"
+)
+
+// Backends soportados para el Event Store y el modelo de lectura
+const (
+	Mongo  = "mongo"  // Event Store y proyecciones en MongoDB
+	SQLite = "sqlite" // Event Store y proyecciones en un único archivo SQLite
+	File   = "file"   // Event Store en JSON Lines, proyecciones en SQLite
+)
+
+// Config define qué backend usar y dónde guardar los datos locales
+type Config struct {
+	Backend           string
+	SQLitePath        string
+	EventsFilePath    string
+	SnapshotInterval  int
+	KeyFile           string // vacío desactiva el cifrado
+	ArchiveDir        string
+	RatesDir          string
+	ReportingCurrency string // moneda de los totales cuando la consulta no indica otra
+}
+
+// ConfigFromEnv lee la configuración desde variables de entorno:
+//
+//	ESCAMA_BACKEND      mongo (por defecto), sqlite o file
+//	ESCAMA_SQLITE_PATH  archivo SQLite (por defecto escama.db)
+//	ESCAMA_EVENTS_FILE  archivo JSON Lines del backend file (por defecto escama-events.jsonl)
+//	ESCAMA_SNAPSHOT_INTERVAL  cada cuántos eventos se guarda un snapshot (por defecto 50, 0 desactiva)
+//	ESCAMA_KEYFILE      keyfile para cifrar importes y descripciones (sin definir, no se cifra)
+//	ESCAMA_ARCHIVE_DIR  directorio de los segmentos de eventos archivados (por defecto escama-archive)
+//	ESCAMA_RATES_DIR    directorio con los CSV de cotizaciones (por defecto escama-rates)
+//	ESCAMA_REPORTING_CURRENCY  moneda de los totales del dashboard (por defecto PYG)
+//
+// El backend mongo sigue usando MONGODB_CONNECTION_STRING.
+func ConfigFromEnv() Config {
+	return Config{
+		Backend:           getEnv("ESCAMA_BACKEND", Mongo),
+		SQLitePath:        getEnv("ESCAMA_SQLITE_PATH", "escama.db"),
+		EventsFilePath:    getEnv("ESCAMA_EVENTS_FILE", "escama-events.jsonl"),
+		SnapshotInterval:  getEnvInt("ESCAMA_SNAPSHOT_INTERVAL", 50),
+		KeyFile:           os.Getenv("ESCAMA_KEYFILE"),
+		ArchiveDir:        getEnv("ESCAMA_ARCHIVE_DIR", "escama-archive"),
+		RatesDir:          getEnv("ESCAMA_RATES_DIR", "escama-rates"),
+		ReportingCurrency: getEnv("ESCAMA_REPORTING_CURRENCY", money.DefaultCurrency),
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value >= 0 {
+		return value
+	}
+	return fallback
+}
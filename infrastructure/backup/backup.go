@@ -0,0 +1,218 @@
+// Package backup exporta el Event Store a un archivo portable y lo importa en cualquier
+// backend.
+//
+// El archivo es JSON Lines comprimido con gzip. La primera línea es un Header con el
+// formato y su versión; cada línea siguiente es un events.StoredEvent tal como está
+// almacenado (sin upcasting, con su metadata y sus hashes).
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"escama/domain/events"
+	"escama/infrastructure/eventstore"
+)
+
+// Format identifica los archivos de exportación de escama
+const Format = "escama-events"
+
+// Version versión actual del formato del archivo
+const Version = 1
+
+// ErrDuplicateEvent se devuelve al importar un evento cuya secuencia ya existe en el
+// destino con un contenido distinto
+var ErrDuplicateEvent = errors.New("event already exists with different content")
+
+// ErrMissingEvents se devuelve cuando el archivo no contiene los eventos anteriores de un
+// stream y el destino tampoco los tiene (por ejemplo, al importar un rango de fechas en
+// un store vacío)
+var ErrMissingEvents = errors.New("archive does not continue the stream in the target store")
+
+// ErrInvalidEvent se devuelve al importar un evento sin agregado o con una secuencia menor
+// que 1, que ningún store pudo haber exportado
+var ErrInvalidEvent = errors.New("invalid archive event")
+
+// Header primera línea del archivo
+type Header struct {
+	Format     string     `json:"format"`
+	Version    int        `json:"version"`
+	ExportedAt time.Time  `json:"exported_at"`
+	From       *time.Time `json:"from,omitempty"`
+	To         *time.Time `json:"to,omitempty"`
+}
+
+// ExportOptions rango de fechas (OccurredAt) a exportar; nil no limita
+type ExportOptions struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// ImportResult resumen de una importación
+type ImportResult struct {
+	Header   Header
+	Imported int
+	Skipped  int // eventos que el destino ya tenía
+}
+
+// Export escribe en w los eventos del store, en orden de posición global, y devuelve
+// cuántos exportó
+func Export(ctx context.Context, store eventstore.EventStore, w io.Writer, opts ExportOptions) (int, error) {
+	gz := gzip.NewWriter(w)
+	encoder := json.NewEncoder(gz)
+
+	header := Header{
+		Format:     Format,
+		Version:    Version,
+		ExportedAt: time.Now().UTC(),
+		From:       opts.From,
+		To:         opts.To,
+	}
+	if err := encoder.Encode(header); err != nil {
+		return 0, fmt.Errorf("failed to write archive header: %w", err)
+	}
+
+	exported := 0
+	filter := eventstore.StreamFilter{
+		TimeFilter: eventstore.TimeFilter{RecordedFrom: opts.From, RecordedTo: opts.To},
+		Raw:        true,
+	}
+	err := store.Stream(ctx, filter, func(storedEvent events.StoredEvent) error {
+		if err := encoder.Encode(storedEvent); err != nil {
+			return fmt.Errorf("failed to write event %s: %w", storedEvent.ID, err)
+		}
+		exported++
+		return nil
+	})
+	if err != nil {
+		return exported, err
+	}
+
+	if err := gz.Close(); err != nil {
+		return exported, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return exported, nil
+}
+
+// Import agrega al store los eventos del archivo a través de EventStore.Store, de modo
+// que el destino asigna sus propias posiciones globales y su cadena de hashes. Los
+// eventos cuya secuencia ya existe en el destino se omiten si son el mismo evento (mismo
+// tipo y mismo payload); si no, la importación se detiene con ErrDuplicateEvent. Los IDs
+// no se comparan: el destino asigna los suyos al guardar. Los eventos ya importados antes
+// de un error quedan guardados: volver a importar el archivo completa el resto.
+//
+// Los archivos exportados desde un store con cifrado conservan los campos cifrados; cipher
+// (nil si el destino no cifra) debe tener las claves del origen para poder leerlos.
+func Import(ctx context.Context, store eventstore.EventStore, r io.Reader, cipher eventstore.PayloadCipher) (ImportResult, error) {
+	var result ImportResult
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return result, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer gz.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(gz))
+	if err := decoder.Decode(&result.Header); err != nil {
+		return result, fmt.Errorf("failed to read archive header: %w", err)
+	}
+	if result.Header.Format != Format {
+		return result, fmt.Errorf("unsupported archive format %q", result.Header.Format)
+	}
+	if result.Header.Version < 1 || result.Header.Version > Version {
+		return result, fmt.Errorf("unsupported archive version %d (supported: %d)", result.Header.Version, Version)
+	}
+
+	// Eventos ya presentes en el destino por agregado, cargados la primera vez que aparece
+	streams := make(map[string][]events.StoredEvent)
+
+	for {
+		var storedEvent events.StoredEvent
+		if err := decoder.Decode(&storedEvent); err == io.EOF {
+			break
+		} else if err != nil {
+			return result, fmt.Errorf("failed to read archive event: %w", err)
+		}
+		if storedEvent.AggregateID == "" || storedEvent.Sequence < 1 {
+			return result, fmt.Errorf("%w: %s has aggregate %q and sequence %d",
+				ErrInvalidEvent, storedEvent.ID, storedEvent.AggregateID, storedEvent.Sequence)
+		}
+
+		existing, ok := streams[storedEvent.AggregateID]
+		if !ok {
+			existing, err = store.Load(ctx, storedEvent.AggregateID)
+			if err != nil {
+				return result, err
+			}
+			streams[storedEvent.AggregateID] = existing
+		}
+
+		if cipher != nil {
+			if storedEvent.Payload, err = cipher.DecryptPayload(storedEvent.Payload); err != nil {
+				return result, fmt.Errorf("failed to decrypt event %s: %w", storedEvent.ID, err)
+			}
+		}
+
+		domainEvent, err := storedEvent.Decode()
+		if err != nil {
+			return result, err
+		}
+
+		version := len(existing)
+		if storedEvent.Sequence <= version {
+			same, err := sameEvent(existing[storedEvent.Sequence-1], domainEvent)
+			if err != nil {
+				return result, err
+			}
+			if !same {
+				return result, fmt.Errorf("%w: %s at sequence %d", ErrDuplicateEvent, storedEvent.AggregateID, storedEvent.Sequence)
+			}
+			result.Skipped++
+			continue
+		}
+		if storedEvent.Sequence != version+1 {
+			return result, fmt.Errorf("%w: %s has version %d, archive continues at sequence %d",
+				ErrMissingEvents, storedEvent.AggregateID, version, storedEvent.Sequence)
+		}
+
+		// Conservar la metadata original (actor, fuente, correlación) del evento
+		eventCtx := events.WithMetadata(ctx, storedEvent.Metadata)
+		if err := store.Store(eventCtx, storedEvent.AggregateID, storedEvent.AggregateType, version, []events.DomainEvent{domainEvent}); err != nil {
+			return result, fmt.Errorf("failed to import event %s: %w", storedEvent.ID, err)
+		}
+
+		streams[storedEvent.AggregateID] = append(existing, storedEvent)
+		result.Imported++
+	}
+
+	return result, nil
+}
+
+// sameEvent indica si el evento guardado en el destino es el del archivo: mismo tipo y el
+// mismo payload una vez llevados ambos a la versión actual de su esquema
+func sameEvent(current events.StoredEvent, imported events.DomainEvent) (bool, error) {
+	if current.EventType != imported.EventType() {
+		return false, nil
+	}
+
+	currentEvent, err := current.Decode()
+	if err != nil {
+		return false, err
+	}
+	currentPayload, err := json.Marshal(currentEvent)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode event %s: %w", current.ID, err)
+	}
+	importedPayload, err := json.Marshal(imported)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode imported event: %w", err)
+	}
+	return bytes.Equal(currentPayload, importedPayload), nil
+}
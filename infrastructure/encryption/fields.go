@@ -0,0 +1,78 @@
+package encryption
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SensitiveFields campos del payload de los eventos que se cifran
+var SensitiveFields = []string{"amount", "received_amount", "description"}
+
+// FieldCipher cifra los campos sensibles de los payloads con la clave del actor que
+// produjo el evento
+type FieldCipher struct {
+	keyring *Keyring
+}
+
+func NewFieldCipher(keyring *Keyring) *FieldCipher {
+	return &FieldCipher{keyring: keyring}
+}
+
+// EncryptPayload devuelve una copia del payload con cada campo sensible reemplazado por
+// su valor JSON cifrado. Los campos ausentes o nulos no se tocan.
+func (c *FieldCipher) EncryptPayload(payload map[string]interface{}, owner string) (map[string]interface{}, error) {
+	encrypted := make(map[string]interface{}, len(payload))
+	for key, value := range payload {
+		encrypted[key] = value
+	}
+
+	for _, field := range SensitiveFields {
+		value, ok := payload[field]
+		if !ok || value == nil {
+			continue
+		}
+
+		plaintext, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode field %s: %w", field, err)
+		}
+		sealed, err := c.keyring.Seal(owner, plaintext)
+		if err != nil {
+			return nil, err
+		}
+		encrypted[field] = sealed
+	}
+
+	return encrypted, nil
+}
+
+// DecryptPayload devuelve una copia del payload con los campos cifrados restaurados. Si
+// la clave de un campo fue eliminada, el campo queda nulo: el dato ya no es recuperable.
+func (c *FieldCipher) DecryptPayload(payload map[string]interface{}) (map[string]interface{}, error) {
+	decrypted := make(map[string]interface{}, len(payload))
+	for key, value := range payload {
+		sealed, ok := value.(string)
+		if !ok || !IsSealed(sealed) {
+			decrypted[key] = value
+			continue
+		}
+
+		plaintext, err := c.keyring.Open(sealed)
+		if errors.Is(err, ErrKeyNotFound) {
+			decrypted[key] = nil
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt field %s: %w", key, err)
+		}
+
+		var restored interface{}
+		if err := json.Unmarshal(plaintext, &restored); err != nil {
+			return nil, fmt.Errorf("failed to decode field %s: %w", key, err)
+		}
+		decrypted[key] = restored
+	}
+
+	return decrypted, nil
+}
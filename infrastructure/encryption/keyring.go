@@ -0,0 +1,273 @@
+// Package encryption cifra campos sensibles con AES-256-GCM usando claves de un archivo
+// local (keyfile). Cada dueño (el actor de la metadata de los eventos) tiene sus propias
+// claves: borrarlas deja ilegibles sus datos (crypto-shredding).
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sealedPrefix identifica un valor cifrado: enc:v1:<id de clave>:<base64(nonce+cifrado)>
+const sealedPrefix = "enc:v1:"
+
+// DefaultOwner dueño de las claves cuando los datos no tienen actor
+const DefaultOwner = "default"
+
+// ErrKeyNotFound se devuelve al abrir un valor cuya clave no existe (o fue eliminada)
+var ErrKeyNotFound = errors.New("encryption key not found")
+
+// Key clave de un dueño. La más reciente de cada dueño es la activa: cifra los datos
+// nuevos, mientras que las anteriores solo se usan para descifrar.
+type Key struct {
+	ID        string    `json:"id"`
+	Owner     string    `json:"owner"`
+	Secret    []byte    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type keyFile struct {
+	Version int   `json:"version"`
+	Keys    []Key `json:"keys"`
+}
+
+// Keyring claves leídas del keyfile. Los cambios (claves nuevas, rotaciones, borrados)
+// se escriben en el archivo de inmediato.
+type Keyring struct {
+	mu   sync.Mutex
+	path string
+	keys []Key
+}
+
+// OpenKeyring lee el keyfile; si no existe se crea al generar la primera clave
+func OpenKeyring(path string) (*Keyring, error) {
+	k := &Keyring{path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return k, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyfile: %w", err)
+	}
+
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode keyfile %s: %w", path, err)
+	}
+	if file.Version != 1 {
+		return nil, fmt.Errorf("unsupported keyfile version %d", file.Version)
+	}
+	k.keys = file.Keys
+
+	return k, nil
+}
+
+// IsSealed indica si el valor fue producido por Seal
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+// Seal cifra plaintext con la clave activa del dueño, generándola si todavía no tiene
+func (k *Keyring) Seal(owner string, plaintext []byte) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key, ok := k.activeKey(ownerOrDefault(owner))
+	if !ok {
+		var err error
+		if key, err = k.generate(ownerOrDefault(owner)); err != nil {
+			return "", err
+		}
+	}
+
+	aead, err := newAEAD(key.Secret)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(key.ID))
+	return sealedPrefix + key.ID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open descifra un valor producido por Seal. Devuelve ErrKeyNotFound si su clave ya no
+// está en el keyring.
+func (k *Keyring) Open(value string) ([]byte, error) {
+	keyID, encoded, ok := strings.Cut(strings.TrimPrefix(value, sealedPrefix), ":")
+	if !IsSealed(value) || !ok {
+		return nil, fmt.Errorf("value is not sealed")
+	}
+
+	k.mu.Lock()
+	key, found := k.keyByID(keyID)
+	k.mu.Unlock()
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode sealed value: %w", err)
+	}
+
+	aead, err := newAEAD(key.Secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("sealed value is too short")
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(key.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value with key %s: %w", keyID, err)
+	}
+	return plaintext, nil
+}
+
+// Rotate genera una nueva clave activa para el dueño. Las anteriores se conservan para
+// poder leer los datos ya cifrados.
+func (k *Keyring) Rotate(owner string) (Key, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.generate(ownerOrDefault(owner))
+}
+
+// Delete elimina todas las claves del dueño y devuelve cuántas borró. Sus datos cifrados
+// quedan ilegibles de forma permanente.
+func (k *Keyring) Delete(owner string) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	owner = ownerOrDefault(owner)
+	kept := make([]Key, 0, len(k.keys))
+	for _, key := range k.keys {
+		if key.Owner != owner {
+			kept = append(kept, key)
+		}
+	}
+
+	deleted := len(k.keys) - len(kept)
+	if deleted == 0 {
+		return 0, nil
+	}
+
+	previous := k.keys
+	k.keys = kept
+	if err := k.save(); err != nil {
+		k.keys = previous
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// Keys devuelve las claves del keyring sin su secreto, en orden de creación
+func (k *Keyring) Keys() []Key {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	keys := make([]Key, len(k.keys))
+	for i, key := range k.keys {
+		key.Secret = nil
+		keys[i] = key
+	}
+	return keys
+}
+
+func (k *Keyring) activeKey(owner string) (Key, bool) {
+	for i := len(k.keys) - 1; i >= 0; i-- {
+		if k.keys[i].Owner == owner {
+			return k.keys[i], true
+		}
+	}
+	return Key{}, false
+}
+
+func (k *Keyring) keyByID(id string) (Key, bool) {
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+func (k *Keyring) generate(owner string) (Key, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return Key{}, fmt.Errorf("failed to generate key: %w", err)
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Key{}, fmt.Errorf("failed to generate key id: %w", err)
+	}
+
+	key := Key{
+		ID:        hex.EncodeToString(id),
+		Owner:     owner,
+		Secret:    secret,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	k.keys = append(k.keys, key)
+	if err := k.save(); err != nil {
+		k.keys = k.keys[:len(k.keys)-1]
+		return Key{}, err
+	}
+	return key, nil
+}
+
+// save reescribe el keyfile de forma atómica, legible solo por el usuario
+func (k *Keyring) save() error {
+	data, err := json.MarshalIndent(keyFile{Version: 1, Keys: k.keys}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode keyfile: %w", err)
+	}
+
+	if dir := filepath.Dir(k.path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("failed to create keyfile directory: %w", err)
+		}
+	}
+
+	tmpPath := k.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write keyfile: %w", err)
+	}
+	if err := os.Rename(tmpPath, k.path); err != nil {
+		return fmt.Errorf("failed to replace keyfile: %w", err)
+	}
+	return nil
+}
+
+func newAEAD(secret []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func ownerOrDefault(owner string) string {
+	if owner == "" {
+		return DefaultOwner
+	}
+	return owner
+}
@@ -0,0 +1,61 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+
+	"escama/domain/events"
+	"escama/infrastructure/eventstore"
+)
+
+// outboxBatchSize cantidad de eventos pendientes publicados por vuelta
+const outboxBatchSize = 100
+
+// OutboxRelay publica los eventos pendientes del outbox y los confirma una vez
+// publicados. La entrega es al menos una vez: si el proceso cae entre publicar y
+// confirmar, el lote se vuelve a publicar en la siguiente ejecución.
+type OutboxRelay struct {
+	outbox  eventstore.Outbox
+	publish func(ctx context.Context, events []events.DomainEvent) error
+}
+
+func NewOutboxRelay(outbox eventstore.Outbox, publish func(ctx context.Context, events []events.DomainEvent) error) *OutboxRelay {
+	return &OutboxRelay{
+		outbox:  outbox,
+		publish: publish,
+	}
+}
+
+// Relay publica todos los eventos pendientes, en orden, y devuelve cuántos publicó
+func (r *OutboxRelay) Relay(ctx context.Context) (int, error) {
+	published := 0
+	for {
+		pending, err := r.outbox.Pending(ctx, outboxBatchSize)
+		if err != nil {
+			return published, err
+		}
+		if len(pending) == 0 {
+			return published, nil
+		}
+
+		domainEvents := make([]events.DomainEvent, 0, len(pending))
+		positions := make([]int64, 0, len(pending))
+		for _, storedEvent := range pending {
+			domainEvent, err := storedEvent.Decode()
+			if err != nil {
+				return published, fmt.Errorf("failed to decode outbox event %s: %w", storedEvent.ID, err)
+			}
+			domainEvents = append(domainEvents, domainEvent)
+			positions = append(positions, storedEvent.GlobalPosition)
+		}
+
+		if err := r.publish(ctx, domainEvents); err != nil {
+			return published, fmt.Errorf("failed to publish outbox events: %w", err)
+		}
+
+		if err := r.outbox.Ack(ctx, positions); err != nil {
+			return published, err
+		}
+		published += len(pending)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"escama/domain/events"
+	"escama/infrastructure/subscriptions"
 )
 
 // EventPublisher define el contrato para publicar eventos de dominio
@@ -12,17 +13,18 @@ type EventPublisher interface {
 	Publish(ctx context.Context, events []events.DomainEvent) error
 }
 
-// InMemoryEventPublisher implementación simple que loggea los eventos y actualiza proyecciones
+// InMemoryEventPublisher implementación simple que loggea los eventos y pone al día las
+// suscripciones (proyecciones) leyendo el Event Store desde sus checkpoints
 type InMemoryEventPublisher struct {
-	projectionSubscriber *ProjectionSubscriber
+	subscriptions *subscriptions.Manager
 }
 
 func NewInMemoryEventPublisher() *InMemoryEventPublisher {
 	return &InMemoryEventPublisher{}
 }
 
-func (p *InMemoryEventPublisher) SetProjectionSubscriber(subscriber *ProjectionSubscriber) {
-	p.projectionSubscriber = subscriber
+func (p *InMemoryEventPublisher) SetSubscriptions(manager *subscriptions.Manager) {
+	p.subscriptions = manager
 }
 
 func (p *InMemoryEventPublisher) Publish(ctx context.Context, domainEvents []events.DomainEvent) error {
@@ -43,9 +45,10 @@ func (p *InMemoryEventPublisher) Publish(ctx context.Context, domainEvents []eve
 		}
 	}
 
-	// Actualizar proyecciones si hay un suscriptor configurado
-	if p.projectionSubscriber != nil {
-		if err := p.projectionSubscriber.Handle(ctx, domainEvents); err != nil {
+	// Los eventos ya están en el Event Store: las suscripciones los leen desde ahí, junto
+	// con cualquier otro evento que todavía no hayan procesado
+	if p.subscriptions != nil {
+		if err := p.subscriptions.CatchUp(ctx); err != nil {
 			fmt.Printf("⚠️  Error updating projections: %v\n", err)
 			// No devolvemos el error para no fallar el comando principal
 		}
@@ -56,12 +59,12 @@ func (p *InMemoryEventPublisher) Publish(ctx context.Context, domainEvents []eve
 
 func (p *InMemoryEventPublisher) handleEvent(event events.DomainEvent) error {
 	// Aquí puedes agregar lógica específica para cada tipo de evento
-	switch event.EventType() {
-	case "CategoryCreated":
+	switch event.(type) {
+	case events.CategoryCreated:
 		fmt.Printf("   ✓ New category created successfully!\n")
-	case "ExpenseCreated":
+	case events.ExpenseCreated:
 		fmt.Printf("   ✓ New expense recorded successfully!\n")
-	case "IncomeCreated":
+	case events.IncomeCreated:
 		fmt.Printf("   ✓ New income recorded successfully!\n")
 	}
 	return nil
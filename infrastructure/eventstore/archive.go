@@ -0,0 +1,633 @@
+package eventstore
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"escama/domain/events"
+)
+
+// archiveManifestFile nombre del manifiesto dentro del directorio de archivo
+const archiveManifestFile = "manifest.json"
+
+// maxCachedSegments cantidad de segmentos leídos que se conservan en memoria; al leer
+// otro se descarta el usado hace más tiempo
+const maxCachedSegments = 4
+
+// Archivable lo implementan los Event Stores cuyos eventos más antiguos pueden moverse a
+// segmentos de archivo (ver ArchivedEventStore)
+type Archivable interface {
+	// RemoveArchived elimina los eventos con GlobalPosition menor o igual a position y sus
+	// entradas del outbox. De cada stream que queda vacío se conservan la versión y el hash
+	// de su último evento para que Store siga validando y encadenando sus eventos nuevos.
+	// El último evento del store no puede eliminarse: la cadena global continúa desde él.
+	RemoveArchived(ctx context.Context, position int64) error
+}
+
+// archivedHead versión y hash del último evento de un stream cuyos eventos se archivaron
+// por completo
+type archivedHead struct {
+	Sequence int    `json:"sequence" bson:"sequence"`
+	Hash     string `json:"hash" bson:"hash"`
+}
+
+// ArchiveSegment describe un segmento del archivo: un archivo JSON Lines comprimido con
+// gzip que contiene, tal como estaban almacenados, los eventos de un rango contiguo de
+// posiciones globales
+type ArchiveSegment struct {
+	File            string    `json:"file"`
+	FromPosition    int64     `json:"from_position"`
+	ToPosition      int64     `json:"to_position"`
+	Events          int       `json:"events"`
+	FirstOccurredAt time.Time `json:"first_occurred_at"`
+	LastOccurredAt  time.Time `json:"last_occurred_at"`
+	Before          time.Time `json:"before"` // fecha de corte con la que se archivó
+	SHA256          string    `json:"sha256"`
+	ArchivedAt      time.Time `json:"archived_at"`
+	// Streams última secuencia de cada stream con eventos en el segmento. Load y LoadFrom
+	// solo abren los segmentos que tienen eventos del stream pedido.
+	Streams map[string]int `json:"streams,omitempty"`
+	// Keys claves de idempotencia de cada stream del segmento, para consultarlas sin
+	// abrirlo. Es nil en los segmentos archivados antes de que existiera el índice.
+	Keys map[string][]string `json:"keys"`
+}
+
+// archiveManifest índice de los segmentos, en orden de posición global
+type archiveManifest struct {
+	Version  int              `json:"version"`
+	Segments []ArchiveSegment `json:"segments"`
+}
+
+// ArchivedEventStore decora un Event Store con un archivo en frío: los eventos de los
+// períodos cerrados se mueven a segmentos comprimidos en un directorio local y dejan de
+// ocupar el store principal, pero Load, LoadFrom, GetAllEvents y Stream los siguen
+// devolviendo junto con los eventos recientes, como si nunca se hubieran movido.
+//
+// Los segmentos cubren siempre un prefijo de las posiciones globales, por lo que todo
+// evento archivado precede a los del store principal. Con un rango de fecha de registro,
+// solo se abren los segmentos que se superponen con él.
+type ArchivedEventStore struct {
+	hot    EventStore
+	dir    string
+	cipher PayloadCipher
+
+	mu       sync.Mutex
+	manifest archiveManifest
+	modTime  time.Time                       // del manifiesto leído, para notar cambios de otro proceso
+	segments map[string][]events.StoredEvent // últimos segmentos leídos (son inmutables)
+	recent   []string                        // archivos de segments, del usado hace más tiempo al último
+}
+
+// NewArchivedEventStore abre el archivo del directorio dir; si todavía no existe, el store
+// se comporta igual que hot hasta el primer ArchiveBefore
+func NewArchivedEventStore(hot EventStore, dir string) (*ArchivedEventStore, error) {
+	s := &ArchivedEventStore{
+		hot:      hot,
+		dir:      dir,
+		segments: make(map[string][]events.StoredEvent),
+	}
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetCipher indica cómo descifrar los payloads de los eventos archivados. Los segmentos
+// guardan los eventos cifrados, igual que el store principal.
+func (s *ArchivedEventStore) SetCipher(cipher PayloadCipher) {
+	s.cipher = cipher
+}
+
+// Segments devuelve los segmentos archivados, en orden de posición global
+func (s *ArchivedEventStore) Segments() ([]ArchiveSegment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	return append([]ArchiveSegment(nil), s.manifest.Segments...), nil
+}
+
+// ArchivedThrough devuelve la última posición global archivada, o 0 si no hay segmentos
+func (s *ArchivedEventStore) ArchivedThrough() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.refresh(); err != nil {
+		return 0, err
+	}
+	return s.archivedThrough(), nil
+}
+
+func (s *ArchivedEventStore) Store(ctx context.Context, aggregateID, aggregateType string, expectedVersion int, domainEvents []events.DomainEvent) error {
+	return s.hot.Store(ctx, aggregateID, aggregateType, expectedVersion, domainEvents)
+}
+
+func (s *ArchivedEventStore) Load(ctx context.Context, aggregateID string) ([]events.StoredEvent, error) {
+	return s.LoadFrom(ctx, aggregateID, 0)
+}
+
+func (s *ArchivedEventStore) LoadFrom(ctx context.Context, aggregateID string, afterSequence int) ([]events.StoredEvent, error) {
+	segments, through, err := s.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	hotEvents, err := s.hot.LoadFrom(ctx, aggregateID, afterSequence)
+	if err != nil {
+		return nil, err
+	}
+	hotEvents = withoutArchived(hotEvents, through)
+
+	// Si el store principal tiene el stream completo desde afterSequence no hace falta
+	// abrir los segmentos
+	if len(segments) == 0 || (len(hotEvents) > 0 && hotEvents[0].Sequence == afterSequence+1) {
+		return hotEvents, nil
+	}
+
+	var archived []events.StoredEvent
+	for _, segment := range segments {
+		// Los segmentos archivados antes de que existiera el índice se leen completos
+		if segment.Streams != nil {
+			if last, ok := segment.Streams[aggregateID]; !ok || last <= afterSequence {
+				continue
+			}
+		}
+
+		segmentEvents, err := s.readSegment(segment)
+		if err != nil {
+			return nil, err
+		}
+		for _, storedEvent := range segmentEvents {
+			if storedEvent.AggregateID == aggregateID && storedEvent.Sequence > afterSequence {
+				archived = append(archived, storedEvent)
+			}
+		}
+	}
+
+	archived, err = readEvents(s.cipher, archived)
+	if err != nil {
+		return nil, err
+	}
+	return append(archived, hotEvents...), nil
+}
+
+// HasIdempotencyKey busca la clave en el store principal y, si no está, en el índice de
+// claves de los segmentos con eventos del stream
+func (s *ArchivedEventStore) HasIdempotencyKey(ctx context.Context, aggregateID, key string) (bool, error) {
+	segments, _, err := s.snapshot()
+	if err != nil {
+		return false, err
+	}
+
+	found, err := HasIdempotencyKey(ctx, s.hot, aggregateID, key)
+	if err != nil || found {
+		return found, err
+	}
+
+	for _, segment := range segments {
+		if segment.Streams != nil {
+			if _, ok := segment.Streams[aggregateID]; !ok {
+				continue
+			}
+		}
+		if segment.Keys != nil {
+			if containsKey(segment.Keys[aggregateID], key) {
+				return true, nil
+			}
+			continue
+		}
+
+		// Los segmentos archivados antes del índice de claves se leen completos
+		segmentEvents, err := s.readSegment(segment)
+		if err != nil {
+			return false, err
+		}
+		if hasKey(segmentEvents, aggregateID, key) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *ArchivedEventStore) GetAllEvents(ctx context.Context, filter TimeFilter) ([]events.StoredEvent, error) {
+	return collectEvents(ctx, s, StreamFilter{TimeFilter: filter})
+}
+
+func (s *ArchivedEventStore) Stream(ctx context.Context, filter StreamFilter, fn StreamFunc) error {
+	segments, through, err := s.snapshot()
+	if err != nil {
+		return err
+	}
+
+	for _, segment := range segments {
+		if segment.ToPosition <= filter.FromPosition {
+			continue
+		}
+		// Los segmentos fuera del rango de registro no se leen
+		if filter.RecordedFrom != nil && segment.LastOccurredAt.Before(*filter.RecordedFrom) {
+			continue
+		}
+		if filter.RecordedTo != nil && segment.FirstOccurredAt.After(*filter.RecordedTo) {
+			continue
+		}
+
+		segmentEvents, err := s.readSegment(segment)
+		if err != nil {
+			return err
+		}
+		for _, storedEvent := range segmentEvents {
+			if !filter.matches(storedEvent) {
+				continue
+			}
+			if err := deliver(filter, s.cipher, storedEvent, fn); err != nil {
+				if errors.Is(err, ErrStopStream) {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+
+	if filter.FromPosition < through {
+		filter.FromPosition = through
+	}
+	return s.hot.Stream(ctx, filter, fn)
+}
+
+// ArchiveBefore mueve a un segmento nuevo los eventos del store principal ocurridos antes
+// de before y devuelve el segmento creado, o nil si no había nada que archivar.
+//
+// Se archiva el tramo inicial de eventos (en orden de posición global) anterior a la
+// fecha: un evento posterior detiene el tramo aunque le sigan otros más antiguos. El
+// último evento del store tampoco se archiva. El segmento y el manifiesto se escriben
+// antes de borrar los eventos del store principal; si el proceso se interrumpe en medio,
+// los eventos duplicados se ignoran al leer y volver a archivar termina de borrarlos.
+func (s *ArchivedEventStore) ArchiveBefore(ctx context.Context, before time.Time) (*ArchiveSegment, error) {
+	archivable, ok := s.hot.(Archivable)
+	if !ok {
+		return nil, fmt.Errorf("event store does not support archiving")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	if err := s.indexSegments(); err != nil {
+		return nil, err
+	}
+	through := s.archivedThrough()
+
+	var batch []events.StoredEvent
+	reachedEnd := true
+	err := s.hot.Stream(ctx, StreamFilter{FromPosition: through, Raw: true}, func(storedEvent events.StoredEvent) error {
+		if !storedEvent.OccurredAt.Before(before) {
+			reachedEnd = false
+			return ErrStopStream
+		}
+		batch = append(batch, storedEvent)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read events to archive: %w", err)
+	}
+
+	// El último evento del store queda en el store principal
+	if reachedEnd && len(batch) > 0 {
+		batch = batch[:len(batch)-1]
+	}
+
+	if len(batch) == 0 {
+		// Completar un archivado anterior interrumpido antes de borrar los eventos
+		if through > 0 {
+			if err := archivable.RemoveArchived(ctx, through); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	}
+
+	segment, err := s.writeSegment(batch, before)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := s.manifest
+	manifest.Version = 1
+	manifest.Segments = append(append([]ArchiveSegment(nil), manifest.Segments...), segment)
+	if err := s.saveManifest(manifest); err != nil {
+		os.Remove(filepath.Join(s.dir, segment.File))
+		return nil, err
+	}
+	s.manifest = manifest
+	s.cacheSegment(segment.File, batch)
+
+	if err := archivable.RemoveArchived(ctx, segment.ToPosition); err != nil {
+		return nil, fmt.Errorf("events were archived but not removed from the event store: %w", err)
+	}
+	return &segment, nil
+}
+
+// indexSegments agrega al manifiesto los índices de streams y de claves de los segmentos
+// archivados antes de que existieran. Se llama con el lock tomado.
+func (s *ArchivedEventStore) indexSegments() error {
+	var manifest archiveManifest
+	for i, segment := range s.manifest.Segments {
+		if segment.Streams != nil && segment.Keys != nil {
+			continue
+		}
+		if manifest.Segments == nil {
+			manifest = s.manifest
+			manifest.Segments = append([]ArchiveSegment(nil), s.manifest.Segments...)
+		}
+
+		segmentEvents, err := s.decodeSegment(segment)
+		if err != nil {
+			return err
+		}
+		manifest.Segments[i].Streams = streamsIndex(segmentEvents)
+		manifest.Segments[i].Keys = keysIndex(segmentEvents)
+	}
+	if manifest.Segments == nil {
+		return nil
+	}
+
+	if err := s.saveManifest(manifest); err != nil {
+		return err
+	}
+	s.manifest = manifest
+	return nil
+}
+
+// snapshot devuelve los segmentos vigentes y la última posición archivada
+func (s *ArchivedEventStore) snapshot() ([]ArchiveSegment, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.refresh(); err != nil {
+		return nil, 0, err
+	}
+	return s.manifest.Segments, s.archivedThrough(), nil
+}
+
+func (s *ArchivedEventStore) archivedThrough() int64 {
+	if len(s.manifest.Segments) == 0 {
+		return 0
+	}
+	return s.manifest.Segments[len(s.manifest.Segments)-1].ToPosition
+}
+
+// refresh vuelve a leer el manifiesto si otro proceso lo modificó
+func (s *ArchivedEventStore) refresh() error {
+	path := filepath.Join(s.dir, archiveManifestFile)
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat archive manifest: %w", err)
+	}
+	if info.ModTime().Equal(s.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read archive manifest: %w", err)
+	}
+
+	var manifest archiveManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("failed to decode archive manifest %s: %w", path, err)
+	}
+	if manifest.Version != 1 {
+		return fmt.Errorf("unsupported archive manifest version %d", manifest.Version)
+	}
+
+	s.manifest = manifest
+	s.modTime = info.ModTime()
+	return nil
+}
+
+// saveManifest reemplaza el manifiesto de forma atómica (archivo temporal + rename)
+func (s *ArchivedEventStore) saveManifest(manifest archiveManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode archive manifest: %w", err)
+	}
+
+	path := filepath.Join(s.dir, archiveManifestFile)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write archive manifest: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to write archive manifest: %w", err)
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		s.modTime = info.ModTime()
+	}
+	return nil
+}
+
+// writeSegment escribe y sincroniza el archivo de un segmento con los eventos indicados
+func (s *ArchivedEventStore) writeSegment(batch []events.StoredEvent, before time.Time) (ArchiveSegment, error) {
+	first, last := batch[0], batch[len(batch)-1]
+	segment := ArchiveSegment{
+		File:            fmt.Sprintf("events-%012d-%012d.jsonl.gz", first.GlobalPosition, last.GlobalPosition),
+		FromPosition:    first.GlobalPosition,
+		ToPosition:      last.GlobalPosition,
+		Events:          len(batch),
+		FirstOccurredAt: first.OccurredAt,
+		LastOccurredAt:  first.OccurredAt,
+		Before:          before,
+		ArchivedAt:      time.Now().UTC(),
+		Streams:         streamsIndex(batch),
+		Keys:            keysIndex(batch),
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(gz)
+	for _, storedEvent := range batch {
+		// OccurredAt no es estrictamente creciente: se guarda el rango real del segmento
+		if storedEvent.OccurredAt.Before(segment.FirstOccurredAt) {
+			segment.FirstOccurredAt = storedEvent.OccurredAt
+		}
+		if storedEvent.OccurredAt.After(segment.LastOccurredAt) {
+			segment.LastOccurredAt = storedEvent.OccurredAt
+		}
+		if err := encoder.Encode(storedEvent); err != nil {
+			return ArchiveSegment{}, fmt.Errorf("failed to encode event %s: %w", storedEvent.ID, err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return ArchiveSegment{}, fmt.Errorf("failed to compress archive segment: %w", err)
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	segment.SHA256 = hex.EncodeToString(sum[:])
+
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return ArchiveSegment{}, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	if err := writeFileAtomic(filepath.Join(s.dir, segment.File), buf.Bytes()); err != nil {
+		return ArchiveSegment{}, fmt.Errorf("failed to write archive segment: %w", err)
+	}
+
+	return segment, nil
+}
+
+// streamsIndex devuelve la última secuencia de cada stream con eventos en el lote
+func streamsIndex(batch []events.StoredEvent) map[string]int {
+	streams := make(map[string]int)
+	for _, storedEvent := range batch {
+		if storedEvent.Sequence > streams[storedEvent.AggregateID] {
+			streams[storedEvent.AggregateID] = storedEvent.Sequence
+		}
+	}
+	return streams
+}
+
+// readSegment devuelve los eventos de un segmento tal como están almacenados. Los últimos
+// segmentos leídos quedan en memoria.
+func (s *ArchivedEventStore) readSegment(segment ArchiveSegment) ([]events.StoredEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cached, ok := s.segments[segment.File]; ok {
+		s.cacheSegment(segment.File, cached)
+		return cached, nil
+	}
+
+	segmentEvents, err := s.decodeSegment(segment)
+	if err != nil {
+		return nil, err
+	}
+	s.cacheSegment(segment.File, segmentEvents)
+	return segmentEvents, nil
+}
+
+// cacheSegment guarda en memoria los eventos de un segmento como el último usado y
+// descarta los que excedan maxCachedSegments. Se llama con el lock tomado.
+func (s *ArchivedEventStore) cacheSegment(file string, segmentEvents []events.StoredEvent) {
+	for i, cached := range s.recent {
+		if cached == file {
+			s.recent = append(s.recent[:i], s.recent[i+1:]...)
+			break
+		}
+	}
+	s.recent = append(s.recent, file)
+	s.segments[file] = segmentEvents
+
+	for len(s.recent) > maxCachedSegments {
+		delete(s.segments, s.recent[0])
+		s.recent = s.recent[1:]
+	}
+}
+
+// decodeSegment lee los eventos de un segmento comprobando que el archivo no cambió desde
+// que se archivó
+func (s *ArchivedEventStore) decodeSegment(segment ArchiveSegment) ([]events.StoredEvent, error) {
+	path := filepath.Join(s.dir, segment.File)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive segment: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != segment.SHA256 {
+		return nil, fmt.Errorf("archive segment %s does not match its checksum", segment.File)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive segment %s: %w", segment.File, err)
+	}
+	defer gz.Close()
+
+	segmentEvents := make([]events.StoredEvent, 0, segment.Events)
+	decoder := json.NewDecoder(bufio.NewReader(gz))
+	for {
+		var storedEvent events.StoredEvent
+		if err := decoder.Decode(&storedEvent); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read archive segment %s: %w", segment.File, err)
+		}
+		segmentEvents = append(segmentEvents, storedEvent)
+	}
+	if len(segmentEvents) != segment.Events {
+		return nil, fmt.Errorf("archive segment %s has %d events, expected %d", segment.File, len(segmentEvents), segment.Events)
+	}
+	return segmentEvents, nil
+}
+
+// withoutArchived descarta los eventos con posición global menor o igual a position: los
+// que un archivado interrumpido dejó también en el store principal
+func withoutArchived(storedEvents []events.StoredEvent, position int64) []events.StoredEvent {
+	if position == 0 {
+		return storedEvents
+	}
+
+	kept := storedEvents[:0]
+	for _, storedEvent := range storedEvents {
+		if storedEvent.GlobalPosition > position {
+			kept = append(kept, storedEvent)
+		}
+	}
+	return kept
+}
+
+// streamHead devuelve la versión de un stream indexado en memoria y el hash de su último
+// evento; si el stream ya no tiene eventos se usa su head archivado
+func streamHead(streamEvents []events.StoredEvent, heads map[string]archivedHead, aggregateID string) (int, string) {
+	if len(streamEvents) > 0 {
+		last := streamEvents[len(streamEvents)-1]
+		return last.Sequence, last.Hash
+	}
+	head := heads[aggregateID]
+	return head.Sequence, head.Hash
+}
+
+// removeIndexed quita de los índices en memoria los eventos con posición global menor o
+// igual a position, registra en heads el último evento de cada stream que queda vacío y
+// devuelve la nueva lista global
+func removeIndexed(streams map[string][]events.StoredEvent, allEvents []events.StoredEvent, heads map[string]archivedHead, position int64) []events.StoredEvent {
+	for aggregateID, streamEvents := range streams {
+		kept := withoutArchived(append([]events.StoredEvent(nil), streamEvents...), position)
+		if len(kept) == 0 {
+			last := streamEvents[len(streamEvents)-1]
+			heads[aggregateID] = archivedHead{Sequence: last.Sequence, Hash: last.Hash}
+			delete(streams, aggregateID)
+			continue
+		}
+		streams[aggregateID] = kept
+	}
+	return withoutArchived(append([]events.StoredEvent(nil), allEvents...), position)
+}
+
+// checkArchivable comprueba que el último evento del store quede fuera del archivado
+func checkArchivable(position, lastPosition int64) error {
+	if position >= lastPosition {
+		return fmt.Errorf("cannot archive through position %d: the last event of the store (%d) must remain", position, lastPosition)
+	}
+	return nil
+}
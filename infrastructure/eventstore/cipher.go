@@ -0,0 +1,52 @@
+package eventstore
+
+import "escama/domain/events"
+
+// PayloadCipher cifra campos del payload al guardar los eventos y los descifra al
+// leerlos. El dueño de la clave es el actor de la metadata del evento.
+//
+// Los payloads se cifran antes de calcular la cadena de hashes, de modo que borrar una
+// clave (crypto-shredding) no invalida la verificación del store.
+type PayloadCipher interface {
+	EncryptPayload(payload map[string]interface{}, owner string) (map[string]interface{}, error)
+	DecryptPayload(payload map[string]interface{}) (map[string]interface{}, error)
+}
+
+// Encryptable lo implementan los Event Stores que pueden cifrar los payloads
+type Encryptable interface {
+	SetCipher(cipher PayloadCipher)
+}
+
+// sealPayload cifra el payload si el store tiene un cifrador configurado
+func sealPayload(cipher PayloadCipher, payload map[string]interface{}, metadata events.Metadata) (map[string]interface{}, error) {
+	if cipher == nil {
+		return payload, nil
+	}
+	return cipher.EncryptPayload(payload, metadata.Actor)
+}
+
+// readEvent descifra el payload de un evento leído del store y lo lleva a la versión de
+// esquema actual
+func readEvent(cipher PayloadCipher, storedEvent events.StoredEvent) (events.StoredEvent, error) {
+	if cipher != nil {
+		payload, err := cipher.DecryptPayload(storedEvent.Payload)
+		if err != nil {
+			return events.StoredEvent{}, err
+		}
+		storedEvent.Payload = payload
+	}
+	return events.Upcast(storedEvent)
+}
+
+// readEvents aplica readEvent a cada evento
+func readEvents(cipher PayloadCipher, storedEvents []events.StoredEvent) ([]events.StoredEvent, error) {
+	result := make([]events.StoredEvent, 0, len(storedEvents))
+	for _, storedEvent := range storedEvents {
+		read, err := readEvent(cipher, storedEvent)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, read)
+	}
+	return result, nil
+}
@@ -0,0 +1,531 @@
+package eventstore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"escama/domain/events"
+	"escama/domain/money"
+
+	_ "github.com/mattn/go-sqlite3"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// conformanceMongoEnv conexión a un MongoDB descartable para incluirlo en las pruebas de
+// conformidad. La prueba borra la base "escama" de ese servidor.
+const conformanceMongoEnv = "ESCAMA_TEST_MONGODB_URI"
+
+// conformanceBackends abre cada implementación del EventStore vacía
+func conformanceBackends(t *testing.T) map[string]func(t *testing.T) EventStore {
+	backends := map[string]func(t *testing.T) EventStore{
+		"memory": func(t *testing.T) EventStore {
+			return NewInMemoryEventStore()
+		},
+		"file": func(t *testing.T) EventStore {
+			store, err := NewFileEventStore(filepath.Join(t.TempDir(), "events.jsonl"))
+			if err != nil {
+				t.Fatalf("failed to open file store: %v", err)
+			}
+			t.Cleanup(func() { store.Close() })
+			return store
+		},
+		"sqlite": func(t *testing.T) EventStore {
+			db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "escama.db")+"?_txlock=immediate")
+			if err != nil {
+				t.Fatalf("failed to open sqlite: %v", err)
+			}
+			t.Cleanup(func() { db.Close() })
+			store, err := NewSQLiteEventStore(db)
+			if err != nil {
+				t.Fatalf("failed to open sqlite store: %v", err)
+			}
+			return store
+		},
+		"archived": func(t *testing.T) EventStore {
+			store, err := NewArchivedEventStore(NewInMemoryEventStore(), t.TempDir())
+			if err != nil {
+				t.Fatalf("failed to open archived store: %v", err)
+			}
+			return store
+		},
+	}
+
+	if uri := os.Getenv(conformanceMongoEnv); uri != "" {
+		backends["mongodb"] = func(t *testing.T) EventStore {
+			t.Setenv("MONGODB_CONNECTION_STRING", uri)
+			store, err := NewMongoEventStore()
+			if err != nil {
+				t.Fatalf("failed to open mongodb store: %v", err)
+			}
+			if err := store.database.Drop(context.Background()); err != nil {
+				t.Fatalf("failed to reset mongodb: %v", err)
+			}
+			store.Close()
+
+			// Reabrir para crear los índices sobre la base vacía
+			store, err = NewMongoEventStore()
+			if err != nil {
+				t.Fatalf("failed to open mongodb store: %v", err)
+			}
+			t.Cleanup(func() { store.Close() })
+			return store
+		}
+	}
+
+	return backends
+}
+
+func date(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("invalid date %q: %v", value, err)
+	}
+	return parsed
+}
+
+// seedTimeline guarda eventos cuya fecha de registro y fecha de negocio no coinciden:
+//
+//	#1 categoría, sin fecha de negocio
+//	#2 gasto del 27/02 registrado el 01/03
+//	#3 ingreso del 05/03 a las 23:30 (-03:00), que en UTC ya es 06/03
+//	#4 el gasto pasa al 10/03
+//	#5 baja del gasto, sin fecha de negocio
+func seedTimeline(t *testing.T, store EventStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	steps := []struct {
+		aggregateID   string
+		aggregateType string
+		version       int
+		event         events.DomainEvent
+	}{
+		{"category-1", "Category", 0, events.CategoryCreated{
+			CategoryID: "category-1", Name: "Comida", Occurred: date(t, "2025-03-01T10:00:00Z"),
+		}},
+		{"expense-1", "Expense", 0, events.ExpenseCreated{
+			ExpenseID: "expense-1", CategoryID: "category-1", Amount: money.New(5000, money.PYG),
+			Date: date(t, "2025-02-27T00:00:00Z"), Occurred: date(t, "2025-03-01T12:00:00Z"),
+		}},
+		{"income-1", "Income", 0, events.IncomeCreated{
+			IncomeID: "income-1", CategoryID: "category-1", Amount: money.New(90000, money.PYG),
+			Date: date(t, "2025-03-05T23:30:00-03:00"), Occurred: date(t, "2025-03-05T12:00:00Z"),
+		}},
+		{"expense-1", "Expense", 1, events.ExpenseUpdated{
+			ExpenseID: "expense-1", CategoryID: "category-1", Amount: money.New(6000, money.PYG),
+			Date: date(t, "2025-03-10T00:00:00Z"), Occurred: date(t, "2025-03-10T09:00:00Z"),
+		}},
+		{"expense-1", "Expense", 2, events.ExpenseDeleted{
+			ExpenseID: "expense-1", Occurred: date(t, "2025-03-12T09:00:00Z"),
+		}},
+	}
+
+	for _, step := range steps {
+		if err := store.Store(ctx, step.aggregateID, step.aggregateType, step.version, []events.DomainEvent{step.event}); err != nil {
+			t.Fatalf("failed to store %s: %v", step.event.EventType(), err)
+		}
+	}
+}
+
+func positions(storedEvents []events.StoredEvent) []int64 {
+	result := []int64{}
+	for _, storedEvent := range storedEvents {
+		result = append(result, storedEvent.GlobalPosition)
+	}
+	return result
+}
+
+func TestEventStoreTimeFilterConformance(t *testing.T) {
+	at := func(value string) *time.Time {
+		parsed := date(t, value)
+		return &parsed
+	}
+
+	cases := []struct {
+		name   string
+		filter TimeFilter
+		want   []int64
+	}{
+		{"sin filtro", TimeFilter{}, []int64{1, 2, 3, 4, 5}},
+		{"registro con límites inclusivos", TimeFilter{
+			RecordedFrom: at("2025-03-01T12:00:00Z"),
+			RecordedTo:   at("2025-03-10T09:00:00Z"),
+		}, []int64{2, 3, 4}},
+		{"registro hasta", TimeFilter{RecordedTo: at("2025-03-01T23:59:59Z")}, []int64{1, 2}},
+		{"negocio de un día", TimeFilter{
+			BusinessFrom: at("2025-02-27T00:00:00Z"),
+			BusinessTo:   at("2025-02-27T00:00:00Z"),
+		}, []int64{2}},
+		{"negocio en la zona del cliente", TimeFilter{
+			BusinessFrom: at("2025-03-01T00:00:00Z"),
+			BusinessTo:   at("2025-03-05T00:00:00Z"),
+		}, []int64{3}},
+		{"negocio excluye eventos sin fecha", TimeFilter{BusinessTo: at("2025-03-31T00:00:00Z")}, []int64{2, 3, 4}},
+		{"registro y negocio combinados", TimeFilter{
+			RecordedFrom: at("2025-03-02T00:00:00Z"),
+			BusinessTo:   at("2025-03-05T00:00:00Z"),
+		}, []int64{3}},
+	}
+
+	for name, open := range conformanceBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			store := open(t)
+			seedTimeline(t, store)
+
+			if archived, ok := store.(*ArchivedEventStore); ok {
+				// Los primeros eventos pasan a un segmento: la consulta debe leerlos igual
+				if _, err := archived.ArchiveBefore(context.Background(), date(t, "2025-03-06T00:00:00Z")); err != nil {
+					t.Fatalf("failed to archive: %v", err)
+				}
+			}
+
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					all, err := store.GetAllEvents(context.Background(), tc.filter)
+					if err != nil {
+						t.Fatalf("GetAllEvents: %v", err)
+					}
+					if got := positions(all); !reflect.DeepEqual(got, tc.want) {
+						t.Errorf("GetAllEvents = %v, want %v", got, tc.want)
+					}
+
+					// Lotes de un evento: los descartados por fecha no deben cortar la lectura
+					var streamed []events.StoredEvent
+					filter := StreamFilter{TimeFilter: tc.filter, BatchSize: 1}
+					err = store.Stream(context.Background(), filter, func(storedEvent events.StoredEvent) error {
+						streamed = append(streamed, storedEvent)
+						return nil
+					})
+					if err != nil {
+						t.Fatalf("Stream: %v", err)
+					}
+					if got := positions(streamed); !reflect.DeepEqual(got, tc.want) {
+						t.Errorf("Stream = %v, want %v", got, tc.want)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestEventStoreConcurrencyConflictConformance(t *testing.T) {
+	ctx := context.Background()
+	expenseEvent := func(occurred string) []events.DomainEvent {
+		return []events.DomainEvent{events.ExpenseUpdated{
+			ExpenseID: "expense-1", Amount: money.New(6000, money.PYG), Occurred: date(t, occurred),
+		}}
+	}
+
+	cases := []struct {
+		name            string
+		expectedVersion int
+	}{
+		{"versión de un stream nuevo", 0},
+		{"versión anterior", 1},
+		{"versión adelantada", 5},
+	}
+
+	for name, open := range conformanceBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			store := open(t)
+			for version, occurred := range []string{"2025-03-01T12:00:00Z", "2025-03-02T12:00:00Z"} {
+				if err := store.Store(ctx, "expense-1", "Expense", version, expenseEvent(occurred)); err != nil {
+					t.Fatalf("failed to store version %d: %v", version+1, err)
+				}
+			}
+			if err := store.Store(ctx, "category-1", "Category", 0, []events.DomainEvent{events.CategoryCreated{
+				CategoryID: "category-1", Name: "Comida", Occurred: date(t, "2025-04-01T12:00:00Z"),
+			}}); err != nil {
+				t.Fatalf("failed to store category: %v", err)
+			}
+
+			if archived, ok := store.(*ArchivedEventStore); ok {
+				// El stream queda archivado por completo: la versión sale de su head
+				if _, err := archived.ArchiveBefore(ctx, date(t, "2025-03-15T00:00:00Z")); err != nil {
+					t.Fatalf("failed to archive: %v", err)
+				}
+			}
+
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					err := store.Store(ctx, "expense-1", "Expense", tc.expectedVersion, expenseEvent("2025-04-02T12:00:00Z"))
+					if !errors.Is(err, ErrConcurrencyConflict) {
+						t.Fatalf("Store with version %d = %v, want ErrConcurrencyConflict", tc.expectedVersion, err)
+					}
+					var conflict *ConcurrencyError
+					if !errors.As(err, &conflict) {
+						t.Fatalf("Store with version %d = %T, want *ConcurrencyError", tc.expectedVersion, err)
+					}
+					if conflict.AggregateID != "expense-1" || conflict.ExpectedVersion != tc.expectedVersion || conflict.ActualVersion != 2 {
+						t.Errorf("ConcurrencyError = %+v, want expense-1 expected %d actual 2", conflict, tc.expectedVersion)
+					}
+
+					stream, err := store.Load(ctx, "expense-1")
+					if err != nil {
+						t.Fatalf("Load: %v", err)
+					}
+					if len(stream) != 2 {
+						t.Errorf("Load after conflict = %d events, want 2", len(stream))
+					}
+				})
+			}
+
+			// Con la versión actual el stream sigue creciendo
+			if err := store.Store(ctx, "expense-1", "Expense", 2, expenseEvent("2025-04-03T12:00:00Z")); err != nil {
+				t.Fatalf("Store with current version: %v", err)
+			}
+		})
+	}
+}
+
+func TestEventStoreIdempotencyKeyConformance(t *testing.T) {
+	keyed := func(key string) context.Context {
+		return events.WithMetadata(context.Background(), events.Metadata{IdempotencyKey: key})
+	}
+
+	cases := []struct {
+		name        string
+		aggregateID string
+		key         string
+		want        bool
+	}{
+		{"clave del primer evento", "expense-1", "crear", true},
+		{"clave de un evento posterior", "expense-1", "actualizar", true},
+		{"clave de otro stream", "income-1", "crear", false},
+		{"clave desconocida", "expense-1", "otra", false},
+		{"stream inexistente", "expense-2", "crear", false},
+	}
+
+	for name, open := range conformanceBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			store := open(t)
+			if _, ok := store.(KeyIndex); !ok {
+				t.Fatalf("%T does not implement KeyIndex", store)
+			}
+
+			steps := []struct {
+				ctx         context.Context
+				aggregateID string
+				version     int
+				event       events.DomainEvent
+			}{
+				{keyed("crear"), "expense-1", 0, events.ExpenseCreated{
+					ExpenseID: "expense-1", Amount: money.New(5000, money.PYG), Occurred: date(t, "2025-03-01T12:00:00Z"),
+				}},
+				{keyed("actualizar"), "expense-1", 1, events.ExpenseUpdated{
+					ExpenseID: "expense-1", Amount: money.New(6000, money.PYG), Occurred: date(t, "2025-03-02T12:00:00Z"),
+				}},
+				{context.Background(), "income-1", 0, events.IncomeCreated{
+					IncomeID: "income-1", Amount: money.New(90000, money.PYG), Occurred: date(t, "2025-04-01T12:00:00Z"),
+				}},
+			}
+			for _, step := range steps {
+				if err := store.Store(step.ctx, step.aggregateID, "Test", step.version, []events.DomainEvent{step.event}); err != nil {
+					t.Fatalf("failed to store %s: %v", step.event.EventType(), err)
+				}
+			}
+
+			if archived, ok := store.(*ArchivedEventStore); ok {
+				// Los eventos con clave pasan a un segmento: se encuentran en su índice
+				segment, err := archived.ArchiveBefore(context.Background(), date(t, "2025-03-15T00:00:00Z"))
+				if err != nil {
+					t.Fatalf("failed to archive: %v", err)
+				}
+				if segment == nil || len(segment.Keys["expense-1"]) != 2 {
+					t.Fatalf("archived segment = %+v, want both keys of expense-1 indexed", segment)
+				}
+			}
+
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					got, err := HasIdempotencyKey(context.Background(), store, tc.aggregateID, tc.key)
+					if err != nil {
+						t.Fatalf("HasIdempotencyKey: %v", err)
+					}
+					if got != tc.want {
+						t.Errorf("HasIdempotencyKey(%s, %s) = %v, want %v", tc.aggregateID, tc.key, got, tc.want)
+					}
+				})
+			}
+		})
+	}
+}
+
+// tamperEvent modifica el evento de la posición indicada directamente en el almacenamiento
+// del backend, sin pasar por Store, como lo haría alguien con acceso a los datos
+func tamperEvent(t *testing.T, store EventStore, position int64, change func(*events.StoredEvent)) {
+	t.Helper()
+	ctx := context.Background()
+
+	var stored []events.StoredEvent
+	if err := store.Stream(ctx, StreamFilter{Raw: true}, func(storedEvent events.StoredEvent) error {
+		stored = append(stored, storedEvent)
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to read events: %v", err)
+	}
+	index := -1
+	for i, storedEvent := range stored {
+		if storedEvent.GlobalPosition == position {
+			index = i
+		}
+	}
+	if index < 0 {
+		t.Fatalf("no event at global position %d", position)
+	}
+	tampered := stored[index]
+	change(&tampered)
+
+	switch s := store.(type) {
+	case *InMemoryEventStore:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i := range s.allEvents {
+			if s.allEvents[i].GlobalPosition == position {
+				s.allEvents[i] = tampered
+			}
+		}
+		stream := s.events[tampered.AggregateID]
+		for i := range stream {
+			if stream[i].GlobalPosition == position {
+				stream[i] = tampered
+			}
+		}
+	case *FileEventStore:
+		stored[index] = tampered
+		var buf bytes.Buffer
+		for _, storedEvent := range stored {
+			line, err := json.Marshal(storedEvent)
+			if err != nil {
+				t.Fatalf("failed to encode event: %v", err)
+			}
+			buf.Write(append(line, '\n'))
+		}
+		if err := writeFileAtomic(s.path, buf.Bytes()); err != nil {
+			t.Fatalf("failed to rewrite events file: %v", err)
+		}
+	case *SQLiteEventStore:
+		payload, err := json.Marshal(tampered.Payload)
+		if err != nil {
+			t.Fatalf("failed to encode payload: %v", err)
+		}
+		if _, err := s.db.ExecContext(ctx,
+			`UPDATE events SET payload = ?, hash = ?, prev_stream_hash = ?, prev_global_hash = ? WHERE global_position = ?`,
+			string(payload), tampered.Hash, tampered.PrevStreamHash, tampered.PrevGlobalHash, position); err != nil {
+			t.Fatalf("failed to update event: %v", err)
+		}
+	case *ArchivedEventStore:
+		tamperEvent(t, s.hot, position, change)
+	case *MongoEventStore:
+		update := bson.M{"$set": bson.M{
+			"payload":          tampered.Payload,
+			"hash":             tampered.Hash,
+			"prev_stream_hash": tampered.PrevStreamHash,
+			"prev_global_hash": tampered.PrevGlobalHash,
+		}}
+		if _, err := s.collection.UpdateOne(ctx, bson.M{"global_position": position}, update); err != nil {
+			t.Fatalf("failed to update event: %v", err)
+		}
+	default:
+		t.Fatalf("cannot tamper with events of %T", store)
+	}
+}
+
+func TestVerifyChainDetectsTamperingConformance(t *testing.T) {
+	// rehash vuelve a calcular el hash del evento, como haría quien altera un enlace y
+	// quiere que el contenido siga coincidiendo con su hash
+	rehash := func(t *testing.T, storedEvent *events.StoredEvent) {
+		hash, err := hashEvent(*storedEvent)
+		if err != nil {
+			t.Fatalf("failed to hash event: %v", err)
+		}
+		storedEvent.Hash = hash
+	}
+
+	// Se altera el #4 (segundo evento de expense-1): el #1 es de otro stream
+	cases := []struct {
+		name   string
+		change func(t *testing.T, storedEvent *events.StoredEvent)
+		want   string
+	}{
+		{"payload alterado", func(t *testing.T, storedEvent *events.StoredEvent) {
+			payload := make(map[string]interface{}, len(storedEvent.Payload))
+			for key, value := range storedEvent.Payload {
+				payload[key] = value
+			}
+			payload["category_id"] = "category-2"
+			storedEvent.Payload = payload
+		}, BreakHashMismatch},
+		{"hash borrado", func(t *testing.T, storedEvent *events.StoredEvent) {
+			storedEvent.Hash = ""
+		}, BreakMissingHash},
+		{"enlace global roto", func(t *testing.T, storedEvent *events.StoredEvent) {
+			storedEvent.PrevGlobalHash = storedEvent.PrevStreamHash
+			rehash(t, storedEvent)
+		}, BreakGlobalLink},
+		{"enlace de stream roto", func(t *testing.T, storedEvent *events.StoredEvent) {
+			storedEvent.PrevStreamHash = storedEvent.PrevGlobalHash
+			rehash(t, storedEvent)
+		}, BreakStreamLink},
+	}
+
+	for name, open := range conformanceBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			store := open(t)
+			seedTimeline(t, store)
+			report, err := VerifyChain(ctx, store)
+			if err != nil {
+				t.Fatalf("VerifyChain: %v", err)
+			}
+			if report.Break != nil || report.Checked != 5 || report.Unhashed != 0 {
+				t.Fatalf("VerifyChain on an intact store = %+v, want 5 events checked and no break", report)
+			}
+
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					store := open(t)
+					seedTimeline(t, store)
+					tamperEvent(t, store, 4, func(storedEvent *events.StoredEvent) { tc.change(t, storedEvent) })
+
+					report, err := VerifyChain(ctx, store)
+					if err != nil {
+						t.Fatalf("VerifyChain: %v", err)
+					}
+					if report.Break == nil || report.Break.Kind != tc.want || report.Break.GlobalPosition != 4 ||
+						report.Break.AggregateID != "expense-1" {
+						t.Fatalf("VerifyChain = %+v (break %+v), want %s at position 4", report, report.Break, tc.want)
+					}
+					if report.Checked != 3 {
+						t.Errorf("VerifyChain checked %d events before the break, want 3", report.Checked)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestBusinessDayUpcastsLegacyPayloads(t *testing.T) {
+	legacy := events.StoredEvent{
+		EventType: "ExpenseCreated",
+		Payload: map[string]interface{}{
+			"ExpenseID": "expense-1",
+			"Date":      "2025-02-27 18:30:00",
+		},
+	}
+
+	day, ok := legacy.BusinessDay()
+	if !ok || day != "2025-02-27" {
+		t.Fatalf("BusinessDay = %q, %v; want 2025-02-27", day, ok)
+	}
+}
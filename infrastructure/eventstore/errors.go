@@ -0,0 +1,26 @@
+package eventstore
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrConcurrencyConflict se devuelve (envuelto en ConcurrencyError) cuando el stream
+// de un agregado cambió desde que fue cargado
+var ErrConcurrencyConflict = errors.New("concurrency conflict")
+
+// ConcurrencyError indica que la versión esperada del stream no coincide con la almacenada
+type ConcurrencyError struct {
+	AggregateID     string
+	ExpectedVersion int
+	ActualVersion   int
+}
+
+func (e *ConcurrencyError) Error() string {
+	return fmt.Sprintf("concurrency conflict on aggregate %s: expected version %d, actual version %d",
+		e.AggregateID, e.ExpectedVersion, e.ActualVersion)
+}
+
+func (e *ConcurrencyError) Unwrap() error {
+	return ErrConcurrencyConflict
+}
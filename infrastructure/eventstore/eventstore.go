@@ -2,91 +2,224 @@ package eventstore
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"time"
+	"sort"
+	"sync"
 
 	"escama/domain/events"
 )
 
 // EventStore define el contrato para persistir eventos
+//
+// Store agrega los eventos al stream del agregado solo si su versión actual (cantidad de
+// eventos ya almacenados) es igual a expectedVersion; en caso contrario devuelve un
+// *ConcurrencyError. Un agregado nuevo se guarda con expectedVersion 0.
+//
+// Load devuelve los eventos de un agregado ordenados por Sequence y GetAllEvents los
+// de todo el store ordenados por GlobalPosition, para que los replays sean deterministas.
+// GetAllEvents filtra por fecha de registro o por fecha de negocio (ver TimeFilter).
+// LoadFrom devuelve solo los eventos posteriores a afterSequence (por ejemplo, los que
+// siguen a un snapshot).
+//
+// Stream recorre los eventos que cumplen el filtro en orden de GlobalPosition, por lotes,
+// sin cargar todo el historial en memoria. Si fn devuelve un error la lectura se detiene
+// y Stream lo devuelve (salvo ErrStopStream).
 type EventStore interface {
-	Store(ctx context.Context, aggregateID, aggregateType string, domainEvents []events.DomainEvent) error
+	Store(ctx context.Context, aggregateID, aggregateType string, expectedVersion int, domainEvents []events.DomainEvent) error
 	Load(ctx context.Context, aggregateID string) ([]events.StoredEvent, error)
-	GetAllEvents(ctx context.Context, startDate, endDate *time.Time) ([]events.StoredEvent, error)
+	LoadFrom(ctx context.Context, aggregateID string, afterSequence int) ([]events.StoredEvent, error)
+	GetAllEvents(ctx context.Context, filter TimeFilter) ([]events.StoredEvent, error)
+	Stream(ctx context.Context, filter StreamFilter, fn StreamFunc) error
 }
 
 // InMemoryEventStore implementación en memoria del EventStore
 type InMemoryEventStore struct {
+	mu        sync.RWMutex
 	events    map[string][]events.StoredEvent
-	allEvents []events.StoredEvent // Para queries globales
+	allEvents []events.StoredEvent // Para queries globales, en orden de posición global
+	position  int64
+	outbox    map[int64]bool // posiciones pendientes de publicar
+	archived  map[string]archivedHead
+	keys      map[streamKey]bool // claves de idempotencia de los eventos en memoria
+	cipher    PayloadCipher
 }
 
 func NewInMemoryEventStore() *InMemoryEventStore {
 	return &InMemoryEventStore{
 		events:    make(map[string][]events.StoredEvent),
 		allEvents: make([]events.StoredEvent, 0),
+		outbox:    make(map[int64]bool),
+		archived:  make(map[string]archivedHead),
+		keys:      make(map[streamKey]bool),
 	}
 }
 
-func (s *InMemoryEventStore) Store(ctx context.Context, aggregateID, aggregateType string, domainEvents []events.DomainEvent) error {
-	for _, event := range domainEvents {
-		payload, err := s.serializeEvent(event)
+// SetCipher activa el cifrado de los campos sensibles de los payloads
+func (s *InMemoryEventStore) SetCipher(cipher PayloadCipher) {
+	s.cipher = cipher
+}
+
+func (s *InMemoryEventStore) Store(ctx context.Context, aggregateID, aggregateType string, expectedVersion int, domainEvents []events.DomainEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	actualVersion, prevStreamHash := streamHead(s.events[aggregateID], s.archived, aggregateID)
+	if actualVersion != expectedVersion {
+		return &ConcurrencyError{
+			AggregateID:     aggregateID,
+			ExpectedVersion: expectedVersion,
+			ActualVersion:   actualVersion,
+		}
+	}
+
+	// Serializar todo antes de modificar el stream para no dejarlo a medias
+	storedEvents := make([]events.StoredEvent, 0, len(domainEvents))
+	metadata := events.MetadataFromContext(ctx)
+	for i, event := range domainEvents {
+		payload, err := events.EncodePayload(event)
 		if err != nil {
 			return fmt.Errorf("failed to serialize event: %w", err)
 		}
-
-		storedEvent := events.StoredEvent{
-			ID:            fmt.Sprintf("%s-%d", aggregateID, len(s.events[aggregateID])),
-			AggregateID:   aggregateID,
-			AggregateType: aggregateType,
-			EventType:     event.EventType(),
-			Payload:       payload,
-			OccurredAt:    event.OccurredAt(),
+		payload, err = sealPayload(s.cipher, payload, metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt event: %w", err)
 		}
 
-		s.events[aggregateID] = append(s.events[aggregateID], storedEvent)
-		s.allEvents = append(s.allEvents, storedEvent) // Mantener lista global
+		sequence := expectedVersion + i + 1
+		storedEvents = append(storedEvents, events.StoredEvent{
+			ID:             fmt.Sprintf("%s-%d", aggregateID, sequence),
+			AggregateID:    aggregateID,
+			AggregateType:  aggregateType,
+			Sequence:       sequence,
+			GlobalPosition: s.position + int64(i) + 1,
+			EventType:      event.EventType(),
+			SchemaVersion:  events.SchemaVersion(event.EventType()),
+			Payload:        payload,
+			OccurredAt:     event.OccurredAt(),
+			Metadata:       metadata,
+		})
+	}
+
+	if err := linkEvents(storedEvents, prevStreamHash, lastHash(s.allEvents)); err != nil {
+		return err
+	}
+
+	s.position += int64(len(storedEvents))
+	s.events[aggregateID] = append(s.events[aggregateID], storedEvents...)
+	s.allEvents = append(s.allEvents, storedEvents...) // Mantener lista global
+	for _, storedEvent := range storedEvents {
+		s.outbox[storedEvent.GlobalPosition] = true
+	}
+	if metadata.IdempotencyKey != "" {
+		s.keys[streamKey{aggregateID, metadata.IdempotencyKey}] = true
 	}
 
 	return nil
 }
 
+func (s *InMemoryEventStore) HasIdempotencyKey(ctx context.Context, aggregateID, key string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.keys[streamKey{aggregateID, key}], nil
+}
+
 func (s *InMemoryEventStore) Load(ctx context.Context, aggregateID string) ([]events.StoredEvent, error) {
-	storedEvents, exists := s.events[aggregateID]
-	if !exists {
-		return []events.StoredEvent{}, nil
+	return s.LoadFrom(ctx, aggregateID, 0)
+}
+
+func (s *InMemoryEventStore) LoadFrom(ctx context.Context, aggregateID string, afterSequence int) ([]events.StoredEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	storedEvents := []events.StoredEvent{}
+	for _, storedEvent := range s.events[aggregateID] {
+		if storedEvent.Sequence > afterSequence {
+			storedEvents = append(storedEvents, storedEvent)
+		}
 	}
-	return storedEvents, nil
+	return readEvents(s.cipher, storedEvents)
+}
+
+func (s *InMemoryEventStore) GetAllEvents(ctx context.Context, filter TimeFilter) ([]events.StoredEvent, error) {
+	return collectEvents(ctx, s, StreamFilter{TimeFilter: filter})
 }
 
-func (s *InMemoryEventStore) GetAllEvents(ctx context.Context, startDate, endDate *time.Time) ([]events.StoredEvent, error) {
-	var filteredEvents []events.StoredEvent
+func (s *InMemoryEventStore) Stream(ctx context.Context, filter StreamFilter, fn StreamFunc) error {
+	return streamBatches(filter, s.cipher, fn, func(afterPosition int64, limit int) ([]events.StoredEvent, error) {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		return nextBatch(s.allEvents, filter, afterPosition, limit), nil
+	})
+}
 
-	for _, event := range s.allEvents {
-		// Filtrar por fechas si se proporcionan
-		if startDate != nil && event.OccurredAt.Before(*startDate) {
-			continue
+// nextBatch toma de una lista ordenada por posición global hasta limit eventos que cumplen
+// el filtro a partir de afterPosition
+func nextBatch(allEvents []events.StoredEvent, filter StreamFilter, afterPosition int64, limit int) []events.StoredEvent {
+	start := sort.Search(len(allEvents), func(i int) bool {
+		return allEvents[i].GlobalPosition > afterPosition
+	})
+
+	var batch []events.StoredEvent
+	for _, storedEvent := range allEvents[start:] {
+		if len(batch) == limit {
+			break
 		}
-		if endDate != nil && event.OccurredAt.After(*endDate) {
-			continue
+		if filter.matches(storedEvent) {
+			batch = append(batch, storedEvent)
 		}
-		filteredEvents = append(filteredEvents, event)
 	}
+	return batch
+}
+
+func (s *InMemoryEventStore) Pending(ctx context.Context, limit int) ([]events.StoredEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	return filteredEvents, nil
+	var pending []events.StoredEvent
+	for _, storedEvent := range s.allEvents {
+		if len(pending) == limit {
+			break
+		}
+		if s.outbox[storedEvent.GlobalPosition] {
+			pending = append(pending, storedEvent)
+		}
+	}
+	return readEvents(s.cipher, pending)
 }
 
-func (s *InMemoryEventStore) serializeEvent(event events.DomainEvent) (map[string]interface{}, error) {
-	data, err := json.Marshal(event)
-	if err != nil {
-		return nil, err
+func (s *InMemoryEventStore) Ack(ctx context.Context, positions []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, position := range positions {
+		delete(s.outbox, position)
 	}
+	return nil
+}
 
-	var payload map[string]interface{}
-	if err := json.Unmarshal(data, &payload); err != nil {
-		return nil, err
+// RemoveArchived quita de memoria los eventos ya archivados
+func (s *InMemoryEventStore) RemoveArchived(ctx context.Context, position int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := checkArchivable(position, s.position); err != nil {
+		return err
 	}
 
-	return payload, nil
+	s.allEvents = removeIndexed(s.events, s.allEvents, s.archived, position)
+	// Las claves de los eventos archivados quedan en el índice de sus segmentos
+	s.keys = make(map[streamKey]bool)
+	for _, storedEvent := range s.allEvents {
+		if key := storedEvent.Metadata.IdempotencyKey; key != "" {
+			s.keys[streamKey{storedEvent.AggregateID, key}] = true
+		}
+	}
+	for outboxPosition := range s.outbox {
+		if outboxPosition <= position {
+			delete(s.outbox, outboxPosition)
+		}
+	}
+	return nil
 }
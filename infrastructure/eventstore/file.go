@@ -0,0 +1,540 @@
+package eventstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"escama/domain/events"
+)
+
+// FileEventStore implementación del EventStore sobre un archivo JSON Lines append-only.
+// Cada línea es un StoredEvent; los índices viven en memoria y se reconstruyen al abrir
+// el archivo. Antes de cada operación se leen las líneas que otro proceso haya agregado.
+// Las escrituras toman un flock exclusivo sobre un archivo lateral (<archivo>.lock) que
+// abarca la lectura del head, el chequeo de versión y el append con fsync, de modo que dos
+// procesos no pueden agregar eventos con la misma secuencia o posición.
+//
+// El outbox es un archivo lateral (<archivo>.outbox) con la última posición global
+// publicada: todo evento posterior está pendiente. Como el archivo de eventos es
+// append-only, agregar un evento ya lo deja pendiente sin una segunda escritura.
+//
+// Al archivar eventos (RemoveArchived) el archivo se reescribe sin ellos y se reemplaza
+// con un rename; los procesos que lo tenían abierto lo notan y vuelven a abrirlo. El head
+// de los streams archivados por completo se guarda en otro archivo lateral (<archivo>.archived).
+type FileEventStore struct {
+	mu           sync.Mutex
+	path         string
+	file         *os.File
+	lock         *os.File
+	ackPath      string
+	archivedPath string
+	offset       int64 // bytes del archivo ya indexados
+	events       map[string][]events.StoredEvent
+	allEvents    []events.StoredEvent
+	position     int64
+	archived     map[string]archivedHead
+	keys         map[streamKey]bool // claves de idempotencia de los eventos del archivo
+	cipher       PayloadCipher
+}
+
+func NewFileEventStore(path string) (*FileEventStore, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create event store directory: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event store file: %w", err)
+	}
+
+	lock, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open event store lock file: %w", err)
+	}
+
+	s := &FileEventStore{
+		path:         path,
+		file:         file,
+		lock:         lock,
+		ackPath:      path + ".outbox",
+		archivedPath: path + ".archived",
+		events:       make(map[string][]events.StoredEvent),
+		archived:     make(map[string]archivedHead),
+		keys:         make(map[streamKey]bool),
+	}
+
+	// La reparación va bajo el lock: sin él, el append en curso de otro proceso
+	// parecería una escritura interrumpida y se truncaría
+	if err := s.lockWriters(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	err = s.repairTornWrite()
+	s.unlockWriters()
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	if err := s.loadArchivedHeads(); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	if err := s.refresh(); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	// Sin archivo de outbox, el historial existente se considera ya publicado
+	if _, err := os.Stat(s.ackPath); os.IsNotExist(err) {
+		if err := s.writeAckedPosition(s.position); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// SetCipher activa el cifrado de los campos sensibles de los payloads
+func (s *FileEventStore) SetCipher(cipher PayloadCipher) {
+	s.cipher = cipher
+}
+
+func (s *FileEventStore) Store(ctx context.Context, aggregateID, aggregateType string, expectedVersion int, domainEvents []events.DomainEvent) error {
+	if len(domainEvents) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.lockWriters(); err != nil {
+		return err
+	}
+	defer s.unlockWriters()
+
+	if err := s.refresh(); err != nil {
+		return err
+	}
+
+	actualVersion, prevStreamHash := streamHead(s.events[aggregateID], s.archived, aggregateID)
+	if actualVersion != expectedVersion {
+		return &ConcurrencyError{
+			AggregateID:     aggregateID,
+			ExpectedVersion: expectedVersion,
+			ActualVersion:   actualVersion,
+		}
+	}
+
+	var buf bytes.Buffer
+	storedEvents := make([]events.StoredEvent, 0, len(domainEvents))
+	metadata := events.MetadataFromContext(ctx)
+	for i, event := range domainEvents {
+		payload, err := events.EncodePayload(event)
+		if err != nil {
+			return fmt.Errorf("failed to serialize event: %w", err)
+		}
+		payload, err = sealPayload(s.cipher, payload, metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt event: %w", err)
+		}
+
+		sequence := expectedVersion + i + 1
+		storedEvent := events.StoredEvent{
+			ID:             fmt.Sprintf("%s-%d", aggregateID, sequence),
+			AggregateID:    aggregateID,
+			AggregateType:  aggregateType,
+			Sequence:       sequence,
+			GlobalPosition: s.position + int64(i) + 1,
+			EventType:      event.EventType(),
+			SchemaVersion:  events.SchemaVersion(event.EventType()),
+			Payload:        payload,
+			OccurredAt:     event.OccurredAt(),
+			Metadata:       metadata,
+		}
+		storedEvents = append(storedEvents, storedEvent)
+	}
+
+	if err := linkEvents(storedEvents, prevStreamHash, lastHash(s.allEvents)); err != nil {
+		return err
+	}
+
+	for _, storedEvent := range storedEvents {
+		line, err := json.Marshal(storedEvent)
+		if err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	// Una sola escritura por lote y fsync antes de confirmar
+	n, err := s.file.Write(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to append events: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync event store file: %w", err)
+	}
+
+	s.offset += int64(n)
+	for _, storedEvent := range storedEvents {
+		s.index(storedEvent)
+	}
+
+	return nil
+}
+
+func (s *FileEventStore) HasIdempotencyKey(ctx context.Context, aggregateID, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.refresh(); err != nil {
+		return false, err
+	}
+	return s.keys[streamKey{aggregateID, key}], nil
+}
+
+func (s *FileEventStore) Load(ctx context.Context, aggregateID string) ([]events.StoredEvent, error) {
+	return s.LoadFrom(ctx, aggregateID, 0)
+}
+
+func (s *FileEventStore) LoadFrom(ctx context.Context, aggregateID string, afterSequence int) ([]events.StoredEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+
+	storedEvents := []events.StoredEvent{}
+	for _, storedEvent := range s.events[aggregateID] {
+		if storedEvent.Sequence > afterSequence {
+			storedEvents = append(storedEvents, storedEvent)
+		}
+	}
+	return readEvents(s.cipher, storedEvents)
+}
+
+func (s *FileEventStore) GetAllEvents(ctx context.Context, filter TimeFilter) ([]events.StoredEvent, error) {
+	return collectEvents(ctx, s, StreamFilter{TimeFilter: filter})
+}
+
+func (s *FileEventStore) Stream(ctx context.Context, filter StreamFilter, fn StreamFunc) error {
+	return streamBatches(filter, s.cipher, fn, func(afterPosition int64, limit int) ([]events.StoredEvent, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if err := s.refresh(); err != nil {
+			return nil, err
+		}
+		return nextBatch(s.allEvents, filter, afterPosition, limit), nil
+	})
+}
+
+func (s *FileEventStore) Pending(ctx context.Context, limit int) ([]events.StoredEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+
+	acked, err := s.readAckedPosition()
+	if err != nil {
+		return nil, err
+	}
+
+	return readEvents(s.cipher, nextBatch(s.allEvents, StreamFilter{FromPosition: acked}, acked, limit))
+}
+
+// Ack avanza la última posición publicada. Las posiciones deben confirmarse en orden,
+// como lo hace el relay: todas las anteriores a la mayor quedan confirmadas.
+func (s *FileEventStore) Ack(ctx context.Context, positions []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.lockWriters(); err != nil {
+		return err
+	}
+	defer s.unlockWriters()
+
+	acked, err := s.readAckedPosition()
+	if err != nil {
+		return err
+	}
+
+	highest := acked
+	for _, position := range positions {
+		if position > highest {
+			highest = position
+		}
+	}
+	if highest == acked {
+		return nil
+	}
+
+	return s.writeAckedPosition(highest)
+}
+
+func (s *FileEventStore) readAckedPosition() (int64, error) {
+	data, err := os.ReadFile(s.ackPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read outbox file: %w", err)
+	}
+
+	position, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("corrupt outbox file %s: %w", s.ackPath, err)
+	}
+	return position, nil
+}
+
+// writeAckedPosition reemplaza el archivo de outbox de forma atómica (archivo temporal + rename)
+func (s *FileEventStore) writeAckedPosition(position int64) error {
+	tmpPath := s.ackPath + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(strconv.FormatInt(position, 10)+"\n"), 0o600); err != nil {
+		return fmt.Errorf("failed to write outbox file: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.ackPath); err != nil {
+		return fmt.Errorf("failed to write outbox file: %w", err)
+	}
+	return nil
+}
+
+func (s *FileEventStore) Close() error {
+	s.lock.Close()
+	return s.file.Close()
+}
+
+// lockWriters toma el lock entre procesos de las escrituras
+func (s *FileEventStore) lockWriters() error {
+	if err := lockFile(s.lock); err != nil {
+		return fmt.Errorf("failed to lock event store file: %w", err)
+	}
+	return nil
+}
+
+// unlockWriters libera el lock tomado con lockWriters
+func (s *FileEventStore) unlockWriters() {
+	unlockFile(s.lock)
+}
+
+// refresh indexa las líneas completas agregadas desde la última lectura
+func (s *FileEventStore) refresh() error {
+	if err := s.reopenIfReplaced(); err != nil {
+		return err
+	}
+
+	info, err := s.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat event store file: %w", err)
+	}
+	if info.Size() <= s.offset {
+		return nil
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(s.file, s.offset, info.Size()-s.offset))
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// Línea incompleta: otro proceso todavía la está escribiendo
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read event store file: %w", err)
+		}
+
+		lineOffset := s.offset
+		s.offset += int64(len(line))
+
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var storedEvent events.StoredEvent
+		if err := json.Unmarshal(line, &storedEvent); err != nil {
+			return fmt.Errorf("corrupt event at offset %d: %w", lineOffset, err)
+		}
+		s.index(storedEvent)
+	}
+}
+
+// reopenIfReplaced vuelve a abrir e indexar el archivo si otro proceso lo reemplazó al
+// archivar eventos
+func (s *FileEventStore) reopenIfReplaced() error {
+	current, err := s.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat event store file: %w", err)
+	}
+	onDisk, err := os.Stat(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat event store file: %w", err)
+	}
+	if os.SameFile(current, onDisk) {
+		return nil
+	}
+
+	file, err := os.OpenFile(s.path, os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to reopen event store file: %w", err)
+	}
+	s.file.Close()
+	s.file = file
+	s.offset = 0
+	s.events = make(map[string][]events.StoredEvent)
+	s.allEvents = nil
+	s.keys = make(map[streamKey]bool)
+	return s.loadArchivedHeads()
+}
+
+// loadArchivedHeads lee los heads de los streams archivados por completo
+func (s *FileEventStore) loadArchivedHeads() error {
+	data, err := os.ReadFile(s.archivedPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read archived streams file: %w", err)
+	}
+
+	archived := make(map[string]archivedHead)
+	if err := json.Unmarshal(data, &archived); err != nil {
+		return fmt.Errorf("corrupt archived streams file %s: %w", s.archivedPath, err)
+	}
+	s.archived = archived
+	return nil
+}
+
+// RemoveArchived reescribe el archivo sin los eventos ya archivados. Los heads de los
+// streams que quedan vacíos se guardan antes de reemplazar el archivo: si el proceso se
+// interrumpe en medio, los eventos siguen en el archivo y tienen prioridad sobre ellos.
+func (s *FileEventStore) RemoveArchived(ctx context.Context, position int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.lockWriters(); err != nil {
+		return err
+	}
+	defer s.unlockWriters()
+
+	if err := s.refresh(); err != nil {
+		return err
+	}
+	if err := checkArchivable(position, s.position); err != nil {
+		return err
+	}
+
+	streams := make(map[string][]events.StoredEvent, len(s.events))
+	for aggregateID, streamEvents := range s.events {
+		streams[aggregateID] = streamEvents
+	}
+	archived := make(map[string]archivedHead, len(s.archived))
+	for aggregateID, head := range s.archived {
+		archived[aggregateID] = head
+	}
+	remaining := removeIndexed(streams, s.allEvents, archived, position)
+	if len(remaining) == len(s.allEvents) {
+		return nil
+	}
+
+	data, err := json.Marshal(archived)
+	if err != nil {
+		return fmt.Errorf("failed to encode archived streams: %w", err)
+	}
+	if err := writeFileAtomic(s.archivedPath, data); err != nil {
+		return fmt.Errorf("failed to write archived streams file: %w", err)
+	}
+
+	var buf bytes.Buffer
+	for _, storedEvent := range remaining {
+		line, err := json.Marshal(storedEvent)
+		if err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if err := writeFileAtomic(s.path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to rewrite event store file: %w", err)
+	}
+
+	return s.refresh()
+}
+
+// writeFileAtomic reemplaza un archivo con data: escribe y sincroniza un temporal y lo renombra
+func writeFileAtomic(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+	}
+	return err
+}
+
+// repairTornWrite descarta una última línea sin terminar, dejada por una escritura interrumpida
+func (s *FileEventStore) repairTornWrite() error {
+	info, err := s.file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat event store file: %w", err)
+	}
+	if info.Size() == 0 {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.NewSectionReader(s.file, 0, info.Size()))
+	if err != nil {
+		return fmt.Errorf("failed to read event store file: %w", err)
+	}
+
+	end := bytes.LastIndexByte(data, '\n') + 1
+	if end == len(data) {
+		return nil
+	}
+
+	if err := s.file.Truncate(int64(end)); err != nil {
+		return fmt.Errorf("failed to truncate torn write: %w", err)
+	}
+	return s.file.Sync()
+}
+
+func (s *FileEventStore) index(storedEvent events.StoredEvent) {
+	s.events[storedEvent.AggregateID] = append(s.events[storedEvent.AggregateID], storedEvent)
+	s.allEvents = append(s.allEvents, storedEvent)
+	if key := storedEvent.Metadata.IdempotencyKey; key != "" {
+		s.keys[streamKey{storedEvent.AggregateID, key}] = true
+	}
+	if storedEvent.GlobalPosition > s.position {
+		s.position = storedEvent.GlobalPosition
+	}
+}
@@ -0,0 +1,116 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"escama/domain/events"
+	"escama/domain/money"
+)
+
+func openFileStore(t *testing.T, path string) *FileEventStore {
+	t.Helper()
+	store, err := NewFileEventStore(path)
+	if err != nil {
+		t.Fatalf("failed to open file store: %v", err)
+	}
+	return store
+}
+
+func expenseCreated(id string, amount int64) []events.DomainEvent {
+	return []events.DomainEvent{events.ExpenseCreated{
+		ExpenseID: id, Amount: money.New(amount, money.PYG), Occurred: time.Now().UTC(),
+	}}
+}
+
+// TestFileEventStoreSharedByTwoWriters abre dos stores sobre el mismo archivo, como la CLI
+// y el servidor, escribe con ambos y vuelve a abrir el archivo
+func TestFileEventStoreSharedByTwoWriters(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	first := openFileStore(t, path)
+	second := openFileStore(t, path)
+
+	if err := first.Store(ctx, "expense-1", "Expense", 0, expenseCreated("expense-1", 1000)); err != nil {
+		t.Fatalf("first store: %v", err)
+	}
+	// El segundo store ve el evento del primero antes de escribir
+	if err := second.Store(ctx, "expense-1", "Expense", 0, expenseCreated("expense-1", 2000)); !errors.Is(err, ErrConcurrencyConflict) {
+		t.Fatalf("second store with a stale version = %v, want ErrConcurrencyConflict", err)
+	}
+
+	// Escrituras simultáneas desde los dos stores: el lock las serializa
+	const writesPerStore = 100
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*writesPerStore)
+	for s, store := range []*FileEventStore{first, second} {
+		wg.Add(1)
+		go func(s int, store *FileEventStore) {
+			defer wg.Done()
+			for i := 0; i < writesPerStore; i++ {
+				id := fmt.Sprintf("store-%d-expense-%d", s, i)
+				if err := store.Store(ctx, id, "Expense", 0, expenseCreated(id, int64(i))); err != nil {
+					errs <- fmt.Errorf("%s: %w", id, err)
+				}
+			}
+		}(s, store)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent store: %v", err)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatalf("failed to close first store: %v", err)
+	}
+	if err := second.Close(); err != nil {
+		t.Fatalf("failed to close second store: %v", err)
+	}
+
+	// Una escritura interrumpida deja una línea incompleta al final del archivo
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatalf("failed to open events file: %v", err)
+	}
+	if _, err := file.WriteString(`{"id":"expense-torn-1","aggregate_id":`); err != nil {
+		t.Fatalf("failed to write torn line: %v", err)
+	}
+	file.Close()
+
+	reopened := openFileStore(t, path)
+	t.Cleanup(func() { reopened.Close() })
+
+	all, err := reopened.GetAllEvents(ctx, TimeFilter{})
+	if err != nil {
+		t.Fatalf("GetAllEvents: %v", err)
+	}
+	want := 1 + 2*writesPerStore
+	if len(all) != want {
+		t.Fatalf("reopened store has %d events, want %d", len(all), want)
+	}
+	for i, storedEvent := range all {
+		if storedEvent.GlobalPosition != int64(i+1) {
+			t.Fatalf("event %d has global position %d, want %d", i, storedEvent.GlobalPosition, i+1)
+		}
+	}
+
+	report, err := VerifyChain(ctx, reopened)
+	if err != nil {
+		t.Fatalf("VerifyChain: %v", err)
+	}
+	if report.Break != nil || report.Checked != want {
+		t.Errorf("VerifyChain = %+v, want %d events checked and no break", report, want)
+	}
+
+	// El stream sigue en su versión y acepta eventos nuevos
+	if err := reopened.Store(ctx, "expense-1", "Expense", 1, []events.DomainEvent{events.NewExpenseDeleted("expense-1")}); err != nil {
+		t.Fatalf("store after reopening: %v", err)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package eventstore
+
+import "os"
+
+// lockFile no bloquea entre procesos en esta plataforma: solo el mutex del store
+// serializa las escrituras, así que el archivo debe usarse desde un único proceso
+func lockFile(file *os.File) error {
+	return nil
+}
+
+// unlockFile libera el lock tomado con lockFile
+func unlockFile(file *os.File) error {
+	return nil
+}
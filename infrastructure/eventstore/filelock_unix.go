@@ -0,0 +1,24 @@
+//go:build unix
+
+package eventstore
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile toma un lock exclusivo (flock) sobre el archivo, esperando a que otro proceso lo libere
+func lockFile(file *os.File) error {
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+		if !errors.Is(err, syscall.EINTR) {
+			return err
+		}
+	}
+}
+
+// unlockFile libera el lock tomado con lockFile
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,162 @@
+package eventstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"escama/domain/events"
+)
+
+// Tipos de eslabón roto que puede informar VerifyChain
+const (
+	BreakMissingHash  = "missing_hash"  // evento sin hash después del inicio de la cadena
+	BreakHashMismatch = "hash_mismatch" // el contenido del evento no coincide con su hash
+	BreakGlobalLink   = "global_link"   // no apunta al evento anterior del store
+	BreakStreamLink   = "stream_link"   // no apunta al evento anterior de su stream
+)
+
+// ChainBreak primer eslabón roto encontrado al verificar la cadena de hashes
+type ChainBreak struct {
+	Kind           string
+	EventID        string
+	AggregateID    string
+	GlobalPosition int64
+}
+
+// ChainReport resultado de VerifyChain. Unhashed cuenta los eventos guardados antes de
+// que existiera la cadena; solo pueden aparecer al principio del store.
+type ChainReport struct {
+	Checked  int
+	Unhashed int
+	Break    *ChainBreak
+}
+
+// hashedContent campos cubiertos por el hash de un evento, en un orden fijo. OccurredAt
+// se trunca a milisegundos, la precisión que conservan todos los stores (MongoDB incluido).
+type hashedContent struct {
+	ID             string                 `json:"id"`
+	AggregateID    string                 `json:"aggregate_id"`
+	AggregateType  string                 `json:"aggregate_type"`
+	Sequence       int                    `json:"sequence"`
+	GlobalPosition int64                  `json:"global_position"`
+	EventType      string                 `json:"event_type"`
+	SchemaVersion  int                    `json:"schema_version"`
+	Payload        map[string]interface{} `json:"payload"`
+	OccurredAt     string                 `json:"occurred_at"`
+	Metadata       events.Metadata        `json:"metadata"`
+	PrevStreamHash string                 `json:"prev_stream_hash"`
+	PrevGlobalHash string                 `json:"prev_global_hash"`
+}
+
+// hashEvent calcula el SHA-256 (hexadecimal) del evento tal como está almacenado, sin
+// upcasting, incluyendo los hashes de los eventos anteriores
+func hashEvent(storedEvent events.StoredEvent) (string, error) {
+	payload := storedEvent.Payload
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+
+	// encoding/json ordena las claves de los mapas: la codificación es determinista
+	data, err := json.Marshal(hashedContent{
+		ID:             storedEvent.ID,
+		AggregateID:    storedEvent.AggregateID,
+		AggregateType:  storedEvent.AggregateType,
+		Sequence:       storedEvent.Sequence,
+		GlobalPosition: storedEvent.GlobalPosition,
+		EventType:      storedEvent.EventType,
+		SchemaVersion:  storedEvent.SchemaVersion,
+		Payload:        payload,
+		OccurredAt:     storedEvent.OccurredAt.UTC().Truncate(time.Millisecond).Format(time.RFC3339Nano),
+		Metadata:       storedEvent.Metadata,
+		PrevStreamHash: storedEvent.PrevStreamHash,
+		PrevGlobalHash: storedEvent.PrevGlobalHash,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode event %s for hashing: %w", storedEvent.ID, err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// linkEvents encadena un lote de eventos de un mismo agregado al último evento de su
+// stream y al último evento del store, calculando el hash de cada uno
+func linkEvents(storedEvents []events.StoredEvent, prevStreamHash, prevGlobalHash string) error {
+	for i := range storedEvents {
+		storedEvents[i].PrevStreamHash = prevStreamHash
+		storedEvents[i].PrevGlobalHash = prevGlobalHash
+
+		hash, err := hashEvent(storedEvents[i])
+		if err != nil {
+			return err
+		}
+		storedEvents[i].Hash = hash
+		prevStreamHash, prevGlobalHash = hash, hash
+	}
+	return nil
+}
+
+// lastHash devuelve el hash del último evento de la lista, o "" si está vacía
+func lastHash(storedEvents []events.StoredEvent) string {
+	if len(storedEvents) == 0 {
+		return ""
+	}
+	return storedEvents[len(storedEvents)-1].Hash
+}
+
+// VerifyChain recorre todo el store en orden de posición global, recalcula el hash de
+// cada evento y comprueba sus enlaces con el evento anterior del store y de su stream.
+// Se detiene en el primer eslabón roto.
+func VerifyChain(ctx context.Context, store EventStore) (ChainReport, error) {
+	var report ChainReport
+	prevGlobalHash := ""
+	prevStreamHashes := make(map[string]string)
+
+	err := store.Stream(ctx, StreamFilter{Raw: true}, func(storedEvent events.StoredEvent) error {
+		chainBreak := func(kind string) error {
+			report.Break = &ChainBreak{
+				Kind:           kind,
+				EventID:        storedEvent.ID,
+				AggregateID:    storedEvent.AggregateID,
+				GlobalPosition: storedEvent.GlobalPosition,
+			}
+			return ErrStopStream
+		}
+
+		if storedEvent.Hash == "" {
+			if report.Checked > 0 {
+				return chainBreak(BreakMissingHash)
+			}
+			report.Unhashed++
+			return nil
+		}
+
+		hash, err := hashEvent(storedEvent)
+		if err != nil {
+			return err
+		}
+		if hash != storedEvent.Hash {
+			return chainBreak(BreakHashMismatch)
+		}
+		if storedEvent.PrevGlobalHash != prevGlobalHash {
+			return chainBreak(BreakGlobalLink)
+		}
+		if storedEvent.PrevStreamHash != prevStreamHashes[storedEvent.AggregateID] {
+			return chainBreak(BreakStreamLink)
+		}
+
+		report.Checked++
+		prevGlobalHash = hash
+		prevStreamHashes[storedEvent.AggregateID] = hash
+		return nil
+	})
+	if err != nil {
+		return report, fmt.Errorf("failed to verify event chain: %w", err)
+	}
+
+	return report, nil
+}
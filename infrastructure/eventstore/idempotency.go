@@ -0,0 +1,67 @@
+package eventstore
+
+import (
+	"context"
+	"fmt"
+
+	"escama/domain/events"
+)
+
+// KeyIndex lo implementan los Event Stores que indexan las claves de idempotencia de los
+// eventos (Metadata.IdempotencyKey): la clave queda registrada en la misma escritura que
+// los eventos del comando y se consulta sin cargar el stream
+type KeyIndex interface {
+	// HasIdempotencyKey indica si el stream tiene algún evento guardado con la clave
+	HasIdempotencyKey(ctx context.Context, aggregateID, key string) (bool, error)
+}
+
+// HasIdempotencyKey indica si el stream tiene algún evento guardado con la clave. Usa el
+// índice del store si lo tiene; si no, recorre el stream completo.
+func HasIdempotencyKey(ctx context.Context, store EventStore, aggregateID, key string) (bool, error) {
+	if index, ok := store.(KeyIndex); ok {
+		return index.HasIdempotencyKey(ctx, aggregateID, key)
+	}
+
+	storedEvents, err := store.Load(ctx, aggregateID)
+	if err != nil {
+		return false, fmt.Errorf("failed to load events of %s: %w", aggregateID, err)
+	}
+	return hasKey(storedEvents, aggregateID, key), nil
+}
+
+// hasKey indica si alguno de los eventos del stream fue guardado con la clave
+func hasKey(storedEvents []events.StoredEvent, aggregateID, key string) bool {
+	for _, storedEvent := range storedEvents {
+		if storedEvent.AggregateID == aggregateID && storedEvent.Metadata.IdempotencyKey == key {
+			return true
+		}
+	}
+	return false
+}
+
+// keysIndex devuelve las claves de idempotencia de cada stream con eventos en el lote
+func keysIndex(batch []events.StoredEvent) map[string][]string {
+	keys := make(map[string][]string)
+	for _, storedEvent := range batch {
+		key := storedEvent.Metadata.IdempotencyKey
+		if key != "" && !containsKey(keys[storedEvent.AggregateID], key) {
+			keys[storedEvent.AggregateID] = append(keys[storedEvent.AggregateID], key)
+		}
+	}
+	return keys
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, existing := range keys {
+		if existing == key {
+			return true
+		}
+	}
+	return false
+}
+
+// streamKey clave de idempotencia de un stream, para los índices en memoria
+type streamKey struct {
+	aggregateID string
+	key         string
+}
@@ -2,7 +2,7 @@ package eventstore
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -19,6 +19,16 @@ type MongoEventStore struct {
 	client     *mongo.Client
 	database   *mongo.Database
 	collection *mongo.Collection
+	counters   *mongo.Collection
+	archived   *mongo.Collection // último evento de los streams archivados por completo
+	cipher     PayloadCipher
+}
+
+// mongoEventDocument documento de un evento: el StoredEvent más la marca del outbox, que
+// se guarda en el mismo documento para que ambos se escriban de forma atómica
+type mongoEventDocument struct {
+	events.StoredEvent `bson:",inline"`
+	OutboxPending      bool `bson:"outbox_pending,omitempty"`
 }
 
 func NewMongoEventStore() (*MongoEventStore, error) {
@@ -41,55 +51,401 @@ func NewMongoEventStore() (*MongoEventStore, error) {
 	}
 
 	database := client.Database("escama")
-	collection := database.Collection("events")
-
-	fmt.Println("✅ Connected to MongoDB successfully")
 
-	return &MongoEventStore{
+	store := &MongoEventStore{
 		client:     client,
 		database:   database,
-		collection: collection,
-	}, nil
+		collection: database.Collection("events"),
+		counters:   database.Collection("counters"),
+		archived:   database.Collection("archived_streams"),
+	}
+
+	// Los eventos anteriores a la numeración reciben secuencia y posición global
+	// antes de crear los índices únicos
+	if err := store.backfillPositions(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := store.ensureIndexes(ctx); err != nil {
+		return nil, err
+	}
+
+	fmt.Println("✅ Connected to MongoDB successfully")
+
+	return store, nil
+}
+
+// SetCipher activa el cifrado de los campos sensibles de los payloads
+func (s *MongoEventStore) SetCipher(cipher PayloadCipher) {
+	s.cipher = cipher
+}
+
+func (s *MongoEventStore) ensureIndexes(ctx context.Context) error {
+	// Índice único por stream: dos escrituras concurrentes con la misma versión
+	// esperada no pueden insertar la misma secuencia
+	_, err := s.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "aggregate_id", Value: 1}, {Key: "sequence", Value: 1}},
+			Options: options.Index().
+				SetName("aggregate_sequence_unique").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"sequence": bson.M{"$exists": true}}),
+		},
+		{
+			Keys: bson.D{{Key: "global_position", Value: 1}},
+			Options: options.Index().
+				SetName("global_position_unique").
+				SetUnique(true).
+				SetPartialFilterExpression(bson.M{"global_position": bson.M{"$exists": true}}),
+		},
+		{
+			// Solo indexa los eventos pendientes de publicar
+			Keys: bson.D{{Key: "outbox_pending", Value: 1}, {Key: "global_position", Value: 1}},
+			Options: options.Index().
+				SetName("outbox_pending").
+				SetPartialFilterExpression(bson.M{"outbox_pending": true}),
+		},
+		{
+			Keys:    bson.D{{Key: "metadata.correlation_id", Value: 1}, {Key: "global_position", Value: 1}},
+			Options: options.Index().SetName("correlation_id"),
+		},
+		{
+			// Solo indexa los eventos guardados por comandos con clave de idempotencia
+			Keys: bson.D{{Key: "aggregate_id", Value: 1}, {Key: "metadata.idempotency_key", Value: 1}},
+			Options: options.Index().
+				SetName("idempotency_key").
+				SetPartialFilterExpression(bson.M{"metadata.idempotency_key": bson.M{"$exists": true}}),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create events indexes: %w", err)
+	}
+	return nil
+}
+
+// backfillPositions numera los eventos guardados antes de que existieran Sequence y
+// GlobalPosition, respetando el orden de occurred_at
+func (s *MongoEventStore) backfillPositions(ctx context.Context) error {
+	findOptions := options.Find().SetSort(bson.D{{Key: "occurred_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := s.collection.Find(ctx, bson.M{"global_position": bson.M{"$exists": false}}, findOptions)
+	if err != nil {
+		return fmt.Errorf("failed to query unnumbered events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var pending []events.StoredEvent
+	if err := cursor.All(ctx, &pending); err != nil {
+		return fmt.Errorf("failed to decode unnumbered events: %w", err)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	first, err := s.reservePositions(ctx, len(pending))
+	if err != nil {
+		return err
+	}
+
+	// Los eventos sin secuencia son los más antiguos de su stream
+	sequences := make(map[string]int)
+	for i, storedEvent := range pending {
+		set := bson.M{"global_position": first + int64(i)}
+		if storedEvent.Sequence == 0 {
+			sequences[storedEvent.AggregateID]++
+			set["sequence"] = sequences[storedEvent.AggregateID]
+		}
+
+		if _, err := s.collection.UpdateByID(ctx, storedEvent.ID, bson.M{"$set": set}); err != nil {
+			return fmt.Errorf("failed to number event %s: %w", storedEvent.ID, err)
+		}
+	}
+
+	fmt.Printf("🔢 %d eventos existentes numerados\n", len(pending))
+	return nil
+}
+
+// reservePositions reserva count posiciones globales consecutivas para eventos sin hash
+// (los anteriores a la cadena) y devuelve la primera. El hash del head no cambia: el
+// próximo lote se sigue encadenando al último evento con hash.
+func (s *MongoEventStore) reservePositions(ctx context.Context, count int) (int64, error) {
+	var counter struct {
+		Position int64 `bson:"position"`
+	}
+
+	err := s.counters.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": "events"},
+		bson.M{"$inc": bson.M{"position": int64(count)}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reserve global positions: %w", err)
+	}
+
+	return counter.Position - int64(count) + 1, nil
 }
 
-func (s *MongoEventStore) Store(ctx context.Context, aggregateID, aggregateType string, domainEvents []events.DomainEvent) error {
-	var docs []interface{}
+// maxAppendAttempts intentos de encadenar un lote cuando otros escritores mueven el contador
+const maxAppendAttempts = 10
 
-	for _, event := range domainEvents {
-		payload, err := s.serializeEvent(event)
+// errHeadChanged indica que otro escritor movió el contador entre la lectura y la
+// escritura del lote
+var errHeadChanged = errors.New("event store head changed")
+
+// chainHead estado del contador "events": posición global y hash del último evento
+type chainHead struct {
+	Position int64  `bson:"position"`
+	Hash     string `bson:"hash"`
+}
+
+func (s *MongoEventStore) readHead(ctx context.Context) (chainHead, error) {
+	var head chainHead
+	err := s.counters.FindOne(ctx, bson.M{"_id": "events"}).Decode(&head)
+	if err == mongo.ErrNoDocuments {
+		return chainHead{}, nil
+	}
+	if err != nil {
+		return chainHead{}, fmt.Errorf("failed to read event store head: %w", err)
+	}
+	return head, nil
+}
+
+// moveHead cambia el contador de from a to solo si nadie lo movió desde que se leyó.
+// Devuelve false si perdió la carrera contra otro escritor.
+func (s *MongoEventStore) moveHead(ctx context.Context, from, to chainHead) (bool, error) {
+	result, err := s.counters.UpdateOne(ctx,
+		bson.M{"_id": "events", "position": from.Position},
+		bson.M{"$set": bson.M{"position": to.Position, "hash": to.Hash}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		// El upsert choca con el _id cuando el contador existe con otra posición
+		if mongo.IsDuplicateKeyError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to move event store head: %w", err)
+	}
+	return result.MatchedCount > 0 || result.UpsertedCount > 0, nil
+}
+
+func (s *MongoEventStore) Store(ctx context.Context, aggregateID, aggregateType string, expectedVersion int, domainEvents []events.DomainEvent) error {
+	if len(domainEvents) == 0 {
+		return nil
+	}
+
+	actualVersion, prevStreamHash, err := s.streamHead(ctx, aggregateID)
+	if err != nil {
+		return err
+	}
+	if actualVersion != expectedVersion {
+		return &ConcurrencyError{
+			AggregateID:     aggregateID,
+			ExpectedVersion: expectedVersion,
+			ActualVersion:   actualVersion,
+		}
+	}
+
+	metadata := events.MetadataFromContext(ctx)
+	storedEvents := make([]events.StoredEvent, 0, len(domainEvents))
+	for i, event := range domainEvents {
+		payload, err := events.EncodePayload(event)
 		if err != nil {
 			return fmt.Errorf("failed to serialize event: %w", err)
 		}
+		payload, err = sealPayload(s.cipher, payload, metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt event: %w", err)
+		}
 
-		storedEvent := events.StoredEvent{
-			ID:            fmt.Sprintf("%s-%d", aggregateID, time.Now().UnixNano()),
+		sequence := expectedVersion + i + 1
+		storedEvents = append(storedEvents, events.StoredEvent{
+			ID:            fmt.Sprintf("%s-%d", aggregateID, sequence),
 			AggregateID:   aggregateID,
 			AggregateType: aggregateType,
+			Sequence:      sequence,
 			EventType:     event.EventType(),
+			SchemaVersion: events.SchemaVersion(event.EventType()),
 			Payload:       payload,
 			OccurredAt:    event.OccurredAt(),
+			Metadata:      metadata,
+		})
+	}
+
+	session, err := s.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	// El contador y los eventos se escriben en una transacción: quedan los dos o ninguno.
+	// WithTransaction solo reintenta los errores transitorios del servidor; si otro
+	// escritor movió el contador, moveHead no lo encuentra y el lote se vuelve a encadenar
+	// al nuevo head en otra transacción
+	appendBatch := func(sc mongo.SessionContext) (interface{}, error) {
+		head, err := s.readHead(sc)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range storedEvents {
+			storedEvents[i].GlobalPosition = head.Position + int64(i) + 1
+		}
+		if err := linkEvents(storedEvents, prevStreamHash, head.Hash); err != nil {
+			return nil, err
+		}
+
+		last := storedEvents[len(storedEvents)-1]
+		moved, err := s.moveHead(sc, head, chainHead{Position: last.GlobalPosition, Hash: last.Hash})
+		if err != nil {
+			return nil, err
+		}
+		if !moved {
+			return nil, errHeadChanged
 		}
 
-		docs = append(docs, storedEvent)
+		docs := make([]interface{}, 0, len(storedEvents))
+		for _, storedEvent := range storedEvents {
+			docs = append(docs, mongoEventDocument{StoredEvent: storedEvent, OutboxPending: true})
+		}
+		if _, err := s.collection.InsertMany(sc, docs); err != nil {
+			return nil, err
+		}
+		return nil, nil
 	}
 
-	if len(docs) == 0 {
-		return nil
+	for attempt := 1; ; attempt++ {
+		_, err = session.WithTransaction(ctx, appendBatch)
+		if !errors.Is(err, errHeadChanged) {
+			break
+		}
+		if attempt == maxAppendAttempts {
+			// Quien llame puede recargar el agregado y reintentar, como en cualquier conflicto
+			return fmt.Errorf("failed to append events: event store head kept changing: %w", ErrConcurrencyConflict)
+		}
 	}
+	if err != nil {
+		// Otro proceso escribió la misma secuencia entre la verificación y el insert
+		if mongo.IsDuplicateKeyError(err) {
+			actualVersion, _, _ = s.streamHead(ctx, aggregateID)
+			return &ConcurrencyError{
+				AggregateID:     aggregateID,
+				ExpectedVersion: expectedVersion,
+				ActualVersion:   actualVersion,
+			}
+		}
+		return fmt.Errorf("failed to append events: %w", err)
+	}
+
+	return nil
+}
 
-	_, err := s.collection.InsertMany(ctx, docs)
+func (s *MongoEventStore) HasIdempotencyKey(ctx context.Context, aggregateID, key string) (bool, error) {
+	filter := bson.M{"aggregate_id": aggregateID, "metadata.idempotency_key": key}
+	count, err := s.collection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
 	if err != nil {
-		return fmt.Errorf("failed to insert events: %w", err)
+		return false, fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+	return count > 0, nil
+}
+
+// streamHead devuelve la versión actual del stream y el hash de su último evento
+func (s *MongoEventStore) streamHead(ctx context.Context, aggregateID string) (int, string, error) {
+	findOptions := options.FindOne().
+		SetSort(bson.D{{Key: "sequence", Value: -1}}).
+		SetProjection(bson.M{"sequence": 1, "hash": 1})
+
+	var last events.StoredEvent
+	err := s.collection.FindOne(ctx, bson.M{"aggregate_id": aggregateID}, findOptions).Decode(&last)
+	if err == mongo.ErrNoDocuments {
+		var head archivedHead
+		err = s.archived.FindOne(ctx, bson.M{"_id": aggregateID}).Decode(&head)
+		if err == mongo.ErrNoDocuments {
+			return 0, "", nil
+		}
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to get archived stream version: %w", err)
+		}
+		return head.Sequence, head.Hash, nil
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to get stream version: %w", err)
+	}
+	return last.Sequence, last.Hash, nil
+}
+
+// RemoveArchived guarda el head de los streams archivados por completo y borra sus eventos
+func (s *MongoEventStore) RemoveArchived(ctx context.Context, position int64) error {
+	head, err := s.readHead(ctx)
+	if err != nil {
+		return err
+	}
+	if err := checkArchivable(position, head.Position); err != nil {
+		return err
+	}
+
+	// Último evento archivado de cada stream; los heads se guardan antes de borrar
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"global_position": bson.M{"$lte": position}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "sequence", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$aggregate_id",
+			"sequence": bson.M{"$last": "$sequence"},
+			"hash":     bson.M{"$last": "$hash"},
+		}}},
+	}
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to find archived streams: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var stream struct {
+			AggregateID  string `bson:"_id"`
+			archivedHead `bson:",inline"`
+		}
+		if err := cursor.Decode(&stream); err != nil {
+			return fmt.Errorf("failed to decode archived stream: %w", err)
+		}
+
+		remaining, err := s.collection.CountDocuments(ctx, bson.M{
+			"aggregate_id":    stream.AggregateID,
+			"global_position": bson.M{"$gt": position},
+		}, options.Count().SetLimit(1))
+		if err != nil {
+			return fmt.Errorf("failed to count stream events: %w", err)
+		}
+		if remaining > 0 {
+			continue
+		}
+
+		_, err = s.archived.ReplaceOne(ctx, bson.M{"_id": stream.AggregateID}, stream.archivedHead, options.Replace().SetUpsert(true))
+		if err != nil {
+			return fmt.Errorf("failed to save archived stream %s: %w", stream.AggregateID, err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to find archived streams: %w", err)
 	}
 
+	if _, err := s.collection.DeleteMany(ctx, bson.M{"global_position": bson.M{"$lte": position}}); err != nil {
+		return fmt.Errorf("failed to delete archived events: %w", err)
+	}
 	return nil
 }
 
 func (s *MongoEventStore) Load(ctx context.Context, aggregateID string) ([]events.StoredEvent, error) {
+	return s.LoadFrom(ctx, aggregateID, 0)
+}
+
+func (s *MongoEventStore) LoadFrom(ctx context.Context, aggregateID string, afterSequence int) ([]events.StoredEvent, error) {
 	filter := bson.M{"aggregate_id": aggregateID}
+	if afterSequence > 0 {
+		filter["sequence"] = bson.M{"$gt": afterSequence}
+	}
 
 	findOptions := options.Find()
-	findOptions.SetSort(bson.M{"occurred_at": 1})
+	findOptions.SetSort(bson.D{{Key: "sequence", Value: 1}})
 
 	cursor, err := s.collection.Find(ctx, filter, findOptions)
 	if err != nil {
@@ -102,52 +458,83 @@ func (s *MongoEventStore) Load(ctx context.Context, aggregateID string) ([]event
 		return nil, fmt.Errorf("failed to decode events: %w", err)
 	}
 
-	return storedEvents, nil
+	return readEvents(s.cipher, storedEvents)
 }
 
-func (s *MongoEventStore) GetAllEvents(ctx context.Context, startDate, endDate *time.Time) ([]events.StoredEvent, error) {
-	filter := bson.M{}
+func (s *MongoEventStore) GetAllEvents(ctx context.Context, filter TimeFilter) ([]events.StoredEvent, error) {
+	return collectEvents(ctx, s, StreamFilter{TimeFilter: filter})
+}
 
-	// Agregar filtros de fecha si se proporcionan
-	// Filtramos por la fecha del movimiento en el payload, no por occurred_at
-	if startDate != nil || endDate != nil {
-		dateFilter := bson.M{}
-		if startDate != nil {
-			// Incluir toda la fecha de inicio (desde las 00:00:00)
-			dateFilter["$gte"] = startDate.Format("2006-01-02")
+func (s *MongoEventStore) Stream(ctx context.Context, filter StreamFilter, fn StreamFunc) error {
+	query := bson.M{}
+	if filter.FromPosition > 0 {
+		query["global_position"] = bson.M{"$gt": filter.FromPosition}
+	}
+	if len(filter.AggregateTypes) > 0 {
+		query["aggregate_type"] = bson.M{"$in": filter.AggregateTypes}
+	}
+	if len(filter.EventTypes) > 0 {
+		query["event_type"] = bson.M{"$in": filter.EventTypes}
+	}
+	if filter.CorrelationID != "" {
+		query["metadata.correlation_id"] = filter.CorrelationID
+	}
+	if filter.Actor != "" {
+		query["metadata.actor"] = filter.Actor
+	}
+	if filter.Source != "" {
+		query["metadata.source"] = filter.Source
+	}
+	if filter.RecordedFrom != nil || filter.RecordedTo != nil {
+		occurredAt := bson.M{}
+		if filter.RecordedFrom != nil {
+			occurredAt["$gte"] = *filter.RecordedFrom
 		}
-		if endDate != nil {
-			// Incluir toda la fecha de fin (hasta las 23:59:59)
-			endOfDay := endDate.AddDate(0, 0, 1).Format("2006-01-02")
-			dateFilter["$lt"] = endOfDay
+		if filter.RecordedTo != nil {
+			occurredAt["$lte"] = *filter.RecordedTo
 		}
+		query["occurred_at"] = occurredAt
+	}
 
-		// Crear un filtro OR para buscar en diferentes campos de fecha del payload
-		orFilter := bson.A{
-			bson.M{"payload.Date": dateFilter},
-			bson.M{"payload.date": dateFilter},
-		}
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "global_position", Value: 1}})
+	findOptions.SetBatchSize(int32(filter.batchSize()))
+
+	cursor, err := s.collection.Find(ctx, query, findOptions)
+	if err != nil {
+		return fmt.Errorf("failed to query events: %w", err)
+	}
+	defer cursor.Close(ctx)
 
-		// Si también hay fechas en formato completo, incluirlas
-		if startDate != nil && endDate != nil {
-			startDateTime := *startDate
-			endDateTime := endDate.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	// El cursor trae los documentos por lotes; solo se decodifica uno a la vez
+	for cursor.Next(ctx) {
+		var storedEvent events.StoredEvent
+		if err := cursor.Decode(&storedEvent); err != nil {
+			return fmt.Errorf("failed to decode event: %w", err)
+		}
 
-			orFilter = append(orFilter,
-				bson.M{"payload.Date": bson.M{"$gte": startDateTime, "$lte": endDateTime}},
-				bson.M{"payload.date": bson.M{"$gte": startDateTime, "$lte": endDateTime}},
-			)
+		if err := deliver(filter, s.cipher, storedEvent, fn); err != nil {
+			if errors.Is(err, ErrStopStream) {
+				return nil
+			}
+			return err
 		}
+	}
 
-		filter["$or"] = orFilter
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to read events: %w", err)
 	}
+	return nil
+}
 
+func (s *MongoEventStore) Pending(ctx context.Context, limit int) ([]events.StoredEvent, error) {
 	findOptions := options.Find()
-	findOptions.SetSort(bson.M{"occurred_at": -1})
+	findOptions.SetSort(bson.D{{Key: "global_position", Value: 1}})
+	findOptions.SetLimit(int64(limit))
 
-	cursor, err := s.collection.Find(ctx, filter, findOptions)
+	cursor, err := s.collection.Find(ctx, bson.M{"outbox_pending": true}, findOptions)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query all events: %w", err)
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
 	}
 	defer cursor.Close(ctx)
 
@@ -156,25 +543,31 @@ func (s *MongoEventStore) GetAllEvents(ctx context.Context, startDate, endDate *
 		return nil, fmt.Errorf("failed to decode events: %w", err)
 	}
 
-	return storedEvents, nil
+	return readEvents(s.cipher, storedEvents)
 }
 
-func (s *MongoEventStore) Close() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	return s.client.Disconnect(ctx)
-}
+func (s *MongoEventStore) Ack(ctx context.Context, positions []int64) error {
+	if len(positions) == 0 {
+		return nil
+	}
 
-func (s *MongoEventStore) serializeEvent(event events.DomainEvent) (map[string]interface{}, error) {
-	data, err := json.Marshal(event)
+	_, err := s.collection.UpdateMany(ctx,
+		bson.M{"global_position": bson.M{"$in": positions}},
+		bson.M{"$unset": bson.M{"outbox_pending": ""}},
+	)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to ack outbox entries: %w", err)
 	}
+	return nil
+}
 
-	var payload map[string]interface{}
-	if err := json.Unmarshal(data, &payload); err != nil {
-		return nil, err
-	}
+// Client devuelve el cliente MongoDB para reutilizar la conexión en el modelo de lectura
+func (s *MongoEventStore) Client() *mongo.Client {
+	return s.client
+}
 
-	return payload, nil
+func (s *MongoEventStore) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.client.Disconnect(ctx)
 }
@@ -0,0 +1,17 @@
+package eventstore
+
+import (
+	"context"
+
+	"escama/domain/events"
+)
+
+// Outbox registra, en la misma escritura que los eventos, cuáles falta publicar.
+// Un evento queda pendiente desde que Store lo guarda hasta que se confirma con Ack, de
+// modo que una caída entre guardar y publicar no pierde la publicación.
+type Outbox interface {
+	// Pending devuelve hasta limit eventos sin publicar, en orden de posición global
+	Pending(ctx context.Context, limit int) ([]events.StoredEvent, error)
+	// Ack marca como publicados los eventos en las posiciones indicadas
+	Ack(ctx context.Context, positions []int64) error
+}
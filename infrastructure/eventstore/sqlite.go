@@ -0,0 +1,418 @@
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"escama/domain/events"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteTimeLayout formato de ancho fijo en UTC para que las fechas se ordenen como texto
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// sqliteEventColumns columnas leídas por scanEvents, en su orden
+const sqliteEventColumns = `global_position, id, aggregate_id, aggregate_type, sequence, event_type, schema_version,
+	payload, occurred_at, correlation_id, causation_id, actor, source, client_version, idempotency_key, hash,
+	prev_stream_hash, prev_global_hash`
+
+// SQLiteEventStore implementación del EventStore sobre una base SQLite embebida.
+// La posición global es el rowid autoincremental de la tabla events.
+type SQLiteEventStore struct {
+	db     *sql.DB
+	cipher PayloadCipher
+}
+
+func NewSQLiteEventStore(db *sql.DB) (*SQLiteEventStore, error) {
+	schema := `
+CREATE TABLE IF NOT EXISTS events (
+	global_position INTEGER PRIMARY KEY AUTOINCREMENT,
+	id              TEXT NOT NULL UNIQUE,
+	aggregate_id    TEXT NOT NULL,
+	aggregate_type  TEXT NOT NULL,
+	sequence        INTEGER NOT NULL,
+	event_type      TEXT NOT NULL,
+	schema_version  INTEGER NOT NULL DEFAULT 1,
+	payload         TEXT NOT NULL,
+	occurred_at     TEXT NOT NULL,
+	correlation_id  TEXT NOT NULL DEFAULT '',
+	causation_id    TEXT NOT NULL DEFAULT '',
+	actor           TEXT NOT NULL DEFAULT '',
+	source          TEXT NOT NULL DEFAULT '',
+	client_version  TEXT NOT NULL DEFAULT '',
+	idempotency_key TEXT NOT NULL DEFAULT '',
+	hash            TEXT NOT NULL DEFAULT '',
+	prev_stream_hash TEXT NOT NULL DEFAULT '',
+	prev_global_hash TEXT NOT NULL DEFAULT '',
+	UNIQUE (aggregate_id, sequence)
+);
+
+-- Eventos pendientes de publicar, insertados en la misma transacción que los eventos
+CREATE TABLE IF NOT EXISTS outbox (
+	global_position INTEGER PRIMARY KEY REFERENCES events (global_position)
+);
+
+-- Último evento de los streams cuyos eventos se archivaron por completo
+CREATE TABLE IF NOT EXISTS archived_streams (
+	aggregate_id TEXT PRIMARY KEY,
+	sequence     INTEGER NOT NULL,
+	hash         TEXT NOT NULL
+);`
+
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("failed to create events table: %w", err)
+	}
+
+	if err := addColumnIfMissing(db, "events", "schema_version", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return nil, err
+	}
+	for _, column := range []string{"correlation_id", "causation_id", "actor", "source", "client_version",
+		"idempotency_key", "hash", "prev_stream_hash", "prev_global_hash"} {
+		if err := addColumnIfMissing(db, "events", column, "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return nil, err
+		}
+	}
+	indexes := `
+CREATE INDEX IF NOT EXISTS events_by_correlation ON events (correlation_id, global_position);
+
+-- Solo los eventos guardados por comandos con clave de idempotencia
+CREATE INDEX IF NOT EXISTS events_by_idempotency_key ON events (aggregate_id, idempotency_key)
+	WHERE idempotency_key <> '';`
+	if _, err := db.Exec(indexes); err != nil {
+		return nil, fmt.Errorf("failed to create events indexes: %w", err)
+	}
+
+	return &SQLiteEventStore{db: db}, nil
+}
+
+// SetCipher activa el cifrado de los campos sensibles de los payloads
+func (s *SQLiteEventStore) SetCipher(cipher PayloadCipher) {
+	s.cipher = cipher
+}
+
+// addColumnIfMissing agrega una columna a una tabla creada por una versión anterior
+func addColumnIfMissing(db *sql.DB, table, column, definition string) error {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+func (s *SQLiteEventStore) Store(ctx context.Context, aggregateID, aggregateType string, expectedVersion int, domainEvents []events.DomainEvent) error {
+	if len(domainEvents) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// El último evento del stream da la versión actual y el hash al que se encadena el lote
+	var actualVersion int
+	var prevStreamHash, prevGlobalHash string
+	err = tx.QueryRowContext(ctx, `SELECT sequence, hash FROM events WHERE aggregate_id = ? ORDER BY sequence DESC LIMIT 1`,
+		aggregateID).Scan(&actualVersion, &prevStreamHash)
+	if err == sql.ErrNoRows {
+		err = tx.QueryRowContext(ctx, `SELECT sequence, hash FROM archived_streams WHERE aggregate_id = ?`,
+			aggregateID).Scan(&actualVersion, &prevStreamHash)
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get stream version: %w", err)
+	}
+	if actualVersion != expectedVersion {
+		return &ConcurrencyError{
+			AggregateID:     aggregateID,
+			ExpectedVersion: expectedVersion,
+			ActualVersion:   actualVersion,
+		}
+	}
+
+	// La transacción es IMMEDIATE: ningún otro escritor puede agregar eventos hasta el commit
+	var lastPosition int64
+	err = tx.QueryRowContext(ctx, `SELECT global_position, hash FROM events ORDER BY global_position DESC LIMIT 1`).
+		Scan(&lastPosition, &prevGlobalHash)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get last event: %w", err)
+	}
+
+	metadata := events.MetadataFromContext(ctx)
+	storedEvents := make([]events.StoredEvent, 0, len(domainEvents))
+	for i, event := range domainEvents {
+		payload, err := events.EncodePayload(event)
+		if err != nil {
+			return fmt.Errorf("failed to serialize event: %w", err)
+		}
+		payload, err = sealPayload(s.cipher, payload, metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt event: %w", err)
+		}
+
+		sequence := expectedVersion + i + 1
+		storedEvents = append(storedEvents, events.StoredEvent{
+			ID:             fmt.Sprintf("%s-%d", aggregateID, sequence),
+			AggregateID:    aggregateID,
+			AggregateType:  aggregateType,
+			Sequence:       sequence,
+			GlobalPosition: lastPosition + int64(i) + 1,
+			EventType:      event.EventType(),
+			SchemaVersion:  events.SchemaVersion(event.EventType()),
+			Payload:        payload,
+			OccurredAt:     event.OccurredAt(),
+			Metadata:       metadata,
+		})
+	}
+
+	if err := linkEvents(storedEvents, prevStreamHash, prevGlobalHash); err != nil {
+		return err
+	}
+
+	for i, storedEvent := range storedEvents {
+		payload, err := json.Marshal(storedEvent.Payload)
+		if err != nil {
+			return fmt.Errorf("failed to serialize event: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+INSERT INTO events (global_position, id, aggregate_id, aggregate_type, sequence, event_type, schema_version, payload,
+	occurred_at, correlation_id, causation_id, actor, source, client_version, idempotency_key, hash, prev_stream_hash,
+	prev_global_hash)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			storedEvent.GlobalPosition, storedEvent.ID, aggregateID, aggregateType, storedEvent.Sequence,
+			storedEvent.EventType, storedEvent.SchemaVersion, string(payload),
+			storedEvent.OccurredAt.UTC().Format(sqliteTimeLayout), metadata.CorrelationID, metadata.CausationID,
+			metadata.Actor, metadata.Source, metadata.ClientVersion, metadata.IdempotencyKey, storedEvent.Hash,
+			storedEvent.PrevStreamHash, storedEvent.PrevGlobalHash)
+		if err != nil {
+			var sqliteErr sqlite3.Error
+			if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+				return &ConcurrencyError{
+					AggregateID:     aggregateID,
+					ExpectedVersion: expectedVersion,
+					ActualVersion:   expectedVersion + i + 1,
+				}
+			}
+			return fmt.Errorf("failed to insert events: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `INSERT INTO outbox (global_position) VALUES (?)`, storedEvent.GlobalPosition); err != nil {
+			return fmt.Errorf("failed to insert outbox entry: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit events: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLiteEventStore) HasIdempotencyKey(ctx context.Context, aggregateID, key string) (bool, error) {
+	var found bool
+	err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM events WHERE aggregate_id = ? AND idempotency_key = ? AND idempotency_key <> '')`,
+		aggregateID, key).Scan(&found)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up idempotency key: %w", err)
+	}
+	return found, nil
+}
+
+func (s *SQLiteEventStore) Load(ctx context.Context, aggregateID string) ([]events.StoredEvent, error) {
+	return s.LoadFrom(ctx, aggregateID, 0)
+}
+
+func (s *SQLiteEventStore) LoadFrom(ctx context.Context, aggregateID string, afterSequence int) ([]events.StoredEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+sqliteEventColumns+`
+FROM events WHERE aggregate_id = ? AND sequence > ? ORDER BY sequence`, aggregateID, afterSequence)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+
+	storedEvents, err := s.scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+	return readEvents(s.cipher, storedEvents)
+}
+
+func (s *SQLiteEventStore) GetAllEvents(ctx context.Context, filter TimeFilter) ([]events.StoredEvent, error) {
+	return collectEvents(ctx, s, StreamFilter{TimeFilter: filter})
+}
+
+func (s *SQLiteEventStore) Stream(ctx context.Context, filter StreamFilter, fn StreamFunc) error {
+	return streamBatches(filter, s.cipher, fn, func(afterPosition int64, limit int) ([]events.StoredEvent, error) {
+		query := `
+SELECT ` + sqliteEventColumns + `
+FROM events WHERE global_position > ?`
+		args := []interface{}{afterPosition}
+
+		query, args = appendInFilter(query, args, "aggregate_type", filter.AggregateTypes)
+		query, args = appendInFilter(query, args, "event_type", filter.EventTypes)
+		query, args = appendEqualFilter(query, args, "correlation_id", filter.CorrelationID)
+		query, args = appendEqualFilter(query, args, "actor", filter.Actor)
+		query, args = appendEqualFilter(query, args, "source", filter.Source)
+		if filter.RecordedFrom != nil {
+			query += " AND occurred_at >= ?"
+			args = append(args, filter.RecordedFrom.UTC().Format(sqliteTimeLayout))
+		}
+		if filter.RecordedTo != nil {
+			query += " AND occurred_at <= ?"
+			args = append(args, filter.RecordedTo.UTC().Format(sqliteTimeLayout))
+		}
+		query += " ORDER BY global_position LIMIT ?"
+		args = append(args, limit)
+
+		// Cada lote es una consulta independiente para no mantener abierta una lectura
+		// mientras el callback escribe en la misma base
+		rows, err := s.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query events: %w", err)
+		}
+		return s.scanEvents(rows)
+	})
+}
+
+func (s *SQLiteEventStore) Pending(ctx context.Context, limit int) ([]events.StoredEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT `+sqliteEventColumns+`
+FROM events WHERE global_position IN (SELECT global_position FROM outbox ORDER BY global_position LIMIT ?)
+ORDER BY global_position`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	storedEvents, err := s.scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+	return readEvents(s.cipher, storedEvents)
+}
+
+func (s *SQLiteEventStore) Ack(ctx context.Context, positions []int64) error {
+	if len(positions) == 0 {
+		return nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(positions)), ", ")
+	args := make([]interface{}, len(positions))
+	for i, position := range positions {
+		args[i] = position
+	}
+
+	query := fmt.Sprintf("DELETE FROM outbox WHERE global_position IN (%s)", placeholders)
+	if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to ack outbox entries: %w", err)
+	}
+	return nil
+}
+
+// appendInFilter agrega "AND column IN (?, ...)" cuando hay valores
+func appendInFilter(query string, args []interface{}, column string, values []string) (string, []interface{}) {
+	if len(values) == 0 {
+		return query, args
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	query += fmt.Sprintf(" AND %s IN (%s)", column, placeholders)
+	for _, value := range values {
+		args = append(args, value)
+	}
+	return query, args
+}
+
+// appendEqualFilter agrega "AND column = ?" cuando el valor no está vacío
+func appendEqualFilter(query string, args []interface{}, column, value string) (string, []interface{}) {
+	if value == "" {
+		return query, args
+	}
+	return query + fmt.Sprintf(" AND %s = ?", column), append(args, value)
+}
+
+// RemoveArchived borra los eventos ya archivados en una sola transacción
+func (s *SQLiteEventStore) RemoveArchived(ctx context.Context, position int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var lastPosition int64
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(global_position), 0) FROM events`).Scan(&lastPosition); err != nil {
+		return fmt.Errorf("failed to get last event: %w", err)
+	}
+	if err := checkArchivable(position, lastPosition); err != nil {
+		return err
+	}
+
+	// Streams cuyo último evento se archiva
+	_, err = tx.ExecContext(ctx, `
+INSERT INTO archived_streams (aggregate_id, sequence, hash)
+SELECT aggregate_id, sequence, hash FROM events e
+WHERE global_position <= ? AND sequence = (SELECT MAX(sequence) FROM events WHERE aggregate_id = e.aggregate_id)
+ON CONFLICT (aggregate_id) DO UPDATE SET sequence = excluded.sequence, hash = excluded.hash`, position)
+	if err != nil {
+		return fmt.Errorf("failed to save archived streams: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM outbox WHERE global_position <= ?`, position); err != nil {
+		return fmt.Errorf("failed to delete outbox entries: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM events WHERE global_position <= ?`, position); err != nil {
+		return fmt.Errorf("failed to delete archived events: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit archived events: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteEventStore) scanEvents(rows *sql.Rows) ([]events.StoredEvent, error) {
+	defer rows.Close()
+
+	var storedEvents []events.StoredEvent
+	for rows.Next() {
+		var storedEvent events.StoredEvent
+		var payload, occurredAt string
+
+		err := rows.Scan(&storedEvent.GlobalPosition, &storedEvent.ID, &storedEvent.AggregateID,
+			&storedEvent.AggregateType, &storedEvent.Sequence, &storedEvent.EventType, &storedEvent.SchemaVersion,
+			&payload, &occurredAt, &storedEvent.Metadata.CorrelationID, &storedEvent.Metadata.CausationID,
+			&storedEvent.Metadata.Actor, &storedEvent.Metadata.Source, &storedEvent.Metadata.ClientVersion,
+			&storedEvent.Metadata.IdempotencyKey, &storedEvent.Hash, &storedEvent.PrevStreamHash, &storedEvent.PrevGlobalHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode events: %w", err)
+		}
+
+		if err := json.Unmarshal([]byte(payload), &storedEvent.Payload); err != nil {
+			return nil, fmt.Errorf("failed to decode payload of event %s: %w", storedEvent.ID, err)
+		}
+		if storedEvent.OccurredAt, err = time.Parse(sqliteTimeLayout, occurredAt); err != nil {
+			return nil, fmt.Errorf("failed to decode occurred_at of event %s: %w", storedEvent.ID, err)
+		}
+
+		storedEvents = append(storedEvents, storedEvent)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to decode events: %w", err)
+	}
+
+	return storedEvents, nil
+}
@@ -0,0 +1,169 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"escama/domain/events"
+)
+
+// defaultStreamBatchSize cantidad de eventos leídos por lote cuando el filtro no la indica
+const defaultStreamBatchSize = 500
+
+// ErrStopStream puede devolverse desde el callback de Stream para terminar la lectura
+// antes de tiempo sin que Stream devuelva un error
+var ErrStopStream = errors.New("stop stream")
+
+// TimeFilter rangos de fechas de los eventos, con límites inclusivos; nil no limita.
+//
+// RecordedFrom y RecordedTo comparan el momento en que se registró el evento (OccurredAt).
+// BusinessFrom y BusinessTo comparan días completos: el día del movimiento según la fecha
+// de su payload (ver events.StoredEvent.BusinessDay) contra el día de cada límite en su
+// propia zona horaria. Los eventos sin fecha de negocio quedan fuera cuando se usan.
+type TimeFilter struct {
+	RecordedFrom *time.Time
+	RecordedTo   *time.Time
+	BusinessFrom *time.Time
+	BusinessTo   *time.Time
+}
+
+// StreamFilter selecciona los eventos que recorre Stream. Los campos vacíos no filtran.
+// CorrelationID, Actor y Source filtran por la metadata registrada con cada evento.
+// Raw entrega los eventos tal como están almacenados, sin descifrarlos ni llevarlos a la
+// versión de esquema actual (por ejemplo, para verificar sus hashes).
+type StreamFilter struct {
+	TimeFilter
+	AggregateTypes []string
+	EventTypes     []string
+	CorrelationID  string
+	Actor          string
+	Source         string
+	FromPosition   int64 // se leen solo los eventos con GlobalPosition mayor
+	BatchSize      int   // eventos por lote; 0 usa el valor por defecto
+	Raw            bool
+}
+
+// StreamFunc recibe cada evento, ya descifrado y llevado a la versión de esquema actual
+// salvo con Raw
+type StreamFunc func(event events.StoredEvent) error
+
+func (f StreamFilter) batchSize() int {
+	if f.BatchSize > 0 {
+		return f.BatchSize
+	}
+	return defaultStreamBatchSize
+}
+
+func (f StreamFilter) matches(event events.StoredEvent) bool {
+	if event.GlobalPosition <= f.FromPosition {
+		return false
+	}
+	if len(f.AggregateTypes) > 0 && !containsString(f.AggregateTypes, event.AggregateType) {
+		return false
+	}
+	if len(f.EventTypes) > 0 && !containsString(f.EventTypes, event.EventType) {
+		return false
+	}
+	if f.CorrelationID != "" && event.Metadata.CorrelationID != f.CorrelationID {
+		return false
+	}
+	if f.Actor != "" && event.Metadata.Actor != f.Actor {
+		return false
+	}
+	if f.Source != "" && event.Metadata.Source != f.Source {
+		return false
+	}
+	return true
+}
+
+// matches aplica los rangos de fechas. Todos los backends lo evalúan al entregar cada
+// evento (ver deliver), aunque adelanten parte del filtro en su consulta, para que una
+// misma consulta responda igual en cualquiera de ellos.
+func (f TimeFilter) matches(event events.StoredEvent) bool {
+	if f.RecordedFrom != nil && event.OccurredAt.Before(*f.RecordedFrom) {
+		return false
+	}
+	if f.RecordedTo != nil && event.OccurredAt.After(*f.RecordedTo) {
+		return false
+	}
+	if f.BusinessFrom == nil && f.BusinessTo == nil {
+		return true
+	}
+
+	day, ok := event.BusinessDay()
+	if !ok {
+		return false
+	}
+	if f.BusinessFrom != nil && day < f.BusinessFrom.Format(events.BusinessDayLayout) {
+		return false
+	}
+	if f.BusinessTo != nil && day > f.BusinessTo.Format(events.BusinessDayLayout) {
+		return false
+	}
+	return true
+}
+
+// streamBatches recorre los eventos en lotes pedidos a next, que recibe la última
+// posición entregada. El callback se invoca fuera de cualquier lock o cursor del store.
+func streamBatches(filter StreamFilter, cipher PayloadCipher, fn StreamFunc, next func(afterPosition int64, limit int) ([]events.StoredEvent, error)) error {
+	position := filter.FromPosition
+	for {
+		batch, err := next(position, filter.batchSize())
+		if err != nil {
+			return err
+		}
+
+		for _, storedEvent := range batch {
+			if err := deliver(filter, cipher, storedEvent, fn); err != nil {
+				if errors.Is(err, ErrStopStream) {
+					return nil
+				}
+				return err
+			}
+			position = storedEvent.GlobalPosition
+		}
+
+		if len(batch) < filter.batchSize() {
+			return nil
+		}
+	}
+}
+
+// deliver entrega un evento al callback, descifrado y llevado a la versión de esquema
+// actual salvo que el filtro pida los eventos tal como están almacenados
+func deliver(filter StreamFilter, cipher PayloadCipher, storedEvent events.StoredEvent, fn StreamFunc) error {
+	if !filter.TimeFilter.matches(storedEvent) {
+		return nil
+	}
+	if !filter.Raw {
+		read, err := readEvent(cipher, storedEvent)
+		if err != nil {
+			return err
+		}
+		storedEvent = read
+	}
+	return fn(storedEvent)
+}
+
+// collectEvents reúne en memoria los eventos que recorre Stream con el filtro dado
+func collectEvents(ctx context.Context, store EventStore, filter StreamFilter) ([]events.StoredEvent, error) {
+	var storedEvents []events.StoredEvent
+	err := store.Stream(ctx, filter, func(storedEvent events.StoredEvent) error {
+		storedEvents = append(storedEvents, storedEvent)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return storedEvents, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,846 @@
+package projections
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"escama/domain/events"
+	"escama/domain/money"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoProjectionStore maneja las proyecciones en MongoDB
+type MongoProjectionStore struct {
+	client               *mongo.Client
+	database             *mongo.Database
+	movementsCollection  *mongo.Collection
+	categoriesCollection *mongo.Collection
+	accountsCollection   *mongo.Collection
+	transfersCollection  *mongo.Collection
+	sealer               Sealer
+}
+
+func NewMongoProjectionStore(client *mongo.Client, databaseName string) *MongoProjectionStore {
+	database := client.Database(databaseName)
+
+	return &MongoProjectionStore{
+		client:               client,
+		database:             database,
+		movementsCollection:  database.Collection("movements"),
+		categoriesCollection: database.Collection("categories"),
+		accountsCollection:   database.Collection("accounts"),
+		transfersCollection:  database.Collection("transfers"),
+	}
+}
+
+// MigrateAmounts lleva los movimientos proyectados antes de que los importes tuvieran
+// moneda (montos double en guaraníes) al formato de money.Money en PYG
+func (ps *MongoProjectionStore) MigrateAmounts(ctx context.Context) error {
+	_, err := ps.movementsCollection.UpdateMany(ctx,
+		bson.M{"amount": bson.M{"$type": "number"}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"amount": bson.M{
+				"amount":   bson.M{"$toLong": bson.M{"$round": bson.A{"$amount", 0}}},
+				"currency": money.PYG,
+			},
+		}}}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to migrate movement amounts: %w", err)
+	}
+	return nil
+}
+
+// EnsureIndexes crea los índices de las consultas de movimientos
+func (ps *MongoProjectionStore) EnsureIndexes(ctx context.Context) error {
+	_, err := ps.movementsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "is_deleted", Value: 1}, {Key: "date", Value: -1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("movements_by_date"),
+		},
+		{
+			// Índice multiclave: una entrada por cada etiqueta del movimiento
+			Keys:    bson.D{{Key: "tags", Value: 1}},
+			Options: options.Index().SetName("movements_by_tag"),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create movement projection indexes: %w", err)
+	}
+	return nil
+}
+
+// SetSealer activa el cifrado del importe y la descripción de los movimientos
+func (ps *MongoProjectionStore) SetSealer(sealer Sealer) {
+	ps.sealer = sealer
+}
+
+// ProcessEvent procesa un evento y actualiza las proyecciones
+func (ps *MongoProjectionStore) ProcessEvent(ctx context.Context, event events.StoredEvent) error {
+	return dispatchEvent(ctx, ps, event)
+}
+
+func (ps *MongoProjectionStore) handleCategoryCreated(ctx context.Context, category CategoryProjection) error {
+	if category.ID == "" || category.Name == "" {
+		return fmt.Errorf("invalid category created event: missing required fields")
+	}
+
+	_, err := ps.categoriesCollection.ReplaceOne(
+		ctx,
+		newerThan(category.ID, category.Version),
+		category,
+		options.Replace().SetUpsert(true),
+	)
+
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("failed to upsert category projection: %w", err)
+	}
+
+	log.Printf("Category projection updated: %s - %s", category.ID, category.Name)
+	return nil
+}
+
+func (ps *MongoProjectionStore) handleCategoryParentChanged(ctx context.Context, categoryID, parentID string, occurredAt time.Time, sequence int) error {
+	update := bson.M{
+		"$set": bson.M{
+			"parent_id":  parentID,
+			"updated_at": occurredAt,
+			"version":    sequence,
+		},
+	}
+
+	if _, err := ps.categoriesCollection.UpdateOne(ctx, newerThan(categoryID, sequence), update); err != nil {
+		return fmt.Errorf("failed to move category projection: %w", err)
+	}
+
+	log.Printf("Category projection moved: %s -> %q", categoryID, parentID)
+	return nil
+}
+
+func (ps *MongoProjectionStore) handleCategoryKindChanged(ctx context.Context, categoryID, kind string, occurredAt time.Time, sequence int) error {
+	update := bson.M{
+		"$set": bson.M{
+			"kind":       kind,
+			"updated_at": occurredAt,
+			"version":    sequence,
+		},
+	}
+
+	if _, err := ps.categoriesCollection.UpdateOne(ctx, newerThan(categoryID, sequence), update); err != nil {
+		return fmt.Errorf("failed to change category projection kind: %w", err)
+	}
+
+	log.Printf("Category projection kind changed: %s - %s", categoryID, kind)
+	return nil
+}
+
+// handleCategoryRenamed renombra la categoría y, si el evento es más nuevo que la
+// proyección, actualiza el nombre en todos sus movimientos
+func (ps *MongoProjectionStore) handleCategoryRenamed(ctx context.Context, categoryID, name string, occurredAt time.Time, sequence int) error {
+	update := bson.M{
+		"$set": bson.M{
+			"name":       name,
+			"updated_at": occurredAt,
+			"version":    sequence,
+		},
+	}
+
+	result, err := ps.categoriesCollection.UpdateOne(ctx, newerThan(categoryID, sequence), update)
+	if err != nil {
+		return fmt.Errorf("failed to rename category projection: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return nil
+	}
+
+	_, err = ps.movementsCollection.UpdateMany(ctx,
+		bson.M{"category_id": categoryID},
+		bson.M{"$set": bson.M{"category_name": name}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to rename category in movements: %w", err)
+	}
+
+	log.Printf("Category projection renamed: %s - %s", categoryID, name)
+	return nil
+}
+
+func (ps *MongoProjectionStore) handleCategoryArchived(ctx context.Context, categoryID string, archived bool, occurredAt time.Time, sequence int) error {
+	update := bson.M{
+		"$set": bson.M{
+			"archived":   archived,
+			"updated_at": occurredAt,
+			"version":    sequence,
+		},
+	}
+
+	if _, err := ps.categoriesCollection.UpdateOne(ctx, newerThan(categoryID, sequence), update); err != nil {
+		return fmt.Errorf("failed to archive category projection: %w", err)
+	}
+
+	log.Printf("Category projection archived: %s - %t", categoryID, archived)
+	return nil
+}
+
+func (ps *MongoProjectionStore) handleCategoryDeleted(ctx context.Context, categoryID string, occurredAt time.Time, sequence int) error {
+	update := bson.M{
+		"$set": bson.M{
+			"is_deleted": true,
+			"updated_at": occurredAt,
+			"version":    sequence,
+		},
+	}
+
+	if _, err := ps.categoriesCollection.UpdateOne(ctx, newerThan(categoryID, sequence), update); err != nil {
+		return fmt.Errorf("failed to delete category projection: %w", err)
+	}
+
+	log.Printf("Category projection deleted: %s", categoryID)
+	return nil
+}
+
+// handleCategoryMerged elimina la categoría y pasa sus movimientos y subcategorías a la
+// categoría destino
+func (ps *MongoProjectionStore) handleCategoryMerged(ctx context.Context, categoryID, targetID string, occurredAt time.Time, sequence int) error {
+	update := bson.M{
+		"$set": bson.M{
+			"is_deleted": true,
+			"updated_at": occurredAt,
+			"version":    sequence,
+		},
+	}
+
+	result, err := ps.categoriesCollection.UpdateOne(ctx, newerThan(categoryID, sequence), update)
+	if err != nil {
+		return fmt.Errorf("failed to merge category projection: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return nil
+	}
+
+	_, err = ps.movementsCollection.UpdateMany(ctx,
+		bson.M{"category_id": categoryID},
+		bson.M{"$set": bson.M{"category_id": targetID, "category_name": ps.categoryName(ctx, targetID)}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to move movements to merged category: %w", err)
+	}
+
+	_, err = ps.categoriesCollection.UpdateMany(ctx,
+		bson.M{"parent_id": categoryID},
+		bson.M{"$set": bson.M{"parent_id": targetID}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to move subcategories to merged category: %w", err)
+	}
+
+	log.Printf("Category projection merged: %s -> %s", categoryID, targetID)
+	return nil
+}
+
+func (ps *MongoProjectionStore) handleMovementCreated(ctx context.Context, change movementChange) error {
+	if change.ID == "" {
+		return fmt.Errorf("invalid %s created event: missing ID", change.Type)
+	}
+
+	date := change.Date
+	if date.IsZero() {
+		date = change.OccurredAt
+	}
+
+	amount, description, sealed, err := sealMovement(ps.sealer, change.Owner, change.Amount, change.Description)
+	if err != nil {
+		return err
+	}
+
+	movement := MovementProjection{
+		ID:           change.ID,
+		Type:         change.Type,
+		AccountID:    change.AccountID,
+		CategoryID:   change.CategoryID,
+		CategoryName: ps.categoryName(ctx, change.CategoryID),
+		Amount:       amount,
+		Description:  description,
+		Date:         date,
+		CreatedAt:    change.OccurredAt,
+		UpdatedAt:    change.OccurredAt,
+		IsDeleted:    false,
+		Version:      change.Sequence,
+		Sealed:       sealed,
+	}
+
+	_, err = ps.movementsCollection.ReplaceOne(
+		ctx,
+		newerThan(change.ID, change.Sequence),
+		movement,
+		options.Replace().SetUpsert(true),
+	)
+
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("failed to upsert movement projection: %w", err)
+	}
+
+	log.Printf("%s projection updated: %s - %s", change.Type, change.ID, change.Amount)
+	return nil
+}
+
+func (ps *MongoProjectionStore) handleMovementUpdated(ctx context.Context, change movementChange) error {
+	if change.ID == "" {
+		return fmt.Errorf("invalid %s updated event: missing ID", change.Type)
+	}
+
+	amount, description, sealed, err := sealMovement(ps.sealer, change.Owner, change.Amount, change.Description)
+	if err != nil {
+		return err
+	}
+
+	// Actualizar la proyección con los nuevos valores del evento
+	update := bson.M{
+		"$set": bson.M{
+			"account_id":    change.AccountID,
+			"category_id":   change.CategoryID,
+			"category_name": ps.categoryName(ctx, change.CategoryID),
+			"amount":        amount,
+			"description":   description,
+			"sealed":        sealed,
+			"date":          change.Date,
+			"updated_at":    change.OccurredAt,
+			"version":       change.Sequence,
+		},
+	}
+
+	_, err = ps.movementsCollection.UpdateOne(ctx, newerThan(change.ID, change.Sequence), update)
+	if err != nil {
+		return fmt.Errorf("failed to update movement projection: %w", err)
+	}
+
+	log.Printf("%s projection updated: %s", change.Type, change.ID)
+	return nil
+}
+
+func (ps *MongoProjectionStore) handleMovementDeleted(ctx context.Context, movementType, movementID string, occurredAt time.Time, sequence int) error {
+	if movementID == "" {
+		return fmt.Errorf("invalid %s deleted event: missing ID", movementType)
+	}
+
+	// Marcar como eliminado (soft delete)
+	update := bson.M{
+		"$set": bson.M{
+			"is_deleted": true,
+			"updated_at": occurredAt,
+			"version":    sequence,
+		},
+	}
+
+	_, err := ps.movementsCollection.UpdateOne(ctx, newerThan(movementID, sequence), update)
+	if err != nil {
+		return fmt.Errorf("failed to delete movement projection: %w", err)
+	}
+
+	log.Printf("%s projection deleted: %s", movementType, movementID)
+	return nil
+}
+
+// handleMovementTagged agrega o quita una etiqueta del movimiento
+func (ps *MongoProjectionStore) handleMovementTagged(ctx context.Context, movementType, movementID, tag string, added bool, occurredAt time.Time, sequence int) error {
+	update := bson.M{
+		"$set": bson.M{
+			"updated_at": occurredAt,
+			"version":    sequence,
+		},
+	}
+	if added {
+		update["$addToSet"] = bson.M{"tags": tag}
+	} else {
+		update["$pull"] = bson.M{"tags": tag}
+	}
+
+	if _, err := ps.movementsCollection.UpdateOne(ctx, newerThan(movementID, sequence), update); err != nil {
+		return fmt.Errorf("failed to tag movement projection: %w", err)
+	}
+
+	log.Printf("%s projection tagged: %s - %s", movementType, movementID, tag)
+	return nil
+}
+
+func (ps *MongoProjectionStore) handleAccountCreated(ctx context.Context, account AccountProjection) error {
+	if account.ID == "" || account.Name == "" {
+		return fmt.Errorf("invalid account created event: missing required fields")
+	}
+
+	_, err := ps.accountsCollection.ReplaceOne(
+		ctx,
+		newerThan(account.ID, account.Version),
+		account,
+		options.Replace().SetUpsert(true),
+	)
+
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("failed to upsert account projection: %w", err)
+	}
+
+	log.Printf("Account projection updated: %s - %s", account.ID, account.Name)
+	return nil
+}
+
+func (ps *MongoProjectionStore) handleAccountRenamed(ctx context.Context, accountID, name string, occurredAt time.Time, sequence int) error {
+	update := bson.M{
+		"$set": bson.M{
+			"name":       name,
+			"updated_at": occurredAt,
+			"version":    sequence,
+		},
+	}
+
+	if _, err := ps.accountsCollection.UpdateOne(ctx, newerThan(accountID, sequence), update); err != nil {
+		return fmt.Errorf("failed to rename account projection: %w", err)
+	}
+
+	log.Printf("Account projection renamed: %s - %s", accountID, name)
+	return nil
+}
+
+func (ps *MongoProjectionStore) handleAccountOpeningBalanceSet(ctx context.Context, change openingBalanceChange) error {
+	amount, _, sealed, err := sealMovement(ps.sealer, change.Owner, change.Amount, nil)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"opening_balance": amount,
+			"opening_date":    change.Date,
+			"sealed":          sealed,
+			"updated_at":      change.OccurredAt,
+			"version":         change.Sequence,
+		},
+	}
+
+	if _, err := ps.accountsCollection.UpdateOne(ctx, newerThan(change.AccountID, change.Sequence), update); err != nil {
+		return fmt.Errorf("failed to update account opening balance: %w", err)
+	}
+
+	log.Printf("Account projection opening balance set: %s", change.AccountID)
+	return nil
+}
+
+func (ps *MongoProjectionStore) handleAccountClosed(ctx context.Context, accountID string, occurredAt time.Time, sequence int) error {
+	update := bson.M{
+		"$set": bson.M{
+			"closed":     true,
+			"updated_at": occurredAt,
+			"version":    sequence,
+		},
+	}
+
+	if _, err := ps.accountsCollection.UpdateOne(ctx, newerThan(accountID, sequence), update); err != nil {
+		return fmt.Errorf("failed to close account projection: %w", err)
+	}
+
+	log.Printf("Account projection closed: %s", accountID)
+	return nil
+}
+
+func (ps *MongoProjectionStore) handleTransferCreated(ctx context.Context, change transferChange) error {
+	if change.ID == "" {
+		return fmt.Errorf("invalid transfer created event: missing ID")
+	}
+
+	transfer, err := change.projection(ps.sealer)
+	if err != nil {
+		return err
+	}
+
+	_, err = ps.transfersCollection.ReplaceOne(
+		ctx,
+		newerThan(change.ID, change.Sequence),
+		transfer,
+		options.Replace().SetUpsert(true),
+	)
+
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("failed to upsert transfer projection: %w", err)
+	}
+
+	log.Printf("transfer projection updated: %s - %s", change.ID, change.Amount)
+	return nil
+}
+
+func (ps *MongoProjectionStore) handleTransferUpdated(ctx context.Context, change transferChange) error {
+	if change.ID == "" {
+		return fmt.Errorf("invalid transfer updated event: missing ID")
+	}
+
+	transfer, err := change.projection(ps.sealer)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"from_account_id": transfer.FromAccountID,
+			"to_account_id":   transfer.ToAccountID,
+			"amount":          transfer.Amount,
+			"received_amount": transfer.ReceivedAmount,
+			"description":     transfer.Description,
+			"sealed":          transfer.Sealed,
+			"date":            transfer.Date,
+			"updated_at":      change.OccurredAt,
+			"version":         change.Sequence,
+		},
+	}
+
+	if _, err := ps.transfersCollection.UpdateOne(ctx, newerThan(change.ID, change.Sequence), update); err != nil {
+		return fmt.Errorf("failed to update transfer projection: %w", err)
+	}
+
+	log.Printf("transfer projection updated: %s", change.ID)
+	return nil
+}
+
+func (ps *MongoProjectionStore) handleTransferDeleted(ctx context.Context, transferID string, occurredAt time.Time, sequence int) error {
+	if transferID == "" {
+		return fmt.Errorf("invalid transfer deleted event: missing ID")
+	}
+
+	// Marcar como eliminada (soft delete)
+	update := bson.M{
+		"$set": bson.M{
+			"is_deleted": true,
+			"updated_at": occurredAt,
+			"version":    sequence,
+		},
+	}
+
+	if _, err := ps.transfersCollection.UpdateOne(ctx, newerThan(transferID, sequence), update); err != nil {
+		return fmt.Errorf("failed to delete transfer projection: %w", err)
+	}
+
+	log.Printf("transfer projection deleted: %s", transferID)
+	return nil
+}
+
+func (ps *MongoProjectionStore) Reset(ctx context.Context) error {
+	if _, err := ps.movementsCollection.DeleteMany(ctx, bson.M{}); err != nil {
+		return fmt.Errorf("failed to reset movement projections: %w", err)
+	}
+	if _, err := ps.categoriesCollection.DeleteMany(ctx, bson.M{}); err != nil {
+		return fmt.Errorf("failed to reset category projections: %w", err)
+	}
+	if _, err := ps.accountsCollection.DeleteMany(ctx, bson.M{}); err != nil {
+		return fmt.Errorf("failed to reset account projections: %w", err)
+	}
+	if _, err := ps.transfersCollection.DeleteMany(ctx, bson.M{}); err != nil {
+		return fmt.Errorf("failed to reset transfer projections: %w", err)
+	}
+	return nil
+}
+
+// newerThan filtra el documento solo si todavía no aplicó el evento con esa secuencia.
+// En un upsert, si el documento ya existe con una versión igual o mayor el filtro no
+// coincide y el insert choca con el _id: ese error de clave duplicada indica un evento
+// repetido y se ignora. Los documentos proyectados antes de versionar no tienen el campo
+// version y cualquier evento les aplica.
+func newerThan(id string, sequence int) bson.M {
+	return bson.M{
+		"_id": id,
+		"$or": bson.A{
+			bson.M{"version": bson.M{"$exists": false}},
+			bson.M{"version": bson.M{"$lt": sequence}},
+		},
+	}
+}
+
+// categoryName obtiene el nombre de la categoría para desnormalizarlo en el movimiento
+func (ps *MongoProjectionStore) categoryName(ctx context.Context, categoryID string) string {
+	categoryName := "Sin categoría"
+	if categoryID != "" {
+		var category CategoryProjection
+		err := ps.categoriesCollection.FindOne(ctx, bson.M{"_id": categoryID}).Decode(&category)
+		if err == nil {
+			categoryName = category.Name
+		}
+	}
+	return categoryName
+}
+
+// Query methods for reading projections
+func (ps *MongoProjectionStore) GetMovements(ctx context.Context, startDate, endDate *time.Time, tag string, limit, offset int) ([]MovementProjection, int, error) {
+	filter := bson.M{"is_deleted": false}
+	if tag != "" {
+		filter["tags"] = tag
+	}
+
+	// Agregar filtros de fecha
+	if startDate != nil || endDate != nil {
+		dateFilter := bson.M{}
+		if startDate != nil {
+			dateFilter["$gte"] = *startDate
+		}
+		if endDate != nil {
+			dateFilter["$lte"] = *endDate
+		}
+		filter["date"] = dateFilter
+	}
+
+	// Contar total
+	total, err := ps.movementsCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count movements: %w", err)
+	}
+
+	// Configurar opciones de búsqueda
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "date", Value: -1}, {Key: "created_at", Value: -1}})
+	if limit > 0 {
+		findOptions.SetLimit(int64(limit))
+	}
+	if offset > 0 {
+		findOptions.SetSkip(int64(offset))
+	}
+
+	cursor, err := ps.movementsCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find movements: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var movements []MovementProjection
+	if err := cursor.All(ctx, &movements); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode movements: %w", err)
+	}
+	for i := range movements {
+		openMovement(ps.sealer, &movements[i])
+		sort.Strings(movements[i].Tags)
+	}
+
+	return movements, int(total), nil
+}
+
+func (ps *MongoProjectionStore) GetCategories(ctx context.Context) ([]CategoryProjection, error) {
+	filter := bson.M{"is_deleted": false}
+	findOptions := options.Find().SetSort(bson.M{"name": 1})
+
+	cursor, err := ps.categoriesCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find categories: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var categories []CategoryProjection
+	if err := cursor.All(ctx, &categories); err != nil {
+		return nil, fmt.Errorf("failed to decode categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+func (ps *MongoProjectionStore) GetMovementByID(ctx context.Context, id string) (*MovementProjection, error) {
+	var movement MovementProjection
+	err := ps.movementsCollection.FindOne(ctx, bson.M{"_id": id, "is_deleted": false}).Decode(&movement)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find movement: %w", err)
+	}
+
+	openMovement(ps.sealer, &movement)
+	sort.Strings(movement.Tags)
+	return &movement, nil
+}
+
+func (ps *MongoProjectionStore) GetCategoryByID(ctx context.Context, id string) (*CategoryProjection, error) {
+	var category CategoryProjection
+	err := ps.categoriesCollection.FindOne(ctx, bson.M{"_id": id, "is_deleted": false}).Decode(&category)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find category: %w", err)
+	}
+
+	return &category, nil
+}
+
+func (ps *MongoProjectionStore) GetAccounts(ctx context.Context) ([]AccountProjection, error) {
+	findOptions := options.Find().SetSort(bson.M{"name": 1})
+
+	cursor, err := ps.accountsCollection.Find(ctx, bson.M{}, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find accounts: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var accounts []AccountProjection
+	if err := cursor.All(ctx, &accounts); err != nil {
+		return nil, fmt.Errorf("failed to decode accounts: %w", err)
+	}
+	for i := range accounts {
+		openAccount(ps.sealer, &accounts[i])
+	}
+
+	return accounts, nil
+}
+
+func (ps *MongoProjectionStore) GetAccountByID(ctx context.Context, id string) (*AccountProjection, error) {
+	var account AccountProjection
+	err := ps.accountsCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&account)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find account: %w", err)
+	}
+
+	openAccount(ps.sealer, &account)
+	return &account, nil
+}
+
+// GetAccountTotals suma los importes en claro con un pipeline de agregación por colección;
+// los cifrados se devuelven documento por documento para abrirlos
+func (ps *MongoProjectionStore) GetAccountTotals(ctx context.Context, accountID string) ([]AccountTotal, error) {
+	negated := func(field string) bson.M { return bson.M{"$subtract": bson.A{0, field}} }
+
+	totals := accountTotals{}
+	sources := []struct {
+		collection *mongo.Collection
+		account    string
+		kind       interface{}
+		amount     interface{}
+		currency   string
+	}{
+		{ps.movementsCollection, "account_id", "$type",
+			bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$type", "expense"}}, negated("$amount.amount"), "$amount.amount"}}, "$amount.currency"},
+		{ps.transfersCollection, "from_account_id", bson.M{"$literal": "sent"}, negated("$amount.amount"), "$amount.currency"},
+		{ps.transfersCollection, "to_account_id", bson.M{"$literal": "received"}, "$received_amount.amount", "$received_amount.currency"},
+	}
+	for _, source := range sources {
+		if err := ps.sumAccountActivity(ctx, source.collection, source.account, source.kind, source.amount, source.currency, accountID, totals); err != nil {
+			return nil, err
+		}
+	}
+	return totals.result(), nil
+}
+
+// sumAccountActivity agrega a totals los documentos de collection de cada cuenta (el
+// campo account) desde el día de su saldo inicial. kind, amount y currency son las
+// expresiones con el tipo para addSealed, el importe con signo y su moneda.
+func (ps *MongoProjectionStore) sumAccountActivity(ctx context.Context, collection *mongo.Collection, account string, kind, amount interface{}, currency, accountID string, totals accountTotals) error {
+	match := bson.M{"is_deleted": false, account: bson.M{"$ne": ""}}
+	if accountID != "" {
+		match[account] = accountID
+	}
+	day := func(field string) bson.M {
+		return bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": field}}
+	}
+	inClear := bson.M{"sealed": bson.M{"$in": bson.A{nil, ""}}}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$lookup", Value: bson.M{"from": ps.accountsCollection.Name(), "localField": account, "foreignField": "_id", "as": "account"}}},
+		{{Key: "$unwind", Value: "$account"}},
+		{{Key: "$match", Value: bson.M{"$expr": bson.M{"$gte": bson.A{day("$date"), day("$account.opening_date")}}}}},
+		{{Key: "$facet", Value: bson.M{
+			"clear": bson.A{
+				bson.M{"$match": inClear},
+				bson.M{"$group": bson.M{
+					"_id":   bson.M{"account": "$" + account, "currency": currency},
+					"total": bson.M{"$sum": amount},
+				}},
+			},
+			"sealed": bson.A{
+				bson.M{"$match": bson.M{"$nor": bson.A{inClear}}},
+				bson.M{"$project": bson.M{"account": "$" + account, "kind": kind, "sealed": 1}},
+			},
+		}}},
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to sum account movements: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Clear []struct {
+			ID struct {
+				Account  string `bson:"account"`
+				Currency string `bson:"currency"`
+			} `bson:"_id"`
+			Total int64 `bson:"total"`
+		} `bson:"clear"`
+		Sealed []struct {
+			ID      string `bson:"_id"`
+			Account string `bson:"account"`
+			Kind    string `bson:"kind"`
+			Sealed  string `bson:"sealed"`
+		} `bson:"sealed"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return fmt.Errorf("failed to decode account totals: %w", err)
+	}
+
+	for _, result := range results {
+		for _, group := range result.Clear {
+			totals.add(group.ID.Account, money.New(group.Total, group.ID.Currency))
+		}
+		for _, document := range result.Sealed {
+			totals.addSealed(ps.sealer, document.Account, document.Kind, document.ID, document.Sealed)
+		}
+	}
+	return nil
+}
+
+func (ps *MongoProjectionStore) GetTransfers(ctx context.Context, startDate, endDate *time.Time) ([]TransferProjection, error) {
+	filter := bson.M{"is_deleted": false}
+
+	if startDate != nil || endDate != nil {
+		dateFilter := bson.M{}
+		if startDate != nil {
+			dateFilter["$gte"] = *startDate
+		}
+		if endDate != nil {
+			dateFilter["$lte"] = *endDate
+		}
+		filter["date"] = dateFilter
+	}
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "date", Value: -1}, {Key: "created_at", Value: -1}})
+
+	cursor, err := ps.transfersCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find transfers: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var transfers []TransferProjection
+	if err := cursor.All(ctx, &transfers); err != nil {
+		return nil, fmt.Errorf("failed to decode transfers: %w", err)
+	}
+	for i := range transfers {
+		openTransfer(ps.sealer, &transfers[i])
+	}
+
+	return transfers, nil
+}
+
+func (ps *MongoProjectionStore) GetTransferByID(ctx context.Context, id string) (*TransferProjection, error) {
+	var transfer TransferProjection
+	err := ps.transfersCollection.FindOne(ctx, bson.M{"_id": id, "is_deleted": false}).Decode(&transfer)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find transfer: %w", err)
+	}
+
+	openTransfer(ps.sealer, &transfer)
+	return &transfer, nil
+}
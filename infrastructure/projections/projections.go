@@ -2,431 +2,487 @@ package projections
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"time"
 
 	"escama/domain/events"
-
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"escama/domain/money"
 )
 
 // MovementProjection representa un movimiento en la base de datos de lectura
 type MovementProjection struct {
-	ID           string    `bson:"_id" json:"id"`
-	Type         string    `bson:"type" json:"type"`
-	CategoryID   string    `bson:"category_id" json:"category_id"`
-	CategoryName string    `bson:"category_name" json:"category_name"`
-	Amount       float64   `bson:"amount" json:"amount"`
-	Description  *string   `bson:"description" json:"description"`
-	Date         time.Time `bson:"date" json:"date"`
-	CreatedAt    time.Time `bson:"created_at" json:"created_at"`
-	UpdatedAt    time.Time `bson:"updated_at" json:"updated_at"`
-	IsDeleted    bool      `bson:"is_deleted" json:"is_deleted"`
+	ID           string      `bson:"_id" json:"id"`
+	Type         string      `bson:"type" json:"type"`
+	AccountID    string      `bson:"account_id" json:"account_id"`
+	CategoryID   string      `bson:"category_id" json:"category_id"`
+	CategoryName string      `bson:"category_name" json:"category_name"`
+	Amount       money.Money `bson:"amount" json:"amount"`
+	Description  *string     `bson:"description" json:"description"`
+	Date         time.Time   `bson:"date" json:"date"`
+	Tags         []string    `bson:"tags,omitempty" json:"tags,omitempty"` // ordenadas alfabéticamente
+	CreatedAt    time.Time   `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time   `bson:"updated_at" json:"updated_at"`
+	IsDeleted    bool        `bson:"is_deleted" json:"is_deleted"`
+	Version      int         `bson:"version" json:"version"`    // secuencia del último evento aplicado
+	Sealed       string      `bson:"sealed,omitempty" json:"-"` // importe y descripción cifrados
 }
 
 // CategoryProjection representa una categoría en la base de datos de lectura
 type CategoryProjection struct {
 	ID        string    `bson:"_id" json:"id"`
 	Name      string    `bson:"name" json:"name"`
+	ParentID  string    `bson:"parent_id" json:"parent_id,omitempty"` // vacío en las de primer nivel
+	Kind      string    `bson:"kind" json:"kind"`                     // income, expense o both; vacío equivale a both
+	Archived  bool      `bson:"archived" json:"archived"`
 	CreatedAt time.Time `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
 	IsDeleted bool      `bson:"is_deleted" json:"is_deleted"`
+	Version   int       `bson:"version" json:"version"` // secuencia del último evento aplicado
 }
 
-// ProjectionStore maneja las proyecciones en MongoDB
-type ProjectionStore struct {
-	client               *mongo.Client
-	database             *mongo.Database
-	movementsCollection  *mongo.Collection
-	categoriesCollection *mongo.Collection
+// AccountProjection representa una cuenta en la base de datos de lectura. El saldo se
+// calcula al consultarla: el saldo inicial más lo que suma el store con GetAccountTotals.
+type AccountProjection struct {
+	ID             string      `bson:"_id" json:"id"`
+	Name           string      `bson:"name" json:"name"`
+	Kind           string      `bson:"kind" json:"kind"`
+	Currency       string      `bson:"currency" json:"currency"`
+	OpeningBalance money.Money `bson:"opening_balance" json:"opening_balance"`
+	OpeningDate    time.Time   `bson:"opening_date" json:"opening_date"`
+	Closed         bool        `bson:"closed" json:"closed"`
+	CreatedAt      time.Time   `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time   `bson:"updated_at" json:"updated_at"`
+	Version        int         `bson:"version" json:"version"`    // secuencia del último evento aplicado
+	Sealed         string      `bson:"sealed,omitempty" json:"-"` // saldo inicial cifrado
 }
 
-func NewProjectionStore(client *mongo.Client, databaseName string) *ProjectionStore {
-	database := client.Database(databaseName)
-
-	return &ProjectionStore{
-		client:               client,
-		database:             database,
-		movementsCollection:  database.Collection("movements"),
-		categoriesCollection: database.Collection("categories"),
-	}
+// AccountTotal lo que suman, en una moneda, los movimientos y transferencias de una cuenta
+// desde el día de su saldo inicial: ingresos y transferencias recibidas menos gastos y
+// transferencias enviadas
+type AccountTotal struct {
+	AccountID string
+	Total     money.Money
 }
 
-// ProcessEvent procesa un evento y actualiza las proyecciones
-func (ps *ProjectionStore) ProcessEvent(ctx context.Context, event events.StoredEvent) error {
-	switch event.EventType {
-	case "CategoryCreated":
-		return ps.handleCategoryCreated(ctx, event)
-	case "ExpenseCreated":
-		return ps.handleExpenseCreated(ctx, event)
-	case "IncomeCreated":
-		return ps.handleIncomeCreated(ctx, event)
-	case "ExpenseUpdated":
-		return ps.handleExpenseUpdated(ctx, event)
-	case "IncomeUpdated":
-		return ps.handleIncomeUpdated(ctx, event)
-	case "ExpenseDeleted":
-		return ps.handleExpenseDeleted(ctx, event)
-	case "IncomeDeleted":
-		return ps.handleIncomeDeleted(ctx, event)
-	default:
-		log.Printf("Unknown event type: %s", event.EventType)
-		return nil
-	}
+// TransferProjection representa una transferencia entre cuentas en la base de datos de
+// lectura. Se guarda aparte de los movimientos: no suma a ingresos ni a gastos.
+type TransferProjection struct {
+	ID             string      `bson:"_id" json:"id"`
+	FromAccountID  string      `bson:"from_account_id" json:"from_account_id"`
+	ToAccountID    string      `bson:"to_account_id" json:"to_account_id"`
+	Amount         money.Money `bson:"amount" json:"amount"`
+	ReceivedAmount money.Money `bson:"received_amount" json:"received_amount"`
+	Description    *string     `bson:"description" json:"description"`
+	Date           time.Time   `bson:"date" json:"date"`
+	CreatedAt      time.Time   `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time   `bson:"updated_at" json:"updated_at"`
+	IsDeleted      bool        `bson:"is_deleted" json:"is_deleted"`
+	Version        int         `bson:"version" json:"version"`    // secuencia del último evento aplicado
+	Sealed         string      `bson:"sealed,omitempty" json:"-"` // importes y descripción cifrados
 }
 
-func (ps *ProjectionStore) handleCategoryCreated(ctx context.Context, event events.StoredEvent) error {
-	categoryID := ps.getStringFromPayload(event.Payload, "CategoryID", "category_id")
-	name := ps.getStringFromPayload(event.Payload, "Name", "name")
-
-	if categoryID == "" || name == "" {
-		return fmt.Errorf("invalid category created event: missing required fields")
-	}
-
-	category := CategoryProjection{
-		ID:        categoryID,
-		Name:      name,
-		CreatedAt: event.OccurredAt,
-		UpdatedAt: event.OccurredAt,
-		IsDeleted: false,
-	}
-
-	_, err := ps.categoriesCollection.ReplaceOne(
-		ctx,
-		bson.M{"_id": categoryID},
-		category,
-		options.Replace().SetUpsert(true),
-	)
-
-	if err != nil {
-		return fmt.Errorf("failed to upsert category projection: %w", err)
-	}
-
-	log.Printf("Category projection updated: %s - %s", categoryID, name)
-	return nil
+// ProjectionStore define el contrato del modelo de lectura: aplica eventos y responde
+// las consultas de movimientos y categorías
+type ProjectionStore interface {
+	ProcessEvent(ctx context.Context, event events.StoredEvent) error
+	// Reset borra todas las proyecciones para reconstruirlas desde el Event Store
+	Reset(ctx context.Context) error
+	// GetMovements devuelve los movimientos no eliminados del rango, del más reciente al
+	// más antiguo; con tag, solo los que tienen esa etiqueta
+	GetMovements(ctx context.Context, startDate, endDate *time.Time, tag string, limit, offset int) ([]MovementProjection, int, error)
+	GetCategories(ctx context.Context) ([]CategoryProjection, error)
+	GetMovementByID(ctx context.Context, id string) (*MovementProjection, error)
+	GetCategoryByID(ctx context.Context, id string) (*CategoryProjection, error)
+	// GetAccounts devuelve todas las cuentas, también las cerradas, ordenadas por nombre
+	GetAccounts(ctx context.Context) ([]AccountProjection, error)
+	GetAccountByID(ctx context.Context, id string) (*AccountProjection, error)
+	// GetAccountTotals suma en el store los movimientos y transferencias de cada cuenta,
+	// por moneda; con accountID, solo los de esa cuenta
+	GetAccountTotals(ctx context.Context, accountID string) ([]AccountTotal, error)
+	// GetTransfers devuelve las transferencias no eliminadas del rango, de la más reciente
+	// a la más antigua
+	GetTransfers(ctx context.Context, startDate, endDate *time.Time) ([]TransferProjection, error)
+	GetTransferByID(ctx context.Context, id string) (*TransferProjection, error)
 }
 
-func (ps *ProjectionStore) handleExpenseCreated(ctx context.Context, event events.StoredEvent) error {
-	return ps.handleMovementCreated(ctx, event, "expense")
+// movementChange datos de un gasto o ingreso tomados de su evento. Sequence es la
+// posición del evento en el stream del agregado: los handlers ignoran los eventos con
+// Sequence menor o igual a la versión ya proyectada, para tolerar entregas duplicadas.
+type movementChange struct {
+	Type        string // "expense" o "income"
+	ID          string
+	AccountID   string
+	CategoryID  string
+	Amount      money.Money
+	Description *string
+	Date        time.Time
+	OccurredAt  time.Time
+	Sequence    int
+	Owner       string // actor del evento: dueño de la clave con que se cifran los datos
 }
 
-func (ps *ProjectionStore) handleIncomeCreated(ctx context.Context, event events.StoredEvent) error {
-	return ps.handleMovementCreated(ctx, event, "income")
+// eventHandlers operaciones que cada implementación del modelo de lectura aplica sobre
+// su almacenamiento; dispatchEvent decide cuál corresponde a cada evento
+type eventHandlers interface {
+	handleCategoryCreated(ctx context.Context, category CategoryProjection) error
+	handleCategoryParentChanged(ctx context.Context, categoryID, parentID string, occurredAt time.Time, sequence int) error
+	handleCategoryKindChanged(ctx context.Context, categoryID, kind string, occurredAt time.Time, sequence int) error
+	handleCategoryRenamed(ctx context.Context, categoryID, name string, occurredAt time.Time, sequence int) error
+	handleCategoryArchived(ctx context.Context, categoryID string, archived bool, occurredAt time.Time, sequence int) error
+	handleCategoryDeleted(ctx context.Context, categoryID string, occurredAt time.Time, sequence int) error
+	handleCategoryMerged(ctx context.Context, categoryID, targetID string, occurredAt time.Time, sequence int) error
+	handleMovementCreated(ctx context.Context, change movementChange) error
+	handleMovementUpdated(ctx context.Context, change movementChange) error
+	handleMovementDeleted(ctx context.Context, movementType, movementID string, occurredAt time.Time, sequence int) error
+	handleMovementTagged(ctx context.Context, movementType, movementID, tag string, added bool, occurredAt time.Time, sequence int) error
+	handleAccountCreated(ctx context.Context, account AccountProjection) error
+	handleAccountRenamed(ctx context.Context, accountID, name string, occurredAt time.Time, sequence int) error
+	handleAccountOpeningBalanceSet(ctx context.Context, change openingBalanceChange) error
+	handleAccountClosed(ctx context.Context, accountID string, occurredAt time.Time, sequence int) error
+	handleTransferCreated(ctx context.Context, change transferChange) error
+	handleTransferUpdated(ctx context.Context, change transferChange) error
+	handleTransferDeleted(ctx context.Context, transferID string, occurredAt time.Time, sequence int) error
 }
 
-func (ps *ProjectionStore) handleMovementCreated(ctx context.Context, event events.StoredEvent, movementType string) error {
-	var movementID, categoryID string
-	if movementType == "expense" {
-		movementID = ps.getStringFromPayload(event.Payload, "ExpenseID", "expense_id")
-	} else {
-		movementID = ps.getStringFromPayload(event.Payload, "IncomeID", "income_id")
-	}
-
-	categoryID = ps.getStringFromPayload(event.Payload, "CategoryID", "category_id")
-	amount := ps.getFloat64FromPayload(event.Payload, "Amount", "amount")
-	description := ps.getStringPtrFromPayload(event.Payload, "Description", "description")
-	date := ps.getTimeFromPayload(event.Payload, "Date", "date")
+// openingBalanceChange saldo inicial de una cuenta tomado de su evento
+type openingBalanceChange struct {
+	AccountID  string
+	Amount     money.Money
+	Date       time.Time
+	OccurredAt time.Time
+	Sequence   int
+	Owner      string // actor del evento: dueño de la clave con que se cifra el saldo
+}
 
-	if movementID == "" {
-		return fmt.Errorf("invalid %s created event: missing ID", movementType)
-	}
+// transferChange datos de una transferencia tomados de su evento
+type transferChange struct {
+	ID             string
+	FromAccountID  string
+	ToAccountID    string
+	Amount         money.Money
+	ReceivedAmount money.Money
+	Description    *string
+	Date           time.Time
+	OccurredAt     time.Time
+	Sequence       int
+	Owner          string // actor del evento: dueño de la clave con que se cifran los datos
+}
 
+// projection arma la fila de la transferencia, con los importes y la descripción
+// cifrados si hay sealer
+func (c transferChange) projection(sealer Sealer) (TransferProjection, error) {
+	date := c.Date
 	if date.IsZero() {
-		date = event.OccurredAt
+		date = c.OccurredAt
 	}
 
-	// Obtener nombre de la categoría
-	categoryName := "Sin categoría"
-	if categoryID != "" {
-		var category CategoryProjection
-		err := ps.categoriesCollection.FindOne(ctx, bson.M{"_id": categoryID}).Decode(&category)
-		if err == nil {
-			categoryName = category.Name
-		}
+	transfer := TransferProjection{
+		ID:             c.ID,
+		FromAccountID:  c.FromAccountID,
+		ToAccountID:    c.ToAccountID,
+		Amount:         c.Amount,
+		ReceivedAmount: c.ReceivedAmount,
+		Description:    c.Description,
+		Date:           date,
+		CreatedAt:      c.OccurredAt,
+		UpdatedAt:      c.OccurredAt,
+		Version:        c.Sequence,
 	}
-
-	movement := MovementProjection{
-		ID:           movementID,
-		Type:         movementType,
-		CategoryID:   categoryID,
-		CategoryName: categoryName,
-		Amount:       amount,
-		Description:  description,
-		Date:         date,
-		CreatedAt:    event.OccurredAt,
-		UpdatedAt:    event.OccurredAt,
-		IsDeleted:    false,
+	if err := sealTransfer(sealer, c.Owner, &transfer); err != nil {
+		return TransferProjection{}, err
 	}
+	return transfer, nil
+}
 
-	_, err := ps.movementsCollection.ReplaceOne(
-		ctx,
-		bson.M{"_id": movementID},
-		movement,
-		options.Replace().SetUpsert(true),
-	)
-
+// dispatchEvent decodifica el evento almacenado y lo aplica según su tipo Go
+func dispatchEvent(ctx context.Context, h eventHandlers, storedEvent events.StoredEvent) error {
+	domainEvent, err := storedEvent.Decode()
 	if err != nil {
-		return fmt.Errorf("failed to upsert movement projection: %w", err)
+		if errors.Is(err, events.ErrUnknownEventType) {
+			log.Printf("Unknown event type: %s", storedEvent.EventType)
+			return nil
+		}
+		return err
+	}
+
+	occurredAt := storedEvent.OccurredAt
+	sequence := storedEvent.Sequence
+	owner := storedEvent.Metadata.Actor
+
+	switch e := domainEvent.(type) {
+	case events.CategoryCreated:
+		return h.handleCategoryCreated(ctx, CategoryProjection{
+			ID: e.CategoryID, Name: e.Name, ParentID: e.ParentID, Kind: e.Kind, CreatedAt: occurredAt, UpdatedAt: occurredAt, Version: sequence,
+		})
+	case events.CategoryKindChanged:
+		return h.handleCategoryKindChanged(ctx, e.CategoryID, e.Kind, occurredAt, sequence)
+	case events.CategoryParentChanged:
+		return h.handleCategoryParentChanged(ctx, e.CategoryID, e.ParentID, occurredAt, sequence)
+	case events.CategoryRenamed:
+		return h.handleCategoryRenamed(ctx, e.CategoryID, e.Name, occurredAt, sequence)
+	case events.CategoryArchived:
+		return h.handleCategoryArchived(ctx, e.CategoryID, true, occurredAt, sequence)
+	case events.CategoryUnarchived:
+		return h.handleCategoryArchived(ctx, e.CategoryID, false, occurredAt, sequence)
+	case events.CategoryDeleted:
+		return h.handleCategoryDeleted(ctx, e.CategoryID, occurredAt, sequence)
+	case events.CategoryMerged:
+		return h.handleCategoryMerged(ctx, e.CategoryID, e.TargetID, occurredAt, sequence)
+	case events.ExpenseCreated:
+		return h.handleMovementCreated(ctx, movementChange{
+			Type: "expense", ID: e.ExpenseID, AccountID: e.AccountID, CategoryID: e.CategoryID, Amount: e.Amount,
+			Description: e.Description, Date: e.Date, OccurredAt: occurredAt, Sequence: sequence, Owner: owner,
+		})
+	case events.IncomeCreated:
+		return h.handleMovementCreated(ctx, movementChange{
+			Type: "income", ID: e.IncomeID, AccountID: e.AccountID, CategoryID: e.CategoryID, Amount: e.Amount,
+			Description: e.Description, Date: e.Date, OccurredAt: occurredAt, Sequence: sequence, Owner: owner,
+		})
+	case events.ExpenseUpdated:
+		return h.handleMovementUpdated(ctx, movementChange{
+			Type: "expense", ID: e.ExpenseID, AccountID: e.AccountID, CategoryID: e.CategoryID, Amount: e.Amount,
+			Description: e.Description, Date: e.Date, OccurredAt: occurredAt, Sequence: sequence, Owner: owner,
+		})
+	case events.IncomeUpdated:
+		return h.handleMovementUpdated(ctx, movementChange{
+			Type: "income", ID: e.IncomeID, AccountID: e.AccountID, CategoryID: e.CategoryID, Amount: e.Amount,
+			Description: e.Description, Date: e.Date, OccurredAt: occurredAt, Sequence: sequence, Owner: owner,
+		})
+	case events.ExpenseDeleted:
+		return h.handleMovementDeleted(ctx, "expense", e.ExpenseID, occurredAt, sequence)
+	case events.IncomeDeleted:
+		return h.handleMovementDeleted(ctx, "income", e.IncomeID, occurredAt, sequence)
+	case events.ExpenseTagAdded:
+		return h.handleMovementTagged(ctx, "expense", e.ExpenseID, e.Tag, true, occurredAt, sequence)
+	case events.ExpenseTagRemoved:
+		return h.handleMovementTagged(ctx, "expense", e.ExpenseID, e.Tag, false, occurredAt, sequence)
+	case events.IncomeTagAdded:
+		return h.handleMovementTagged(ctx, "income", e.IncomeID, e.Tag, true, occurredAt, sequence)
+	case events.IncomeTagRemoved:
+		return h.handleMovementTagged(ctx, "income", e.IncomeID, e.Tag, false, occurredAt, sequence)
+	case events.AccountCreated:
+		return h.handleAccountCreated(ctx, AccountProjection{
+			ID: e.AccountID, Name: e.Name, Kind: e.Kind, Currency: e.Currency,
+			CreatedAt: occurredAt, UpdatedAt: occurredAt, Version: sequence,
+		})
+	case events.AccountRenamed:
+		return h.handleAccountRenamed(ctx, e.AccountID, e.Name, occurredAt, sequence)
+	case events.AccountOpeningBalanceSet:
+		return h.handleAccountOpeningBalanceSet(ctx, openingBalanceChange{
+			AccountID: e.AccountID, Amount: e.Amount, Date: e.Date, OccurredAt: occurredAt, Sequence: sequence, Owner: owner,
+		})
+	case events.AccountClosed:
+		return h.handleAccountClosed(ctx, e.AccountID, occurredAt, sequence)
+	case events.TransferCreated:
+		return h.handleTransferCreated(ctx, transferChange{
+			ID: e.TransferID, FromAccountID: e.FromAccountID, ToAccountID: e.ToAccountID, Amount: e.Amount, ReceivedAmount: e.ReceivedAmount,
+			Description: e.Description, Date: e.Date, OccurredAt: occurredAt, Sequence: sequence, Owner: owner,
+		})
+	case events.TransferUpdated:
+		return h.handleTransferUpdated(ctx, transferChange{
+			ID: e.TransferID, FromAccountID: e.FromAccountID, ToAccountID: e.ToAccountID, Amount: e.Amount, ReceivedAmount: e.ReceivedAmount,
+			Description: e.Description, Date: e.Date, OccurredAt: occurredAt, Sequence: sequence, Owner: owner,
+		})
+	case events.TransferDeleted:
+		return h.handleTransferDeleted(ctx, e.TransferID, occurredAt, sequence)
+	default:
+		log.Printf("Unknown event type: %s", storedEvent.EventType)
+		return nil
 	}
-
-	log.Printf("%s projection updated: %s - ₲%.0f", movementType, movementID, amount)
-	return nil
 }
 
-func (ps *ProjectionStore) handleExpenseUpdated(ctx context.Context, event events.StoredEvent) error {
-	return ps.handleMovementUpdated(ctx, event, "expense")
+// Sealer cifra los datos sensibles de las proyecciones con la clave de su dueño
+type Sealer interface {
+	Seal(owner string, plaintext []byte) (string, error)
+	Open(sealed string) ([]byte, error)
 }
 
-func (ps *ProjectionStore) handleIncomeUpdated(ctx context.Context, event events.StoredEvent) error {
-	return ps.handleMovementUpdated(ctx, event, "income")
+// Sealable lo implementan los ProjectionStore que pueden cifrar los movimientos
+type Sealable interface {
+	SetSealer(sealer Sealer)
 }
 
-func (ps *ProjectionStore) handleMovementUpdated(ctx context.Context, event events.StoredEvent, movementType string) error {
-	var movementID string
-	if movementType == "expense" {
-		movementID = ps.getStringFromPayload(event.Payload, "ExpenseID", "expense_id")
-	} else {
-		movementID = ps.getStringFromPayload(event.Payload, "IncomeID", "income_id")
-	}
-
-	if movementID == "" {
-		return fmt.Errorf("invalid %s updated event: missing ID", movementType)
-	}
+// sealedMovement campos de un movimiento que se guardan cifrados en Sealed (en una
+// cuenta, solo el importe de su saldo inicial)
+type sealedMovement struct {
+	Amount      money.Money `json:"amount"`
+	Description *string     `json:"description,omitempty"`
+}
 
-	// Obtener los nuevos valores del evento
-	categoryID := ps.getStringFromPayload(event.Payload, "CategoryID", "category_id")
-	amount := ps.getFloat64FromPayload(event.Payload, "Amount", "amount")
-	description := ps.getStringPtrFromPayload(event.Payload, "Description", "description")
-	date := ps.getTimeFromPayload(event.Payload, "Date", "date")
-
-	// Obtener nombre de la categoría
-	categoryName := "Sin categoría"
-	if categoryID != "" {
-		var category CategoryProjection
-		err := ps.categoriesCollection.FindOne(ctx, bson.M{"_id": categoryID}).Decode(&category)
-		if err == nil {
-			categoryName = category.Name
-		}
+// sealMovement devuelve el importe, la descripción y el valor cifrado a guardar. Sin
+// sealer los datos quedan en claro; con sealer, importe y descripción solo viven en Sealed.
+func sealMovement(sealer Sealer, owner string, amount money.Money, description *string) (money.Money, *string, string, error) {
+	// Sin datos que proteger (por ejemplo, eventos de un actor cuya clave se eliminó) no
+	// se cifra: así no se genera una clave nueva para ese actor
+	if sealer == nil || (amount.IsZero() && description == nil) {
+		return amount, description, "", nil
 	}
 
-	// Actualizar la proyección
-	update := bson.M{
-		"$set": bson.M{
-			"category_id":   categoryID,
-			"category_name": categoryName,
-			"amount":        amount,
-			"description":   description,
-			"date":          date,
-			"updated_at":    event.OccurredAt,
-		},
+	plaintext, err := json.Marshal(sealedMovement{Amount: amount, Description: description})
+	if err != nil {
+		return money.Money{}, nil, "", fmt.Errorf("failed to encode movement: %w", err)
 	}
-
-	_, err := ps.movementsCollection.UpdateOne(ctx, bson.M{"_id": movementID}, update)
+	sealed, err := sealer.Seal(owner, plaintext)
 	if err != nil {
-		return fmt.Errorf("failed to update movement projection: %w", err)
+		return money.Money{}, nil, "", fmt.Errorf("failed to encrypt movement: %w", err)
 	}
-
-	log.Printf("%s projection updated: %s", movementType, movementID)
-	return nil
+	return money.Money{}, nil, sealed, nil
 }
 
-func (ps *ProjectionStore) handleExpenseDeleted(ctx context.Context, event events.StoredEvent) error {
-	return ps.handleMovementDeleted(ctx, event, "expense")
-}
-
-func (ps *ProjectionStore) handleIncomeDeleted(ctx context.Context, event events.StoredEvent) error {
-	return ps.handleMovementDeleted(ctx, event, "income")
-}
+// openMovement restaura el importe y la descripción de un movimiento cifrado. Si la
+// clave fue eliminada (o no hay sealer) el movimiento queda con importe 0 y sin descripción.
+func openMovement(sealer Sealer, movement *MovementProjection) {
+	if movement.Sealed == "" || sealer == nil {
+		return
+	}
 
-func (ps *ProjectionStore) handleMovementDeleted(ctx context.Context, event events.StoredEvent, movementType string) error {
-	var movementID string
-	if movementType == "expense" {
-		movementID = ps.getStringFromPayload(event.Payload, "ExpenseID", "expense_id")
-	} else {
-		movementID = ps.getStringFromPayload(event.Payload, "IncomeID", "income_id")
+	plaintext, err := sealer.Open(movement.Sealed)
+	if err != nil {
+		log.Printf("Movement %s is unreadable: %v", movement.ID, err)
+		return
 	}
 
-	if movementID == "" {
-		return fmt.Errorf("invalid %s deleted event: missing ID", movementType)
+	var fields sealedMovement
+	if err := json.Unmarshal(plaintext, &fields); err != nil {
+		log.Printf("Movement %s is unreadable: %v", movement.ID, err)
+		return
 	}
+	movement.Amount = fields.Amount
+	movement.Description = fields.Description
+}
 
-	// Marcar como eliminado (soft delete)
-	update := bson.M{
-		"$set": bson.M{
-			"is_deleted": true,
-			"updated_at": event.OccurredAt,
-		},
+// openAccount restaura el saldo inicial cifrado de una cuenta. Si la clave fue eliminada
+// (o no hay sealer) el saldo inicial queda en 0.
+func openAccount(sealer Sealer, account *AccountProjection) {
+	if account.Sealed == "" || sealer == nil {
+		return
 	}
 
-	_, err := ps.movementsCollection.UpdateOne(ctx, bson.M{"_id": movementID}, update)
+	plaintext, err := sealer.Open(account.Sealed)
 	if err != nil {
-		return fmt.Errorf("failed to delete movement projection: %w", err)
+		log.Printf("Account %s opening balance is unreadable: %v", account.ID, err)
+		return
 	}
 
-	log.Printf("%s projection deleted: %s", movementType, movementID)
-	return nil
+	var fields sealedMovement
+	if err := json.Unmarshal(plaintext, &fields); err != nil {
+		log.Printf("Account %s opening balance is unreadable: %v", account.ID, err)
+		return
+	}
+	account.OpeningBalance = fields.Amount
 }
 
-// Helper functions
-func (ps *ProjectionStore) getStringFromPayload(payload map[string]interface{}, keys ...string) string {
-	for _, key := range keys {
-		if val, ok := payload[key]; ok {
-			if strVal, ok := val.(string); ok {
-				return strVal
-			}
-		}
-	}
-	return ""
+// accountCurrency clave de accountTotals
+type accountCurrency struct {
+	accountID string
+	currency  string
 }
 
-func (ps *ProjectionStore) getFloat64FromPayload(payload map[string]interface{}, keys ...string) float64 {
-	for _, key := range keys {
-		if val, ok := payload[key]; ok {
-			switch v := val.(type) {
-			case float64:
-				return v
-			case float32:
-				return float64(v)
-			case int:
-				return float64(v)
-			case int64:
-				return float64(v)
-			}
-		}
+// accountTotals acumula los importes de cada cuenta por moneda
+type accountTotals map[accountCurrency]int64
+
+func (t accountTotals) add(accountID string, amount money.Money) {
+	if amount.IsZero() {
+		return
 	}
-	return 0
+	t[accountCurrency{accountID: accountID, currency: amount.Currency}] += amount.Amount
 }
 
-func (ps *ProjectionStore) getStringPtrFromPayload(payload map[string]interface{}, keys ...string) *string {
-	for _, key := range keys {
-		if val, ok := payload[key]; ok {
-			if strVal, ok := val.(string); ok && strVal != "" {
-				return &strVal
-			}
+// addSealed suma el importe cifrado de un movimiento o transferencia, que la base no
+// puede sumar. kind es el tipo del movimiento ("expense" o "income"), o "sent" o
+// "received" para una transferencia según el lado de la cuenta.
+func (t accountTotals) addSealed(sealer Sealer, accountID, kind, id, sealed string) {
+	switch kind {
+	case "expense", "income":
+		movement := MovementProjection{ID: id, Sealed: sealed}
+		openMovement(sealer, &movement)
+		if kind == "expense" {
+			movement.Amount = movement.Amount.Neg()
+		}
+		t.add(accountID, movement.Amount)
+	case "sent", "received":
+		transfer := TransferProjection{ID: id, Sealed: sealed}
+		openTransfer(sealer, &transfer)
+		if kind == "sent" {
+			t.add(accountID, transfer.Amount.Neg())
+		} else {
+			t.add(accountID, transfer.ReceivedAmount)
 		}
 	}
-	return nil
 }
 
-func (ps *ProjectionStore) getTimeFromPayload(payload map[string]interface{}, keys ...string) time.Time {
-	for _, key := range keys {
-		if val, ok := payload[key]; ok {
-			switch v := val.(type) {
-			case string:
-				// RFC3339 format
-				if parsedDate, err := time.Parse(time.RFC3339, v); err == nil {
-					return parsedDate
-				}
-				// Otros formatos comunes
-				formats := []string{
-					"2006-01-02T15:04:05Z07:00",
-					"2006-01-02T15:04:05.000Z",
-					"2006-01-02T15:04:05",
-					"2006-01-02 15:04:05",
-					"2006-01-02",
-				}
-				for _, format := range formats {
-					if parsedDate, err := time.Parse(format, v); err == nil {
-						return parsedDate
-					}
-				}
-			case time.Time:
-				return v
-			}
-		}
+// result devuelve los totales ordenados por cuenta y moneda
+func (t accountTotals) result() []AccountTotal {
+	totals := make([]AccountTotal, 0, len(t))
+	for key, amount := range t {
+		totals = append(totals, AccountTotal{AccountID: key.accountID, Total: money.New(amount, key.currency)})
 	}
-	return time.Time{}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].AccountID != totals[j].AccountID {
+			return totals[i].AccountID < totals[j].AccountID
+		}
+		return totals[i].Total.Currency < totals[j].Total.Currency
+	})
+	return totals
 }
 
-// Query methods for reading projections
-func (ps *ProjectionStore) GetMovements(ctx context.Context, startDate, endDate *time.Time, limit, offset int) ([]MovementProjection, int, error) {
-	filter := bson.M{"is_deleted": false}
+// sealedTransfer campos de una transferencia que se guardan cifrados en Sealed
+type sealedTransfer struct {
+	Amount         money.Money `json:"amount"`
+	ReceivedAmount money.Money `json:"received_amount"`
+	Description    *string     `json:"description,omitempty"`
+}
 
-	// Agregar filtros de fecha
-	if startDate != nil || endDate != nil {
-		dateFilter := bson.M{}
-		if startDate != nil {
-			dateFilter["$gte"] = *startDate
-		}
-		if endDate != nil {
-			dateFilter["$lte"] = *endDate
-		}
-		filter["date"] = dateFilter
+// sealTransfer cifra los importes y la descripción de la transferencia con la clave de
+// owner; sin sealer los deja en claro
+func sealTransfer(sealer Sealer, owner string, transfer *TransferProjection) error {
+	if sealer == nil || (transfer.Amount.IsZero() && transfer.ReceivedAmount.IsZero() && transfer.Description == nil) {
+		return nil
 	}
 
-	// Contar total
-	total, err := ps.movementsCollection.CountDocuments(ctx, filter)
+	plaintext, err := json.Marshal(sealedTransfer{
+		Amount:         transfer.Amount,
+		ReceivedAmount: transfer.ReceivedAmount,
+		Description:    transfer.Description,
+	})
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count movements: %w", err)
+		return fmt.Errorf("failed to encode transfer: %w", err)
 	}
-
-	// Configurar opciones de búsqueda
-	findOptions := options.Find()
-	findOptions.SetSort(bson.D{{"date", -1}, {"created_at", -1}})
-	if limit > 0 {
-		findOptions.SetLimit(int64(limit))
-	}
-	if offset > 0 {
-		findOptions.SetSkip(int64(offset))
-	}
-
-	cursor, err := ps.movementsCollection.Find(ctx, filter, findOptions)
+	sealed, err := sealer.Seal(owner, plaintext)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to find movements: %w", err)
-	}
-	defer cursor.Close(ctx)
-
-	var movements []MovementProjection
-	if err := cursor.All(ctx, &movements); err != nil {
-		return nil, 0, fmt.Errorf("failed to decode movements: %w", err)
+		return fmt.Errorf("failed to encrypt transfer: %w", err)
 	}
 
-	return movements, int(total), nil
+	transfer.Amount = money.Money{}
+	transfer.ReceivedAmount = money.Money{}
+	transfer.Description = nil
+	transfer.Sealed = sealed
+	return nil
 }
 
-func (ps *ProjectionStore) GetCategories(ctx context.Context) ([]CategoryProjection, error) {
-	filter := bson.M{"is_deleted": false}
-	findOptions := options.Find().SetSort(bson.M{"name": 1})
-
-	cursor, err := ps.categoriesCollection.Find(ctx, filter, findOptions)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find categories: %w", err)
+// openTransfer restaura los importes y la descripción de una transferencia cifrada. Si
+// la clave fue eliminada (o no hay sealer) quedan en 0 y sin descripción.
+func openTransfer(sealer Sealer, transfer *TransferProjection) {
+	if transfer.Sealed == "" || sealer == nil {
+		return
 	}
-	defer cursor.Close(ctx)
 
-	var categories []CategoryProjection
-	if err := cursor.All(ctx, &categories); err != nil {
-		return nil, fmt.Errorf("failed to decode categories: %w", err)
-	}
-
-	return categories, nil
-}
-
-func (ps *ProjectionStore) GetMovementByID(ctx context.Context, id string) (*MovementProjection, error) {
-	var movement MovementProjection
-	err := ps.movementsCollection.FindOne(ctx, bson.M{"_id": id, "is_deleted": false}).Decode(&movement)
+	plaintext, err := sealer.Open(transfer.Sealed)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("failed to find movement: %w", err)
+		log.Printf("Transfer %s is unreadable: %v", transfer.ID, err)
+		return
 	}
 
-	return &movement, nil
-}
-
-func (ps *ProjectionStore) GetCategoryByID(ctx context.Context, id string) (*CategoryProjection, error) {
-	var category CategoryProjection
-	err := ps.categoriesCollection.FindOne(ctx, bson.M{"_id": id, "is_deleted": false}).Decode(&category)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("failed to find category: %w", err)
+	var fields sealedTransfer
+	if err := json.Unmarshal(plaintext, &fields); err != nil {
+		log.Printf("Transfer %s is unreadable: %v", transfer.ID, err)
+		return
 	}
-
-	return &category, nil
+	transfer.Amount = fields.Amount
+	transfer.ReceivedAmount = fields.ReceivedAmount
+	transfer.Description = fields.Description
 }
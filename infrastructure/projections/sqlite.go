@@ -0,0 +1,958 @@
+package projections
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"escama/domain/events"
+	"escama/domain/money"
+)
+
+// sqliteTimeLayout formato de ancho fijo en UTC para que las fechas se ordenen como texto
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// SQLiteProjectionStore maneja las proyecciones en una base SQLite local
+type SQLiteProjectionStore struct {
+	db     *sql.DB
+	sealer Sealer
+}
+
+func NewSQLiteProjectionStore(db *sql.DB) (*SQLiteProjectionStore, error) {
+	schema := `
+CREATE TABLE IF NOT EXISTS movements (
+	id            TEXT PRIMARY KEY,
+	type          TEXT NOT NULL,
+	account_id    TEXT NOT NULL DEFAULT '',
+	category_id   TEXT NOT NULL,
+	category_name TEXT NOT NULL,
+	amount        INTEGER NOT NULL,
+	currency      TEXT NOT NULL DEFAULT '',
+	description   TEXT,
+	date          TEXT NOT NULL,
+	created_at    TEXT NOT NULL,
+	updated_at    TEXT NOT NULL,
+	is_deleted    INTEGER NOT NULL DEFAULT 0,
+	version       INTEGER NOT NULL DEFAULT 0,
+	sealed        TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS movements_by_date ON movements (is_deleted, date DESC, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS movement_tags (
+	movement_id TEXT NOT NULL,
+	tag         TEXT NOT NULL,
+	PRIMARY KEY (movement_id, tag)
+);
+CREATE INDEX IF NOT EXISTS movement_tags_by_tag ON movement_tags (tag, movement_id);
+
+CREATE TABLE IF NOT EXISTS categories (
+	id         TEXT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	parent_id  TEXT NOT NULL DEFAULT '',
+	kind       TEXT NOT NULL DEFAULT '',
+	archived   INTEGER NOT NULL DEFAULT 0,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+	is_deleted INTEGER NOT NULL DEFAULT 0,
+	version    INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS accounts (
+	id               TEXT PRIMARY KEY,
+	name             TEXT NOT NULL,
+	kind             TEXT NOT NULL,
+	currency         TEXT NOT NULL,
+	opening_amount   INTEGER NOT NULL DEFAULT 0,
+	opening_currency TEXT NOT NULL DEFAULT '',
+	opening_date     TEXT NOT NULL DEFAULT '',
+	closed           INTEGER NOT NULL DEFAULT 0,
+	created_at       TEXT NOT NULL,
+	updated_at       TEXT NOT NULL,
+	version          INTEGER NOT NULL DEFAULT 0,
+	sealed           TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS transfers (
+	id                TEXT PRIMARY KEY,
+	from_account_id   TEXT NOT NULL,
+	to_account_id     TEXT NOT NULL,
+	amount            INTEGER NOT NULL,
+	currency          TEXT NOT NULL,
+	received_amount   INTEGER NOT NULL,
+	received_currency TEXT NOT NULL,
+	description       TEXT,
+	date              TEXT NOT NULL,
+	created_at        TEXT NOT NULL,
+	updated_at        TEXT NOT NULL,
+	is_deleted        INTEGER NOT NULL DEFAULT 0,
+	version           INTEGER NOT NULL DEFAULT 0,
+	sealed            TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS transfers_by_date ON transfers (is_deleted, date DESC, created_at DESC);`
+
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("failed to create projection tables: %w", err)
+	}
+
+	// Bases creadas antes de que las proyecciones guardaran la versión aplicada y los
+	// datos cifrados
+	for _, table := range []string{"movements", "categories"} {
+		if err := addColumnIfMissing(db, table, "version", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+			return nil, err
+		}
+	}
+	if err := addColumnIfMissing(db, "movements", "sealed", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return nil, err
+	}
+	// Los movimientos anteriores a las cuentas no tienen cuenta
+	if err := addColumnIfMissing(db, "movements", "account_id", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return nil, err
+	}
+	if err := addColumnIfMissing(db, "categories", "archived", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return nil, err
+	}
+	if err := addColumnIfMissing(db, "categories", "parent_id", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return nil, err
+	}
+	if err := addColumnIfMissing(db, "categories", "kind", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return nil, err
+	}
+	if err := migrateAmounts(db); err != nil {
+		return nil, err
+	}
+	// Después de agregar account_id a las bases anteriores a las cuentas
+	if _, err := db.Exec(accountIndexes); err != nil {
+		return nil, fmt.Errorf("failed to create account indexes: %w", err)
+	}
+
+	return &SQLiteProjectionStore{db: db}, nil
+}
+
+// SetSealer activa el cifrado del importe y la descripción de los movimientos
+func (ps *SQLiteProjectionStore) SetSealer(sealer Sealer) {
+	ps.sealer = sealer
+}
+
+// ProcessEvent procesa un evento y actualiza las proyecciones
+func (ps *SQLiteProjectionStore) ProcessEvent(ctx context.Context, event events.StoredEvent) error {
+	return dispatchEvent(ctx, ps, event)
+}
+
+func (ps *SQLiteProjectionStore) handleCategoryCreated(ctx context.Context, category CategoryProjection) error {
+	if category.ID == "" || category.Name == "" {
+		return fmt.Errorf("invalid category created event: missing required fields")
+	}
+
+	_, err := ps.db.ExecContext(ctx, `
+INSERT INTO categories (id, name, parent_id, kind, created_at, updated_at, is_deleted, version) VALUES (?, ?, ?, ?, ?, ?, 0, ?)
+ON CONFLICT (id) DO UPDATE SET name = excluded.name, parent_id = excluded.parent_id, kind = excluded.kind,
+	created_at = excluded.created_at, updated_at = excluded.updated_at, is_deleted = 0, version = excluded.version
+WHERE categories.version < excluded.version`,
+		category.ID, category.Name, category.ParentID, category.Kind, formatTime(category.CreatedAt), formatTime(category.UpdatedAt), category.Version)
+	if err != nil {
+		return fmt.Errorf("failed to upsert category projection: %w", err)
+	}
+
+	log.Printf("Category projection updated: %s - %s", category.ID, category.Name)
+	return nil
+}
+
+func (ps *SQLiteProjectionStore) handleCategoryParentChanged(ctx context.Context, categoryID, parentID string, occurredAt time.Time, sequence int) error {
+	_, err := ps.db.ExecContext(ctx,
+		`UPDATE categories SET parent_id = ?, updated_at = ?, version = ? WHERE id = ? AND version < ?`,
+		parentID, formatTime(occurredAt), sequence, categoryID, sequence)
+	if err != nil {
+		return fmt.Errorf("failed to move category projection: %w", err)
+	}
+
+	log.Printf("Category projection moved: %s -> %q", categoryID, parentID)
+	return nil
+}
+
+func (ps *SQLiteProjectionStore) handleCategoryKindChanged(ctx context.Context, categoryID, kind string, occurredAt time.Time, sequence int) error {
+	_, err := ps.db.ExecContext(ctx,
+		`UPDATE categories SET kind = ?, updated_at = ?, version = ? WHERE id = ? AND version < ?`,
+		kind, formatTime(occurredAt), sequence, categoryID, sequence)
+	if err != nil {
+		return fmt.Errorf("failed to change category projection kind: %w", err)
+	}
+
+	log.Printf("Category projection kind changed: %s - %s", categoryID, kind)
+	return nil
+}
+
+// handleCategoryRenamed renombra la categoría y, si el evento es más nuevo que la
+// proyección, actualiza el nombre en todos sus movimientos
+func (ps *SQLiteProjectionStore) handleCategoryRenamed(ctx context.Context, categoryID, name string, occurredAt time.Time, sequence int) error {
+	tx, err := ps.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin category rename: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE categories SET name = ?, updated_at = ?, version = ? WHERE id = ? AND version < ?`,
+		name, formatTime(occurredAt), sequence, categoryID, sequence)
+	if err != nil {
+		return fmt.Errorf("failed to rename category projection: %w", err)
+	}
+	if applied, err := result.RowsAffected(); err != nil || applied == 0 {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE movements SET category_name = ? WHERE category_id = ?`, name, categoryID); err != nil {
+		return fmt.Errorf("failed to rename category in movements: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit category rename: %w", err)
+	}
+
+	log.Printf("Category projection renamed: %s - %s", categoryID, name)
+	return nil
+}
+
+func (ps *SQLiteProjectionStore) handleCategoryArchived(ctx context.Context, categoryID string, archived bool, occurredAt time.Time, sequence int) error {
+	_, err := ps.db.ExecContext(ctx,
+		`UPDATE categories SET archived = ?, updated_at = ?, version = ? WHERE id = ? AND version < ?`,
+		archived, formatTime(occurredAt), sequence, categoryID, sequence)
+	if err != nil {
+		return fmt.Errorf("failed to archive category projection: %w", err)
+	}
+
+	log.Printf("Category projection archived: %s - %t", categoryID, archived)
+	return nil
+}
+
+func (ps *SQLiteProjectionStore) handleCategoryDeleted(ctx context.Context, categoryID string, occurredAt time.Time, sequence int) error {
+	_, err := ps.db.ExecContext(ctx,
+		`UPDATE categories SET is_deleted = 1, updated_at = ?, version = ? WHERE id = ? AND version < ?`,
+		formatTime(occurredAt), sequence, categoryID, sequence)
+	if err != nil {
+		return fmt.Errorf("failed to delete category projection: %w", err)
+	}
+
+	log.Printf("Category projection deleted: %s", categoryID)
+	return nil
+}
+
+// handleCategoryMerged elimina la categoría y pasa sus movimientos y subcategorías a la
+// categoría destino
+func (ps *SQLiteProjectionStore) handleCategoryMerged(ctx context.Context, categoryID, targetID string, occurredAt time.Time, sequence int) error {
+	targetName := ps.categoryName(ctx, targetID)
+
+	tx, err := ps.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin category merge: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE categories SET is_deleted = 1, updated_at = ?, version = ? WHERE id = ? AND version < ?`,
+		formatTime(occurredAt), sequence, categoryID, sequence)
+	if err != nil {
+		return fmt.Errorf("failed to merge category projection: %w", err)
+	}
+	if applied, err := result.RowsAffected(); err != nil || applied == 0 {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE movements SET category_id = ?, category_name = ? WHERE category_id = ?`,
+		targetID, targetName, categoryID); err != nil {
+		return fmt.Errorf("failed to move movements to merged category: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE categories SET parent_id = ? WHERE parent_id = ?`, targetID, categoryID); err != nil {
+		return fmt.Errorf("failed to move subcategories to merged category: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit category merge: %w", err)
+	}
+
+	log.Printf("Category projection merged: %s -> %s", categoryID, targetID)
+	return nil
+}
+
+func (ps *SQLiteProjectionStore) handleMovementCreated(ctx context.Context, change movementChange) error {
+	if change.ID == "" {
+		return fmt.Errorf("invalid %s created event: missing ID", change.Type)
+	}
+
+	date := change.Date
+	if date.IsZero() {
+		date = change.OccurredAt
+	}
+
+	amount, description, sealed, err := sealMovement(ps.sealer, change.Owner, change.Amount, change.Description)
+	if err != nil {
+		return err
+	}
+
+	occurredAt := formatTime(change.OccurredAt)
+	_, err = ps.db.ExecContext(ctx, `
+INSERT INTO movements (id, type, account_id, category_id, category_name, amount, currency, description, date, created_at, updated_at, is_deleted, version, sealed)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?)
+ON CONFLICT (id) DO UPDATE SET type = excluded.type, account_id = excluded.account_id, category_id = excluded.category_id,
+	category_name = excluded.category_name, amount = excluded.amount, currency = excluded.currency, description = excluded.description,
+	date = excluded.date, created_at = excluded.created_at, updated_at = excluded.updated_at, is_deleted = 0,
+	version = excluded.version, sealed = excluded.sealed
+WHERE movements.version < excluded.version`,
+		change.ID, change.Type, change.AccountID, change.CategoryID, ps.categoryName(ctx, change.CategoryID), amount.Amount, amount.Currency,
+		description, formatTime(date), occurredAt, occurredAt, change.Sequence, sealed)
+	if err != nil {
+		return fmt.Errorf("failed to upsert movement projection: %w", err)
+	}
+
+	log.Printf("%s projection updated: %s - %s", change.Type, change.ID, change.Amount)
+	return nil
+}
+
+func (ps *SQLiteProjectionStore) handleMovementUpdated(ctx context.Context, change movementChange) error {
+	if change.ID == "" {
+		return fmt.Errorf("invalid %s updated event: missing ID", change.Type)
+	}
+
+	amount, description, sealed, err := sealMovement(ps.sealer, change.Owner, change.Amount, change.Description)
+	if err != nil {
+		return err
+	}
+
+	_, err = ps.db.ExecContext(ctx, `
+UPDATE movements SET account_id = ?, category_id = ?, category_name = ?, amount = ?, currency = ?, description = ?, date = ?,
+	updated_at = ?, version = ?, sealed = ?
+WHERE id = ? AND version < ?`,
+		change.AccountID, change.CategoryID, ps.categoryName(ctx, change.CategoryID), amount.Amount, amount.Currency, description,
+		formatTime(change.Date), formatTime(change.OccurredAt), change.Sequence, sealed, change.ID, change.Sequence)
+	if err != nil {
+		return fmt.Errorf("failed to update movement projection: %w", err)
+	}
+
+	log.Printf("%s projection updated: %s", change.Type, change.ID)
+	return nil
+}
+
+func (ps *SQLiteProjectionStore) handleMovementDeleted(ctx context.Context, movementType, movementID string, occurredAt time.Time, sequence int) error {
+	if movementID == "" {
+		return fmt.Errorf("invalid %s deleted event: missing ID", movementType)
+	}
+
+	// Marcar como eliminado (soft delete)
+	_, err := ps.db.ExecContext(ctx,
+		`UPDATE movements SET is_deleted = 1, updated_at = ?, version = ? WHERE id = ? AND version < ?`,
+		formatTime(occurredAt), sequence, movementID, sequence)
+	if err != nil {
+		return fmt.Errorf("failed to delete movement projection: %w", err)
+	}
+
+	log.Printf("%s projection deleted: %s", movementType, movementID)
+	return nil
+}
+
+// handleMovementTagged agrega o quita una etiqueta del movimiento
+func (ps *SQLiteProjectionStore) handleMovementTagged(ctx context.Context, movementType, movementID, tag string, added bool, occurredAt time.Time, sequence int) error {
+	tx, err := ps.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin movement tag change: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`UPDATE movements SET updated_at = ?, version = ? WHERE id = ? AND version < ?`,
+		formatTime(occurredAt), sequence, movementID, sequence)
+	if err != nil {
+		return fmt.Errorf("failed to tag movement projection: %w", err)
+	}
+	if applied, err := result.RowsAffected(); err != nil || applied == 0 {
+		return err
+	}
+
+	if added {
+		_, err = tx.ExecContext(ctx, `INSERT OR IGNORE INTO movement_tags (movement_id, tag) VALUES (?, ?)`, movementID, tag)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM movement_tags WHERE movement_id = ? AND tag = ?`, movementID, tag)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to tag movement projection: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit movement tag change: %w", err)
+	}
+
+	log.Printf("%s projection tagged: %s - %s", movementType, movementID, tag)
+	return nil
+}
+
+func (ps *SQLiteProjectionStore) handleAccountCreated(ctx context.Context, account AccountProjection) error {
+	if account.ID == "" || account.Name == "" {
+		return fmt.Errorf("invalid account created event: missing required fields")
+	}
+
+	_, err := ps.db.ExecContext(ctx, `
+INSERT INTO accounts (id, name, kind, currency, created_at, updated_at, version) VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE SET name = excluded.name, kind = excluded.kind, currency = excluded.currency,
+	created_at = excluded.created_at, updated_at = excluded.updated_at, version = excluded.version
+WHERE accounts.version < excluded.version`,
+		account.ID, account.Name, account.Kind, account.Currency, formatTime(account.CreatedAt), formatTime(account.UpdatedAt), account.Version)
+	if err != nil {
+		return fmt.Errorf("failed to upsert account projection: %w", err)
+	}
+
+	log.Printf("Account projection updated: %s - %s", account.ID, account.Name)
+	return nil
+}
+
+func (ps *SQLiteProjectionStore) handleAccountRenamed(ctx context.Context, accountID, name string, occurredAt time.Time, sequence int) error {
+	_, err := ps.db.ExecContext(ctx,
+		`UPDATE accounts SET name = ?, updated_at = ?, version = ? WHERE id = ? AND version < ?`,
+		name, formatTime(occurredAt), sequence, accountID, sequence)
+	if err != nil {
+		return fmt.Errorf("failed to rename account projection: %w", err)
+	}
+
+	log.Printf("Account projection renamed: %s - %s", accountID, name)
+	return nil
+}
+
+func (ps *SQLiteProjectionStore) handleAccountOpeningBalanceSet(ctx context.Context, change openingBalanceChange) error {
+	amount, _, sealed, err := sealMovement(ps.sealer, change.Owner, change.Amount, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = ps.db.ExecContext(ctx, `
+UPDATE accounts SET opening_amount = ?, opening_currency = ?, opening_date = ?, sealed = ?, updated_at = ?, version = ?
+WHERE id = ? AND version < ?`,
+		amount.Amount, amount.Currency, formatTime(change.Date), sealed, formatTime(change.OccurredAt), change.Sequence,
+		change.AccountID, change.Sequence)
+	if err != nil {
+		return fmt.Errorf("failed to update account opening balance: %w", err)
+	}
+
+	log.Printf("Account projection opening balance set: %s", change.AccountID)
+	return nil
+}
+
+func (ps *SQLiteProjectionStore) handleAccountClosed(ctx context.Context, accountID string, occurredAt time.Time, sequence int) error {
+	_, err := ps.db.ExecContext(ctx,
+		`UPDATE accounts SET closed = 1, updated_at = ?, version = ? WHERE id = ? AND version < ?`,
+		formatTime(occurredAt), sequence, accountID, sequence)
+	if err != nil {
+		return fmt.Errorf("failed to close account projection: %w", err)
+	}
+
+	log.Printf("Account projection closed: %s", accountID)
+	return nil
+}
+
+func (ps *SQLiteProjectionStore) handleTransferCreated(ctx context.Context, change transferChange) error {
+	if change.ID == "" {
+		return fmt.Errorf("invalid transfer created event: missing ID")
+	}
+
+	transfer, err := change.projection(ps.sealer)
+	if err != nil {
+		return err
+	}
+
+	occurredAt := formatTime(change.OccurredAt)
+	_, err = ps.db.ExecContext(ctx, `
+INSERT INTO transfers (id, from_account_id, to_account_id, amount, currency, received_amount, received_currency, description, date,
+	created_at, updated_at, is_deleted, version, sealed)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 0, ?, ?)
+ON CONFLICT (id) DO UPDATE SET from_account_id = excluded.from_account_id, to_account_id = excluded.to_account_id,
+	amount = excluded.amount, currency = excluded.currency, received_amount = excluded.received_amount,
+	received_currency = excluded.received_currency, description = excluded.description, date = excluded.date,
+	created_at = excluded.created_at, updated_at = excluded.updated_at, is_deleted = 0, version = excluded.version,
+	sealed = excluded.sealed
+WHERE transfers.version < excluded.version`,
+		transfer.ID, transfer.FromAccountID, transfer.ToAccountID, transfer.Amount.Amount, transfer.Amount.Currency,
+		transfer.ReceivedAmount.Amount, transfer.ReceivedAmount.Currency, transfer.Description, formatTime(transfer.Date),
+		occurredAt, occurredAt, change.Sequence, transfer.Sealed)
+	if err != nil {
+		return fmt.Errorf("failed to upsert transfer projection: %w", err)
+	}
+
+	log.Printf("transfer projection updated: %s - %s", change.ID, change.Amount)
+	return nil
+}
+
+func (ps *SQLiteProjectionStore) handleTransferUpdated(ctx context.Context, change transferChange) error {
+	if change.ID == "" {
+		return fmt.Errorf("invalid transfer updated event: missing ID")
+	}
+
+	transfer, err := change.projection(ps.sealer)
+	if err != nil {
+		return err
+	}
+
+	_, err = ps.db.ExecContext(ctx, `
+UPDATE transfers SET from_account_id = ?, to_account_id = ?, amount = ?, currency = ?, received_amount = ?, received_currency = ?,
+	description = ?, date = ?, updated_at = ?, version = ?, sealed = ?
+WHERE id = ? AND version < ?`,
+		transfer.FromAccountID, transfer.ToAccountID, transfer.Amount.Amount, transfer.Amount.Currency, transfer.ReceivedAmount.Amount,
+		transfer.ReceivedAmount.Currency, transfer.Description, formatTime(transfer.Date), formatTime(change.OccurredAt),
+		change.Sequence, transfer.Sealed, change.ID, change.Sequence)
+	if err != nil {
+		return fmt.Errorf("failed to update transfer projection: %w", err)
+	}
+
+	log.Printf("transfer projection updated: %s", change.ID)
+	return nil
+}
+
+func (ps *SQLiteProjectionStore) handleTransferDeleted(ctx context.Context, transferID string, occurredAt time.Time, sequence int) error {
+	if transferID == "" {
+		return fmt.Errorf("invalid transfer deleted event: missing ID")
+	}
+
+	// Marcar como eliminada (soft delete)
+	_, err := ps.db.ExecContext(ctx,
+		`UPDATE transfers SET is_deleted = 1, updated_at = ?, version = ? WHERE id = ? AND version < ?`,
+		formatTime(occurredAt), sequence, transferID, sequence)
+	if err != nil {
+		return fmt.Errorf("failed to delete transfer projection: %w", err)
+	}
+
+	log.Printf("transfer projection deleted: %s", transferID)
+	return nil
+}
+
+func (ps *SQLiteProjectionStore) Reset(ctx context.Context) error {
+	if _, err := ps.db.ExecContext(ctx, `DELETE FROM movements; DELETE FROM movement_tags; DELETE FROM categories; DELETE FROM accounts; DELETE FROM transfers`); err != nil {
+		return fmt.Errorf("failed to reset projections: %w", err)
+	}
+	return nil
+}
+
+// migrateAmounts lleva los movimientos proyectados antes de que los importes tuvieran
+// moneda (montos REAL en guaraníes) a unidades menores enteras en PYG
+func migrateAmounts(db *sql.DB) error {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('movements') WHERE name = 'currency'`).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to inspect movements table: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to migrate movement amounts: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`ALTER TABLE movements ADD COLUMN currency TEXT NOT NULL DEFAULT ''`); err != nil {
+		return fmt.Errorf("failed to add currency column to movements: %w", err)
+	}
+	// Los movimientos cifrados guardan importe 0: su moneda viaja en el valor cifrado
+	if _, err := tx.Exec(`UPDATE movements SET amount = CAST(ROUND(amount) AS INTEGER),
+	currency = CASE WHEN sealed = '' THEN 'PYG' ELSE '' END`); err != nil {
+		return fmt.Errorf("failed to migrate movement amounts: %w", err)
+	}
+	return tx.Commit()
+}
+
+// addColumnIfMissing agrega una columna a una tabla creada por una versión anterior
+func addColumnIfMissing(db *sql.DB, table, column, definition string) error {
+	var count int
+	err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&count)
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s table: %w", table, err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add %s column to %s: %w", column, table, err)
+	}
+	return nil
+}
+
+func (ps *SQLiteProjectionStore) categoryName(ctx context.Context, categoryID string) string {
+	categoryName := "Sin categoría"
+	if categoryID != "" {
+		var name string
+		err := ps.db.QueryRowContext(ctx, `SELECT name FROM categories WHERE id = ?`, categoryID).Scan(&name)
+		if err == nil {
+			categoryName = name
+		}
+	}
+	return categoryName
+}
+
+// Query methods for reading projections
+func (ps *SQLiteProjectionStore) GetMovements(ctx context.Context, startDate, endDate *time.Time, tag string, limit, offset int) ([]MovementProjection, int, error) {
+	where := "is_deleted = 0"
+	var args []interface{}
+
+	if startDate != nil {
+		where += " AND date >= ?"
+		args = append(args, formatTime(*startDate))
+	}
+	if endDate != nil {
+		where += " AND date <= ?"
+		args = append(args, formatTime(*endDate))
+	}
+	if tag != "" {
+		where += " AND id IN (SELECT movement_id FROM movement_tags WHERE tag = ?)"
+		args = append(args, tag)
+	}
+
+	var total int
+	if err := ps.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM movements WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count movements: %w", err)
+	}
+
+	query := "SELECT " + movementColumns + " FROM movements WHERE " + where + " ORDER BY date DESC, created_at DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	} else if offset > 0 {
+		query += " LIMIT -1"
+	}
+	if offset > 0 {
+		query += " OFFSET ?"
+		args = append(args, offset)
+	}
+
+	rows, err := ps.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find movements: %w", err)
+	}
+	defer rows.Close()
+
+	var movements []MovementProjection
+	for rows.Next() {
+		movement, err := scanMovement(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to decode movements: %w", err)
+		}
+		openMovement(ps.sealer, &movement)
+		movements = append(movements, movement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode movements: %w", err)
+	}
+
+	return movements, total, nil
+}
+
+func (ps *SQLiteProjectionStore) GetCategories(ctx context.Context) ([]CategoryProjection, error) {
+	rows, err := ps.db.QueryContext(ctx,
+		`SELECT id, name, parent_id, kind, archived, created_at, updated_at, is_deleted FROM categories WHERE is_deleted = 0 ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []CategoryProjection
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode categories: %w", err)
+		}
+		categories = append(categories, category)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to decode categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+func (ps *SQLiteProjectionStore) GetMovementByID(ctx context.Context, id string) (*MovementProjection, error) {
+	row := ps.db.QueryRowContext(ctx,
+		"SELECT "+movementColumns+" FROM movements WHERE id = ? AND is_deleted = 0", id)
+
+	movement, err := scanMovement(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find movement: %w", err)
+	}
+
+	openMovement(ps.sealer, &movement)
+	return &movement, nil
+}
+
+func (ps *SQLiteProjectionStore) GetCategoryByID(ctx context.Context, id string) (*CategoryProjection, error) {
+	row := ps.db.QueryRowContext(ctx,
+		`SELECT id, name, parent_id, kind, archived, created_at, updated_at, is_deleted FROM categories WHERE id = ? AND is_deleted = 0`, id)
+
+	category, err := scanCategory(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find category: %w", err)
+	}
+
+	return &category, nil
+}
+
+// accountColumns columnas que lee scanAccount
+const accountColumns = "id, name, kind, currency, opening_amount, opening_currency, opening_date, closed, created_at, updated_at, sealed"
+
+func (ps *SQLiteProjectionStore) GetAccounts(ctx context.Context) ([]AccountProjection, error) {
+	rows, err := ps.db.QueryContext(ctx, "SELECT "+accountColumns+" FROM accounts ORDER BY name")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []AccountProjection
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode accounts: %w", err)
+		}
+		openAccount(ps.sealer, &account)
+		accounts = append(accounts, account)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to decode accounts: %w", err)
+	}
+
+	return accounts, nil
+}
+
+func (ps *SQLiteProjectionStore) GetAccountByID(ctx context.Context, id string) (*AccountProjection, error) {
+	row := ps.db.QueryRowContext(ctx, "SELECT "+accountColumns+" FROM accounts WHERE id = ?", id)
+
+	account, err := scanAccount(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find account: %w", err)
+	}
+
+	openAccount(ps.sealer, &account)
+	return &account, nil
+}
+
+// accountIndexes índices con que GetAccountTotals lee los movimientos y transferencias
+// de cada cuenta
+const accountIndexes = `
+CREATE INDEX IF NOT EXISTS movements_by_account ON movements (account_id, is_deleted);
+CREATE INDEX IF NOT EXISTS transfers_by_from_account ON transfers (from_account_id, is_deleted);
+CREATE INDEX IF NOT EXISTS transfers_by_to_account ON transfers (to_account_id, is_deleted);`
+
+// accountActivity movimientos y transferencias de cada cuenta desde el día de su saldo
+// inicial, con el signo con que suman al saldo. Recibe dos veces la cuenta pedida
+// (vacía para todas).
+const accountActivity = `
+SELECT a.id, t.kind, t.row_id, t.amount, t.currency, t.sealed
+FROM accounts a JOIN (
+	SELECT account_id, type AS kind, id AS row_id, CASE type WHEN 'expense' THEN -amount ELSE amount END AS amount,
+		currency, date, sealed
+	FROM movements WHERE is_deleted = 0
+	UNION ALL
+	SELECT from_account_id, 'sent', id, -amount, currency, date, sealed FROM transfers WHERE is_deleted = 0
+	UNION ALL
+	SELECT to_account_id, 'received', id, received_amount, received_currency, date, sealed FROM transfers WHERE is_deleted = 0
+) t ON t.account_id = a.id AND substr(t.date, 1, 10) >= substr(a.opening_date, 1, 10)
+WHERE ? = '' OR a.id = ?`
+
+// GetAccountTotals suma los importes en claro con la consulta; los cifrados se leen fila
+// por fila para abrirlos
+func (ps *SQLiteProjectionStore) GetAccountTotals(ctx context.Context, accountID string) ([]AccountTotal, error) {
+	totals := accountTotals{}
+
+	rows, err := ps.db.QueryContext(ctx,
+		"SELECT id, currency, SUM(amount) FROM ("+accountActivity+") WHERE sealed = '' GROUP BY id, currency",
+		accountID, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum account movements: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, currency string
+		var amount int64
+		if err := rows.Scan(&id, &currency, &amount); err != nil {
+			return nil, fmt.Errorf("failed to decode account totals: %w", err)
+		}
+		totals.add(id, money.New(amount, currency))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to decode account totals: %w", err)
+	}
+
+	sealedRows, err := ps.db.QueryContext(ctx,
+		"SELECT id, kind, row_id, sealed FROM ("+accountActivity+") WHERE sealed <> ''",
+		accountID, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find encrypted account movements: %w", err)
+	}
+	defer sealedRows.Close()
+	for sealedRows.Next() {
+		var id, kind, rowID, sealed string
+		if err := sealedRows.Scan(&id, &kind, &rowID, &sealed); err != nil {
+			return nil, fmt.Errorf("failed to decode account totals: %w", err)
+		}
+		totals.addSealed(ps.sealer, id, kind, rowID, sealed)
+	}
+	if err := sealedRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to decode account totals: %w", err)
+	}
+
+	return totals.result(), nil
+}
+
+// transferColumns columnas que lee scanTransfer
+const transferColumns = "id, from_account_id, to_account_id, amount, currency, received_amount, received_currency, description, date, " +
+	"created_at, updated_at, is_deleted, sealed"
+
+func (ps *SQLiteProjectionStore) GetTransfers(ctx context.Context, startDate, endDate *time.Time) ([]TransferProjection, error) {
+	where := "is_deleted = 0"
+	var args []interface{}
+
+	if startDate != nil {
+		where += " AND date >= ?"
+		args = append(args, formatTime(*startDate))
+	}
+	if endDate != nil {
+		where += " AND date <= ?"
+		args = append(args, formatTime(*endDate))
+	}
+
+	rows, err := ps.db.QueryContext(ctx,
+		"SELECT "+transferColumns+" FROM transfers WHERE "+where+" ORDER BY date DESC, created_at DESC", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find transfers: %w", err)
+	}
+	defer rows.Close()
+
+	var transfers []TransferProjection
+	for rows.Next() {
+		transfer, err := scanTransfer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode transfers: %w", err)
+		}
+		openTransfer(ps.sealer, &transfer)
+		transfers = append(transfers, transfer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to decode transfers: %w", err)
+	}
+
+	return transfers, nil
+}
+
+func (ps *SQLiteProjectionStore) GetTransferByID(ctx context.Context, id string) (*TransferProjection, error) {
+	row := ps.db.QueryRowContext(ctx, "SELECT "+transferColumns+" FROM transfers WHERE id = ? AND is_deleted = 0", id)
+
+	transfer, err := scanTransfer(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find transfer: %w", err)
+	}
+
+	openTransfer(ps.sealer, &transfer)
+	return &transfer, nil
+}
+
+// movementColumns columnas que lee scanMovement; las etiquetas llegan unidas por comas
+const movementColumns = "id, type, account_id, category_id, category_name, amount, currency, description, date, created_at, updated_at, is_deleted, sealed, " +
+	"(SELECT group_concat(tag, ',') FROM movement_tags WHERE movement_id = movements.id)"
+
+// rowScanner abstrae *sql.Row y *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMovement(row rowScanner) (MovementProjection, error) {
+	var movement MovementProjection
+	var description, tags sql.NullString
+	var date, createdAt, updatedAt string
+
+	err := row.Scan(&movement.ID, &movement.Type, &movement.AccountID, &movement.CategoryID, &movement.CategoryName,
+		&movement.Amount.Amount, &movement.Amount.Currency, &description, &date, &createdAt, &updatedAt, &movement.IsDeleted, &movement.Sealed, &tags)
+	if err != nil {
+		return MovementProjection{}, err
+	}
+
+	if description.Valid {
+		movement.Description = &description.String
+	}
+	if tags.Valid {
+		movement.Tags = strings.Split(tags.String, ",")
+		sort.Strings(movement.Tags)
+	}
+	movement.Date = parseTime(date)
+	movement.CreatedAt = parseTime(createdAt)
+	movement.UpdatedAt = parseTime(updatedAt)
+
+	return movement, nil
+}
+
+func scanCategory(row rowScanner) (CategoryProjection, error) {
+	var category CategoryProjection
+	var createdAt, updatedAt string
+
+	if err := row.Scan(&category.ID, &category.Name, &category.ParentID, &category.Kind, &category.Archived, &createdAt, &updatedAt, &category.IsDeleted); err != nil {
+		return CategoryProjection{}, err
+	}
+
+	category.CreatedAt = parseTime(createdAt)
+	category.UpdatedAt = parseTime(updatedAt)
+
+	return category, nil
+}
+
+func scanAccount(row rowScanner) (AccountProjection, error) {
+	var account AccountProjection
+	var openingDate, createdAt, updatedAt string
+
+	err := row.Scan(&account.ID, &account.Name, &account.Kind, &account.Currency, &account.OpeningBalance.Amount,
+		&account.OpeningBalance.Currency, &openingDate, &account.Closed, &createdAt, &updatedAt, &account.Sealed)
+	if err != nil {
+		return AccountProjection{}, err
+	}
+
+	account.OpeningDate = parseTime(openingDate)
+	account.CreatedAt = parseTime(createdAt)
+	account.UpdatedAt = parseTime(updatedAt)
+
+	return account, nil
+}
+
+func scanTransfer(row rowScanner) (TransferProjection, error) {
+	var transfer TransferProjection
+	var description sql.NullString
+	var date, createdAt, updatedAt string
+
+	err := row.Scan(&transfer.ID, &transfer.FromAccountID, &transfer.ToAccountID, &transfer.Amount.Amount, &transfer.Amount.Currency,
+		&transfer.ReceivedAmount.Amount, &transfer.ReceivedAmount.Currency, &description, &date, &createdAt, &updatedAt,
+		&transfer.IsDeleted, &transfer.Sealed)
+	if err != nil {
+		return TransferProjection{}, err
+	}
+
+	if description.Valid {
+		transfer.Description = &description.String
+	}
+	transfer.Date = parseTime(date)
+	transfer.CreatedAt = parseTime(createdAt)
+	transfer.UpdatedAt = parseTime(updatedAt)
+
+	return transfer, nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+func parseTime(value string) time.Time {
+	t, _ := time.Parse(sqliteTimeLayout, value)
+	return t
+}
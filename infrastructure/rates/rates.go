@@ -0,0 +1,213 @@
+// Package rates carga cotizaciones de monedas desde archivos CSV locales y convierte
+// importes con la cotización vigente en una fecha.
+//
+// Cada archivo *.csv del directorio tiene la cabecera date,from,to,rate y una fila por
+// cotización: 1 unidad de from equivale a rate unidades de to en la fecha indicada
+// (YYYY-MM-DD). Las líneas que empiezan con # se ignoran. Por ejemplo:
+//
+//	date,from,to,rate
+//	2025-07-01,USD,PYG,7985.50
+//	2025-07-01,BRL,PYG,1460
+package rates
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"escama/domain/money"
+)
+
+// dayLayout formato de las fechas de las cotizaciones
+const dayLayout = "2006-01-02"
+
+// ErrRateNotFound se devuelve cuando no hay cotización entre las monedas en la fecha
+// pedida ni antes
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// Rate cotización de un par de monedas en un día
+type Rate struct {
+	Date  string // YYYY-MM-DD
+	From  string
+	To    string
+	Value *big.Rat // unidades de To por unidad de From
+}
+
+type pair struct {
+	from, to string
+}
+
+// Store cotizaciones cargadas en memoria, ordenadas por fecha para cada par de monedas
+type Store struct {
+	rates map[pair][]Rate
+}
+
+// Load lee todos los archivos CSV del directorio. Un directorio inexistente da un Store
+// vacío: solo se pueden convertir importes a su misma moneda.
+func Load(dir string) (*Store, error) {
+	s := &Store{rates: make(map[pair][]Rate)}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.csv"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rate files: %w", err)
+	}
+	for _, file := range files {
+		if err := s.loadFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	for key := range s.rates {
+		rates := s.rates[key]
+		sort.SliceStable(rates, func(i, j int) bool { return rates[i].Date < rates[j].Date })
+	}
+	return s, nil
+}
+
+func (s *Store) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open rate file: %w", err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if strings.ToLower(strings.Join(header, ",")) != "date,from,to,rate" {
+		return fmt.Errorf("invalid header in %s: expected date,from,to,rate", path)
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		rate, err := parseRate(record)
+		if err != nil {
+			return fmt.Errorf("invalid rate in %s line %d: %w", path, line, err)
+		}
+		key := pair{rate.From, rate.To}
+		s.rates[key] = append(s.rates[key], rate)
+	}
+}
+
+func parseRate(record []string) (Rate, error) {
+	date, err := time.Parse(dayLayout, strings.TrimSpace(record[0]))
+	if err != nil {
+		return Rate{}, fmt.Errorf("invalid date %q", record[0])
+	}
+
+	from := strings.ToUpper(strings.TrimSpace(record[1]))
+	to := strings.ToUpper(strings.TrimSpace(record[2]))
+	if from == "" || to == "" || from == to {
+		return Rate{}, fmt.Errorf("invalid currency pair %q/%q", record[1], record[2])
+	}
+
+	// big.Rat lee el decimal exacto, sin pasar por float64
+	value, ok := new(big.Rat).SetString(strings.TrimSpace(record[3]))
+	if !ok || value.Sign() <= 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q", record[3])
+	}
+
+	return Rate{Date: date.Format(dayLayout), From: from, To: to, Value: value}, nil
+}
+
+// Lookup devuelve la cotización de from a to vigente el día de on (en su zona horaria):
+// la de ese día o, si no hay, la más reciente anterior. Si solo existe la cotización
+// inversa (de to a from), se usa su recíproca.
+func (s *Store) Lookup(from, to string, on time.Time) (*big.Rat, error) {
+	day := on.Format(dayLayout)
+
+	direct, directOK := latest(s.rates[pair{from, to}], day)
+	inverse, inverseOK := latest(s.rates[pair{to, from}], day)
+	switch {
+	case directOK && (!inverseOK || direct.Date >= inverse.Date):
+		return direct.Value, nil
+	case inverseOK:
+		return new(big.Rat).Inv(inverse.Value), nil
+	}
+	return nil, fmt.Errorf("%w: %s to %s on %s", ErrRateNotFound, from, to, day)
+}
+
+// Convert convierte el importe a la moneda indicada con la cotización del día on,
+// redondeando a la unidad menor de la moneda de destino
+func (s *Store) Convert(amount money.Money, currency string, on time.Time) (money.Money, error) {
+	currency = strings.ToUpper(currency)
+	if amount.Currency == currency || amount.IsZero() {
+		return money.New(amount.Amount, currency), nil
+	}
+
+	rate, err := s.Lookup(amount.Currency, currency, on)
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	// unidades menores de destino = origen * cotización * 10^(decimales destino - decimales origen)
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount.Amount), rate)
+	scale := money.MinorDigits(currency) - money.MinorDigits(amount.Currency)
+	factor := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(scale))), nil))
+	if scale >= 0 {
+		converted.Mul(converted, factor)
+	} else {
+		converted.Quo(converted, factor)
+	}
+
+	minor := roundHalfAway(converted)
+	if !minor.IsInt64() {
+		return money.Money{}, fmt.Errorf("converted amount overflows %s", currency)
+	}
+	return money.New(minor.Int64(), currency), nil
+}
+
+// latest devuelve la última cotización de la lista con fecha menor o igual a day
+func latest(rates []Rate, day string) (Rate, bool) {
+	i := sort.Search(len(rates), func(i int) bool { return rates[i].Date > day })
+	if i == 0 {
+		return Rate{}, false
+	}
+	return rates[i-1], true
+}
+
+// roundHalfAway redondea al entero más cercano; los medios se alejan del cero
+func roundHalfAway(value *big.Rat) *big.Int {
+	numerator := new(big.Int).Abs(value.Num())
+	denominator := value.Denom()
+
+	// (2|n| + d) / 2d
+	doubled := new(big.Int).Mul(numerator, big.NewInt(2))
+	doubled.Add(doubled, denominator)
+	rounded := doubled.Quo(doubled, new(big.Int).Mul(denominator, big.NewInt(2)))
+	if value.Sign() < 0 {
+		rounded.Neg(rounded)
+	}
+	return rounded
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,114 @@
+package rates
+
+import (
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"escama/domain/money"
+)
+
+// testRates cotizaciones de las pruebas: USD/PYG cambia el 10 de julio y el 5 de julio
+// solo hay cotización inversa (PYG/USD), más reciente que la directa
+const testRates = `date,from,to,rate
+# cotizaciones de prueba
+2025-07-10,USD,PYG,8010
+2025-07-01,USD,PYG,7985.50
+2025-07-05,PYG,USD,0.000125
+2025-07-01,USD,KWD,0.305
+`
+
+func loadTestStore(t *testing.T) *Store {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "rates.csv"), []byte(testRates), 0o644); err != nil {
+		t.Fatalf("failed to write rates: %v", err)
+	}
+	store, err := Load(dir)
+	if err != nil {
+		t.Fatalf("failed to load rates: %v", err)
+	}
+	return store
+}
+
+func TestConvert(t *testing.T) {
+	store := loadTestStore(t)
+	day := func(value string) time.Time {
+		parsed, err := time.Parse(dayLayout, value)
+		if err != nil {
+			t.Fatalf("invalid date %q: %v", value, err)
+		}
+		return parsed
+	}
+
+	cases := []struct {
+		name     string
+		amount   money.Money
+		currency string
+		on       string
+		want     money.Money
+		wantErr  error
+	}{
+		{"misma moneda", money.New(2550, "USD"), "usd", "2025-01-01", money.New(2550, "USD"), nil},
+		{"cero sin cotización", money.New(0, "BRL"), "USD", "2025-07-01", money.New(0, "USD"), nil},
+		{"USD a PYG el día de la cotización", money.New(2550, "USD"), "PYG", "2025-07-01", money.New(203630, money.PYG), nil},
+		{"USD a PYG con la cotización anterior más cercana", money.New(2550, "USD"), "PYG", "2025-07-03", money.New(203630, money.PYG), nil},
+		{"inversa más reciente que la directa", money.New(2550, "USD"), "PYG", "2025-07-06", money.New(204000, money.PYG), nil},
+		{"directa más reciente que la inversa", money.New(2550, "USD"), "PYG", "2025-07-15", money.New(204255, money.PYG), nil},
+		{"PYG a USD con la inversa", money.New(100000, money.PYG), "USD", "2025-07-01", money.New(1252, "USD"), nil},
+		{"PYG a USD con la directa", money.New(100000, money.PYG), "USD", "2025-07-06", money.New(1250, "USD"), nil},
+		{"a una moneda con más decimales", money.New(1000, "USD"), "KWD", "2025-07-01", money.New(3050, "KWD"), nil},
+		{"a una moneda con menos decimales", money.New(3050, "KWD"), "USD", "2025-07-01", money.New(1000, "USD"), nil},
+		{"medio se aleja del cero", money.New(100, "USD"), "PYG", "2025-07-01", money.New(7986, money.PYG), nil},
+		{"medio negativo se aleja del cero", money.New(-100, "USD"), "PYG", "2025-07-01", money.New(-7986, money.PYG), nil},
+		{"antes de la primera cotización", money.New(100, "USD"), "PYG", "2025-06-30", money.Money{}, ErrRateNotFound},
+		{"par sin cotizaciones", money.New(100, "BRL"), "USD", "2025-07-01", money.Money{}, ErrRateNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := store.Convert(tc.amount, tc.currency, day(tc.on))
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("Convert(%v, %s, %s) error = %v, want %v", tc.amount, tc.currency, tc.on, err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Convert(%v, %s, %s): %v", tc.amount, tc.currency, tc.on, err)
+			}
+			if got != tc.want {
+				t.Errorf("Convert(%v, %s, %s) = %+v, want %+v", tc.amount, tc.currency, tc.on, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRoundHalfAway(t *testing.T) {
+	cases := []struct {
+		num, denom int64
+		want       int64
+	}{
+		{0, 1, 0},
+		{7, 1, 7},
+		{1, 2, 1},
+		{-1, 2, -1},
+		{5, 2, 3},
+		{-5, 2, -3},
+		{7, 3, 2},
+		{-7, 3, -2},
+		{5, 3, 2},
+		{-5, 3, -2},
+		{249, 100, 2},
+		{-251, 100, -3},
+	}
+
+	for _, tc := range cases {
+		value := big.NewRat(tc.num, tc.denom)
+		if got := roundHalfAway(value); got.Int64() != tc.want {
+			t.Errorf("roundHalfAway(%s) = %s, want %d", value.RatString(), got, tc.want)
+		}
+	}
+}
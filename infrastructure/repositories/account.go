@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"escama/domain"
+	"escama/domain/events"
+	"escama/infrastructure/eventstore"
+)
+
+// AccountRepository maneja la persistencia de agregados Account vía Event Store
+type AccountRepository struct {
+	eventStore eventstore.EventStore
+}
+
+func NewAccountRepository(eventStore eventstore.EventStore) *AccountRepository {
+	return &AccountRepository{
+		eventStore: eventStore,
+	}
+}
+
+// Processed indica si el stream de la cuenta ya tiene eventos de un comando
+// con la clave de idempotencia dada
+func (r *AccountRepository) Processed(ctx context.Context, id, key string) (bool, error) {
+	return processedKey(ctx, r.eventStore, id, key)
+}
+
+// Save persiste los eventos uncommitted del agregado Account, verificando que el stream
+// no haya cambiado desde la versión con la que fue cargado
+func (r *AccountRepository) Save(ctx context.Context, account *domain.Account) error {
+	uncommittedEvents := account.UncommittedEvents()
+	if len(uncommittedEvents) == 0 {
+		return nil
+	}
+
+	if err := r.eventStore.Store(ctx, account.ID, "Account", account.Version, uncommittedEvents); err != nil {
+		return err
+	}
+
+	account.Version += len(uncommittedEvents)
+	account.ClearUncommittedEvents()
+	return nil
+}
+
+// GetByID reconstruye un agregado Account desde sus eventos; devuelve nil si no existe
+func (r *AccountRepository) GetByID(ctx context.Context, id string) (*domain.Account, error) {
+	storedEvents, err := r.eventStore.Load(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load events for account %s: %w", id, err)
+	}
+	if len(storedEvents) == 0 {
+		return nil, nil
+	}
+
+	account := &domain.Account{ID: id}
+	for _, storedEvent := range storedEvents {
+		domainEvent, err := storedEvent.Decode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode event for account %s: %w", id, err)
+		}
+
+		switch e := domainEvent.(type) {
+		case events.AccountCreated:
+			account.Name = e.Name
+			account.Kind = e.Kind
+			account.Currency = e.Currency
+		case events.AccountRenamed:
+			account.Name = e.Name
+		case events.AccountOpeningBalanceSet:
+			account.OpeningBalance = e.Amount
+			account.OpeningDate = e.Date
+		case events.AccountClosed:
+			account.Closed = true
+		default:
+			return nil, fmt.Errorf("unexpected %s event in account %s", storedEvent.EventType, id)
+		}
+	}
+
+	account.Version = len(storedEvents) // Versión cargada, usada como versión esperada al guardar
+	return account, nil
+}
@@ -2,8 +2,10 @@ package repositories
 
 import (
 	"context"
+	"fmt"
 
 	"escama/domain"
+	"escama/domain/events"
 	"escama/infrastructure/eventstore"
 )
 
@@ -18,17 +20,71 @@ func NewCategoryRepository(eventStore eventstore.EventStore) *CategoryRepository
 	}
 }
 
-// Save persiste los eventos uncommitted del agregado Category
+// Processed indica si el stream de la categoría ya tiene eventos de un comando
+// con la clave de idempotencia dada
+func (r *CategoryRepository) Processed(ctx context.Context, id, key string) (bool, error) {
+	return processedKey(ctx, r.eventStore, id, key)
+}
+
+// Save persiste los eventos uncommitted del agregado Category, verificando que el stream
+// no haya cambiado desde la versión con la que fue cargado
 func (r *CategoryRepository) Save(ctx context.Context, category *domain.Category) error {
 	uncommittedEvents := category.UncommittedEvents()
 	if len(uncommittedEvents) == 0 {
 		return nil
 	}
 
-	if err := r.eventStore.Store(ctx, category.ID, "Category", uncommittedEvents); err != nil {
+	if err := r.eventStore.Store(ctx, category.ID, "Category", category.Version, uncommittedEvents); err != nil {
 		return err
 	}
 
+	category.Version += len(uncommittedEvents)
 	category.ClearUncommittedEvents()
 	return nil
 }
+
+// GetByID reconstruye un agregado Category desde sus eventos; devuelve nil si no existe
+func (r *CategoryRepository) GetByID(ctx context.Context, id string) (*domain.Category, error) {
+	storedEvents, err := r.eventStore.Load(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load events for category %s: %w", id, err)
+	}
+	if len(storedEvents) == 0 {
+		return nil, nil
+	}
+
+	category := &domain.Category{ID: id}
+	for _, storedEvent := range storedEvents {
+		domainEvent, err := storedEvent.Decode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode event for category %s: %w", id, err)
+		}
+
+		switch e := domainEvent.(type) {
+		case events.CategoryCreated:
+			category.Name = e.Name
+			category.ParentID = e.ParentID
+			category.Kind = e.Kind
+		case events.CategoryKindChanged:
+			category.Kind = e.Kind
+		case events.CategoryParentChanged:
+			category.ParentID = e.ParentID
+		case events.CategoryRenamed:
+			category.Name = e.Name
+		case events.CategoryArchived:
+			category.Archived = true
+		case events.CategoryUnarchived:
+			category.Archived = false
+		case events.CategoryDeleted:
+			category.Deleted = true
+		case events.CategoryMerged:
+			category.Deleted = true
+			category.MergedInto = e.TargetID
+		default:
+			return nil, fmt.Errorf("unexpected %s event in category %s", storedEvent.EventType, id)
+		}
+	}
+
+	category.Version = len(storedEvents) // Versión cargada, usada como versión esperada al guardar
+	return category, nil
+}
@@ -3,16 +3,19 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
 	"escama/domain"
 	"escama/domain/events"
 	"escama/infrastructure/eventstore"
+	"escama/infrastructure/snapshots"
 )
 
 // ExpenseRepository maneja la persistencia de agregados Expense vía Event Store
 type ExpenseRepository struct {
 	eventStore eventstore.EventStore
+	snapshots  snapshotter
 }
 
 func NewExpenseRepository(eventStore eventstore.EventStore) *ExpenseRepository {
@@ -21,37 +24,75 @@ func NewExpenseRepository(eventStore eventstore.EventStore) *ExpenseRepository {
 	}
 }
 
-// Save persiste los eventos uncommitted del agregado Expense
+// SetSnapshots habilita snapshots cada interval eventos (0 solo los usa al cargar)
+func (r *ExpenseRepository) SetSnapshots(store snapshots.Store, interval int) {
+	r.snapshots = snapshotter{store: store, interval: interval}
+}
+
+// Processed indica si el stream del gasto ya tiene eventos de un comando
+// con la clave de idempotencia dada
+func (r *ExpenseRepository) Processed(ctx context.Context, id, key string) (bool, error) {
+	return processedKey(ctx, r.eventStore, id, key)
+}
+
+// Save persiste los eventos uncommitted del agregado Expense, verificando que el stream
+// no haya cambiado desde la versión con la que fue cargado
 func (r *ExpenseRepository) Save(ctx context.Context, expense *domain.Expense) error {
 	uncommittedEvents := expense.UncommittedEvents()
 	if len(uncommittedEvents) == 0 {
 		return nil
 	}
 
-	if err := r.eventStore.Store(ctx, expense.ID, "Expense", uncommittedEvents); err != nil {
+	if err := r.eventStore.Store(ctx, expense.ID, "Expense", expense.Version, uncommittedEvents); err != nil {
 		return err
 	}
 
+	previousVersion := expense.Version
+	expense.Version += len(uncommittedEvents)
 	expense.ClearUncommittedEvents()
+
+	// El snapshot es una optimización: si falla, los eventos ya están guardados
+	if r.snapshots.due(previousVersion, expense.Version) {
+		if err := r.TakeSnapshot(ctx, expense); err != nil {
+			log.Printf("Failed to snapshot expense %s: %v", expense.ID, err)
+		}
+	}
 	return nil
 }
 
-// GetByID reconstruye un agregado Expense desde sus eventos
+// TakeSnapshot guarda el estado actual del agregado Expense en su versión persistida
+func (r *ExpenseRepository) TakeSnapshot(ctx context.Context, expense *domain.Expense) error {
+	return r.snapshots.save(ctx, expense.ID, "Expense", expense.Version, expense)
+}
+
+// GetByID reconstruye un agregado Expense desde su último snapshot y los eventos posteriores
 func (r *ExpenseRepository) GetByID(ctx context.Context, id string) (*domain.Expense, error) {
-	storedEvents, err := r.eventStore.Load(ctx, id)
+	var expense *domain.Expense
+
+	snapshotState := &domain.Expense{}
+	snapshotVersion, ok := r.snapshots.load(ctx, id, snapshotState)
+	if ok {
+		expense = snapshotState
+	}
+
+	storedEvents, err := r.eventStore.LoadFrom(ctx, id, snapshotVersion)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load events for expense %s: %w", id, err)
 	}
 
-	if len(storedEvents) == 0 {
+	if expense == nil && len(storedEvents) == 0 {
 		return nil, nil // No existe
 	}
 
 	// Reconstruir el agregado desde los eventos
-	var expense *domain.Expense
 	for _, storedEvent := range storedEvents {
-		switch storedEvent.EventType {
-		case "ExpenseCreated":
+		domainEvent, err := storedEvent.Decode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode event for expense %s: %w", id, err)
+		}
+
+		switch e := domainEvent.(type) {
+		case events.ExpenseCreated:
 			// Este es el evento de creación, crear el agregado
 			if expense == nil {
 				expense = &domain.Expense{
@@ -60,131 +101,60 @@ func (r *ExpenseRepository) GetByID(ctx context.Context, id string) (*domain.Exp
 			}
 
 			// Aplicar el evento al agregado
-			if err := r.applyExpenseCreated(expense, storedEvent); err != nil {
-				return nil, fmt.Errorf("failed to apply ExpenseCreated event: %w", err)
-			}
+			r.applyExpenseCreated(expense, e, storedEvent.OccurredAt)
 
-		case "ExpenseUpdated":
+		case events.ExpenseUpdated:
 			if expense == nil {
 				return nil, fmt.Errorf("received ExpenseUpdated event before ExpenseCreated for expense %s", id)
 			}
 
-			if err := r.applyExpenseUpdated(expense, storedEvent); err != nil {
-				return nil, fmt.Errorf("failed to apply ExpenseUpdated event: %w", err)
+			r.applyExpenseUpdated(expense, e)
+
+		case events.ExpenseTagAdded:
+			if expense == nil {
+				return nil, fmt.Errorf("received ExpenseTagAdded event before ExpenseCreated for expense %s", id)
+			}
+
+			expense.Tags = append(expense.Tags, e.Tag)
+
+		case events.ExpenseTagRemoved:
+			if expense == nil {
+				return nil, fmt.Errorf("received ExpenseTagRemoved event before ExpenseCreated for expense %s", id)
 			}
 
-		case "ExpenseDeleted":
+			expense.Tags = domain.WithoutTag(expense.Tags, e.Tag)
+
+		case events.ExpenseDeleted:
 			// Marcar como eliminado, pero mantener el agregado para propósitos de auditoría
 			// En una implementación más compleja podrías tener un flag IsDeleted
 		}
 	}
 
 	if expense != nil {
-		expense.ClearUncommittedEvents() // Los eventos ya están persistidos
+		expense.Version = snapshotVersion + len(storedEvents) // Versión cargada, usada como versión esperada al guardar
+		expense.ClearUncommittedEvents()                      // Los eventos ya están persistidos
 	}
 
 	return expense, nil
 }
 
-func (r *ExpenseRepository) applyExpenseCreated(expense *domain.Expense, storedEvent events.StoredEvent) error {
-	categoryID := r.getStringFromPayload(storedEvent.Payload, "CategoryID", "category_id")
-	amount := r.getFloat64FromPayload(storedEvent.Payload, "Amount", "amount")
-	description := r.getStringPtrFromPayload(storedEvent.Payload, "Description", "description")
-	date := r.getTimeFromPayload(storedEvent.Payload, "Date", "date")
-
+func (r *ExpenseRepository) applyExpenseCreated(expense *domain.Expense, event events.ExpenseCreated, occurredAt time.Time) {
+	date := event.Date
 	if date.IsZero() {
-		date = storedEvent.OccurredAt
+		date = occurredAt
 	}
 
-	expense.CategoryID = categoryID
-	expense.Amount = amount
-	expense.Description = description
+	expense.AccountID = event.AccountID
+	expense.CategoryID = event.CategoryID
+	expense.Amount = event.Amount
+	expense.Description = event.Description
 	expense.Date = date
-
-	return nil
 }
 
-func (r *ExpenseRepository) applyExpenseUpdated(expense *domain.Expense, storedEvent events.StoredEvent) error {
-	categoryID := r.getStringFromPayload(storedEvent.Payload, "CategoryID", "category_id")
-	amount := r.getFloat64FromPayload(storedEvent.Payload, "Amount", "amount")
-	description := r.getStringPtrFromPayload(storedEvent.Payload, "Description", "description")
-	date := r.getTimeFromPayload(storedEvent.Payload, "Date", "date")
-
-	expense.CategoryID = categoryID
-	expense.Amount = amount
-	expense.Description = description
-	expense.Date = date
-
-	return nil
-}
-
-// Helper functions
-func (r *ExpenseRepository) getStringFromPayload(payload map[string]interface{}, keys ...string) string {
-	for _, key := range keys {
-		if val, ok := payload[key]; ok {
-			if strVal, ok := val.(string); ok {
-				return strVal
-			}
-		}
-	}
-	return ""
-}
-
-func (r *ExpenseRepository) getFloat64FromPayload(payload map[string]interface{}, keys ...string) float64 {
-	for _, key := range keys {
-		if val, ok := payload[key]; ok {
-			switch v := val.(type) {
-			case float64:
-				return v
-			case float32:
-				return float64(v)
-			case int:
-				return float64(v)
-			case int64:
-				return float64(v)
-			}
-		}
-	}
-	return 0
-}
-
-func (r *ExpenseRepository) getStringPtrFromPayload(payload map[string]interface{}, keys ...string) *string {
-	for _, key := range keys {
-		if val, ok := payload[key]; ok {
-			if strVal, ok := val.(string); ok && strVal != "" {
-				return &strVal
-			}
-		}
-	}
-	return nil
-}
-
-func (r *ExpenseRepository) getTimeFromPayload(payload map[string]interface{}, keys ...string) time.Time {
-	for _, key := range keys {
-		if val, ok := payload[key]; ok {
-			switch v := val.(type) {
-			case string:
-				// RFC3339 format
-				if parsedDate, err := time.Parse(time.RFC3339, v); err == nil {
-					return parsedDate
-				}
-				// Otros formatos comunes
-				formats := []string{
-					"2006-01-02T15:04:05Z07:00",
-					"2006-01-02T15:04:05.000Z",
-					"2006-01-02T15:04:05",
-					"2006-01-02 15:04:05",
-					"2006-01-02",
-				}
-				for _, format := range formats {
-					if parsedDate, err := time.Parse(format, v); err == nil {
-						return parsedDate
-					}
-				}
-			case time.Time:
-				return v
-			}
-		}
-	}
-	return time.Time{}
+func (r *ExpenseRepository) applyExpenseUpdated(expense *domain.Expense, event events.ExpenseUpdated) {
+	expense.AccountID = event.AccountID
+	expense.CategoryID = event.CategoryID
+	expense.Amount = event.Amount
+	expense.Description = event.Description
+	expense.Date = event.Date
 }
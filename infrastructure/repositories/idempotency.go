@@ -0,0 +1,14 @@
+package repositories
+
+import (
+	"context"
+
+	"escama/infrastructure/eventstore"
+)
+
+// processedKey indica si algún evento del stream fue guardado por un comando con la clave
+// de idempotencia dada. Los Event Stores la buscan en su índice de claves, sin cargar el
+// stream ni sus eventos archivados.
+func processedKey(ctx context.Context, eventStore eventstore.EventStore, aggregateID, key string) (bool, error) {
+	return eventstore.HasIdempotencyKey(ctx, eventStore, aggregateID, key)
+}
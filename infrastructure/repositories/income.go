@@ -3,16 +3,19 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
 	"escama/domain"
 	"escama/domain/events"
 	"escama/infrastructure/eventstore"
+	"escama/infrastructure/snapshots"
 )
 
 // IncomeRepository maneja la persistencia de agregados Income vía Event Store
 type IncomeRepository struct {
 	eventStore eventstore.EventStore
+	snapshots  snapshotter
 }
 
 func NewIncomeRepository(eventStore eventstore.EventStore) *IncomeRepository {
@@ -21,37 +24,75 @@ func NewIncomeRepository(eventStore eventstore.EventStore) *IncomeRepository {
 	}
 }
 
-// Save persiste los eventos uncommitted del agregado Income
+// SetSnapshots habilita snapshots cada interval eventos (0 solo los usa al cargar)
+func (r *IncomeRepository) SetSnapshots(store snapshots.Store, interval int) {
+	r.snapshots = snapshotter{store: store, interval: interval}
+}
+
+// Processed indica si el stream del ingreso ya tiene eventos de un comando
+// con la clave de idempotencia dada
+func (r *IncomeRepository) Processed(ctx context.Context, id, key string) (bool, error) {
+	return processedKey(ctx, r.eventStore, id, key)
+}
+
+// Save persiste los eventos uncommitted del agregado Income, verificando que el stream
+// no haya cambiado desde la versión con la que fue cargado
 func (r *IncomeRepository) Save(ctx context.Context, income *domain.Income) error {
 	uncommittedEvents := income.UncommittedEvents()
 	if len(uncommittedEvents) == 0 {
 		return nil
 	}
 
-	if err := r.eventStore.Store(ctx, income.ID, "Income", uncommittedEvents); err != nil {
+	if err := r.eventStore.Store(ctx, income.ID, "Income", income.Version, uncommittedEvents); err != nil {
 		return err
 	}
 
+	previousVersion := income.Version
+	income.Version += len(uncommittedEvents)
 	income.ClearUncommittedEvents()
+
+	// El snapshot es una optimización: si falla, los eventos ya están guardados
+	if r.snapshots.due(previousVersion, income.Version) {
+		if err := r.TakeSnapshot(ctx, income); err != nil {
+			log.Printf("Failed to snapshot income %s: %v", income.ID, err)
+		}
+	}
 	return nil
 }
 
-// GetByID reconstruye un agregado Income desde sus eventos
+// TakeSnapshot guarda el estado actual del agregado Income en su versión persistida
+func (r *IncomeRepository) TakeSnapshot(ctx context.Context, income *domain.Income) error {
+	return r.snapshots.save(ctx, income.ID, "Income", income.Version, income)
+}
+
+// GetByID reconstruye un agregado Income desde su último snapshot y los eventos posteriores
 func (r *IncomeRepository) GetByID(ctx context.Context, id string) (*domain.Income, error) {
-	storedEvents, err := r.eventStore.Load(ctx, id)
+	var income *domain.Income
+
+	snapshotState := &domain.Income{}
+	snapshotVersion, ok := r.snapshots.load(ctx, id, snapshotState)
+	if ok {
+		income = snapshotState
+	}
+
+	storedEvents, err := r.eventStore.LoadFrom(ctx, id, snapshotVersion)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load events for income %s: %w", id, err)
 	}
 
-	if len(storedEvents) == 0 {
+	if income == nil && len(storedEvents) == 0 {
 		return nil, nil // No existe
 	}
 
 	// Reconstruir el agregado desde los eventos
-	var income *domain.Income
 	for _, storedEvent := range storedEvents {
-		switch storedEvent.EventType {
-		case "IncomeCreated":
+		domainEvent, err := storedEvent.Decode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode event for income %s: %w", id, err)
+		}
+
+		switch e := domainEvent.(type) {
+		case events.IncomeCreated:
 			// Este es el evento de creación, crear el agregado
 			if income == nil {
 				income = &domain.Income{
@@ -60,131 +101,60 @@ func (r *IncomeRepository) GetByID(ctx context.Context, id string) (*domain.Inco
 			}
 
 			// Aplicar el evento al agregado
-			if err := r.applyIncomeCreated(income, storedEvent); err != nil {
-				return nil, fmt.Errorf("failed to apply IncomeCreated event: %w", err)
-			}
+			r.applyIncomeCreated(income, e, storedEvent.OccurredAt)
 
-		case "IncomeUpdated":
+		case events.IncomeUpdated:
 			if income == nil {
 				return nil, fmt.Errorf("received IncomeUpdated event before IncomeCreated for income %s", id)
 			}
 
-			if err := r.applyIncomeUpdated(income, storedEvent); err != nil {
-				return nil, fmt.Errorf("failed to apply IncomeUpdated event: %w", err)
+			r.applyIncomeUpdated(income, e)
+
+		case events.IncomeTagAdded:
+			if income == nil {
+				return nil, fmt.Errorf("received IncomeTagAdded event before IncomeCreated for income %s", id)
+			}
+
+			income.Tags = append(income.Tags, e.Tag)
+
+		case events.IncomeTagRemoved:
+			if income == nil {
+				return nil, fmt.Errorf("received IncomeTagRemoved event before IncomeCreated for income %s", id)
 			}
 
-		case "IncomeDeleted":
+			income.Tags = domain.WithoutTag(income.Tags, e.Tag)
+
+		case events.IncomeDeleted:
 			// Marcar como eliminado, pero mantener el agregado para propósitos de auditoría
 			// En una implementación más compleja podrías tener un flag IsDeleted
 		}
 	}
 
 	if income != nil {
-		income.ClearUncommittedEvents() // Los eventos ya están persistidos
+		income.Version = snapshotVersion + len(storedEvents) // Versión cargada, usada como versión esperada al guardar
+		income.ClearUncommittedEvents()                      // Los eventos ya están persistidos
 	}
 
 	return income, nil
 }
 
-func (r *IncomeRepository) applyIncomeCreated(income *domain.Income, storedEvent events.StoredEvent) error {
-	categoryID := r.getStringFromPayload(storedEvent.Payload, "CategoryID", "category_id")
-	amount := r.getFloat64FromPayload(storedEvent.Payload, "Amount", "amount")
-	description := r.getStringPtrFromPayload(storedEvent.Payload, "Description", "description")
-	date := r.getTimeFromPayload(storedEvent.Payload, "Date", "date")
-
+func (r *IncomeRepository) applyIncomeCreated(income *domain.Income, event events.IncomeCreated, occurredAt time.Time) {
+	date := event.Date
 	if date.IsZero() {
-		date = storedEvent.OccurredAt
+		date = occurredAt
 	}
 
-	income.CategoryID = categoryID
-	income.Amount = amount
-	income.Description = description
+	income.AccountID = event.AccountID
+	income.CategoryID = event.CategoryID
+	income.Amount = event.Amount
+	income.Description = event.Description
 	income.Date = date
-
-	return nil
 }
 
-func (r *IncomeRepository) applyIncomeUpdated(income *domain.Income, storedEvent events.StoredEvent) error {
-	categoryID := r.getStringFromPayload(storedEvent.Payload, "CategoryID", "category_id")
-	amount := r.getFloat64FromPayload(storedEvent.Payload, "Amount", "amount")
-	description := r.getStringPtrFromPayload(storedEvent.Payload, "Description", "description")
-	date := r.getTimeFromPayload(storedEvent.Payload, "Date", "date")
-
-	income.CategoryID = categoryID
-	income.Amount = amount
-	income.Description = description
-	income.Date = date
-
-	return nil
-}
-
-// Helper functions
-func (r *IncomeRepository) getStringFromPayload(payload map[string]interface{}, keys ...string) string {
-	for _, key := range keys {
-		if val, ok := payload[key]; ok {
-			if strVal, ok := val.(string); ok {
-				return strVal
-			}
-		}
-	}
-	return ""
-}
-
-func (r *IncomeRepository) getFloat64FromPayload(payload map[string]interface{}, keys ...string) float64 {
-	for _, key := range keys {
-		if val, ok := payload[key]; ok {
-			switch v := val.(type) {
-			case float64:
-				return v
-			case float32:
-				return float64(v)
-			case int:
-				return float64(v)
-			case int64:
-				return float64(v)
-			}
-		}
-	}
-	return 0
-}
-
-func (r *IncomeRepository) getStringPtrFromPayload(payload map[string]interface{}, keys ...string) *string {
-	for _, key := range keys {
-		if val, ok := payload[key]; ok {
-			if strVal, ok := val.(string); ok && strVal != "" {
-				return &strVal
-			}
-		}
-	}
-	return nil
-}
-
-func (r *IncomeRepository) getTimeFromPayload(payload map[string]interface{}, keys ...string) time.Time {
-	for _, key := range keys {
-		if val, ok := payload[key]; ok {
-			switch v := val.(type) {
-			case string:
-				// RFC3339 format
-				if parsedDate, err := time.Parse(time.RFC3339, v); err == nil {
-					return parsedDate
-				}
-				// Otros formatos comunes
-				formats := []string{
-					"2006-01-02T15:04:05Z07:00",
-					"2006-01-02T15:04:05.000Z",
-					"2006-01-02T15:04:05",
-					"2006-01-02 15:04:05",
-					"2006-01-02",
-				}
-				for _, format := range formats {
-					if parsedDate, err := time.Parse(format, v); err == nil {
-						return parsedDate
-					}
-				}
-			case time.Time:
-				return v
-			}
-		}
-	}
-	return time.Time{}
+func (r *IncomeRepository) applyIncomeUpdated(income *domain.Income, event events.IncomeUpdated) {
+	income.AccountID = event.AccountID
+	income.CategoryID = event.CategoryID
+	income.Amount = event.Amount
+	income.Description = event.Description
+	income.Date = event.Date
 }
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"escama/infrastructure/snapshots"
+)
+
+// snapshotter encapsula la política de snapshots compartida por los repositorios.
+// Con store nil o interval 0 los repositorios siempre reproducen el stream completo.
+type snapshotter struct {
+	store    snapshots.Store
+	interval int
+}
+
+// load decodifica el último snapshot del agregado en state y devuelve su versión.
+// Un snapshot ilegible se ignora para que el agregado se reconstruya desde los eventos.
+func (s *snapshotter) load(ctx context.Context, aggregateID string, state interface{}) (int, bool) {
+	if s.store == nil {
+		return 0, false
+	}
+
+	snapshot, err := s.store.Get(ctx, aggregateID)
+	if err != nil {
+		log.Printf("Failed to load snapshot for %s, replaying full stream: %v", aggregateID, err)
+		return 0, false
+	}
+	if snapshot == nil {
+		return 0, false
+	}
+
+	if err := json.Unmarshal(snapshot.State, state); err != nil {
+		log.Printf("Failed to decode snapshot for %s, replaying full stream: %v", aggregateID, err)
+		return 0, false
+	}
+
+	return snapshot.Version, true
+}
+
+// due indica si al pasar de una versión a otra se cruzó un múltiplo del intervalo
+func (s *snapshotter) due(previousVersion, version int) bool {
+	if s.store == nil || s.interval <= 0 {
+		return false
+	}
+	return previousVersion/s.interval != version/s.interval
+}
+
+// save guarda el estado del agregado en la versión indicada
+func (s *snapshotter) save(ctx context.Context, aggregateID, aggregateType string, version int, state interface{}) error {
+	if s.store == nil {
+		return fmt.Errorf("snapshots are not configured")
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	return s.store.Save(ctx, snapshots.Snapshot{
+		AggregateID:   aggregateID,
+		AggregateType: aggregateType,
+		Version:       version,
+		State:         data,
+		TakenAt:       time.Now().UTC(),
+	})
+}
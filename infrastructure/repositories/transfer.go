@@ -0,0 +1,87 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"escama/domain"
+	"escama/domain/events"
+	"escama/infrastructure/eventstore"
+)
+
+// TransferRepository maneja la persistencia de agregados Transfer vía Event Store
+type TransferRepository struct {
+	eventStore eventstore.EventStore
+}
+
+func NewTransferRepository(eventStore eventstore.EventStore) *TransferRepository {
+	return &TransferRepository{
+		eventStore: eventStore,
+	}
+}
+
+// Processed indica si el stream de la transferencia ya tiene eventos de un comando con
+// la clave de idempotencia dada
+func (r *TransferRepository) Processed(ctx context.Context, id, key string) (bool, error) {
+	return processedKey(ctx, r.eventStore, id, key)
+}
+
+// Save persiste los eventos uncommitted del agregado Transfer, verificando que el stream
+// no haya cambiado desde la versión con la que fue cargado
+func (r *TransferRepository) Save(ctx context.Context, transfer *domain.Transfer) error {
+	uncommittedEvents := transfer.UncommittedEvents()
+	if len(uncommittedEvents) == 0 {
+		return nil
+	}
+
+	if err := r.eventStore.Store(ctx, transfer.ID, "Transfer", transfer.Version, uncommittedEvents); err != nil {
+		return err
+	}
+
+	transfer.Version += len(uncommittedEvents)
+	transfer.ClearUncommittedEvents()
+	return nil
+}
+
+// GetByID reconstruye un agregado Transfer desde sus eventos; devuelve nil si no existe
+func (r *TransferRepository) GetByID(ctx context.Context, id string) (*domain.Transfer, error) {
+	storedEvents, err := r.eventStore.Load(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load events for transfer %s: %w", id, err)
+	}
+	if len(storedEvents) == 0 {
+		return nil, nil
+	}
+
+	transfer := &domain.Transfer{ID: id}
+	for _, storedEvent := range storedEvents {
+		domainEvent, err := storedEvent.Decode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode event for transfer %s: %w", id, err)
+		}
+
+		switch e := domainEvent.(type) {
+		case events.TransferCreated:
+			transfer.FromAccountID = e.FromAccountID
+			transfer.ToAccountID = e.ToAccountID
+			transfer.Amount = e.Amount
+			transfer.ReceivedAmount = e.ReceivedAmount
+			transfer.Description = e.Description
+			transfer.Date = e.Date
+		case events.TransferUpdated:
+			transfer.FromAccountID = e.FromAccountID
+			transfer.ToAccountID = e.ToAccountID
+			transfer.Amount = e.Amount
+			transfer.ReceivedAmount = e.ReceivedAmount
+			transfer.Description = e.Description
+			transfer.Date = e.Date
+		case events.TransferDeleted:
+			// Se conserva el agregado para auditoría, como en gastos e ingresos
+		default:
+			return nil, fmt.Errorf("unexpected %s event in transfer %s", storedEvent.EventType, id)
+		}
+	}
+
+	transfer.Version = len(storedEvents) // Versión cargada, usada como versión esperada al guardar
+	return transfer, nil
+}
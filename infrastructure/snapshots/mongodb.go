@@ -0,0 +1,63 @@
+package snapshots
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore implementación de MongoDB del Store de snapshots
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoStore(database *mongo.Database) *MongoStore {
+	return &MongoStore{collection: database.Collection("snapshots")}
+}
+
+func (s *MongoStore) Get(ctx context.Context, aggregateID string) (*Snapshot, error) {
+	var snapshot Snapshot
+	err := s.collection.FindOne(ctx, bson.M{"_id": aggregateID}).Decode(&snapshot)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+func (s *MongoStore) Save(ctx context.Context, snapshot Snapshot) error {
+	_, err := s.collection.ReplaceOne(
+		ctx,
+		bson.M{"_id": snapshot.AggregateID},
+		snapshot,
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return nil
+}
+
+func (s *MongoStore) Delete(ctx context.Context, aggregateID string) error {
+	if _, err := s.collection.DeleteOne(ctx, bson.M{"_id": aggregateID}); err != nil {
+		return fmt.Errorf("failed to delete snapshot: %w", err)
+	}
+	return nil
+}
+
+func (s *MongoStore) DeleteAll(ctx context.Context, aggregateType string) error {
+	filter := bson.M{}
+	if aggregateType != "" {
+		filter["aggregate_type"] = aggregateType
+	}
+
+	if _, err := s.collection.DeleteMany(ctx, filter); err != nil {
+		return fmt.Errorf("failed to delete snapshots: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package snapshots
+
+import (
+	"context"
+	"fmt"
+)
+
+// sealedOwner dueño de la clave con que se cifran los snapshots. Los snapshots son
+// descartables: al eliminar la clave de un actor se invalidan y se regeneran.
+const sealedOwner = "snapshots"
+
+// Sealer cifra el estado de los snapshots
+type Sealer interface {
+	Seal(owner string, plaintext []byte) (string, error)
+	Open(sealed string) ([]byte, error)
+}
+
+// SealedStore decora un Store guardando el estado de cada snapshot cifrado
+type SealedStore struct {
+	Store
+	sealer Sealer
+}
+
+func NewSealedStore(store Store, sealer Sealer) *SealedStore {
+	return &SealedStore{Store: store, sealer: sealer}
+}
+
+// Get devuelve el snapshot con su estado descifrado. Un snapshot ilegible (por ejemplo,
+// guardado antes de activar el cifrado) devuelve un error y el agregado se reconstruye
+// desde sus eventos.
+func (s *SealedStore) Get(ctx context.Context, aggregateID string) (*Snapshot, error) {
+	snapshot, err := s.Store.Get(ctx, aggregateID)
+	if err != nil || snapshot == nil {
+		return snapshot, err
+	}
+
+	state, err := s.sealer.Open(string(snapshot.State))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt snapshot %s: %w", aggregateID, err)
+	}
+	snapshot.State = state
+	return snapshot, nil
+}
+
+func (s *SealedStore) Save(ctx context.Context, snapshot Snapshot) error {
+	sealed, err := s.sealer.Seal(sealedOwner, snapshot.State)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt snapshot %s: %w", snapshot.AggregateID, err)
+	}
+	snapshot.State = []byte(sealed)
+	return s.Store.Save(ctx, snapshot)
+}
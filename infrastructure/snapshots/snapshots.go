@@ -0,0 +1,76 @@
+package snapshots
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Snapshot guarda el estado serializado de un agregado en una versión dada, para no
+// tener que reproducir todo su stream al cargarlo
+type Snapshot struct {
+	AggregateID   string    `bson:"_id" json:"aggregate_id"`
+	AggregateType string    `bson:"aggregate_type" json:"aggregate_type"`
+	Version       int       `bson:"version" json:"version"`
+	State         []byte    `bson:"state" json:"state"`
+	TakenAt       time.Time `bson:"taken_at" json:"taken_at"`
+}
+
+// Store define el contrato para persistir snapshots. Los snapshots son descartables:
+// siempre pueden reconstruirse desde el Event Store.
+type Store interface {
+	Get(ctx context.Context, aggregateID string) (*Snapshot, error)
+	Save(ctx context.Context, snapshot Snapshot) error
+	Delete(ctx context.Context, aggregateID string) error
+	// DeleteAll elimina los snapshots de un tipo de agregado, o todos si aggregateType es ""
+	DeleteAll(ctx context.Context, aggregateType string) error
+}
+
+// InMemoryStore implementación en memoria del Store
+type InMemoryStore struct {
+	mu        sync.RWMutex
+	snapshots map[string]Snapshot
+}
+
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{snapshots: make(map[string]Snapshot)}
+}
+
+func (s *InMemoryStore) Get(ctx context.Context, aggregateID string) (*Snapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot, ok := s.snapshots[aggregateID]
+	if !ok {
+		return nil, nil
+	}
+	return &snapshot, nil
+}
+
+func (s *InMemoryStore) Save(ctx context.Context, snapshot Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshots[snapshot.AggregateID] = snapshot
+	return nil
+}
+
+func (s *InMemoryStore) Delete(ctx context.Context, aggregateID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.snapshots, aggregateID)
+	return nil
+}
+
+func (s *InMemoryStore) DeleteAll(ctx context.Context, aggregateType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, snapshot := range s.snapshots {
+		if aggregateType == "" || snapshot.AggregateType == aggregateType {
+			delete(s.snapshots, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package snapshots
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLiteStore implementación SQLite del Store de snapshots
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteStore(db *sql.DB) (*SQLiteStore, error) {
+	schema := `
+CREATE TABLE IF NOT EXISTS snapshots (
+	aggregate_id   TEXT PRIMARY KEY,
+	aggregate_type TEXT NOT NULL,
+	version        INTEGER NOT NULL,
+	state          BLOB NOT NULL,
+	taken_at       TEXT NOT NULL
+);`
+
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("failed to create snapshots table: %w", err)
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Get(ctx context.Context, aggregateID string) (*Snapshot, error) {
+	var snapshot Snapshot
+	var takenAt string
+
+	err := s.db.QueryRowContext(ctx,
+		`SELECT aggregate_id, aggregate_type, version, state, taken_at FROM snapshots WHERE aggregate_id = ?`,
+		aggregateID).Scan(&snapshot.AggregateID, &snapshot.AggregateType, &snapshot.Version, &snapshot.State, &takenAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find snapshot: %w", err)
+	}
+
+	snapshot.TakenAt, _ = time.Parse(time.RFC3339Nano, takenAt)
+	return &snapshot, nil
+}
+
+func (s *SQLiteStore) Save(ctx context.Context, snapshot Snapshot) error {
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO snapshots (aggregate_id, aggregate_type, version, state, taken_at) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (aggregate_id) DO UPDATE SET aggregate_type = excluded.aggregate_type,
+	version = excluded.version, state = excluded.state, taken_at = excluded.taken_at`,
+		snapshot.AggregateID, snapshot.AggregateType, snapshot.Version, snapshot.State,
+		snapshot.TakenAt.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Delete(ctx context.Context, aggregateID string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM snapshots WHERE aggregate_id = ?`, aggregateID); err != nil {
+		return fmt.Errorf("failed to delete snapshot: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) DeleteAll(ctx context.Context, aggregateType string) error {
+	var err error
+	if aggregateType == "" {
+		_, err = s.db.ExecContext(ctx, `DELETE FROM snapshots`)
+	} else {
+		_, err = s.db.ExecContext(ctx, `DELETE FROM snapshots WHERE aggregate_type = ?`, aggregateType)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete snapshots: %w", err)
+	}
+	return nil
+}
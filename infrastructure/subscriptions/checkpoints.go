@@ -0,0 +1,38 @@
+package subscriptions
+
+import (
+	"context"
+	"sync"
+)
+
+// CheckpointStore guarda, por suscriptor, la posición global del último evento procesado
+type CheckpointStore interface {
+	// Get devuelve 0 si el suscriptor todavía no procesó ningún evento
+	Get(ctx context.Context, name string) (int64, error)
+	Save(ctx context.Context, name string, position int64) error
+}
+
+// InMemoryCheckpointStore implementación en memoria del CheckpointStore
+type InMemoryCheckpointStore struct {
+	mu        sync.RWMutex
+	positions map[string]int64
+}
+
+func NewInMemoryCheckpointStore() *InMemoryCheckpointStore {
+	return &InMemoryCheckpointStore{positions: make(map[string]int64)}
+}
+
+func (s *InMemoryCheckpointStore) Get(ctx context.Context, name string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.positions[name], nil
+}
+
+func (s *InMemoryCheckpointStore) Save(ctx context.Context, name string, position int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.positions[name] = position
+	return nil
+}
@@ -0,0 +1,152 @@
+package subscriptions
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"escama/domain/events"
+	"escama/infrastructure/eventstore"
+)
+
+// checkpointEvery cada cuántos eventos se persiste el checkpoint durante un catch-up
+const checkpointEvery = 100
+
+// gapTimeout cuánto se espera a que aparezca una posición global faltante. En MongoDB una
+// escritura puede hacerse visible antes que otra con una posición menor ya reservada; si el
+// checkpoint saltara el hueco, ese evento no se entregaría nunca. Pasado este tiempo el
+// hueco se da por definitivo (eventos archivados o una escritura que no llegó a guardarse).
+const gapTimeout = time.Minute
+
+// Subscriber recibe, en orden de posición global, los eventos del Event Store.
+// La entrega es al menos una vez: tras un corte se pueden recibir de nuevo los eventos
+// posteriores al último checkpoint guardado.
+type Subscriber interface {
+	// Name identifica el checkpoint del suscriptor; no debe cambiar entre versiones
+	Name() string
+	HandleEvent(ctx context.Context, event events.StoredEvent) error
+}
+
+// Manager lee el Event Store desde el checkpoint de cada suscriptor y le entrega los
+// eventos nuevos, guardando la posición alcanzada
+type Manager struct {
+	mu          sync.Mutex
+	eventStore  eventstore.EventStore
+	checkpoints CheckpointStore
+	subscribers []Subscriber
+}
+
+func NewManager(eventStore eventstore.EventStore, checkpoints CheckpointStore) *Manager {
+	return &Manager{
+		eventStore:  eventStore,
+		checkpoints: checkpoints,
+	}
+}
+
+// Register agrega un suscriptor; recibirá los eventos desde su último checkpoint
+func (m *Manager) Register(subscriber Subscriber) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.subscribers = append(m.subscribers, subscriber)
+}
+
+// CatchUp entrega a cada suscriptor los eventos pendientes. Si un suscriptor falla se
+// guarda su avance hasta el evento anterior y se continúa con los demás.
+func (m *Manager) CatchUp(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var firstErr error
+	for _, subscriber := range m.subscribers {
+		if err := m.catchUp(ctx, subscriber); err != nil {
+			log.Printf("Subscription %s stopped: %v", subscriber.Name(), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (m *Manager) catchUp(ctx context.Context, subscriber Subscriber) error {
+	name := subscriber.Name()
+
+	checkpoint, err := m.checkpoints.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	position := checkpoint
+	pending := 0
+	err = m.eventStore.Stream(ctx, eventstore.StreamFilter{FromPosition: checkpoint}, func(storedEvent events.StoredEvent) error {
+		// El checkpoint solo avanza sobre posiciones contiguas: ante un hueco reciente se
+		// corta el ciclo y los eventos siguientes se leen de nuevo en el próximo
+		if storedEvent.GlobalPosition != position+1 && time.Since(storedEvent.OccurredAt) < gapTimeout {
+			return eventstore.ErrStopStream
+		}
+
+		if err := subscriber.HandleEvent(ctx, storedEvent); err != nil {
+			return fmt.Errorf("failed to handle event %s at position %d: %w", storedEvent.ID, storedEvent.GlobalPosition, err)
+		}
+
+		position = storedEvent.GlobalPosition
+		pending++
+		if pending == checkpointEvery {
+			pending = 0
+			return m.checkpoints.Save(ctx, name, position)
+		}
+		return nil
+	})
+
+	if position != checkpoint {
+		if saveErr := m.checkpoints.Save(ctx, name, position); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+	return err
+}
+
+// Run repite CatchUp cada interval hasta que se cancele el contexto, para recoger los
+// eventos que escriben otros procesos
+func (m *Manager) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Los errores ya se registran en CatchUp; se reintenta en el siguiente ciclo
+		m.CatchUp(ctx)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reset vuelve a 0 el checkpoint de un suscriptor para que reciba todo el historial
+func (m *Manager) Reset(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.checkpoints.Save(ctx, name, 0)
+}
+
+// Positions devuelve el checkpoint de cada suscriptor registrado
+func (m *Manager) Positions(ctx context.Context) (map[string]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	positions := make(map[string]int64, len(m.subscribers))
+	for _, subscriber := range m.subscribers {
+		position, err := m.checkpoints.Get(ctx, subscriber.Name())
+		if err != nil {
+			return nil, err
+		}
+		positions[subscriber.Name()] = position
+	}
+	return positions, nil
+}
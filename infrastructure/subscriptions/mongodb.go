@@ -0,0 +1,51 @@
+package subscriptions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoCheckpointStore implementación de MongoDB del CheckpointStore
+type MongoCheckpointStore struct {
+	collection *mongo.Collection
+}
+
+type checkpointDocument struct {
+	Name      string    `bson:"_id"`
+	Position  int64     `bson:"position"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+func NewMongoCheckpointStore(database *mongo.Database) *MongoCheckpointStore {
+	return &MongoCheckpointStore{collection: database.Collection("checkpoints")}
+}
+
+func (s *MongoCheckpointStore) Get(ctx context.Context, name string) (int64, error) {
+	var checkpoint checkpointDocument
+	err := s.collection.FindOne(ctx, bson.M{"_id": name}).Decode(&checkpoint)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to find checkpoint: %w", err)
+	}
+	return checkpoint.Position, nil
+}
+
+func (s *MongoCheckpointStore) Save(ctx context.Context, name string, position int64) error {
+	_, err := s.collection.ReplaceOne(
+		ctx,
+		bson.M{"_id": name},
+		checkpointDocument{Name: name, Position: position, UpdatedAt: time.Now().UTC()},
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package subscriptions
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SQLiteCheckpointStore implementación SQLite del CheckpointStore
+type SQLiteCheckpointStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteCheckpointStore(db *sql.DB) (*SQLiteCheckpointStore, error) {
+	schema := `
+CREATE TABLE IF NOT EXISTS checkpoints (
+	name       TEXT PRIMARY KEY,
+	position   INTEGER NOT NULL,
+	updated_at TEXT NOT NULL
+);`
+
+	if _, err := db.Exec(schema); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoints table: %w", err)
+	}
+
+	return &SQLiteCheckpointStore{db: db}, nil
+}
+
+func (s *SQLiteCheckpointStore) Get(ctx context.Context, name string) (int64, error) {
+	var position int64
+	err := s.db.QueryRowContext(ctx, `SELECT position FROM checkpoints WHERE name = ?`, name).Scan(&position)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to find checkpoint: %w", err)
+	}
+	return position, nil
+}
+
+func (s *SQLiteCheckpointStore) Save(ctx context.Context, name string, position int64) error {
+	_, err := s.db.ExecContext(ctx, `
+INSERT INTO checkpoints (name, position, updated_at) VALUES (?, ?, ?)
+ON CONFLICT (name) DO UPDATE SET position = excluded.position, updated_at = excluded.updated_at`,
+		name, position, time.Now().UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	return nil
+}
@@ -8,6 +8,8 @@ import (
 
 	"escama/application"
 	"escama/application/commands"
+	"escama/domain/events"
+	"escama/domain/money"
 	"escama/infrastructure/eventbus"
 	"escama/infrastructure/eventstore"
 	"escama/infrastructure/repositories"
@@ -18,24 +20,24 @@ type categoryCommandAdapter struct {
 	handler *commands.CreateCategoryHandler
 }
 
-func (a *categoryCommandAdapter) Handle(cmd application.Command) error {
+func (a *categoryCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
 	categoryCmd, ok := cmd.(commands.CreateCategoryCommand)
 	if !ok {
 		return fmt.Errorf("invalid command type for category handler")
 	}
-	return a.handler.Handle(context.Background(), categoryCmd)
+	return a.handler.Handle(ctx, categoryCmd)
 }
 
 type expenseCommandAdapter struct {
 	handler *commands.CreateExpenseHandler
 }
 
-func (a *expenseCommandAdapter) Handle(cmd application.Command) error {
+func (a *expenseCommandAdapter) Handle(ctx context.Context, cmd application.Command) error {
 	expenseCmd, ok := cmd.(commands.CreateExpenseCommand)
 	if !ok {
 		return fmt.Errorf("invalid command type for expense handler")
 	}
-	return a.handler.Handle(context.Background(), expenseCmd)
+	return a.handler.Handle(ctx, expenseCmd)
 }
 
 func main() {
@@ -45,55 +47,89 @@ func main() {
 	// Configurar infrastructure
 	eventStore := eventstore.NewInMemoryEventStore()
 	eventPublisher := eventbus.NewInMemoryEventPublisher()
+	outboxRelay := eventbus.NewOutboxRelay(eventStore, eventPublisher.Publish)
 
 	categoryRepo := repositories.NewCategoryRepository(eventStore)
 	expenseRepo := repositories.NewExpenseRepository(eventStore)
+	accountRepo := repositories.NewAccountRepository(eventStore)
 
 	// Configurar application layer
 	commandBus := application.NewCommandBus()
 
 	// Registrar handlers con adapters
 	createCategoryHandler := &commands.CreateCategoryHandler{
-		Save:    categoryRepo.Save,
-		Publish: eventPublisher.Publish,
+		Save:         categoryRepo.Save,
+		Processed:    categoryRepo.Processed,
+		LoadCategory: categoryRepo.GetByID,
 	}
 	commandBus.Register(commands.CreateCategoryCommand{}, &categoryCommandAdapter{handler: createCategoryHandler})
 
 	createExpenseHandler := &commands.CreateExpenseHandler{
-		Save:    expenseRepo.Save,
-		Publish: eventPublisher.Publish,
+		Save:         expenseRepo.Save,
+		Processed:    expenseRepo.Processed,
+		LoadAccount:  accountRepo.GetByID,
+		LoadCategory: categoryRepo.GetByID,
 	}
 	commandBus.Register(commands.CreateExpenseCommand{}, &expenseCommandAdapter{handler: createExpenseHandler})
 
+	// Los eventos guardados registran que vienen de esta demo
+	ctx := events.WithMetadata(context.Background(), events.Metadata{Source: "escama-demo"})
+
 	// Demostrar Event Sourcing en acción
 	fmt.Println("\n📝 Creating categories...")
 
+	categoryID := "demo-category"
 	createCategoryCmd := commands.CreateCategoryCommand{
+		ID:   &categoryID,
 		Name: "Alimentación",
+		Kind: "expense",
 	}
-	if err := commandBus.Dispatch(createCategoryCmd); err != nil {
+	if err := commandBus.Dispatch(ctx, createCategoryCmd); err != nil {
 		log.Fatalf("Error creating category: %v", err)
 	}
 
 	createCategoryCmd2 := commands.CreateCategoryCommand{
 		Name: "Transporte",
 	}
-	if err := commandBus.Dispatch(createCategoryCmd2); err != nil {
+	if err := commandBus.Dispatch(ctx, createCategoryCmd2); err != nil {
 		log.Fatalf("Error creating category: %v", err)
 	}
 
+	fmt.Println("\n🏦 Creating account...")
+
+	accountID := "demo-account"
+	createAccountHandler := &commands.CreateAccountHandler{
+		Save:      accountRepo.Save,
+		Processed: accountRepo.Processed,
+	}
+	if err := createAccountHandler.Handle(ctx, commands.CreateAccountCommand{
+		ID:       &accountID,
+		Name:     "Tarjeta",
+		Kind:     "card",
+		Currency: "USD",
+	}); err != nil {
+		log.Fatalf("Error creating account: %v", err)
+	}
+
 	fmt.Println("\n💰 Creating expenses...")
 
 	createExpenseCmd := commands.CreateExpenseCommand{
-		CategoryID:  "some-category-id", // En una app real obtendrías el ID de la category creada
-		Amount:      25.50,
+		AccountID:   accountID,
+		CategoryID:  categoryID,
+		Amount:      money.New(2550, "USD"),
 		Description: stringPtr("Almuerzo en restaurante"),
 		Date:        time.Now(),
 	}
-	if err := commandBus.Dispatch(createExpenseCmd); err != nil {
+	if err := commandBus.Dispatch(ctx, createExpenseCmd); err != nil {
 		log.Fatalf("Error creating expense: %v", err)
 	}
 
+	// Publicar los eventos que quedaron en el outbox
+	fmt.Println("\n📢 Publishing events...")
+	if _, err := outboxRelay.Relay(ctx); err != nil {
+		log.Fatalf("Error publishing events: %v", err)
+	}
+
 	fmt.Println("\n✅ Event Sourcing demo completed!")
 	fmt.Println("\nTu arquitectura Event Sourcing está funcionando:")
 	fmt.Println("✓ Domain events generados por agregados")
@@ -7,6 +7,8 @@ import (
 	"os"
 	"time"
 
+	"escama/domain/events"
+	"escama/domain/money"
 	"escama/infrastructure/eventstore"
 	"escama/infrastructure/projections"
 
@@ -45,7 +47,7 @@ func main() {
 	defer mongoClient.Disconnect(ctx)
 
 	// Configurar proyecciones
-	projectionStore := projections.NewProjectionStore(mongoClient, "escama_read")
+	projectionStore := projections.NewMongoProjectionStore(mongoClient, "escama_read")
 
 	fmt.Println("✅ Conexiones establecidas")
 
@@ -62,31 +64,30 @@ func main() {
 		fmt.Println("✅ Proyecciones limpiadas")
 	}
 
-	// Obtener todos los eventos del Event Store
-	fmt.Println("📖 Obteniendo eventos del Event Store...")
-	storedEvents, err := eventStore.GetAllEvents(ctx, nil, nil)
-	if err != nil {
-		log.Fatalf("❌ Error obteniendo eventos: %v", err)
-	}
+	// Recorrer los eventos del Event Store por lotes, sin cargarlos todos en memoria
+	fmt.Println("📖 Leyendo eventos del Event Store...")
 
-	fmt.Printf("📊 Se encontraron %d eventos para procesar\n", len(storedEvents))
-
-	// Procesar cada evento para actualizar las proyecciones
 	processed := 0
 	errors := 0
+	read := 0
 
-	for i, storedEvent := range storedEvents {
+	err = eventStore.Stream(ctx, eventstore.StreamFilter{}, func(storedEvent events.StoredEvent) error {
+		read++
 		if err := projectionStore.ProcessEvent(ctx, storedEvent); err != nil {
-			log.Printf("⚠️  Error procesando evento %d (%s): %v", i+1, storedEvent.EventType, err)
+			log.Printf("⚠️  Error procesando evento %d (%s): %v", read, storedEvent.EventType, err)
 			errors++
 		} else {
 			processed++
 		}
 
-		// Mostrar progreso cada 10 eventos
-		if (i+1)%10 == 0 || i+1 == len(storedEvents) {
-			fmt.Printf("📈 Progreso: %d/%d eventos procesados\n", i+1, len(storedEvents))
+		// Mostrar progreso cada 100 eventos
+		if read%100 == 0 {
+			fmt.Printf("📈 Progreso: %d eventos procesados\n", read)
 		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("❌ Error leyendo eventos: %v", err)
 	}
 
 	fmt.Println("🎉 Migración completada!")
@@ -119,24 +120,27 @@ func clearProjections(ctx context.Context, mongoClient *mongo.Client) error {
 	return nil
 }
 
-func showProjectionStats(ctx context.Context, projectionStore *projections.ProjectionStore) error {
+func showProjectionStats(ctx context.Context, projectionStore projections.ProjectionStore) error {
 	// Obtener estadísticas de movimientos
-	movements, total, err := projectionStore.GetMovements(ctx, nil, nil, 0, 0)
+	movements, total, err := projectionStore.GetMovements(ctx, nil, nil, "", 0, 0)
 	if err != nil {
 		return fmt.Errorf("error getting movements: %w", err)
 	}
 
 	incomeCount := 0
 	expenseCount := 0
-	var totalIncome, totalExpense float64
+	var totalIncome, totalExpense money.Money
 
 	for _, movement := range movements {
 		if movement.Type == "income" {
 			incomeCount++
-			totalIncome += movement.Amount
+			totalIncome, err = totalIncome.Add(movement.Amount)
 		} else if movement.Type == "expense" {
 			expenseCount++
-			totalExpense += movement.Amount
+			totalExpense, err = totalExpense.Add(movement.Amount)
+		}
+		if err != nil {
+			return fmt.Errorf("error adding movement %s: %w", movement.ID, err)
 		}
 	}
 
@@ -147,9 +151,13 @@ func showProjectionStats(ctx context.Context, projectionStore *projections.Proje
 	}
 
 	fmt.Printf("📋 Movimientos totales: %d\n", total)
-	fmt.Printf("💰 Ingresos: %d (₲%.0f)\n", incomeCount, totalIncome)
-	fmt.Printf("💸 Gastos: %d (₲%.0f)\n", expenseCount, totalExpense)
-	fmt.Printf("📈 Balance neto: ₲%.0f\n", totalIncome-totalExpense)
+	fmt.Printf("💰 Ingresos: %d (%s)\n", incomeCount, totalIncome)
+	fmt.Printf("💸 Gastos: %d (%s)\n", expenseCount, totalExpense)
+	netBalance, err := totalIncome.Sub(totalExpense)
+	if err != nil {
+		return fmt.Errorf("error computing net balance: %w", err)
+	}
+	fmt.Printf("📈 Balance neto: %s\n", netBalance)
 	fmt.Printf("🏷️  Categorías: %d\n", len(categories))
 
 	return nil